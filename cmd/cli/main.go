@@ -1,12 +1,15 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
 	"time"
 
@@ -20,15 +23,14 @@ import (
 	"github.com/andygeiss/go-agent/internal/domain/tooling"
 )
 
+// defaultSystemPrompt is rendered with the current date, the user name, and the registered tools.
 const defaultSystemPrompt = `You are a helpful AI assistant with access to tools and long-term memory.
+You are talking to {{.user}}. Today is {{.date}}.
 
 Available tools:
-- index.scan: Scan directories and create a snapshot of files
-- index.changed_since: Get files changed since a timestamp
-- index.diff_snapshot: Compare two snapshots for changes
-- memory_get: Retrieve a specific memory note by ID
-- memory_search: Search your long-term memory for relevant notes
-- memory_write: Save important information to long-term memory
+{{- range .tools}}
+- {{.Name}}: {{.Description}}
+{{- end}}
 
 When the user shares preferences, important facts, or asks you to remember something,
 use memory_write to save it. When they refer to past conversations or preferences,
@@ -39,62 +41,184 @@ to scan directories, compare snapshots, and identify what has changed.
 
 Be concise, helpful, and proactive about using your memory and indexing capabilities.`
 
+// pingTimeout bounds the reachability check of each endpoint at startup.
+const pingTimeout = 5 * time.Second
+
+// dryRunPreviewLimit is the number of paths printed by "index scan --dry-run".
+const dryRunPreviewLimit = 10
+
+// largeFileThreshold is the size in bytes above which "index scan" warns about a file.
+const largeFileThreshold = 1 << 20
+
+// largeFileWarningLimit is the number of large files listed by "index scan".
+const largeFileWarningLimit = 5
+
+// interruptWindow is how long after a canceled response a second Ctrl+C exits the CLI.
+const interruptWindow = 2 * time.Second
+
+// taskHistorySize is the number of finished tasks kept for the "trace" command.
+const taskHistorySize = 10
+
+// inMemorySnapshotLimit is the number of snapshots kept when indexing without -index-file.
+const inMemorySnapshotLimit = 20
+
+// validationRetries is the number of rounds per task in which the model may fix invalid tool arguments.
+const validationRetries = 2
+
+// loopDetectionWindow is the number of identical tool call iterations after which a task is stopped.
+const loopDetectionWindow = 3
+
+// memoryHistorySize is the number of earlier versions kept per memory note.
+const memoryHistorySize = 5
+
+// maxToolArgumentBytes is the size above which tool call arguments are rejected unread.
+const maxToolArgumentBytes = 256 << 10
+
+// traceValueLimit is the length at which tool arguments and results are cut in the "trace" output.
+const traceValueLimit = 80
+
+// ID generators for memory notes and index snapshots.
+var (
+	noteIDs     = agent.NewUUIDGenerator("note")
+	snapshotIDs = agent.NewUUIDGenerator("snap")
+)
+
 func main() {
 	// Parse command line flags (alphabetically sorted)
 	chattingModel := flag.String("chatting-model", os.Getenv("OPENAI_CHAT_MODEL"), "Model name to use")
 	chattingURL := flag.String("chatting-url", "http://localhost:1234", "OpenAI API base URL")
 	embeddingModel := flag.String("embedding-model", os.Getenv("OPENAI_EMBED_MODEL"), "Embedding model name (empty = no embeddings)")
 	embeddingURL := flag.String("embedding-url", getEnvOrDefault("OPENAI_EMBED_URL", "http://localhost:1234"), "Embedding API URL (defaults to -chatting-url if not set)")
+	historyFile := flag.String("history-file", "", "File to keep the input history in across sessions (empty = current session only)")
 	indexFile := flag.String("index-file", "", "JSON file for persistent indexing (empty = in-memory)")
+	listTools := flag.Bool("list-tools", false, "Expose the list_tools tool so the model can discover its tools")
 	maxIterations := flag.Int("max-iterations", 10, "Maximum iterations per task")
 	maxMessages := flag.Int("max-messages", 50, "Maximum messages to retain (0 = unlimited)")
 	memoryFile := flag.String("memory-file", "", "JSON file for persistent memory (empty = in-memory)")
+	outputFormat := flag.String("output", outputText, "Output format: text or json")
 	parallelTools := flag.Bool("parallel-tools", false, "Enable parallel tool execution")
+	promptCaching := flag.Bool("prompt-caching", false, "Mark the system prompt for provider-side prompt caching (cache_control)")
+	promptText := flag.String("prompt", "", "Run a single prompt, print the response, and exit (stdin is read if it is not a terminal)")
+	recall := flag.Int("recall", 0, "Number of relevant memories to add to the system prompt before each message (0 = off)")
+	sessionFile := flag.String("session-file", "", "JSON file to save the conversation and stats to and resume them from (empty = no persistence)")
 	verbose := flag.Bool("verbose", false, "Show detailed metrics after each response")
 	flag.Parse()
 
-	// Print banner
 	embURL := *embeddingURL
 	if embURL == "" {
 		embURL = *chattingURL
 	}
-	printBanner(*chattingURL, *chattingModel, embURL, *embeddingModel, *maxIterations, *maxMessages, *memoryFile, *indexFile, *parallelTools)
+
+	out, err := newPrinter(*outputFormat, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Determine whether to run a single prompt instead of the interactive chat
+	prompt, oneShot, err := oneShotPrompt(*promptText, os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading prompt: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Setup infrastructure
 	infrastructure := setupInfrastructure(*chattingURL, *chattingModel, *memoryFile, *indexFile, *verbose, *parallelTools, embURL, *embeddingModel)
+	infrastructure.llmClient.WithPromptCaching(*promptCaching)
+
+	// Scope the notes written in this run to a session, so they can be summarized later
+	sessionID := fmt.Sprintf("session-%d", time.Now().Unix())
+	infrastructure.memoryToolSvc.WithSessionID(sessionID)
+
+	// Print banner with the reachability of each endpoint
+	if !oneShot && *outputFormat != outputJSON {
+		chattingStatus := reachability(infrastructure.llmClient)
+		embeddingStatus := ""
+		if infrastructure.embeddingClient != nil {
+			embeddingStatus = reachability(infrastructure.embeddingClient)
+		}
+		printBanner(*chattingURL, chattingStatus, *chattingModel, embURL, embeddingStatus, *embeddingModel, *maxIterations, *maxMessages, *memoryFile, *indexFile, *parallelTools)
+	}
+	if *listTools {
+		registerListTools(infrastructure.toolExecutor)
+	}
 
 	// Create the agent with options
 	agentInstance := agent.NewAgent(
 		"demo-agent",
-		defaultSystemPrompt,
+		"",
+		agent.WithSystemPromptTemplate(defaultSystemPrompt, map[string]any{
+			"date":  time.Now().Format("Monday, 2006-01-02"),
+			"tools": infrastructure.toolExecutor.GetToolDefinitions(),
+			"user":  getEnvOrDefault("USER", "the user"),
+		}),
 		agent.WithMaxIterations(*maxIterations),
 		agent.WithMaxMessages(*maxMessages),
+		agent.WithTaskHistory(taskHistorySize),
 		agent.WithMetadata(agent.Metadata{
 			"created_by": "cli",
 			"model":      *chattingModel,
-			"session_id": fmt.Sprintf("session-%d", time.Now().Unix()),
+			"session_id": sessionID,
 		}),
 	)
 
-	// Create use cases from all domain contexts
-	uc := createUseCases(infrastructure, &agentInstance)
+	// Resume the previous session, if any
+	var session *outbound.SessionStore
+	if *sessionFile != "" {
+		session = outbound.NewSessionStore(*sessionFile)
+		loadSession(session, &agentInstance, os.Stderr)
+	}
+	saveTurn := func() { saveSession(session, &agentInstance, os.Stderr) }
 
-	// Run the interactive chat loop
-	runInteractiveChat(uc, *verbose)
+	// Create use cases from all domain contexts
+	uc := createUseCases(infrastructure, &agentInstance, *recall)
+
+	// Run a single prompt or the interactive chat loop
+	if oneShot {
+		code := runOneShot(context.Background(), uc.sendMessage, prompt, *outputFormat, os.Stdout, os.Stderr, *verbose)
+		saveTurn()
+		closeAll(context.Background(), infrastructure.closers(), os.Stderr)
+		os.Exit(code)
+	}
+	history := loadHistory(*historyFile, os.Stderr)
+	runInteractiveChat(uc, inbound.NewLineReader(os.Stdin, os.Stdout, history), notifyInterrupt, out, *verbose, saveTurn)
+	saveTurn()
+	if *historyFile != "" {
+		if err := history.Save(*historyFile); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not save history: %v\n", err)
+		}
+	}
+	closeAll(context.Background(), infrastructure.closers(), os.Stderr)
 }
 
 // infrastructure holds all infrastructure components.
 type infrastructure struct {
-	dispatcher    messaging.Dispatcher
-	indexService  *indexing.Service
-	indexToolSvc  *tooling.IndexToolService
-	llmClient     *outbound.OpenAIClient
-	logger        *slog.Logger
-	memoryStore   *outbound.MemoryStore
-	memoryToolSvc *tooling.MemoryToolService
-	publisher     *outbound.EventPublisher
-	taskService   *agent.TaskService
-	toolExecutor  *outbound.ToolExecutor
+	dispatcher      messaging.Dispatcher
+	embeddingClient *outbound.OpenAIEmbeddingClient
+	indexService    *indexing.Service
+	indexStore      *outbound.IndexStore
+	indexToolSvc    *tooling.IndexToolService
+	llmClient       *outbound.OpenAIClient
+	logger          *slog.Logger
+	memoryService   *memorizing.Service
+	memoryStore     *outbound.MemoryStore
+	memoryToolSvc   *tooling.MemoryToolService
+	publisher       *outbound.EventPublisher
+	taskService     *agent.TaskService
+	toolExecutor    *outbound.ToolExecutor
+}
+
+// messageSender sends a message to the agent (implemented by chatting.SendMessageUseCase).
+type messageSender interface {
+	Execute(ctx context.Context, input chatting.SendMessageInput) (chatting.SendMessageOutput, error)
+}
+
+// notifyFunc derives a context that is canceled when the user interrupts the running response.
+type notifyFunc func(ctx context.Context) (context.Context, context.CancelFunc)
+
+// pinger checks whether an endpoint is reachable.
+type pinger interface {
+	Ping(ctx context.Context) error
 }
 
 // useCases holds all domain use cases for the CLI.
@@ -102,6 +226,7 @@ type useCases struct {
 	// chatting context
 	clearConversation *chatting.ClearConversationUseCase
 	getAgentStats     *chatting.GetAgentStatsUseCase
+	getLastTaskTrace  *chatting.GetLastTaskTraceUseCase
 	sendMessage       *chatting.SendMessageUseCase
 
 	// indexing context
@@ -112,15 +237,20 @@ type useCases struct {
 	getNote     *memorizing.GetNoteUseCase
 	searchNotes *memorizing.SearchNotesUseCase
 	writeNote   *memorizing.WriteNoteUseCase
+
+	// tooling context
+	toolExecutor agent.ToolExecutor
 }
 
 // createUseCases initializes all domain use cases.
-func createUseCases(infra *infrastructure, ag *agent.Agent) *useCases {
+// A positive recallTopK adds that many relevant memories to the system prompt before each message.
+func createUseCases(infra *infrastructure, ag *agent.Agent, recallTopK int) *useCases {
 	return &useCases{
 		// chatting context
 		clearConversation: chatting.NewClearConversationUseCase(ag),
 		getAgentStats:     chatting.NewGetAgentStatsUseCase(ag),
-		sendMessage:       chatting.NewSendMessageUseCase(infra.taskService, ag),
+		getLastTaskTrace:  chatting.NewGetLastTaskTraceUseCase(ag),
+		sendMessage:       chatting.NewSendMessageUseCase(infra.taskService, ag).WithAutoRecall(infra.memoryService, recallTopK),
 
 		// indexing context
 		indexService: infra.indexService,
@@ -130,6 +260,9 @@ func createUseCases(infra *infrastructure, ag *agent.Agent) *useCases {
 		getNote:     memorizing.NewGetNoteUseCase(infra.memoryStore),
 		searchNotes: memorizing.NewSearchNotesUseCase(infra.memoryStore),
 		writeNote:   memorizing.NewWriteNoteUseCase(infra.memoryStore),
+
+		// tooling context
+		toolExecutor: infra.toolExecutor,
 	}
 }
 
@@ -206,9 +339,28 @@ func parseTagList(s string) []string {
 	return result
 }
 
-// generateNoteID creates a unique note ID.
-func generateNoteID() string {
-	return fmt.Sprintf("note-%d", time.Now().UnixNano())
+// formatExtensionBreakdown renders per-extension file counts like "142 .go, 30 .md",
+// most frequent first. Files without an extension are shown as "(none)".
+func formatExtensionBreakdown(breakdown map[string]int) string {
+	exts := make([]string, 0, len(breakdown))
+	for ext := range breakdown {
+		exts = append(exts, ext)
+	}
+	sort.Slice(exts, func(i, j int) bool {
+		if breakdown[exts[i]] != breakdown[exts[j]] {
+			return breakdown[exts[i]] > breakdown[exts[j]]
+		}
+		return exts[i] < exts[j]
+	})
+
+	parts := make([]string, len(exts))
+	for i, ext := range exts {
+		if ext == "" {
+			ext = "(none)"
+		}
+		parts[i] = fmt.Sprintf("%d %s", breakdown[exts[i]], ext)
+	}
+	return strings.Join(parts, ", ")
 }
 
 // getEnvOrDefault returns the environment variable value or a default if not set.
@@ -220,7 +372,7 @@ func getEnvOrDefault(key, defaultVal string) string {
 }
 
 // handleCommand processes special commands. Returns (handled, shouldBreak).
-func handleCommand(ctx context.Context, input string, uc *useCases) (bool, bool) {
+func handleCommand(ctx context.Context, input string, uc *useCases, out printer) (bool, bool) {
 	parts := strings.Fields(input)
 	if len(parts) == 0 {
 		return false, false
@@ -229,14 +381,17 @@ func handleCommand(ctx context.Context, input string, uc *useCases) (bool, bool)
 	cmd := strings.ToLower(parts[0])
 	switch cmd {
 	case "clear":
-		uc.clearConversation.Execute()
-		fmt.Println("🗑️  Conversation cleared.")
-		fmt.Println()
+		if len(parts) > 1 && strings.EqualFold(parts[1], "all") {
+			uc.clearConversation.ClearAll()
+			out.success("🗑️  Conversation cleared (including pinned messages).", "")
+		} else {
+			uc.clearConversation.Execute()
+			out.success("🗑️  Conversation cleared.", "")
+		}
 		return true, false
 
 	case "exit", "quit":
-		printFinalStats(uc.getAgentStats)
-		fmt.Println("Goodbye! 👋")
+		out.farewell(uc.getAgentStats.Execute())
 		return true, true
 
 	case "help":
@@ -244,15 +399,31 @@ func handleCommand(ctx context.Context, input string, uc *useCases) (bool, bool)
 		return true, false
 
 	case "index":
-		handleIndexCommand(ctx, parts[1:], uc)
+		handleIndexCommand(ctx, parts[1:], uc, out)
 		return true, false
 
 	case "memory":
-		handleMemoryCommand(ctx, parts[1:], uc)
+		handleMemoryCommand(ctx, parts[1:], uc, out)
 		return true, false
 
 	case "stats":
-		printAgentStats(uc.getAgentStats)
+		out.agentStats(uc.getAgentStats.Execute())
+		return true, false
+
+	case "tool":
+		handleToolCommand(ctx, strings.TrimSpace(strings.TrimSpace(input)[len(parts[0]):]), uc, out)
+		return true, false
+
+	case "tools":
+		handleToolsCommand(parts[1:], uc, out)
+		return true, false
+
+	case "trace", "why":
+		if summary, ok := uc.getLastTaskTrace.Execute(); ok {
+			out.trace(summary)
+		} else {
+			out.success("ℹ️  No task has finished yet.", "")
+		}
 		return true, false
 
 	default:
@@ -260,8 +431,43 @@ func handleCommand(ctx context.Context, input string, uc *useCases) (bool, bool)
 	}
 }
 
+// handleToolCommand invokes a registered tool directly, bypassing the LLM, and prints its raw result.
+// The arguments are everything after the tool name, so JSON containing spaces is passed unchanged.
+func handleToolCommand(ctx context.Context, args string, uc *useCases, out printer) {
+	name, arguments, _ := strings.Cut(args, " ")
+	if name == "" {
+		fmt.Println("Usage: tool <name> [json-args]")
+		return
+	}
+	arguments = strings.TrimSpace(arguments)
+	if arguments == "" {
+		arguments = "{}"
+	}
+	result, err := uc.toolExecutor.Execute(ctx, name, arguments)
+	if err != nil {
+		out.error(err)
+		return
+	}
+	out.toolResult(name, result)
+}
+
+// handleToolsCommand handles tools subcommands.
+// "tools export" prints the tools array the agent sends to the model, in the OpenAI format.
+func handleToolsCommand(args []string, uc *useCases, out printer) {
+	if len(args) != 1 || !strings.EqualFold(args[0], "export") {
+		fmt.Println("Usage: tools export")
+		return
+	}
+	document, err := agent.ExportToolsJSON(uc.toolExecutor.GetToolDefinitions())
+	if err != nil {
+		out.error(err)
+		return
+	}
+	out.toolsDocument(document)
+}
+
 // handleIndexCommand handles index subcommands.
-func handleIndexCommand(ctx context.Context, args []string, uc *useCases) {
+func handleIndexCommand(ctx context.Context, args []string, uc *useCases, out printer) {
 	if len(args) == 0 {
 		printIndexUsage()
 		return
@@ -272,11 +478,13 @@ func handleIndexCommand(ctx context.Context, args []string, uc *useCases) {
 
 	switch subcmd {
 	case "changed":
-		handleIndexChanged(ctx, subArgs, uc)
+		handleIndexChanged(ctx, subArgs, uc, out)
 	case "diff":
-		handleIndexDiff(ctx, subArgs, uc)
+		handleIndexDiff(ctx, subArgs, uc, out)
+	case "label":
+		handleIndexLabel(ctx, subArgs, uc, out)
 	case "scan":
-		handleIndexScan(ctx, subArgs, uc)
+		handleIndexScan(ctx, subArgs, uc, out)
 	default:
 		fmt.Printf("Unknown index command: %s\n", subcmd)
 		printIndexUsage()
@@ -284,79 +492,173 @@ func handleIndexCommand(ctx context.Context, args []string, uc *useCases) {
 }
 
 // handleIndexChanged handles the index changed subcommand.
-func handleIndexChanged(ctx context.Context, args []string, uc *useCases) {
+func handleIndexChanged(ctx context.Context, args []string, uc *useCases, out printer) {
 	since := parseSinceTime(args)
 	if since.IsZero() {
 		return // Error already printed by parseSinceTime
 	}
 
-	files, err := uc.indexService.ChangedSince(ctx, since)
+	changed, err := uc.indexService.ChangedSince(ctx, since, "")
 	if err != nil {
-		fmt.Printf("❌ Error: %v\n", err)
+		out.error(err)
 		return
 	}
 
-	printChangedFiles(files, since)
+	out.changedFiles(changed)
 }
 
 // handleIndexDiff handles the index diff subcommand.
-func handleIndexDiff(ctx context.Context, args []string, uc *useCases) {
+// With a single snapshot ID, the snapshot is compared against the current files on disk.
+func handleIndexDiff(ctx context.Context, args []string, uc *useCases, out printer) {
+	if len(args) == 1 {
+		fromID := indexing.SnapshotID(args[0])
+		diff, err := uc.indexService.ChangedSinceSnapshot(ctx, fromID)
+		if err != nil {
+			out.error(err)
+			return
+		}
+		out.diffResult(diff, fromID, "disk")
+		return
+	}
+
 	if len(args) < 2 {
-		fmt.Println("Usage: index diff <from_snapshot_id> <to_snapshot_id>")
+		fmt.Println("Usage: index diff <from_snapshot_id> [to_snapshot_id]")
 		return
 	}
 
 	fromID := indexing.SnapshotID(args[0])
 	toID := indexing.SnapshotID(args[1])
 
-	diff, err := uc.indexService.DiffSnapshots(ctx, fromID, toID)
+	diff, err := uc.indexService.DiffSnapshots(ctx, fromID, toID, "")
 	if err != nil {
-		fmt.Printf("❌ Error: %v\n", err)
+		out.error(err)
+		return
+	}
+
+	out.diffResult(diff, fromID, toID)
+}
+
+// handleIndexLabel handles the index label subcommand.
+func handleIndexLabel(ctx context.Context, args []string, uc *useCases, out printer) {
+	if len(args) != 2 {
+		fmt.Println("Usage: index label <snapshot_id> <label>")
 		return
 	}
 
-	printDiffResult(diff, fromID, toID)
+	id := indexing.SnapshotID(args[0])
+	if err := uc.indexService.LabelSnapshot(ctx, id, args[1]); err != nil {
+		out.error(err)
+		return
+	}
+
+	out.success(fmt.Sprintf("🏷️  Labeled snapshot %s as %q", id, args[1]), string(id))
 }
 
 // handleIndexScan handles the index scan subcommand.
-func handleIndexScan(ctx context.Context, args []string, uc *useCases) {
+func handleIndexScan(ctx context.Context, args []string, uc *useCases, out printer) {
+	args, dryRun := parseDryRunFlag(args)
+	args, label := parseLabelFlag(args)
 	paths, ignore := parseIndexScanArgs(args)
 
-	fmt.Printf("🔍 Scanning %d path(s)...\n", len(paths))
-	snapshot, err := uc.indexService.Scan(ctx, paths, ignore)
+	if dryRun {
+		handleIndexScanDryRun(ctx, paths, ignore, uc, out)
+		return
+	}
+
+	out.progress(fmt.Sprintf("🔍 Scanning %d path(s)...", len(paths)))
+	snapshot, err := uc.indexService.ScanWithLabel(ctx, paths, ignore, label)
 	if err != nil {
-		fmt.Printf("❌ Error: %v\n", err)
+		out.error(err)
 		return
 	}
 
+	out.snapshot(snapshot, false)
+}
+
+// handleIndexScanDryRun previews a scan without saving a snapshot.
+func handleIndexScanDryRun(ctx context.Context, paths, ignore []string, uc *useCases, out printer) {
+	out.progress(fmt.Sprintf("🔍 Scanning %d path(s) (dry run)...", len(paths)))
+	snapshot, err := uc.indexService.ScanDryRun(ctx, paths, ignore)
+	if err != nil {
+		out.error(err)
+		return
+	}
+
+	out.snapshot(snapshot, true)
+}
+
+// printSnapshot displays a saved snapshot.
+func printSnapshot(snapshot indexing.Snapshot) {
 	fmt.Println()
 	fmt.Println("✅ Scan complete!")
 	fmt.Println("------------------------------------------")
 	fmt.Printf("Snapshot ID:   %s\n", snapshot.ID)
+	if snapshot.Label != "" {
+		fmt.Printf("Label:         %s\n", snapshot.Label)
+	}
 	fmt.Printf("Files indexed: %d\n", snapshot.FileCount())
+	fmt.Printf("File types:    %s\n", formatExtensionBreakdown(snapshot.ExtensionBreakdown()))
+	fmt.Printf("Total size:    %d bytes\n", snapshot.TotalSize())
 	fmt.Printf("Created at:    %s\n", snapshot.CreatedAt.Format(time.RFC3339))
+	printLargeFiles(snapshot)
 	fmt.Println()
 }
 
+// printDryRunSnapshot displays a snapshot previewed without saving.
+func printDryRunSnapshot(snapshot indexing.Snapshot) {
+	fmt.Println()
+	fmt.Println("📋 Dry run complete (nothing saved)")
+	fmt.Println("------------------------------------------")
+	fmt.Printf("Files to index: %d\n", snapshot.FileCount())
+	fmt.Printf("File types:     %s\n", formatExtensionBreakdown(snapshot.ExtensionBreakdown()))
+	fmt.Printf("Total size:     %d bytes\n", snapshot.TotalSize())
+	for i, file := range snapshot.Files {
+		if i == dryRunPreviewLimit {
+			fmt.Printf("  ... and %d more\n", snapshot.FileCount()-dryRunPreviewLimit)
+			break
+		}
+		fmt.Printf("  %s\n", file.Path)
+	}
+	printLargeFiles(snapshot)
+	fmt.Println()
+}
+
+// printLargeFiles warns about the largest files above largeFileThreshold, if any.
+func printLargeFiles(snapshot indexing.Snapshot) {
+	large := snapshot.FilesOverSize(largeFileThreshold)
+	if len(large) == 0 {
+		return
+	}
+	fmt.Printf("⚠️  %d file(s) larger than %d bytes:\n", len(large), largeFileThreshold)
+	for _, file := range large[:min(len(large), largeFileWarningLimit)] {
+		fmt.Printf("  %s (%d bytes)\n", file.Path, file.Size)
+	}
+}
+
 // printIndexUsage prints index command usage information.
 func printIndexUsage() {
-	fmt.Println("Usage: index <scan|changed|diff> [args...]")
-	fmt.Println("  index scan [paths...] [-- ignore...]  - Scan directories and create a snapshot")
-	fmt.Println("  index changed [since]                 - Show files changed since timestamp/duration")
-	fmt.Println("  index diff <from_id> <to_id>          - Compare two snapshots")
+	fmt.Println("Usage: index <scan|changed|diff|label> [args...]")
+	fmt.Println("  index scan [opts] [paths...] [-- ignore...]       - Scan directories and create a snapshot (opts: --dry-run, --label name)")
+	fmt.Println("  index changed [since]                             - Show files changed since timestamp/duration")
+	fmt.Println("  index diff <from> [to]                            - Compare two snapshots (IDs or labels), or a snapshot with disk")
+	fmt.Println("  index label <id> <label>                          - Name a snapshot, so the label can be used in place of its ID")
 	fmt.Println()
 	fmt.Println("Examples:")
-	fmt.Println("  index scan                            - Scan current directory")
-	fmt.Println("  index scan ./src ./lib                - Scan specific directories")
-	fmt.Println("  index scan . -- .git node_modules     - Scan with custom ignore patterns")
-	fmt.Println("  index changed 1h                      - Files changed in last hour")
-	fmt.Println("  index changed 2024-01-15T10:00:00Z    - Files changed since timestamp")
-	fmt.Println("  index diff snap-123 snap-456          - Compare snapshots")
+	fmt.Println("  index scan                                        - Scan current directory")
+	fmt.Println("  index scan ./src ./lib                            - Scan specific directories")
+	fmt.Println("  index scan . -- .git node_modules                 - Scan with custom ignore patterns")
+	fmt.Println("  index scan --dry-run ./src                        - Preview a scan without saving it")
+	fmt.Println("  index changed 1h                                  - Files changed in last hour")
+	fmt.Println("  index changed 2024-01-15T10:00:00Z                - Files changed since timestamp")
+	fmt.Println("  index diff snap-123 snap-456                      - Compare snapshots")
+	fmt.Println("  index diff snap-123                               - Show changes on disk since snap-123")
+	fmt.Println("  index scan --label before-refactor                - Scan and name the snapshot")
+	fmt.Println("  index diff before-refactor snap-456               - Compare a labeled snapshot")
 	fmt.Println()
 }
 
 // handleMemoryCommand handles memory subcommands.
-func handleMemoryCommand(ctx context.Context, args []string, uc *useCases) {
+func handleMemoryCommand(ctx context.Context, args []string, uc *useCases, out printer) {
 	if len(args) == 0 {
 		printMemoryUsage()
 		return
@@ -367,34 +669,34 @@ func handleMemoryCommand(ctx context.Context, args []string, uc *useCases) {
 
 	switch subcmd {
 	case "delete":
-		handleMemoryDelete(ctx, subArgs, uc)
+		handleMemoryDelete(ctx, subArgs, uc, out)
 	case "get":
-		handleMemoryGet(ctx, subArgs, uc)
+		handleMemoryGet(ctx, subArgs, uc, out)
 	case "search":
-		handleMemorySearch(ctx, subArgs, uc)
+		handleMemorySearch(ctx, subArgs, uc, out)
 	case "write":
-		handleMemoryWrite(ctx, subArgs, uc)
+		handleMemoryWrite(ctx, subArgs, uc, out)
 	default:
-		fmt.Printf("Unknown memory command: %s\n", subcmd)
+		out.error(fmt.Errorf("unknown memory command: %s", subcmd))
 	}
 }
 
 // handleMemoryDelete handles the memory delete subcommand.
-func handleMemoryDelete(ctx context.Context, args []string, uc *useCases) {
+func handleMemoryDelete(ctx context.Context, args []string, uc *useCases, out printer) {
 	if len(args) < 1 {
 		fmt.Println("Usage: memory delete <id>")
 		return
 	}
 	noteID := agent.NoteID(args[0])
 	if err := uc.deleteNote.Execute(ctx, noteID); err != nil {
-		fmt.Printf("❌ Error: %v\n", err)
+		out.error(err)
 	} else {
-		fmt.Printf("🗑️  Note %s deleted.\n", noteID)
+		out.success(fmt.Sprintf("🗑️  Note %s deleted.", noteID), string(noteID))
 	}
 }
 
 // handleMemoryGet handles the memory get subcommand.
-func handleMemoryGet(ctx context.Context, args []string, uc *useCases) {
+func handleMemoryGet(ctx context.Context, args []string, uc *useCases, out printer) {
 	if len(args) < 1 {
 		fmt.Println("Usage: memory get <id>")
 		return
@@ -402,18 +704,18 @@ func handleMemoryGet(ctx context.Context, args []string, uc *useCases) {
 	noteID := agent.NoteID(args[0])
 	note, err := uc.getNote.Execute(ctx, noteID)
 	if err != nil {
-		fmt.Printf("❌ Error: %v\n", err)
+		out.error(err)
 		return
 	}
 	if note == nil {
-		fmt.Printf("Note %s not found.\n", noteID)
+		out.error(fmt.Errorf("note %s not found", noteID))
 		return
 	}
-	printMemoryNote(note)
+	out.memoryNote(note)
 }
 
 // handleMemorySearch handles the memory search subcommand.
-func handleMemorySearch(ctx context.Context, args []string, uc *useCases) {
+func handleMemorySearch(ctx context.Context, args []string, uc *useCases, out printer) {
 	flags := parseMemoryFlags(args)
 
 	query := strings.Join(flags.remaining, " ")
@@ -424,14 +726,14 @@ func handleMemorySearch(ctx context.Context, args []string, uc *useCases) {
 	opts := buildSearchOptions(flags)
 	notes, err := uc.searchNotes.Execute(ctx, query, 10, opts)
 	if err != nil {
-		fmt.Printf("❌ Error: %v\n", err)
+		out.error(err)
 		return
 	}
-	printMemorySearchResults(notes)
+	out.memorySearchResults(notes)
 }
 
 // handleMemoryWrite handles the memory write subcommand.
-func handleMemoryWrite(ctx context.Context, args []string, uc *useCases) {
+func handleMemoryWrite(ctx context.Context, args []string, uc *useCases, out printer) {
 	if len(args) < 1 {
 		fmt.Println("Usage: memory write [--source-type TYPE] [--importance N] [--tags t1,t2] <text>")
 		return
@@ -441,7 +743,7 @@ func handleMemoryWrite(ctx context.Context, args []string, uc *useCases) {
 
 	content := strings.Join(flags.remaining, " ")
 	if content == "" {
-		fmt.Println("❌ Error: content cannot be empty")
+		out.error(errors.New("content cannot be empty"))
 		return
 	}
 
@@ -451,7 +753,7 @@ func handleMemoryWrite(ctx context.Context, args []string, uc *useCases) {
 		importance = 3
 	}
 
-	note := agent.NewMemoryNote(agent.NoteID(generateNoteID()), flags.sourceType).
+	note := agent.NewMemoryNote(agent.NoteID(noteIDs.NewID()), flags.sourceType).
 		WithRawContent(content).
 		WithSummary(content).
 		WithImportance(importance)
@@ -461,9 +763,9 @@ func handleMemoryWrite(ctx context.Context, args []string, uc *useCases) {
 	}
 
 	if err := uc.writeNote.Execute(ctx, note); err != nil {
-		fmt.Printf("❌ Error: %v\n", err)
+		out.error(err)
 	} else {
-		fmt.Printf("💾 Note saved with ID: %s\n", note.ID)
+		out.success(fmt.Sprintf("💾 Note saved with ID: %s", note.ID), string(note.ID))
 	}
 }
 
@@ -506,8 +808,7 @@ func printMemoryUsage() {
 }
 
 // printAgentStats displays the current agent statistics.
-func printAgentStats(uc *chatting.GetAgentStatsUseCase) {
-	stats := uc.Execute()
+func printAgentStats(stats chatting.AgentStats) {
 	fmt.Println()
 	fmt.Println("📊 Agent Statistics")
 	fmt.Println("-------------------")
@@ -515,6 +816,11 @@ func printAgentStats(uc *chatting.GetAgentStatsUseCase) {
 	fmt.Printf("Messages:        %d\n", stats.MessageCount)
 	fmt.Printf("Tasks:           %d (✓ %d completed, ✗ %d failed)\n",
 		stats.TaskCount, stats.CompletedTasks, stats.FailedTasks)
+	fmt.Printf("Tool calls:      %d\n", stats.TotalToolCalls)
+	fmt.Printf("Avg iterations:  %.1f per task\n", stats.AverageIterationsPerTask)
+	if stats.TotalTokens > 0 {
+		fmt.Printf("Tokens:          %d\n", stats.TotalTokens)
+	}
 	fmt.Printf("Max iterations:  %d\n", stats.MaxIterations)
 	fmt.Printf("Max messages:    %d\n", stats.MaxMessages)
 	if stats.Model != "" {
@@ -523,6 +829,45 @@ func printAgentStats(uc *chatting.GetAgentStatsUseCase) {
 	fmt.Println()
 }
 
+// parseDryRunFlag removes a "--dry-run" flag preceding the ignore separator.
+// Returns the remaining arguments and whether the flag was present.
+func parseDryRunFlag(args []string) ([]string, bool) {
+	rest := make([]string, 0, len(args))
+	dryRun := false
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if arg == "--dry-run" {
+			dryRun = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, dryRun
+}
+
+// parseLabelFlag removes a "--label <name>" flag preceding the ignore separator.
+// Returns the remaining arguments and the label, or "" if the flag was absent.
+func parseLabelFlag(args []string) ([]string, string) {
+	rest := make([]string, 0, len(args))
+	label := ""
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if args[i] == "--label" && i+1 < len(args) {
+			label = args[i+1]
+			i++
+			continue
+		}
+		rest = append(rest, args[i])
+	}
+	return rest, label
+}
+
 // parseIndexScanArgs parses arguments for the index scan command.
 // Returns paths and ignore patterns.
 func parseIndexScanArgs(args []string) ([]string, []string) {
@@ -578,16 +923,66 @@ func parseSinceTime(args []string) time.Time {
 	return time.Time{}
 }
 
+// loadHistory creates the input history and loads the entries of previous sessions from path.
+// An unreadable history file is reported on w and the history starts empty.
+func loadHistory(path string, w io.Writer) *inbound.History {
+	history := inbound.NewHistory(0)
+	if path == "" {
+		return history
+	}
+	if err := history.Load(path); err != nil {
+		fmt.Fprintf(w, "⚠️  Could not load history from %s: %v\n", path, err)
+	}
+	return history
+}
+
+// loadSession restores the saved session into the agent.
+// A missing or unreadable session file is reported on w and the session starts fresh.
+func loadSession(store *outbound.SessionStore, ag *agent.Agent, w io.Writer) {
+	err := store.Load(ag)
+	switch {
+	case err == nil:
+		fmt.Fprintf(w, "📂 Resumed session from %s (%d messages)\n", store.Path(), ag.MessageCount())
+	case errors.Is(err, outbound.ErrSessionNotFound):
+		fmt.Fprintf(w, "📂 No session at %s yet, starting fresh\n", store.Path())
+	default:
+		fmt.Fprintf(w, "⚠️  Could not load session from %s, starting fresh: %v\n", store.Path(), err)
+	}
+}
+
+// notifyInterrupt returns a context that is canceled on Ctrl+C (SIGINT).
+func notifyInterrupt(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt)
+}
+
+// oneShotPrompt returns the prompt for one-shot mode: the -prompt flag if set,
+// otherwise all of stdin if it is not a terminal (e.g. a pipe or a file).
+// Returns false if the interactive chat should run instead.
+func oneShotPrompt(prompt string, stdin *os.File) (string, bool, error) {
+	if prompt != "" {
+		return prompt, true, nil
+	}
+	info, err := stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+		return "", false, nil
+	}
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", false, err
+	}
+	return string(data), true, nil
+}
+
 // printBanner displays the startup banner.
-func printBanner(chattingURL, chattingModel, embeddingURL, embeddingModel string, maxIter, maxMsg int, memoryFile, indexFile string, parallelTools bool) {
+func printBanner(chattingURL, chattingStatus, chattingModel, embeddingURL, embeddingStatus, embeddingModel string, maxIter, maxMsg int, memoryFile, indexFile string, parallelTools bool) {
 	appName := getEnvOrDefault("APP_NAME", "Go Agent")
 	appDescription := getEnvOrDefault("APP_DESCRIPTION", "AI Agent CLI")
 	fmt.Printf("🤖 %s - %s\n", appName, appDescription)
 	fmt.Println(strings.Repeat("=", len(appName)+len(appDescription)+6))
-	fmt.Printf("Chatting URL:    %s\n", chattingURL)
+	fmt.Printf("Chatting URL:    %s %s\n", chattingURL, chattingStatus)
 	fmt.Printf("Chatting Model:  %s\n", chattingModel)
 	if embeddingModel != "" {
-		fmt.Printf("Embedding URL:   %s\n", embeddingURL)
+		fmt.Printf("Embedding URL:   %s %s\n", embeddingURL, embeddingStatus)
 		fmt.Printf("Embedding Model: %s\n", embeddingModel)
 	} else {
 		fmt.Println("Embeddings:      disabled")
@@ -610,18 +1005,18 @@ func printBanner(chattingURL, chattingModel, embeddingURL, embeddingModel string
 	fmt.Println()
 }
 
-// printChangedFiles displays changed files since a timestamp.
-func printChangedFiles(files []indexing.FileInfo, since time.Time) {
+// printChangedFiles displays changed files since a timestamp and the snapshot they were read from.
+func printChangedFiles(changed indexing.ChangedSinceResult) {
 	fmt.Println()
-	fmt.Printf("📁 Files changed since %s\n", since.Format(time.RFC3339))
+	fmt.Printf("📁 Files changed since %s (snapshot %s)\n", changed.Since.Format(time.RFC3339), changed.SnapshotID)
 	fmt.Println("------------------------------------------")
-	if len(files) == 0 {
+	if len(changed.Files) == 0 {
 		fmt.Println("No files changed.")
 	} else {
-		for _, f := range files {
+		for _, f := range changed.Files {
 			fmt.Printf("  %s (%d bytes, %s)\n", f.Path, f.Size, f.ModTime.Format(time.RFC3339))
 		}
-		fmt.Printf("\nTotal: %d file(s)\n", len(files))
+		fmt.Printf("\nTotal: %d file(s)\n", len(changed.Files))
 	}
 	fmt.Println()
 }
@@ -632,36 +1027,23 @@ func printDiffResult(diff indexing.DiffResult, fromID, toID indexing.SnapshotID)
 	fmt.Printf("📊 Diff: %s → %s\n", fromID, toID)
 	fmt.Println("------------------------------------------")
 
-	if len(diff.Added) > 0 {
-		fmt.Printf("\n✅ Added (%d):\n", len(diff.Added))
-		for _, f := range diff.Added {
-			fmt.Printf("  + %s\n", f.Path)
-		}
-	}
-
-	if len(diff.Changed) > 0 {
-		fmt.Printf("\n📝 Changed (%d):\n", len(diff.Changed))
-		for _, f := range diff.Changed {
-			fmt.Printf("  ~ %s\n", f.Path)
-		}
+	if diff.IsEmpty() {
+		fmt.Println("No differences found.")
+		fmt.Println()
+		return
 	}
 
-	if len(diff.Removed) > 0 {
-		fmt.Printf("\n❌ Removed (%d):\n", len(diff.Removed))
-		for _, f := range diff.Removed {
-			fmt.Printf("  - %s\n", f.Path)
-		}
+	for _, line := range strings.Split(strings.TrimSuffix(diff.String(), "\n"), "\n") {
+		fmt.Printf("  %s\n", line)
 	}
 
-	if len(diff.Added) == 0 && len(diff.Changed) == 0 && len(diff.Removed) == 0 {
-		fmt.Println("No differences found.")
-	}
+	summary := diff.Summary()
+	fmt.Printf("\n✅ %d added, 📝 %d changed, ❌ %d removed\n", summary.Added, summary.Changed, summary.Removed)
 	fmt.Println()
 }
 
 // printFinalStats shows a summary of the session upon exit.
-func printFinalStats(uc *chatting.GetAgentStatsUseCase) {
-	stats := uc.Execute()
+func printFinalStats(stats chatting.AgentStats) {
 	if stats.TaskCount > 0 {
 		fmt.Println()
 		fmt.Printf("📈 Session summary: %d tasks (✓ %d, ✗ %d), %d messages\n",
@@ -674,12 +1056,15 @@ func printHelp() {
 	fmt.Println()
 	fmt.Println("📖 Available Commands")
 	fmt.Println("---------------------")
-	fmt.Println("  clear              Clear conversation history")
+	fmt.Println("  clear [all]        Clear conversation history (all: including pinned)")
 	fmt.Println("  help               Show this help message")
 	fmt.Println("  index <subcmd>     Index operations (scan, changed, diff)")
 	fmt.Println("  memory <subcmd>    Memory operations (search, get, write, delete)")
 	fmt.Println("  quit / exit        Exit the CLI")
 	fmt.Println("  stats              Show agent statistics")
+	fmt.Println("  tool <name> [args] Run a tool directly with JSON arguments and print its result")
+	fmt.Println("  tools export       Print the tool definitions as an OpenAI tools JSON array")
+	fmt.Println("  trace / why        Show the tool calls of the last task")
 	fmt.Println()
 	fmt.Println("💡 Tips:")
 	fmt.Println("  - Ask the agent to calculate: 'What is 42 * 17?'")
@@ -747,42 +1132,167 @@ func printResult(output chatting.SendMessageOutput, verbose bool) {
 	}
 }
 
+// printTrace displays the tool calls of a finished task, grouped by iteration.
+func printTrace(summary agent.TaskSummary) {
+	fmt.Println()
+	fmt.Printf("🔍 Trace of %s (%s, %d iterations, %s)\n",
+		summary.ID, summary.Status, summary.Iterations, summary.Duration.Round(time.Millisecond))
+	fmt.Println("------------------------------------------")
+	if len(summary.ToolCalls) == 0 {
+		fmt.Println("No tool calls.")
+	}
+	iteration := 0
+	for _, tc := range summary.ToolCalls {
+		if tc.Iteration != iteration {
+			iteration = tc.Iteration
+			fmt.Printf("Iteration %d:\n", iteration)
+		}
+		if tc.Status == agent.ToolCallStatusFailed {
+			fmt.Printf("  ❌ %s(%s) → %s\n", tc.Name, truncate(tc.Arguments, traceValueLimit), truncate(tc.Error, traceValueLimit))
+		} else {
+			fmt.Printf("  ✅ %s(%s) → %s\n", tc.Name, truncate(tc.Arguments, traceValueLimit), truncate(tc.Result, traceValueLimit))
+		}
+	}
+	fmt.Println()
+}
+
+// runOneShot sends a single prompt and writes only the response to stdout.
+// Diagnostics go to stderr; with the JSON format, the result object is written to stdout. Returns the process exit code: 0 on success,
+// 1 if the task failed, and 2 if the prompt is empty.
+func runOneShot(ctx context.Context, sender messageSender, prompt, format string, stdout, stderr io.Writer, verbose bool) int {
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		fmt.Fprintln(stderr, "❌ Error: empty prompt")
+		return 2
+	}
+
+	output, err := sender.Execute(ctx, chatting.SendMessageInput{Message: prompt})
+	if format == outputJSON {
+		return runOneShotJSON(output, err, stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "❌ Error: %v\n", err)
+		return 1
+	}
+	if !output.Success {
+		fmt.Fprintf(stderr, "⚠️  Task failed: %s\n", output.Error)
+		return 1
+	}
+
+	fmt.Fprintln(stdout, output.Response)
+	if verbose {
+		fmt.Fprintf(stderr, "⏱️  %s | 🔄 %d iterations | 🔧 %d tool calls\n",
+			output.Duration,
+			output.IterationCount,
+			output.ToolCallCount)
+	}
+	return 0
+}
+
+// runOneShotJSON writes the result or error of a one-shot prompt as JSON to stdout.
+func runOneShotJSON(output chatting.SendMessageOutput, err error, stdout io.Writer) int {
+	out := jsonPrinter{w: stdout}
+	switch {
+	case err != nil:
+		out.error(err)
+		return 1
+	case !output.Success:
+		out.result(output, false)
+		return 1
+	default:
+		out.result(output, false)
+		return 0
+	}
+}
+
 // runInteractiveChat starts the interactive chat loop.
-func runInteractiveChat(uc *useCases, verbose bool) {
-	scanner := bufio.NewScanner(os.Stdin)
+// Lines are read from reader; afterTurn is called after each answered message, e.g. to save the session.
+// An interrupt signaled through notify cancels the running response only; pressing Ctrl+C again
+// within interruptWindow ends the chat.
+func runInteractiveChat(uc *useCases, reader inbound.LineReader, notify notifyFunc, out printer, verbose bool, afterTurn func()) {
 	ctx := context.Background()
+	var canceledAt time.Time
 
 	for {
-		fmt.Print("You: ")
-		if !scanner.Scan() {
+		out.prompt()
+		line, err := reader.ReadLine()
+		if errors.Is(err, io.EOF) {
 			break
 		}
+		if errors.Is(err, inbound.ErrInterrupted) {
+			if time.Since(canceledAt) < interruptWindow {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+			os.Exit(1)
+		}
 
-		input := strings.TrimSpace(scanner.Text())
+		input := strings.TrimSpace(line)
 		if input == "" {
 			continue
 		}
 
-		if handled, shouldBreak := handleCommand(ctx, input, uc); handled {
+		if handled, shouldBreak := handleCommand(ctx, input, uc, out); handled {
 			if shouldBreak {
 				break
 			}
 			continue
 		}
 
-		// Send message using use case
-		output, err := uc.sendMessage.Execute(ctx, chatting.SendMessageInput{Message: input})
+		// Send message using use case; Ctrl+C cancels this message only
+		output, canceled, err := sendInterruptible(ctx, uc.sendMessage, input, notify)
+		if canceled {
+			canceledAt = time.Now()
+			out.progress("⏹️  Response canceled. Press Ctrl+C again to exit.")
+		}
 		if err != nil {
-			fmt.Printf("❌ Error: %v\n\n", err)
+			out.error(err)
 			continue
 		}
 
-		printResult(output, verbose)
+		out.result(output, verbose)
+		afterTurn()
 	}
+}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
-		os.Exit(1)
+// sendInterruptible sends the message with a context that notify cancels on interrupt.
+// Once interrupted, the default signal handling is restored, so a second Ctrl+C
+// terminates the process even if the response does not stop in time.
+// Reports whether the message was canceled by an interrupt.
+func sendInterruptible(ctx context.Context, sender messageSender, message string, notify notifyFunc) (chatting.SendMessageOutput, bool, error) {
+	turnCtx, stop := notify(ctx)
+	defer stop()
+	go func() {
+		<-turnCtx.Done()
+		stop()
+	}()
+
+	output, err := sender.Execute(turnCtx, chatting.SendMessageInput{Message: message})
+	canceled := turnCtx.Err() != nil && ctx.Err() == nil
+	return output, canceled, err
+}
+
+// reachability pings the endpoint and returns a status marker for the banner.
+func reachability(p pinger) string {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if err := p.Ping(ctx); err != nil {
+		return fmt.Sprintf("❌ (%v)", err)
+	}
+	return "✅"
+}
+
+// saveSession writes the agent state to the session store; a nil store disables persistence.
+// Failures are reported on w, so that a broken session file never ends the chat.
+func saveSession(store *outbound.SessionStore, ag *agent.Agent, w io.Writer) {
+	if store == nil {
+		return
+	}
+	if err := store.Save(ag); err != nil {
+		fmt.Fprintf(w, "⚠️  Could not save session: %v\n", err)
 	}
 }
 
@@ -791,45 +1301,56 @@ func setupInfrastructure(baseURL, model, memoryFile, indexFile string, verbose,
 	logger := createLogger(verbose)
 	dispatcher := messaging.NewExternalDispatcher()
 	publisher := outbound.NewEventPublisher(dispatcher)
-	memoryStore := createMemoryStore(memoryFile)
-	memoryToolSvc := tooling.NewMemoryToolService(memoryStore, generateNoteID)
+	memoryStore := createMemoryStore(memoryFile, logger)
+	memoryToolSvc := tooling.NewMemoryToolService(memoryStore, noteIDs)
+	memoryService := memorizing.NewService(memoryStore).WithLogger(logger)
 
 	// Configure embedding client if model is specified
+	var embeddingClient *outbound.OpenAIEmbeddingClient
 	if embeddingModel != "" {
-		embeddingClient := outbound.NewOpenAIEmbeddingClient(embeddingURL).
+		embeddingClient = outbound.NewOpenAIEmbeddingClient(embeddingURL).
 			WithModel(embeddingModel)
 		if logger != nil {
 			embeddingClient.WithLogger(logger)
+			memoryToolSvc.WithLogger(logger)
 		}
 		memoryToolSvc.WithEmbedder(embeddingClient)
+		memoryService.WithEmbedder(embeddingClient)
 	}
 
 	// Create indexing infrastructure
 	indexStore := createIndexStore(indexFile)
 	fileWalker := inbound.NewFSWalker()
-	indexService := indexing.NewService(fileWalker, indexStore, generateSnapshotID)
-	indexToolSvc := tooling.NewIndexToolService(indexService)
+	indexService := indexing.NewService(fileWalker, indexStore, snapshotIDs)
+	indexToolSvc := tooling.NewIndexToolService(indexService).WithMemory(memoryStore, noteIDs)
 
 	toolExecutor := createToolExecutor(verbose, logger, memoryToolSvc, indexToolSvc)
 	llmClient := createLLMClient(baseURL, model, verbose, logger)
 	hooks := createHooks(verbose)
 	taskService := createTaskService(llmClient, toolExecutor, publisher, hooks, parallelTools)
+	if logger != nil {
+		taskService.WithLogger(logger)
+	}
 
 	return &infrastructure{
-		dispatcher:    dispatcher,
-		indexService:  indexService,
-		indexToolSvc:  indexToolSvc,
-		llmClient:     llmClient,
-		logger:        logger,
-		memoryStore:   memoryStore,
-		memoryToolSvc: memoryToolSvc,
-		publisher:     publisher,
-		taskService:   taskService,
-		toolExecutor:  toolExecutor,
+		dispatcher:      dispatcher,
+		embeddingClient: embeddingClient,
+		indexService:    indexService,
+		indexStore:      indexStore,
+		indexToolSvc:    indexToolSvc,
+		llmClient:       llmClient,
+		logger:          logger,
+		memoryService:   memoryService,
+		memoryStore:     memoryStore,
+		memoryToolSvc:   memoryToolSvc,
+		publisher:       publisher,
+		taskService:     taskService,
+		toolExecutor:    toolExecutor,
 	}
 }
 
 // createHooks creates task lifecycle hooks (verbose mode enables all hooks).
+// The hooks write to stderr, so they never mix with a one-shot response on stdout.
 func createHooks(verbose bool) agent.Hooks {
 	hooks := agent.NewHooks()
 	if !verbose {
@@ -837,31 +1358,57 @@ func createHooks(verbose bool) agent.Hooks {
 	}
 	return hooks.
 		WithBeforeTask(func(_ context.Context, _ *agent.Agent, t *agent.Task) error {
-			fmt.Printf("   📋 Task started: %s\n", t.Name)
+			fmt.Fprintf(os.Stderr, "   📋 Task started: %s\n", t.Name)
 			return nil
 		}).
 		WithBeforeLLMCall(func(_ context.Context, _ *agent.Agent, _ *agent.Task) error {
-			fmt.Println("   🔄 Calling LLM...")
+			fmt.Fprintln(os.Stderr, "   🔄 Calling LLM...")
 			return nil
 		}).
 		WithAfterLLMCall(func(_ context.Context, _ *agent.Agent, _ *agent.Task) error {
-			fmt.Println("   ✅ LLM response received")
+			fmt.Fprintln(os.Stderr, "   ✅ LLM response received")
 			return nil
 		}).
 		WithBeforeToolCall(func(_ context.Context, _ *agent.Agent, tc *agent.ToolCall) error {
-			fmt.Printf("   🔧 Executing tool: %s\n", tc.Name)
+			fmt.Fprintf(os.Stderr, "   🔧 Executing tool: %s\n", tc.Name)
 			return nil
 		}).
 		WithAfterToolCall(func(_ context.Context, _ *agent.Agent, tc *agent.ToolCall) error {
-			fmt.Printf("   ✅ Tool result: %s\n", truncate(tc.Result, 50))
+			fmt.Fprintf(os.Stderr, "   ✅ Tool result: %s\n", truncate(tc.Result, 50))
 			return nil
 		}).
 		WithAfterTask(func(_ context.Context, _ *agent.Agent, t *agent.Task) error {
-			fmt.Printf("   📋 Task completed: %s\n", t.Status)
+			fmt.Fprintf(os.Stderr, "   📋 Task completed: %s\n", t.Status)
 			return nil
 		})
 }
 
+// closers returns the infrastructure components that hold resources to release on exit.
+// Stores come first, so that pending writes are flushed before connections are dropped.
+func (i *infrastructure) closers() []agent.Closer {
+	candidates := []any{i.memoryStore, i.indexStore, i.llmClient}
+	if i.embeddingClient != nil {
+		candidates = append(candidates, i.embeddingClient)
+	}
+	var closers []agent.Closer
+	for _, candidate := range candidates {
+		if closer, ok := candidate.(agent.Closer); ok {
+			closers = append(closers, closer)
+		}
+	}
+	return closers
+}
+
+// closeAll closes the components in order and reports failures on w.
+// All components are closed even if one fails, so no pending write is lost.
+func closeAll(ctx context.Context, closers []agent.Closer, w io.Writer) {
+	for _, closer := range closers {
+		if err := closer.Close(ctx); err != nil {
+			fmt.Fprintf(w, "⚠️  Could not close %T: %v\n", closer, err)
+		}
+	}
+}
+
 // createLLMClient creates the OpenAI client with optional logging.
 func createLLMClient(baseURL, model string, verbose bool, logger *slog.Logger) *outbound.OpenAIClient {
 	client := outbound.NewOpenAIClient(baseURL, model)
@@ -884,15 +1431,17 @@ func createIndexStore(indexFile string) *outbound.IndexStore {
 	if indexFile != "" {
 		return outbound.NewIndexStore(indexFile)
 	}
-	return outbound.NewInMemoryIndexStore()
+	return outbound.NewInMemoryIndexStore().WithMaxSnapshots(inMemorySnapshotLimit)
 }
 
 // createMemoryStore creates either a file-backed or in-memory store.
-func createMemoryStore(memoryFile string) *outbound.MemoryStore {
+// The logger, if any, reports failures to refresh the backup of the memory file.
+func createMemoryStore(memoryFile string, logger *slog.Logger) *outbound.MemoryStore {
 	if memoryFile != "" {
-		return outbound.NewJsonFileMemoryStore(memoryFile)
+		access := outbound.NewAtomicJsonFileAccess[string, agent.MemoryNote](memoryFile).WithLogger(logger)
+		return outbound.NewMemoryStore(access).WithHistory(memoryHistorySize)
 	}
-	return outbound.NewInMemoryMemoryStore()
+	return outbound.NewInMemoryMemoryStore().WithHistory(memoryHistorySize)
 }
 
 // createTaskService creates the task service with hooks and optional parallelism.
@@ -903,7 +1452,10 @@ func createTaskService(
 	hooks agent.Hooks,
 	parallelTools bool,
 ) *agent.TaskService {
-	svc := agent.NewTaskService(llmClient, toolExecutor, publisher).WithHooks(hooks)
+	svc := agent.NewTaskService(llmClient, toolExecutor, publisher).
+		WithHooks(hooks).
+		WithLoopDetection(loopDetectionWindow).
+		WithValidationRetry(validationRetries)
 	if parallelTools {
 		svc = svc.WithParallelToolExecution()
 	}
@@ -912,7 +1464,9 @@ func createTaskService(
 
 // createToolExecutor creates and configures the tool executor with all tools.
 func createToolExecutor(verbose bool, logger *slog.Logger, memoryToolSvc *tooling.MemoryToolService, indexToolSvc *tooling.IndexToolService) *outbound.ToolExecutor {
-	executor := outbound.NewToolExecutor()
+	executor := outbound.NewToolExecutor().
+		WithArgumentValidation().
+		WithMaxArgumentBytes(maxToolArgumentBytes)
 	if verbose && logger != nil {
 		executor = executor.WithLogger(logger)
 	}
@@ -920,9 +1474,11 @@ func createToolExecutor(verbose bool, logger *slog.Logger, memoryToolSvc *toolin
 	return executor
 }
 
-// generateSnapshotID creates a unique snapshot ID.
-func generateSnapshotID() string {
-	return fmt.Sprintf("snap-%d", time.Now().UnixNano())
+// registerListTools registers the list_tools tool with the executor.
+func registerListTools(executor *outbound.ToolExecutor) {
+	listToolsTool := tooling.NewListToolsTool(executor)
+	executor.RegisterTool(string(listToolsTool.ID), listToolsTool.Func)
+	executor.RegisterToolDefinition(listToolsTool.Definition)
 }
 
 // registerTools registers all available tools with the executor.
@@ -942,6 +1498,11 @@ func registerTools(executor *outbound.ToolExecutor, memoryToolSvc *tooling.Memor
 	executor.RegisterTool(string(indexScanTool.ID), indexScanTool.Func)
 	executor.RegisterToolDefinition(indexScanTool.Definition)
 
+	// Register memory_delete_matching tool
+	memoryDeleteMatchingTool := tooling.NewMemoryDeleteMatchingTool(memoryToolSvc)
+	executor.RegisterTool(string(memoryDeleteMatchingTool.ID), memoryDeleteMatchingTool.Func)
+	executor.RegisterToolDefinition(memoryDeleteMatchingTool.Definition)
+
 	// Register memory_get tool
 	memoryGetTool := tooling.NewMemoryGetTool(memoryToolSvc)
 	executor.RegisterTool(string(memoryGetTool.ID), memoryGetTool.Func)
@@ -952,6 +1513,11 @@ func registerTools(executor *outbound.ToolExecutor, memoryToolSvc *tooling.Memor
 	executor.RegisterTool(string(memorySearchTool.ID), memorySearchTool.Func)
 	executor.RegisterToolDefinition(memorySearchTool.Definition)
 
+	// Register memory_session_summary tool
+	memorySessionSummaryTool := tooling.NewMemorySessionSummaryTool(memoryToolSvc)
+	executor.RegisterTool(string(memorySessionSummaryTool.ID), memorySessionSummaryTool.Func)
+	executor.RegisterToolDefinition(memorySessionSummaryTool.Definition)
+
 	// Register memory_write tool
 	memoryWriteTool := tooling.NewMemoryWriteTool(memoryToolSvc)
 	executor.RegisterTool(string(memoryWriteTool.ID), memoryWriteTool.Func)
@@ -2,19 +2,24 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/andygeiss/cloud-native-utils/event"
+	"github.com/andygeiss/cloud-native-utils/resource"
 	"github.com/andygeiss/go-agent/internal/adapters/inbound"
 	"github.com/andygeiss/go-agent/internal/adapters/outbound"
 	"github.com/andygeiss/go-agent/internal/domain/agent"
 	"github.com/andygeiss/go-agent/internal/domain/chatting"
 	"github.com/andygeiss/go-agent/internal/domain/indexing"
 	"github.com/andygeiss/go-agent/internal/domain/memorizing"
+	"github.com/andygeiss/go-agent/internal/domain/openai"
 	"github.com/andygeiss/go-agent/internal/domain/tooling"
 )
 
@@ -559,6 +564,66 @@ func Benchmark_MemoryStore_Search_WithFilters_1000(b *testing.B) {
 	}
 }
 
+// generateNotesForUsers creates n memory notes spread evenly across the given number of users.
+func generateNotesForUsers(ctx context.Context, store agent.MemoryStore, n, users int) {
+	for i := range n {
+		note := agent.NewMemoryNote(agent.NoteID(fmt.Sprintf("note-%d", i)), agent.SourceTypeFact).
+			WithRawContent(fmt.Sprintf("Content for note %d about programming", i)).
+			WithImportance((i % 5) + 1).
+			WithUserID(fmt.Sprintf("user-%d", i%users))
+		_ = store.Write(ctx, note)
+	}
+}
+
+// Benchmark_MemoryStore_Search_ByUser_10000 benchmarks a user-filtered search on
+// 10000 notes of 100 users, with and without the user index.
+func Benchmark_MemoryStore_Search_ByUser_10000(b *testing.B) {
+	ctx := context.Background()
+	opts := &agent.MemorySearchOptions{UserID: "user-42"}
+	stores := []struct {
+		store *outbound.MemoryStore
+		name  string
+	}{
+		{name: "Indexed", store: outbound.NewInMemoryMemoryStore()},
+		{name: "Unindexed", store: outbound.NewMemoryStore(resource.NewInMemoryAccess[string, agent.MemoryNote]())},
+	}
+
+	for _, bench := range stores {
+		generateNotesForUsers(ctx, bench.store, 10000, 100)
+		b.Run(bench.name, func(b *testing.B) {
+			for b.Loop() {
+				_, _ = bench.store.Search(ctx, "programming", 10, opts)
+			}
+		})
+	}
+}
+
+// Benchmark_MemoryStore_Search_WithSourceTypesAndTags_10000 benchmarks a search filtered by
+// source type and tag on 10000 notes, with and without the secondary indexes.
+func Benchmark_MemoryStore_Search_WithSourceTypesAndTags_10000(b *testing.B) {
+	ctx := context.Background()
+	opts := &agent.MemorySearchOptions{
+		SourceTypes: []agent.SourceType{agent.SourceTypePreference},
+		Tags:        []string{"user"},
+	}
+	stores := []struct {
+		store *outbound.MemoryStore
+		name  string
+	}{
+		{name: "Indexed", store: outbound.NewInMemoryMemoryStore()},
+		{name: "Unindexed", store: outbound.NewMemoryStore(resource.NewInMemoryAccess[string, agent.MemoryNote]())},
+	}
+
+	for _, bench := range stores {
+		generateNotes(ctx, bench.store, 10000)
+		b.Run(bench.name, func(b *testing.B) {
+			for b.Loop() {
+				_, _ = bench.store.Search(ctx, "programming", 10, opts)
+			}
+		})
+	}
+}
+
 // Benchmark_MemoryStore_Search_WithFilters_10000 benchmarks filtered search on 10000 notes.
 func Benchmark_MemoryStore_Search_WithFilters_10000(b *testing.B) {
 	ctx := context.Background()
@@ -907,10 +972,10 @@ func Benchmark_MemoryTools_Write(b *testing.B) {
 	ctx := context.Background()
 	store := outbound.NewInMemoryMemoryStore()
 	idCounter := 0
-	idGen := func() string {
+	idGen := agent.IDGeneratorFunc(func() string {
 		idCounter++
 		return fmt.Sprintf("note-%d", idCounter)
-	}
+	})
 	svc := tooling.NewMemoryToolService(store, idGen)
 
 	b.ResetTimer()
@@ -925,7 +990,7 @@ func Benchmark_MemoryTools_Search_1000(b *testing.B) {
 	ctx := context.Background()
 	store := outbound.NewInMemoryMemoryStore()
 	generateNotes(ctx, store, 1000)
-	idGen := func() string { return unusedIDGenerator }
+	idGen := agent.IDGeneratorFunc(func() string { return unusedIDGenerator })
 	svc := tooling.NewMemoryToolService(store, idGen)
 
 	b.ResetTimer()
@@ -940,7 +1005,7 @@ func Benchmark_MemoryTools_Search_10000(b *testing.B) {
 	ctx := context.Background()
 	store := outbound.NewInMemoryMemoryStore()
 	generateNotes(ctx, store, 10000)
-	idGen := func() string { return unusedIDGenerator }
+	idGen := agent.IDGeneratorFunc(func() string { return unusedIDGenerator })
 	svc := tooling.NewMemoryToolService(store, idGen)
 
 	b.ResetTimer()
@@ -955,7 +1020,7 @@ func Benchmark_MemoryTools_Search_WithSourceTypes(b *testing.B) {
 	ctx := context.Background()
 	store := outbound.NewInMemoryMemoryStore()
 	generateTypedNotes(ctx, store, 1000)
-	idGen := func() string { return unusedIDGenerator }
+	idGen := agent.IDGeneratorFunc(func() string { return unusedIDGenerator })
 	svc := tooling.NewMemoryToolService(store, idGen)
 
 	b.ResetTimer()
@@ -970,7 +1035,7 @@ func Benchmark_MemoryTools_Search_WithMinImportance(b *testing.B) {
 	ctx := context.Background()
 	store := outbound.NewInMemoryMemoryStore()
 	generateTypedNotes(ctx, store, 1000)
-	idGen := func() string { return unusedIDGenerator }
+	idGen := agent.IDGeneratorFunc(func() string { return unusedIDGenerator })
 	svc := tooling.NewMemoryToolService(store, idGen)
 
 	b.ResetTimer()
@@ -985,7 +1050,7 @@ func Benchmark_MemoryTools_Search_WithAllFilters(b *testing.B) {
 	ctx := context.Background()
 	store := outbound.NewInMemoryMemoryStore()
 	generateTypedNotes(ctx, store, 1000)
-	idGen := func() string { return unusedIDGenerator }
+	idGen := agent.IDGeneratorFunc(func() string { return unusedIDGenerator })
 	svc := tooling.NewMemoryToolService(store, idGen)
 
 	b.ResetTimer()
@@ -1000,10 +1065,10 @@ func Benchmark_MemoryTools_Write_TypedNote(b *testing.B) {
 	ctx := context.Background()
 	store := outbound.NewInMemoryMemoryStore()
 	idCounter := 0
-	idGen := func() string {
+	idGen := agent.IDGeneratorFunc(func() string {
 		idCounter++
 		return fmt.Sprintf("note-%d", idCounter)
-	}
+	})
 	svc := tooling.NewMemoryToolService(store, idGen)
 
 	b.ResetTimer()
@@ -1018,7 +1083,7 @@ func Benchmark_MemoryTools_Get(b *testing.B) {
 	ctx := context.Background()
 	store := outbound.NewInMemoryMemoryStore()
 	generateNotes(ctx, store, 1000)
-	idGen := func() string { return unusedIDGenerator }
+	idGen := agent.IDGeneratorFunc(func() string { return unusedIDGenerator })
 	svc := tooling.NewMemoryToolService(store, idGen)
 
 	b.ResetTimer()
@@ -1041,10 +1106,10 @@ func Benchmark_FullStack_WithMemoryTools(b *testing.B) {
 	generateNotes(ctx, store, 100)
 
 	idCounter := 100
-	idGen := func() string {
+	idGen := agent.IDGeneratorFunc(func() string {
 		idCounter++
 		return fmt.Sprintf("note-%d", idCounter)
-	}
+	})
 	memoryToolSvc := tooling.NewMemoryToolService(store, idGen)
 
 	// Create tool executor with memory tools
@@ -1113,10 +1178,10 @@ func benchmarkWriteAndSearchIteration(ctx context.Context, iteration int) {
 	store := outbound.NewInMemoryMemoryStore()
 
 	idCounter := 0
-	idGen := func() string {
+	idGen := agent.IDGeneratorFunc(func() string {
 		idCounter++
 		return fmt.Sprintf("note-%d-%d", iteration, idCounter)
-	}
+	})
 	memoryToolSvc := tooling.NewMemoryToolService(store, idGen)
 
 	toolExecutor := outbound.NewToolExecutor()
@@ -1599,12 +1664,12 @@ func generateMockSnapshot(id string, fileCount int) indexing.Snapshot {
 }
 
 // snapshotIDGen creates a simple ID generator for benchmarks.
-func snapshotIDGen() func() string {
+func snapshotIDGen() agent.IDGenerator {
 	counter := 0
-	return func() string {
+	return agent.IDGeneratorFunc(func() string {
 		counter++
 		return fmt.Sprintf("snap-%d", counter)
-	}
+	})
 }
 
 // Benchmark_IndexingService_Scan_100Files benchmarks scanning 100 files.
@@ -1659,7 +1724,7 @@ func Benchmark_IndexingService_ChangedSince_100Files(b *testing.B) {
 
 	b.ResetTimer()
 	for b.Loop() {
-		_, _ = svc.ChangedSince(ctx, since)
+		_, _ = svc.ChangedSince(ctx, since, "")
 	}
 }
 
@@ -1676,7 +1741,7 @@ func Benchmark_IndexingService_ChangedSince_1000Files(b *testing.B) {
 
 	b.ResetTimer()
 	for b.Loop() {
-		_, _ = svc.ChangedSince(ctx, since)
+		_, _ = svc.ChangedSince(ctx, since, "")
 	}
 }
 
@@ -1693,7 +1758,7 @@ func Benchmark_IndexingService_ChangedSince_10000Files(b *testing.B) {
 
 	b.ResetTimer()
 	for b.Loop() {
-		_, _ = svc.ChangedSince(ctx, since)
+		_, _ = svc.ChangedSince(ctx, since, "")
 	}
 }
 
@@ -1713,7 +1778,7 @@ func Benchmark_IndexingService_DiffSnapshots_100Files(b *testing.B) {
 
 	b.ResetTimer()
 	for b.Loop() {
-		_, _ = svc.DiffSnapshots(ctx, "snap-1", "snap-2")
+		_, _ = svc.DiffSnapshots(ctx, "snap-1", "snap-2", "")
 	}
 }
 
@@ -1733,7 +1798,7 @@ func Benchmark_IndexingService_DiffSnapshots_1000Files(b *testing.B) {
 
 	b.ResetTimer()
 	for b.Loop() {
-		_, _ = svc.DiffSnapshots(ctx, "snap-1", "snap-2")
+		_, _ = svc.DiffSnapshots(ctx, "snap-1", "snap-2", "")
 	}
 }
 
@@ -1753,7 +1818,7 @@ func Benchmark_IndexingService_DiffSnapshots_10000Files(b *testing.B) {
 
 	b.ResetTimer()
 	for b.Loop() {
-		_, _ = svc.DiffSnapshots(ctx, "snap-1", "snap-2")
+		_, _ = svc.DiffSnapshots(ctx, "snap-1", "snap-2", "")
 	}
 }
 
@@ -2002,6 +2067,56 @@ func Test_parseIndexScanArgs_With_EmptyArgs_Should_UseDefaults(t *testing.T) {
 	}
 }
 
+// Test_formatExtensionBreakdown tests ordering by count and the no-extension label.
+func Test_formatExtensionBreakdown_Should_SortByCountDescending(t *testing.T) {
+	breakdown := map[string]int{".md": 30, ".go": 142, "": 2, ".yaml": 2}
+	result := formatExtensionBreakdown(breakdown)
+
+	expected := "142 .go, 30 .md, 2 (none), 2 .yaml"
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+// Test_parseDryRunFlag tests removing the dry-run flag.
+func Test_parseDryRunFlag_With_Flag_Should_RemoveFlag(t *testing.T) {
+	args := []string{"--dry-run", "./src", "--", "--dry-run"}
+	rest, dryRun := parseDryRunFlag(args)
+
+	if !dryRun {
+		t.Error("Expected dry run to be enabled")
+	}
+	if len(rest) != 3 || rest[0] != "./src" || rest[2] != "--dry-run" {
+		t.Errorf("Unexpected arguments: %v", rest)
+	}
+}
+
+// Test_parseDryRunFlag tests arguments without the dry-run flag.
+func Test_parseDryRunFlag_Without_Flag_Should_KeepArgs(t *testing.T) {
+	args := []string{"./src", "--", ".git"}
+	rest, dryRun := parseDryRunFlag(args)
+
+	if dryRun {
+		t.Error("Expected dry run to be disabled")
+	}
+	if len(rest) != 3 {
+		t.Errorf("Unexpected arguments: %v", rest)
+	}
+}
+
+// Test_parseLabelFlag tests removing the label flag and its value.
+func Test_parseLabelFlag_With_Flag_Should_ReturnLabel(t *testing.T) {
+	args := []string{"--label", "before-refactor", "./src", "--", "--label"}
+	rest, label := parseLabelFlag(args)
+
+	if label != "before-refactor" {
+		t.Errorf("Expected label %q, got %q", "before-refactor", label)
+	}
+	if len(rest) != 3 || rest[0] != "./src" || rest[2] != "--label" {
+		t.Errorf("Unexpected arguments: %v", rest)
+	}
+}
+
 // Test_parseSinceTime tests RFC3339 parsing.
 func Test_parseSinceTime_With_RFC3339_Should_ParseCorrectly(t *testing.T) {
 	args := []string{"2024-01-15T10:00:00Z"}
@@ -2077,33 +2192,34 @@ func Test_truncate_With_Newlines_Should_RemoveThem(t *testing.T) {
 	}
 }
 
-// Test_generateNoteID tests ID generation.
-func Test_generateNoteID_Should_ReturnValidFormat(t *testing.T) {
-	id := generateNoteID()
+// Test_noteIDs tests note ID generation.
+func Test_noteIDs_Should_ReturnValidFormat(t *testing.T) {
+	id := noteIDs.NewID()
 
 	// ID should have correct prefix
-	if id[:5] != "note-" {
+	if !strings.HasPrefix(id, "note-") {
 		t.Error("Expected ID to start with 'note-'")
 	}
 
-	// Verify format: note-<nanoseconds>
-	if len(id) <= 5 {
-		t.Error("Expected ID to have timestamp suffix")
+	// Verify format: note-<uuid>
+	if len(id) != len("note-")+36 {
+		t.Errorf("Expected ID to have UUID suffix, got '%s'", id)
 	}
 }
 
-// Test_generateSnapshotID tests snapshot ID generation.
-func Test_generateSnapshotID_Should_ReturnUniqueIDs(t *testing.T) {
-	id1 := generateSnapshotID()
+// Test_snapshotIDs tests snapshot ID generation.
+func Test_snapshotIDs_Should_ReturnUniqueIDs(t *testing.T) {
+	id1 := snapshotIDs.NewID()
+	id2 := snapshotIDs.NewID()
 
 	// IDs should have the correct prefix
-	if id1[:5] != "snap-" {
+	if !strings.HasPrefix(id1, "snap-") {
 		t.Error("Expected ID to start with 'snap-'")
 	}
 
-	// Verify format: snap-<nanoseconds>
-	if len(id1) <= 5 {
-		t.Error("Expected ID to have timestamp suffix")
+	// IDs should be unique
+	if id1 == id2 {
+		t.Errorf("Expected unique IDs, got '%s' twice", id1)
 	}
 }
 
@@ -2207,3 +2323,616 @@ func Test_buildSearchOptions_WithTags_Should_ReturnOptions(t *testing.T) {
 		t.Errorf("Expected 2 tags, got %d", len(opts.Tags))
 	}
 }
+
+// pingerFunc adapts a function to the pinger interface.
+type pingerFunc func(ctx context.Context) error
+
+func (f pingerFunc) Ping(ctx context.Context) error { return f(ctx) }
+
+// Test_reachability_With_ReachableEndpoint_Should_ReturnCheckmark verifies
+// that a successful ping is shown as reachable.
+func Test_reachability_With_ReachableEndpoint_Should_ReturnCheckmark(t *testing.T) {
+	status := reachability(pingerFunc(func(context.Context) error { return nil }))
+
+	if status != "✅" {
+		t.Errorf("Expected ✅, got %q", status)
+	}
+}
+
+// Test_reachability_With_UnreachableEndpoint_Should_ReturnCrossWithError verifies
+// that a failed ping is shown as unreachable together with the reason.
+func Test_reachability_With_UnreachableEndpoint_Should_ReturnCrossWithError(t *testing.T) {
+	status := reachability(pingerFunc(func(context.Context) error { return fmt.Errorf("connection refused") }))
+
+	if !strings.HasPrefix(status, "❌") || !strings.Contains(status, "connection refused") {
+		t.Errorf("Expected ❌ with reason, got %q", status)
+	}
+}
+
+// Test_defaultSystemPrompt_Should_RenderToolsDateAndUser verifies
+// that the CLI's prompt template renders with the variables main provides.
+func Test_defaultSystemPrompt_Should_RenderToolsDateAndUser(t *testing.T) {
+	tmpl, err := agent.NewPromptTemplate(defaultSystemPrompt)
+	if err != nil {
+		t.Fatalf("Expected template to parse, got %v", err)
+	}
+
+	prompt, err := tmpl.Render(map[string]any{
+		"date":  "Thursday, 2026-01-15",
+		"tools": []agent.ToolDefinition{agent.NewToolDefinition("memory_get", "Retrieve a memory note")},
+		"user":  "alice",
+	})
+
+	if err != nil {
+		t.Fatalf("Expected template to render, got %v", err)
+	}
+	for _, want := range []string{"- memory_get: Retrieve a memory note", "Today is Thursday, 2026-01-15.", "talking to alice"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected prompt to contain %q, got:\n%s", want, prompt)
+		}
+	}
+}
+
+// mockMessageSender implements messageSender for testing.
+type mockMessageSender struct {
+	err      error
+	received string
+	output   chatting.SendMessageOutput
+}
+
+func (m *mockMessageSender) Execute(_ context.Context, input chatting.SendMessageInput) (chatting.SendMessageOutput, error) {
+	m.received = input.Message
+	return m.output, m.err
+}
+
+// Test_runOneShot_With_Success_Should_PrintOnlyResponse verifies
+// that stdout contains nothing but the response.
+func Test_runOneShot_With_Success_Should_PrintOnlyResponse(t *testing.T) {
+	sender := &mockMessageSender{output: chatting.SendMessageOutput{
+		Duration:       "1s",
+		IterationCount: 2,
+		Response:       "42",
+		Success:        true,
+	}}
+	var stdout, stderr strings.Builder
+
+	code := runOneShot(context.Background(), sender, "  What is 6 * 7?\n", outputText, &stdout, &stderr, true)
+
+	if code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if stdout.String() != "42\n" {
+		t.Errorf("Expected stdout to contain only the response, got %q", stdout.String())
+	}
+	if sender.received != "What is 6 * 7?" {
+		t.Errorf("Expected trimmed prompt, got %q", sender.received)
+	}
+	if !strings.Contains(stderr.String(), "2 iterations") {
+		t.Errorf("Expected verbose metrics on stderr, got %q", stderr.String())
+	}
+}
+
+// Test_runOneShot_With_TaskFailure_Should_ReturnNonZeroExitCode verifies
+// that a failed task is reported on stderr with exit code 1.
+func Test_runOneShot_With_TaskFailure_Should_ReturnNonZeroExitCode(t *testing.T) {
+	sender := &mockMessageSender{output: chatting.SendMessageOutput{Error: "max iterations reached"}}
+	var stdout, stderr strings.Builder
+
+	code := runOneShot(context.Background(), sender, "loop forever", outputText, &stdout, &stderr, false)
+
+	if code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Expected empty stdout, got %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "max iterations reached") {
+		t.Errorf("Expected failure on stderr, got %q", stderr.String())
+	}
+}
+
+// Test_runOneShot_With_Error_Should_ReturnNonZeroExitCode verifies
+// that an execution error is reported on stderr with exit code 1.
+func Test_runOneShot_With_Error_Should_ReturnNonZeroExitCode(t *testing.T) {
+	sender := &mockMessageSender{err: fmt.Errorf("connection refused")}
+	var stdout, stderr strings.Builder
+
+	code := runOneShot(context.Background(), sender, "Hello", outputText, &stdout, &stderr, false)
+
+	if code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Expected empty stdout, got %q", stdout.String())
+	}
+}
+
+// Test_runOneShot_With_EmptyPrompt_Should_NotSendMessage verifies
+// that an empty prompt fails without calling the agent.
+func Test_runOneShot_With_EmptyPrompt_Should_NotSendMessage(t *testing.T) {
+	sender := &mockMessageSender{}
+	var stdout, stderr strings.Builder
+
+	code := runOneShot(context.Background(), sender, " \n", outputText, &stdout, &stderr, false)
+
+	if code != 2 {
+		t.Errorf("Expected exit code 2, got %d", code)
+	}
+	if sender.received != "" {
+		t.Errorf("Expected no message to be sent, got %q", sender.received)
+	}
+}
+
+// Test_oneShotPrompt_With_Flag_Should_ReturnFlag verifies
+// that the -prompt flag takes precedence over stdin.
+func Test_oneShotPrompt_With_Flag_Should_ReturnFlag(t *testing.T) {
+	prompt, oneShot, err := oneShotPrompt("Hello", os.Stdin)
+
+	if err != nil || !oneShot || prompt != "Hello" {
+		t.Errorf("Expected (Hello, true, nil), got (%q, %v, %v)", prompt, oneShot, err)
+	}
+}
+
+// Test_oneShotPrompt_With_File_Should_ReadAll verifies
+// that a non-terminal stdin is read completely.
+func Test_oneShotPrompt_With_File_Should_ReadAll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.txt")
+	if err := os.WriteFile(path, []byte("Summarize\nthis"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = file.Close() }()
+
+	prompt, oneShot, err := oneShotPrompt("", file)
+
+	if err != nil || !oneShot || prompt != "Summarize\nthis" {
+		t.Errorf("Expected (Summarize\\nthis, true, nil), got (%q, %v, %v)", prompt, oneShot, err)
+	}
+}
+
+// Test_jsonPrinter_result_Should_WriteResultObject verifies
+// the JSON shape of a send-message result.
+func Test_jsonPrinter_result_Should_WriteResultObject(t *testing.T) {
+	var buf strings.Builder
+	out := jsonPrinter{w: &buf}
+
+	out.result(chatting.SendMessageOutput{
+		Duration:       "1.2s",
+		IterationCount: 2,
+		Response:       "42",
+		Success:        true,
+		Tokens:         agent.TokenUsage{CompletionTokens: 5, PromptTokens: 37, TotalTokens: 42},
+		ToolCallCount:  1,
+	}, false)
+
+	want := `{"duration":"1.2s","response":"42","tokens":{"completion":5,"prompt":37,"total":42},"iterations":2,"tool_calls":1,"success":true}` + "\n"
+	if buf.String() != want {
+		t.Errorf("Expected %s, got %s", want, buf.String())
+	}
+}
+
+// Test_jsonPrinter_memorySearchResults_Should_WriteNotesObject verifies
+// the JSON shape of a memory search result.
+func Test_jsonPrinter_memorySearchResults_Should_WriteNotesObject(t *testing.T) {
+	var buf strings.Builder
+	out := jsonPrinter{w: &buf}
+	note := agent.NewMemoryNote("note-1", agent.SourceTypePreference).
+		WithRawContent("I like Go").
+		WithSummary("Likes Go").
+		WithImportance(4).
+		WithTags("language")
+	note.CreatedAt = time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	note.Embedding = agent.Embedding{0.1, 0.2, 0.3}
+
+	out.memorySearchResults([]*agent.MemoryNote{note})
+
+	var got struct {
+		Notes []map[string]any `json:"notes"`
+		Count int              `json:"count"`
+	}
+	if err := json.Unmarshal([]byte(buf.String()), &got); err != nil {
+		t.Fatalf("Expected valid JSON, got %v: %s", err, buf.String())
+	}
+	if got.Count != 1 || len(got.Notes) != 1 {
+		t.Fatalf("Expected one note, got %s", buf.String())
+	}
+	want := map[string]any{
+		"content":              "I like Go",
+		"created_at":           "2026-01-15T10:00:00Z",
+		"embedding_dimensions": float64(3),
+		"id":                   "note-1",
+		"importance":           float64(4),
+		"source_type":          "preference",
+		"summary":              "Likes Go",
+	}
+	for key, value := range want {
+		if got.Notes[0][key] != value {
+			t.Errorf("Expected %s to be %v, got %v", key, value, got.Notes[0][key])
+		}
+	}
+	if _, ok := got.Notes[0]["embedding"]; ok {
+		t.Error("Expected the raw embedding to be omitted")
+	}
+}
+
+// Test_runOneShot_With_JSONFormat_Should_PrintResultObject verifies
+// that one-shot mode prints the JSON result object to stdout.
+func Test_runOneShot_With_JSONFormat_Should_PrintResultObject(t *testing.T) {
+	sender := &mockMessageSender{output: chatting.SendMessageOutput{Error: "max iterations reached"}}
+	var stdout, stderr strings.Builder
+
+	code := runOneShot(context.Background(), sender, "loop forever", outputJSON, &stdout, &stderr, false)
+
+	if code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), `"success":false`) || !strings.Contains(stdout.String(), `"error":"max iterations reached"`) {
+		t.Errorf("Expected JSON failure result, got %q", stdout.String())
+	}
+}
+
+// Test_newPrinter_With_UnknownFormat_Should_ReturnError verifies
+// that only text and json are accepted.
+func Test_newPrinter_With_UnknownFormat_Should_ReturnError(t *testing.T) {
+	if _, err := newPrinter("yaml", os.Stdout); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+// Test_saveSession_loadSession_Should_RestoreMessagesAndStats verifies
+// that a saved session is resumed with its conversation and statistics.
+func Test_saveSession_loadSession_Should_RestoreMessagesAndStats(t *testing.T) {
+	store := outbound.NewSessionStore(filepath.Join(t.TempDir(), "session.json"))
+	original := agent.NewAgent("demo-agent", "prompt")
+	original.AddMessage(agent.NewMessage(agent.RoleUser, "Hello"))
+	original.AddMessage(agent.NewMessage(agent.RoleAssistant, "Hi!"))
+	original.RecordResult(agent.NewResult("task-1", true, "Hi!").WithTokens(agent.TokenUsage{TotalTokens: 42}))
+	var stderr strings.Builder
+
+	saveSession(store, &original, &stderr)
+	restored := agent.NewAgent("demo-agent", "prompt")
+	loadSession(store, &restored, &stderr)
+
+	stats := chatting.NewGetAgentStatsUseCase(&restored).Execute()
+	if stats.MessageCount != 2 {
+		t.Errorf("Expected 2 messages, got %d", stats.MessageCount)
+	}
+	if stats.TotalTokens != 42 {
+		t.Errorf("Expected 42 tokens, got %d", stats.TotalTokens)
+	}
+	if !strings.Contains(stderr.String(), "Resumed session") {
+		t.Errorf("Expected resume note, got %q", stderr.String())
+	}
+}
+
+// Test_loadSession_With_CorruptFile_Should_StartFresh verifies
+// that an unreadable session file is reported and leaves the agent empty.
+func Test_loadSession_With_CorruptFile_Should_StartFresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := os.WriteFile(path, []byte("{broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ag := agent.NewAgent("demo-agent", "prompt")
+	var stderr strings.Builder
+
+	loadSession(outbound.NewSessionStore(path), &ag, &stderr)
+
+	if ag.MessageCount() != 0 {
+		t.Errorf("Expected 0 messages, got %d", ag.MessageCount())
+	}
+	if !strings.Contains(stderr.String(), "starting fresh") {
+		t.Errorf("Expected warning, got %q", stderr.String())
+	}
+}
+
+// Test_loadHistory_With_SavedFile_Should_RestoreEntries verifies
+// that the input history of a previous session is available again.
+func Test_loadHistory_With_SavedFile_Should_RestoreEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(path, []byte("hello\nstats\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var stderr strings.Builder
+
+	history := loadHistory(path, &stderr)
+
+	if history.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", history.Len())
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("Expected no warning, got %q", stderr.String())
+	}
+}
+
+// Test_handleCommand_With_Trace_Should_PrintLastTaskToolCalls verifies
+// that the trace command reports the tool calls of the most recent task.
+func Test_handleCommand_With_Trace_Should_PrintLastTaskToolCalls(t *testing.T) {
+	ag := agent.NewAgent("demo-agent", "prompt", agent.WithTaskHistory(taskHistorySize))
+	task := agent.NewTask("task-1", "chat", "What is 6 * 7?")
+	task.IncrementIterations()
+	call := agent.NewToolCall("tc-1", "calculate", `{"expression":"6*7"}`)
+	call.Complete("42")
+	task.RecordToolCalls([]agent.ToolCall{call})
+	task.Complete("42")
+	ag.RecordTask(task)
+	uc := &useCases{getLastTaskTrace: chatting.NewGetLastTaskTraceUseCase(&ag)}
+	var buf strings.Builder
+
+	handled, _ := handleCommand(context.Background(), "trace", uc, jsonPrinter{w: &buf})
+
+	var got struct {
+		TaskID    string `json:"task_id"`
+		ToolCalls []struct {
+			Arguments string `json:"arguments"`
+			Name      string `json:"name"`
+			Result    string `json:"result"`
+			Status    string `json:"status"`
+			Iteration int    `json:"iteration"`
+		} `json:"tool_calls"`
+	}
+	if err := json.Unmarshal([]byte(buf.String()), &got); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", buf.String(), err)
+	}
+	if !handled {
+		t.Error("Expected trace to be handled")
+	}
+	if got.TaskID != "task-1" || len(got.ToolCalls) != 1 {
+		t.Fatalf("Expected one tool call of task-1, got %+v", got)
+	}
+	if tc := got.ToolCalls[0]; tc.Name != "calculate" || tc.Arguments != `{"expression":"6*7"}` || tc.Result != "42" || tc.Status != "completed" || tc.Iteration != 1 {
+		t.Errorf("Unexpected tool call %+v", tc)
+	}
+}
+
+// blockingMessageSender blocks until the context is canceled.
+type blockingMessageSender struct {
+	started chan struct{}
+}
+
+func (m *blockingMessageSender) Execute(ctx context.Context, _ chatting.SendMessageInput) (chatting.SendMessageOutput, error) {
+	close(m.started)
+	<-ctx.Done()
+	return chatting.SendMessageOutput{Error: ctx.Err().Error()}, nil
+}
+
+// Test_sendInterruptible_With_Interrupt_Should_CancelMessage verifies
+// that an interrupt cancels the in-flight message and is reported as canceled.
+func Test_sendInterruptible_With_Interrupt_Should_CancelMessage(t *testing.T) {
+	sender := &blockingMessageSender{started: make(chan struct{})}
+	interrupt := make(chan context.CancelFunc, 1)
+	notify := func(ctx context.Context) (context.Context, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(ctx)
+		interrupt <- cancel
+		return ctx, cancel
+	}
+	go func() {
+		<-sender.started
+		(<-interrupt)()
+	}()
+
+	output, canceled, err := sendInterruptible(context.Background(), sender, "long task", notify)
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !canceled {
+		t.Error("Expected the message to be canceled")
+	}
+	if output.Error != context.Canceled.Error() {
+		t.Errorf("Expected cancellation result, got %q", output.Error)
+	}
+}
+
+// Test_sendInterruptible_Without_Interrupt_Should_NotReportCancel verifies
+// that a completed message is not reported as canceled.
+func Test_sendInterruptible_Without_Interrupt_Should_NotReportCancel(t *testing.T) {
+	sender := &mockMessageSender{output: chatting.SendMessageOutput{Response: "42", Success: true}}
+	notify := func(ctx context.Context) (context.Context, context.CancelFunc) {
+		return context.WithCancel(ctx)
+	}
+
+	output, canceled, err := sendInterruptible(context.Background(), sender, "What is 6 * 7?", notify)
+
+	if err != nil || canceled || output.Response != "42" {
+		t.Errorf("Expected (42, false, nil), got (%q, %v, %v)", output.Response, canceled, err)
+	}
+}
+
+// recordingCloser records whether it was closed and fails with err.
+type recordingCloser struct {
+	err    error
+	closed bool
+}
+
+func (c *recordingCloser) Close(_ context.Context) error {
+	c.closed = true
+	return c.err
+}
+
+// Test_closeAll_With_FailingCloser_Should_CloseRemainingAndReport verifies
+// that a failing component does not keep the others from being closed.
+func Test_closeAll_With_FailingCloser_Should_CloseRemainingAndReport(t *testing.T) {
+	failing := &recordingCloser{err: errors.New("disk full")}
+	remaining := &recordingCloser{}
+	var out strings.Builder
+
+	closeAll(context.Background(), []agent.Closer{failing, remaining}, &out)
+
+	if !failing.closed || !remaining.closed {
+		t.Error("Expected all components to be closed")
+	}
+	if !strings.Contains(out.String(), "disk full") {
+		t.Errorf("Expected the failure to be reported, got %q", out.String())
+	}
+}
+
+// Test_infrastructure_closers_Should_CloseStoresBeforeClients verifies
+// that pending writes are flushed before connections are released.
+func Test_infrastructure_closers_Should_CloseStoresBeforeClients(t *testing.T) {
+	infra := setupInfrastructure("http://localhost:1234", "model", "", "", false, false, "http://localhost:1234", "")
+
+	closers := infra.closers()
+
+	if len(closers) != 3 {
+		t.Fatalf("Expected 3 closers, got %d", len(closers))
+	}
+	if _, ok := closers[0].(*outbound.MemoryStore); !ok {
+		t.Errorf("Expected the memory store first, got %T", closers[0])
+	}
+	if _, ok := closers[2].(*outbound.OpenAIClient); !ok {
+		t.Errorf("Expected the LLM client last, got %T", closers[2])
+	}
+}
+
+// Test_jsonPrinter_snapshot_With_LargeFile_Should_ListLargeFiles verifies
+// that files above the large file threshold are reported in scan results.
+func Test_jsonPrinter_snapshot_With_LargeFile_Should_ListLargeFiles(t *testing.T) {
+	var buf strings.Builder
+	out := jsonPrinter{w: &buf}
+	now := time.Now()
+	snapshot := indexing.NewSnapshot("snap-1", []indexing.FileInfo{
+		indexing.NewFileInfo("/repo/main.go", now, 100),
+		indexing.NewFileInfo("/repo/dump.sql", now, largeFileThreshold+1),
+	})
+
+	out.snapshot(snapshot, false)
+
+	var got struct {
+		LargeFiles []jsonFile `json:"large_files"`
+	}
+	if err := json.Unmarshal([]byte(buf.String()), &got); err != nil {
+		t.Fatalf("Expected valid JSON, got %v: %s", err, buf.String())
+	}
+	if len(got.LargeFiles) != 1 || got.LargeFiles[0].Path != "/repo/dump.sql" {
+		t.Errorf("Expected only dump.sql as large file, got %+v", got.LargeFiles)
+	}
+}
+
+// Test_handleCommand_With_Tool_Should_PrintRawToolResult verifies
+// that the tool command invokes the registered tool with the JSON arguments unchanged.
+func Test_handleCommand_With_Tool_Should_PrintRawToolResult(t *testing.T) {
+	executor := outbound.NewToolExecutor()
+	var gotArgs string
+	executor.RegisterTool("echo", func(_ context.Context, arguments string) (string, error) {
+		gotArgs = arguments
+		return "echoed", nil
+	})
+	uc := &useCases{toolExecutor: executor}
+	var buf strings.Builder
+
+	handled, _ := handleCommand(context.Background(), `tool echo {"text": "hello world"}`, uc, jsonPrinter{w: &buf})
+
+	var got struct {
+		Result string `json:"result"`
+		Tool   string `json:"tool"`
+	}
+	if err := json.Unmarshal([]byte(buf.String()), &got); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", buf.String(), err)
+	}
+	if !handled {
+		t.Error("Expected tool to be handled")
+	}
+	if gotArgs != `{"text": "hello world"}` {
+		t.Errorf("Expected arguments to be passed unchanged, got %q", gotArgs)
+	}
+	if got.Tool != "echo" || got.Result != "echoed" {
+		t.Errorf("Unexpected output %+v", got)
+	}
+}
+
+// Test_handleCommand_With_ToolWithoutArguments_Should_PassEmptyObject verifies
+// that a tool called without arguments receives an empty JSON object.
+func Test_handleCommand_With_ToolWithoutArguments_Should_PassEmptyObject(t *testing.T) {
+	executor := outbound.NewToolExecutor()
+	var gotArgs string
+	executor.RegisterTool("ping", func(_ context.Context, arguments string) (string, error) {
+		gotArgs = arguments
+		return "pong", nil
+	})
+	uc := &useCases{toolExecutor: executor}
+	var buf strings.Builder
+
+	handleCommand(context.Background(), "tool ping", uc, jsonPrinter{w: &buf})
+
+	if gotArgs != "{}" {
+		t.Errorf("Expected empty object, got %q", gotArgs)
+	}
+	if !strings.Contains(buf.String(), `"result":"pong"`) {
+		t.Errorf("Expected result in output, got %q", buf.String())
+	}
+}
+
+// Test_handleCommand_With_UnknownTool_Should_PrintError verifies
+// that calling an unregistered tool reports the error instead of a result.
+func Test_handleCommand_With_UnknownTool_Should_PrintError(t *testing.T) {
+	uc := &useCases{toolExecutor: outbound.NewToolExecutor()}
+	var buf strings.Builder
+
+	handleCommand(context.Background(), "tool missing {}", uc, jsonPrinter{w: &buf})
+
+	if !strings.Contains(buf.String(), `"status":"error"`) {
+		t.Errorf("Expected error output, got %q", buf.String())
+	}
+}
+
+// Test_handleCommand_With_ToolsExport_Should_PrintToolsArray verifies
+// that tools export prints the registered definitions as an OpenAI tools array.
+func Test_handleCommand_With_ToolsExport_Should_PrintToolsArray(t *testing.T) {
+	executor := outbound.NewToolExecutor()
+	executor.RegisterTool("echo", func(_ context.Context, arguments string) (string, error) { return arguments, nil })
+	executor.RegisterToolDefinition(agent.NewToolDefinition("echo", "Echo the text").
+		WithParameterDef(agent.NewParameterDefinition("text", agent.ParamTypeString).WithRequired()))
+	uc := &useCases{toolExecutor: executor}
+	var buf strings.Builder
+
+	handled, _ := handleCommand(context.Background(), "tools export", uc, jsonPrinter{w: &buf})
+
+	var got []openai.Tool
+	if err := json.Unmarshal([]byte(buf.String()), &got); err != nil {
+		t.Fatalf("Expected a JSON array, got %q: %v", buf.String(), err)
+	}
+	if !handled {
+		t.Error("Expected tools to be handled")
+	}
+	if len(got) != 1 || got[0].Function.Name != "echo" || got[0].Type != "function" {
+		t.Fatalf("Unexpected tools %+v", got)
+	}
+	if required := got[0].Function.Parameters.Required; len(required) != 1 || required[0] != "text" {
+		t.Errorf("Expected text to be required, got %v", required)
+	}
+}
+
+// Test_handleCommand_With_IndexLabel_Should_DiffByLabel verifies that a labeled
+// snapshot can be diffed by its label.
+func Test_handleCommand_With_IndexLabel_Should_DiffByLabel(t *testing.T) {
+	ctx := context.Background()
+	store := outbound.NewInMemoryIndexStore()
+	_ = store.SaveSnapshot(ctx, generateMockSnapshot("snap-1", 2))
+	_ = store.SaveSnapshot(ctx, generateMockSnapshot("snap-2", 3))
+	uc := &useCases{indexService: indexing.NewService(&mockFileWalker{}, store, snapshotIDGen())}
+	var labelBuf, diffBuf strings.Builder
+
+	handleCommand(ctx, "index label snap-1 before-refactor", uc, jsonPrinter{w: &labelBuf})
+	handleCommand(ctx, "index diff before-refactor snap-2", uc, jsonPrinter{w: &diffBuf})
+
+	if !strings.Contains(labelBuf.String(), `"status":"success"`) {
+		t.Fatalf("Expected label to succeed, got %q", labelBuf.String())
+	}
+	var got struct {
+		Error string            `json:"error"`
+		From  string            `json:"from"`
+		Added []json.RawMessage `json:"added"`
+	}
+	if err := json.Unmarshal([]byte(diffBuf.String()), &got); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", diffBuf.String(), err)
+	}
+	if got.Error != "" || got.From != "before-refactor" {
+		t.Errorf("Unexpected diff output %q", diffBuf.String())
+	}
+	if len(got.Added) != 1 {
+		t.Errorf("Expected 1 added file, got %d", len(got.Added))
+	}
+}
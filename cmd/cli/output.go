@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+	"github.com/andygeiss/go-agent/internal/domain/chatting"
+	"github.com/andygeiss/go-agent/internal/domain/indexing"
+)
+
+// Output formats selectable with the -output flag (alphabetically sorted).
+const (
+	outputJSON = "json"
+	outputText = "text"
+)
+
+// printer renders the results of chat turns and commands.
+// The text printer produces the emoji-decorated output for humans,
+// the JSON printer one JSON object per line for tooling.
+type printer interface {
+	agentStats(stats chatting.AgentStats)
+	changedFiles(changed indexing.ChangedSinceResult)
+	diffResult(diff indexing.DiffResult, fromID, toID indexing.SnapshotID)
+	error(err error)
+	farewell(stats chatting.AgentStats)
+	memoryNote(note *agent.MemoryNote)
+	memorySearchResults(notes []*agent.MemoryNote)
+	progress(message string)
+	prompt()
+	result(output chatting.SendMessageOutput, verbose bool)
+	snapshot(snapshot indexing.Snapshot, dryRun bool)
+	success(message string, id string)
+	toolResult(name, result string)
+	toolsDocument(document []byte)
+	trace(summary agent.TaskSummary)
+}
+
+// newPrinter creates the printer for the given output format.
+func newPrinter(format string, w io.Writer) (printer, error) {
+	switch format {
+	case outputJSON:
+		return jsonPrinter{w: w}, nil
+	case outputText, "":
+		return textPrinter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q (use %s or %s)", format, outputJSON, outputText)
+	}
+}
+
+// textPrinter prints human-readable output to stdout.
+type textPrinter struct{}
+
+func (textPrinter) agentStats(stats chatting.AgentStats) { printAgentStats(stats) }
+
+func (textPrinter) changedFiles(changed indexing.ChangedSinceResult) { printChangedFiles(changed) }
+
+func (textPrinter) diffResult(diff indexing.DiffResult, fromID, toID indexing.SnapshotID) {
+	printDiffResult(diff, fromID, toID)
+}
+
+func (textPrinter) error(err error) { fmt.Printf("❌ Error: %v\n", err) }
+
+func (textPrinter) farewell(stats chatting.AgentStats) {
+	printFinalStats(stats)
+	fmt.Println("Goodbye! 👋")
+}
+
+func (textPrinter) memoryNote(note *agent.MemoryNote) { printMemoryNote(note) }
+
+func (textPrinter) memorySearchResults(notes []*agent.MemoryNote) { printMemorySearchResults(notes) }
+
+func (textPrinter) progress(message string) { fmt.Println(message) }
+
+func (textPrinter) prompt() { fmt.Print("You: ") }
+
+func (textPrinter) result(output chatting.SendMessageOutput, verbose bool) {
+	printResult(output, verbose)
+}
+
+func (textPrinter) snapshot(snapshot indexing.Snapshot, dryRun bool) {
+	if dryRun {
+		printDryRunSnapshot(snapshot)
+		return
+	}
+	printSnapshot(snapshot)
+}
+
+func (textPrinter) success(message string, _ string) { fmt.Println(message) }
+
+func (textPrinter) toolResult(name, result string) {
+	fmt.Printf("🔧 %s:\n%s\n", name, result)
+}
+
+func (textPrinter) toolsDocument(document []byte) { fmt.Println(string(document)) }
+
+func (textPrinter) trace(summary agent.TaskSummary) { printTrace(summary) }
+
+// jsonPrinter writes each result as a single-line JSON object.
+type jsonPrinter struct {
+	w io.Writer
+}
+
+// jsonFile is the JSON representation of an indexed file.
+type jsonFile struct {
+	ModTime time.Time `json:"mod_time"`
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+}
+
+// jsonNote is the JSON representation of a memory note (without the raw embedding).
+type jsonNote struct {
+	CreatedAt           time.Time `json:"created_at"`
+	Content             string    `json:"content"`
+	ID                  string    `json:"id"`
+	SourceType          string    `json:"source_type"`
+	Summary             string    `json:"summary"`
+	Keywords            []string  `json:"keywords"`
+	Tags                []string  `json:"tags"`
+	EmbeddingDimensions int       `json:"embedding_dimensions"`
+	Importance          int       `json:"importance"`
+}
+
+// jsonTokens is the JSON representation of token usage.
+type jsonTokens struct {
+	Completion int `json:"completion"`
+	Prompt     int `json:"prompt"`
+	Total      int `json:"total"`
+}
+
+func (p jsonPrinter) agentStats(stats chatting.AgentStats) {
+	p.write(struct {
+		AgentID                  string  `json:"agent_id"`
+		Model                    string  `json:"model"`
+		AverageIterationsPerTask float64 `json:"average_iterations_per_task"`
+		CompletedTasks           int     `json:"completed_tasks"`
+		FailedTasks              int     `json:"failed_tasks"`
+		MaxIterations            int     `json:"max_iterations"`
+		MaxMessages              int     `json:"max_messages"`
+		Messages                 int     `json:"messages"`
+		Tasks                    int     `json:"tasks"`
+		Tokens                   int     `json:"tokens"`
+		ToolCalls                int     `json:"tool_calls"`
+	}{
+		AgentID:                  stats.AgentID,
+		AverageIterationsPerTask: stats.AverageIterationsPerTask,
+		CompletedTasks:           stats.CompletedTasks,
+		FailedTasks:              stats.FailedTasks,
+		MaxIterations:            stats.MaxIterations,
+		MaxMessages:              stats.MaxMessages,
+		Messages:                 stats.MessageCount,
+		Model:                    stats.Model,
+		Tasks:                    stats.TaskCount,
+		Tokens:                   stats.TotalTokens,
+		ToolCalls:                stats.TotalToolCalls,
+	})
+}
+
+func (p jsonPrinter) changedFiles(changed indexing.ChangedSinceResult) {
+	p.write(struct {
+		Since      time.Time  `json:"since"`
+		SnapshotID string     `json:"snapshot_id"`
+		Files      []jsonFile `json:"files"`
+		Count      int        `json:"count"`
+	}{Count: len(changed.Files), Files: toJSONFiles(changed.Files), Since: changed.Since, SnapshotID: string(changed.SnapshotID)})
+}
+
+func (p jsonPrinter) diffResult(diff indexing.DiffResult, fromID, toID indexing.SnapshotID) {
+	p.write(struct {
+		From    string     `json:"from"`
+		To      string     `json:"to"`
+		Added   []jsonFile `json:"added"`
+		Changed []jsonFile `json:"changed"`
+		Removed []jsonFile `json:"removed"`
+	}{
+		Added:   toJSONFiles(diff.Added),
+		Changed: toJSONFiles(diff.Changed),
+		From:    string(fromID),
+		Removed: toJSONFiles(diff.Removed),
+		To:      string(toID),
+	})
+}
+
+func (p jsonPrinter) error(err error) {
+	p.write(struct {
+		Error  string `json:"error"`
+		Status string `json:"status"`
+	}{Error: err.Error(), Status: "error"})
+}
+
+// farewell reports the final session statistics.
+func (p jsonPrinter) farewell(stats chatting.AgentStats) { p.agentStats(stats) }
+
+func (p jsonPrinter) memoryNote(note *agent.MemoryNote) {
+	p.write(toJSONNote(note))
+}
+
+func (p jsonPrinter) memorySearchResults(notes []*agent.MemoryNote) {
+	result := struct {
+		Notes []jsonNote `json:"notes"`
+		Count int        `json:"count"`
+	}{Count: len(notes), Notes: make([]jsonNote, len(notes))}
+	for i, note := range notes {
+		result.Notes[i] = toJSONNote(note)
+	}
+	p.write(result)
+}
+
+// progress is omitted in JSON output, so that each command yields exactly one object.
+func (jsonPrinter) progress(string) {}
+
+// prompt is omitted in JSON output; input is read line by line without a prompt.
+func (jsonPrinter) prompt() {}
+
+func (p jsonPrinter) result(output chatting.SendMessageOutput, _ bool) {
+	p.write(struct {
+		Duration   string     `json:"duration"`
+		Error      string     `json:"error,omitempty"`
+		Response   string     `json:"response"`
+		Tokens     jsonTokens `json:"tokens"`
+		Iterations int        `json:"iterations"`
+		ToolCalls  int        `json:"tool_calls"`
+		Success    bool       `json:"success"`
+	}{
+		Duration:   output.Duration,
+		Error:      output.Error,
+		Iterations: output.IterationCount,
+		Response:   output.Response,
+		Success:    output.Success,
+		Tokens: jsonTokens{
+			Completion: output.Tokens.CompletionTokens,
+			Prompt:     output.Tokens.PromptTokens,
+			Total:      output.Tokens.TotalTokens,
+		},
+		ToolCalls: output.ToolCallCount,
+	})
+}
+
+func (p jsonPrinter) snapshot(snapshot indexing.Snapshot, dryRun bool) {
+	p.write(struct {
+		CreatedAt  time.Time      `json:"created_at"`
+		Extensions map[string]int `json:"extensions"`
+		ID         string         `json:"id,omitempty"`
+		Label      string         `json:"label,omitempty"`
+		LargeFiles []jsonFile     `json:"large_files,omitempty"`
+		FileCount  int            `json:"file_count"`
+		TotalSize  int64          `json:"total_size"`
+		DryRun     bool           `json:"dry_run"`
+	}{
+		CreatedAt:  snapshot.CreatedAt,
+		DryRun:     dryRun,
+		Extensions: snapshot.ExtensionBreakdown(),
+		FileCount:  snapshot.FileCount(),
+		ID:         string(snapshot.ID),
+		Label:      snapshot.Label,
+		LargeFiles: toJSONFiles(snapshot.FilesOverSize(largeFileThreshold)),
+		TotalSize:  snapshot.TotalSize(),
+	})
+}
+
+func (p jsonPrinter) success(message string, id string) {
+	p.write(struct {
+		ID      string `json:"id,omitempty"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	}{ID: id, Message: message, Status: "success"})
+}
+
+func (p jsonPrinter) toolResult(name, result string) {
+	p.write(struct {
+		Result string `json:"result"`
+		Status string `json:"status"`
+		Tool   string `json:"tool"`
+	}{Result: result, Status: "success", Tool: name})
+}
+
+// toolsDocument writes the tools array as it is, compacted to a single line.
+func (p jsonPrinter) toolsDocument(document []byte) { p.write(json.RawMessage(document)) }
+
+func (p jsonPrinter) trace(summary agent.TaskSummary) {
+	type jsonToolCall struct {
+		Arguments string `json:"arguments"`
+		Error     string `json:"error,omitempty"`
+		Name      string `json:"name"`
+		Result    string `json:"result,omitempty"`
+		Status    string `json:"status"`
+		Iteration int    `json:"iteration"`
+	}
+	toolCalls := make([]jsonToolCall, len(summary.ToolCalls))
+	for i, tc := range summary.ToolCalls {
+		toolCalls[i] = jsonToolCall{
+			Arguments: tc.Arguments,
+			Error:     tc.Error,
+			Iteration: tc.Iteration,
+			Name:      tc.Name,
+			Result:    tc.Result,
+			Status:    string(tc.Status),
+		}
+	}
+	p.write(struct {
+		Duration   string         `json:"duration"`
+		Status     string         `json:"status"`
+		TaskID     string         `json:"task_id"`
+		ToolCalls  []jsonToolCall `json:"tool_calls"`
+		Iterations int            `json:"iterations"`
+	}{
+		Duration:   summary.Duration.String(),
+		Iterations: summary.Iterations,
+		Status:     string(summary.Status),
+		TaskID:     string(summary.ID),
+		ToolCalls:  toolCalls,
+	})
+}
+
+// write encodes v as a single line; encoding errors are reported as JSON as well.
+func (p jsonPrinter) write(v any) {
+	if err := json.NewEncoder(p.w).Encode(v); err != nil {
+		msg, _ := json.Marshal(err.Error())
+		fmt.Fprintf(p.w, "{\"error\":%s,\"status\":\"error\"}\n", msg)
+	}
+}
+
+// toJSONFiles converts indexed files to their JSON representation.
+func toJSONFiles(files []indexing.FileInfo) []jsonFile {
+	result := make([]jsonFile, len(files))
+	for i, f := range files {
+		result[i] = jsonFile{ModTime: f.ModTime, Path: f.Path, Size: f.Size}
+	}
+	return result
+}
+
+// toJSONNote converts a memory note to its JSON representation.
+func toJSONNote(note *agent.MemoryNote) jsonNote {
+	return jsonNote{
+		Content:             note.RawContent,
+		CreatedAt:           note.CreatedAt,
+		EmbeddingDimensions: len(note.Embedding),
+		ID:                  string(note.ID),
+		Importance:          note.Importance,
+		Keywords:            note.Keywords,
+		SourceType:          string(note.SourceType),
+		Summary:             note.Summary,
+		Tags:                note.Tags,
+	}
+}
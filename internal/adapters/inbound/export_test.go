@@ -0,0 +1,8 @@
+package inbound
+
+import "io"
+
+// EditLine runs the line editor on the given keys, so tests can simulate terminal input.
+func EditLine(in io.RuneReader, out io.Writer, entries []string) (string, error) {
+	return newLineEditor(in, out, entries).edit()
+}
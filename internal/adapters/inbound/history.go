@@ -0,0 +1,92 @@
+package inbound
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// defaultHistorySize is the number of entries kept if NewHistory is called with a non-positive size.
+const defaultHistorySize = 500
+
+// History stores the lines entered in the interactive chat, oldest first.
+// Empty lines and repetitions of the previous line are not recorded,
+// and only the newest entries up to the size limit are kept.
+type History struct {
+	entries []string
+	size    int
+	mu      sync.Mutex
+}
+
+// NewHistory creates an empty History that keeps at most size entries.
+func NewHistory(size int) *History {
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+	return &History{size: size}
+}
+
+// Add records a line. Leading and trailing whitespace is ignored.
+func (h *History) Add(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.ContainsAny(line, "\r\n") {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if n := len(h.entries); n > 0 && h.entries[n-1] == line {
+		return
+	}
+	h.entries = append(h.entries, line)
+	if over := len(h.entries) - h.size; over > 0 {
+		h.entries = append([]string(nil), h.entries[over:]...)
+	}
+}
+
+// Entries returns a copy of the recorded lines, oldest first.
+func (h *History) Entries() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.entries...)
+}
+
+// Len returns the number of recorded lines.
+func (h *History) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.entries)
+}
+
+// Load appends the lines of the history file, one entry per line.
+// A missing file is not an error, so that the first session starts with an empty history.
+func (h *History) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		h.Add(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// Save writes the history to the file, one entry per line.
+// The file is only readable by the current user, as it may contain sensitive input.
+func (h *History) Save(path string) error {
+	var buf bytes.Buffer
+	for _, entry := range h.Entries() {
+		buf.WriteString(entry)
+		buf.WriteByte('\n')
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
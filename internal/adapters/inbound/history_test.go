@@ -0,0 +1,118 @@
+package inbound_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/adapters/inbound"
+)
+
+func Test_History_Add_Should_AppendEntries(t *testing.T) {
+	// Arrange
+	history := inbound.NewHistory(10)
+
+	// Act
+	history.Add("hello")
+	history.Add("  memory list  ")
+	history.Add("")
+
+	// Assert
+	assert.That(t, "entries must be appended in order", history.Entries(), []string{"hello", "memory list"})
+}
+
+func Test_History_Add_With_ConsecutiveDuplicate_Should_RecordOnce(t *testing.T) {
+	// Arrange
+	history := inbound.NewHistory(10)
+
+	// Act
+	history.Add("stats")
+	history.Add("stats")
+	history.Add("help")
+	history.Add("stats")
+
+	// Assert
+	assert.That(t, "only consecutive duplicates must be dropped", history.Entries(), []string{"stats", "help", "stats"})
+}
+
+func Test_History_Add_With_FullHistory_Should_DropOldest(t *testing.T) {
+	// Arrange
+	history := inbound.NewHistory(2)
+
+	// Act
+	history.Add("one")
+	history.Add("two")
+	history.Add("three")
+
+	// Assert
+	assert.That(t, "oldest entry must be dropped", history.Entries(), []string{"two", "three"})
+}
+
+func Test_History_Load_With_SavedHistory_Should_RestoreEntries(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "history")
+	saved := inbound.NewHistory(10)
+	saved.Add("hello")
+	saved.Add("stats")
+	_ = saved.Save(path)
+	loaded := inbound.NewHistory(10)
+
+	// Act
+	err := loaded.Load(path)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "entries must be restored", loaded.Entries(), []string{"hello", "stats"})
+}
+
+func Test_History_Load_With_MissingFile_Should_StartEmpty(t *testing.T) {
+	// Arrange
+	history := inbound.NewHistory(10)
+
+	// Act
+	err := history.Load(filepath.Join(t.TempDir(), "missing"))
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "history must be empty", history.Len(), 0)
+}
+
+func Test_History_Save_Should_WritePrivateFile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "history")
+	history := inbound.NewHistory(10)
+	history.Add("secret")
+
+	// Act
+	err := history.Save(path)
+
+	// Assert
+	info, _ := os.Stat(path)
+	data, _ := os.ReadFile(path)
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "file must be private", info.Mode().Perm(), os.FileMode(0o600))
+	assert.That(t, "file must contain one entry per line", string(data), "secret\n")
+}
+
+func Test_NewLineReader_With_File_Should_ReadLinesWithoutHistory(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "input")
+	_ = os.WriteFile(path, []byte("hello\nstats\n"), 0o600)
+	file, _ := os.Open(path)
+	defer func() { _ = file.Close() }()
+	history := inbound.NewHistory(10)
+	reader := inbound.NewLineReader(file, io.Discard, history)
+
+	// Act
+	first, _ := reader.ReadLine()
+	second, _ := reader.ReadLine()
+	_, err := reader.ReadLine()
+
+	// Assert
+	assert.That(t, "first line must be read", first, "hello")
+	assert.That(t, "second line must be read", second, "stats")
+	assert.That(t, "end of input must be io.EOF", err, io.EOF)
+	assert.That(t, "piped input must not be recorded", history.Len(), 0)
+}
@@ -0,0 +1,278 @@
+package inbound
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Control keys handled by the terminal line reader.
+const (
+	keyBackspace = 0x7f
+	keyCtrlA     = 0x01
+	keyCtrlB     = 0x02
+	keyCtrlC     = 0x03
+	keyCtrlD     = 0x04
+	keyCtrlE     = 0x05
+	keyCtrlF     = 0x06
+	keyCtrlH     = 0x08
+	keyCtrlK     = 0x0b
+	keyCtrlN     = 0x0e
+	keyCtrlP     = 0x10
+	keyCtrlU     = 0x15
+	keyEnter     = '\r'
+	keyEscape    = 0x1b
+	keyNewline   = '\n'
+)
+
+// ErrInterrupted is returned by LineReader.ReadLine if the line was discarded with Ctrl+C.
+var ErrInterrupted = errors.New("interrupted")
+
+// LineReader reads the lines entered in the interactive chat.
+// ReadLine returns io.EOF when the input ends.
+type LineReader interface {
+	ReadLine() (string, error)
+}
+
+// NewLineReader creates a LineReader for the given input.
+// If in is a terminal, lines can be edited and the history is navigated with
+// the up and down arrow keys; every entered line is added to the history.
+// Otherwise (piped input) lines are read as they are and the history is not used.
+func NewLineReader(in *os.File, out io.Writer, history *History) LineReader {
+	if in != nil && history != nil && isTerminal(int(in.Fd())) {
+		return &terminalReader{
+			fd:      int(in.Fd()),
+			history: history,
+			in:      bufio.NewReader(in),
+			out:     out,
+		}
+	}
+	return &scannerReader{scanner: bufio.NewScanner(in)}
+}
+
+// scannerReader reads lines without editing support.
+type scannerReader struct {
+	scanner *bufio.Scanner
+}
+
+// ReadLine returns the next line without its line ending.
+func (r *scannerReader) ReadLine() (string, error) {
+	if r.scanner.Scan() {
+		return r.scanner.Text(), nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// terminalReader reads lines from a terminal in raw mode and handles editing keys itself.
+// The terminal is only switched to raw mode while a line is read, so output
+// printed between two lines behaves as usual.
+type terminalReader struct {
+	history *History
+	in      *bufio.Reader
+	out     io.Writer
+	fd      int
+}
+
+// ReadLine reads and edits a single line.
+func (r *terminalReader) ReadLine() (string, error) {
+	restore, err := enableRawMode(r.fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	line, err := newLineEditor(r.in, r.out, r.history.Entries()).edit()
+	if err != nil {
+		return "", err
+	}
+	r.history.Add(line)
+	return line, nil
+}
+
+// lineEditor holds the state of the line being edited.
+type lineEditor struct {
+	in      io.RuneReader
+	out     io.Writer
+	entries []string // History entries, oldest first
+	draft   []rune   // Line being typed before the history was navigated
+	line    []rune
+	index   int // Position in the history; len(entries) is the draft
+	cursor  int
+}
+
+// newLineEditor creates a lineEditor reading keys from in and echoing to out.
+func newLineEditor(in io.RuneReader, out io.Writer, entries []string) *lineEditor {
+	return &lineEditor{
+		entries: entries,
+		in:      in,
+		index:   len(entries),
+		out:     out,
+	}
+}
+
+// edit processes keys until the line is entered.
+// Ctrl+C discards the line and returns ErrInterrupted; Ctrl+D on an empty line returns io.EOF.
+func (e *lineEditor) edit() (string, error) {
+	for {
+		key, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch key {
+		case keyEnter, keyNewline:
+			fmt.Fprint(e.out, "\n")
+			return string(e.line), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\n")
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(e.line) == 0 {
+				fmt.Fprint(e.out, "\n")
+				return "", io.EOF
+			}
+			e.deleteAtCursor()
+		case keyBackspace, keyCtrlH:
+			e.backspace()
+		case keyCtrlA:
+			e.moveTo(0)
+		case keyCtrlB:
+			e.moveTo(e.cursor - 1)
+		case keyCtrlE:
+			e.moveTo(len(e.line))
+		case keyCtrlF:
+			e.moveTo(e.cursor + 1)
+		case keyCtrlK:
+			e.replace(e.line[:e.cursor], e.cursor)
+		case keyCtrlN:
+			e.navigate(1)
+		case keyCtrlP:
+			e.navigate(-1)
+		case keyCtrlU:
+			e.replace(e.line[e.cursor:], 0)
+		case keyEscape:
+			if err := e.escape(); err != nil {
+				return "", err
+			}
+		default:
+			if key >= ' ' {
+				e.insert(key)
+			}
+		}
+	}
+}
+
+// escape handles the arrow, Home, End, and Delete key sequences (ESC [ ...).
+func (e *lineEditor) escape() error {
+	prefix, _, err := e.in.ReadRune()
+	if err != nil {
+		return err
+	}
+	if prefix != '[' && prefix != 'O' {
+		return nil
+	}
+	key, _, err := e.in.ReadRune()
+	if err != nil {
+		return err
+	}
+
+	switch key {
+	case 'A':
+		e.navigate(-1)
+	case 'B':
+		e.navigate(1)
+	case 'C':
+		e.moveTo(e.cursor + 1)
+	case 'D':
+		e.moveTo(e.cursor - 1)
+	case 'F':
+		e.moveTo(len(e.line))
+	case 'H':
+		e.moveTo(0)
+	case '3':
+		// Delete is sent as ESC [ 3 ~
+		if next, _, err := e.in.ReadRune(); err == nil && next == '~' {
+			e.deleteAtCursor()
+		}
+	}
+	return nil
+}
+
+// backspace removes the rune before the cursor.
+func (e *lineEditor) backspace() {
+	if e.cursor == 0 {
+		return
+	}
+	line := append(append([]rune(nil), e.line[:e.cursor-1]...), e.line[e.cursor:]...)
+	e.replace(line, e.cursor-1)
+}
+
+// deleteAtCursor removes the rune under the cursor.
+func (e *lineEditor) deleteAtCursor() {
+	if e.cursor == len(e.line) {
+		return
+	}
+	line := append(append([]rune(nil), e.line[:e.cursor]...), e.line[e.cursor+1:]...)
+	e.replace(line, e.cursor)
+}
+
+// insert adds a rune at the cursor.
+func (e *lineEditor) insert(r rune) {
+	line := append(append(append([]rune(nil), e.line[:e.cursor]...), r), e.line[e.cursor:]...)
+	e.replace(line, e.cursor+1)
+}
+
+// moveTo moves the cursor to the position, clamped to the line.
+func (e *lineEditor) moveTo(pos int) {
+	pos = min(max(pos, 0), len(e.line))
+	if pos < e.cursor {
+		fmt.Fprintf(e.out, "\x1b[%dD", e.cursor-pos)
+	} else if pos > e.cursor {
+		fmt.Fprintf(e.out, "\x1b[%dC", pos-e.cursor)
+	}
+	e.cursor = pos
+}
+
+// navigate replaces the line with an older (delta < 0) or newer (delta > 0) history entry.
+// The line typed before navigating is restored when moving past the newest entry.
+func (e *lineEditor) navigate(delta int) {
+	index := e.index + delta
+	if index < 0 || index > len(e.entries) {
+		return
+	}
+	if e.index == len(e.entries) {
+		e.draft = e.line
+	}
+	e.index = index
+
+	line := e.draft
+	if index < len(e.entries) {
+		line = []rune(e.entries[index])
+	}
+	e.replace(line, len(line))
+}
+
+// replace redraws the line with new content and places the cursor at pos.
+// The line is redrawn relative to the cursor, so the prompt printed before
+// the editor started is left untouched.
+func (e *lineEditor) replace(line []rune, pos int) {
+	var b strings.Builder
+	if e.cursor > 0 {
+		fmt.Fprintf(&b, "\x1b[%dD", e.cursor)
+	}
+	b.WriteString(string(line))
+	b.WriteString("\x1b[K")
+	if back := len(line) - pos; back > 0 {
+		fmt.Fprintf(&b, "\x1b[%dD", back)
+	}
+	fmt.Fprint(e.out, b.String())
+
+	e.line = line
+	e.cursor = pos
+}
@@ -0,0 +1,175 @@
+package inbound_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/adapters/inbound"
+)
+
+// Key sequences sent by a terminal in raw mode.
+const (
+	keyBackspace = "\x7f"
+	keyCtrlA     = "\x01"
+	keyCtrlC     = "\x03"
+	keyCtrlD     = "\x04"
+	keyCtrlF     = "\x06"
+	keyCtrlK     = "\x0b"
+	keyCtrlU     = "\x15"
+	keyDelete    = "\x1b[3~"
+	keyDown      = "\x1b[B"
+	keyEnter     = "\r"
+	keyLeft      = "\x1b[D"
+	keyUp        = "\x1b[A"
+)
+
+func Test_EditLine_With_InsertMidLine_Should_InsertAtCursor(t *testing.T) {
+	// Arrange
+	keys := "hllo" + keyCtrlA + keyCtrlF + "e" + keyEnter
+
+	// Act
+	line, err := inbound.EditLine(strings.NewReader(keys), io.Discard, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "line must match", line, "hello")
+}
+
+func Test_EditLine_With_BackspaceMidLine_Should_RemoveRuneBeforeCursor(t *testing.T) {
+	// Arrange
+	keys := "heello" + keyLeft + keyLeft + keyLeft + keyBackspace + keyEnter
+
+	// Act
+	line, err := inbound.EditLine(strings.NewReader(keys), io.Discard, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "line must match", line, "hello")
+}
+
+func Test_EditLine_With_DeleteMidLine_Should_RemoveRuneUnderCursor(t *testing.T) {
+	// Arrange
+	keys := "helxlo" + keyLeft + keyLeft + keyLeft + keyDelete + keyEnter
+
+	// Act
+	line, err := inbound.EditLine(strings.NewReader(keys), io.Discard, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "line must match", line, "hello")
+}
+
+func Test_EditLine_With_CtrlU_Should_RemoveTextBeforeCursor(t *testing.T) {
+	// Arrange
+	keys := "draft world" + keyLeft + keyLeft + keyLeft + keyLeft + keyLeft + keyLeft + keyCtrlU + "hello" + keyEnter
+
+	// Act
+	line, err := inbound.EditLine(strings.NewReader(keys), io.Discard, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "line must match", line, "hello world")
+}
+
+func Test_EditLine_With_CtrlK_Should_RemoveTextAfterCursor(t *testing.T) {
+	// Arrange
+	keys := "hello world" + keyLeft + keyLeft + keyLeft + keyLeft + keyLeft + keyLeft + keyCtrlK + keyEnter
+
+	// Act
+	line, err := inbound.EditLine(strings.NewReader(keys), io.Discard, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "line must match", line, "hello")
+}
+
+func Test_EditLine_With_Up_Should_RecallOlderEntries(t *testing.T) {
+	// Arrange
+	entries := []string{"first", "second"}
+	keys := keyUp + keyUp + keyUp + keyEnter
+
+	// Act
+	line, err := inbound.EditLine(strings.NewReader(keys), io.Discard, entries)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "line must be the oldest entry", line, "first")
+}
+
+func Test_EditLine_With_UpAndDown_Should_RestoreDraft(t *testing.T) {
+	// Arrange
+	entries := []string{"first", "second"}
+	keys := "draft" + keyUp + keyUp + keyDown + keyDown + keyDown + keyEnter
+
+	// Act
+	line, err := inbound.EditLine(strings.NewReader(keys), io.Discard, entries)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "line must be the draft", line, "draft")
+}
+
+func Test_EditLine_With_RecalledEntry_Should_AllowEditing(t *testing.T) {
+	// Arrange
+	entries := []string{"memory list"}
+	keys := keyUp + keyBackspace + keyBackspace + keyBackspace + keyBackspace + "get" + keyEnter
+
+	// Act
+	line, err := inbound.EditLine(strings.NewReader(keys), io.Discard, entries)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "line must be the edited entry", line, "memory get")
+	assert.That(t, "history entry must be unchanged", entries[0], "memory list")
+}
+
+func Test_EditLine_With_CtrlC_Should_ReturnErrInterrupted(t *testing.T) {
+	// Arrange
+	var out strings.Builder
+	keys := "discarded" + keyCtrlC
+
+	// Act
+	line, err := inbound.EditLine(strings.NewReader(keys), &out, nil)
+
+	// Assert
+	assert.That(t, "err must be ErrInterrupted", errors.Is(err, inbound.ErrInterrupted), true)
+	assert.That(t, "line must be empty", line, "")
+	assert.That(t, "interrupt must be echoed", strings.HasSuffix(out.String(), "^C\n"), true)
+}
+
+func Test_EditLine_With_CtrlDOnEmptyLine_Should_ReturnEOF(t *testing.T) {
+	// Arrange
+	keys := keyCtrlD
+
+	// Act
+	_, err := inbound.EditLine(strings.NewReader(keys), io.Discard, nil)
+
+	// Assert
+	assert.That(t, "err must be io.EOF", err, io.EOF)
+}
+
+func Test_EditLine_With_CtrlDOnText_Should_DeleteUnderCursor(t *testing.T) {
+	// Arrange
+	keys := "helxlo" + keyLeft + keyLeft + keyLeft + keyCtrlD + keyEnter
+
+	// Act
+	line, err := inbound.EditLine(strings.NewReader(keys), io.Discard, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "line must match", line, "hello")
+}
+
+func Test_EditLine_With_EndOfInput_Should_ReturnEOF(t *testing.T) {
+	// Arrange
+	keys := "unfinished"
+
+	// Act
+	_, err := inbound.EditLine(strings.NewReader(keys), io.Discard, nil)
+
+	// Assert
+	assert.That(t, "err must be io.EOF", err, io.EOF)
+}
@@ -0,0 +1,9 @@
+package inbound
+
+import "syscall"
+
+// ioctl requests to read and write the terminal attributes.
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package inbound
+
+import "errors"
+
+// enableRawMode is not supported on this platform; input is read line by line instead.
+func enableRawMode(int) (func(), error) {
+	return nil, errors.New("raw mode not supported")
+}
+
+// isTerminal always reports false, so that the line-based reader is used.
+func isTerminal(int) bool {
+	return false
+}
@@ -39,6 +39,12 @@ func NewJsonFileConversationStore(path string) *ConversationStore {
 	return NewConversationStore(resource.NewJsonFileAccess[string, Conversation](path))
 }
 
+// Close flushes pending writes of the backend.
+// Returns nil if the backend does not need closing.
+func (s *ConversationStore) Close(ctx context.Context) error {
+	return closeAccess(ctx, s.access)
+}
+
 // Save persists the conversation history for an agent.
 // Creates a new record if none exists, or updates the existing one.
 func (s *ConversationStore) Save(ctx context.Context, agentID agent.AgentID, messages []agent.Message) error {
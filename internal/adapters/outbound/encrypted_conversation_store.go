@@ -24,6 +24,11 @@ func NewEncryptedConversationStore(store *ConversationStore, key [32]byte) *Encr
 	}
 }
 
+// Close closes the wrapped store.
+func (s *EncryptedConversationStore) Close(ctx context.Context) error {
+	return s.store.Close(ctx)
+}
+
 // Save encrypts and persists the conversation history for an agent.
 func (s *EncryptedConversationStore) Save(ctx context.Context, agentID agent.AgentID, messages []agent.Message) error {
 	// Serialize messages to JSON
@@ -3,6 +3,8 @@ package outbound
 import (
 	"context"
 	"encoding/json"
+	"sync"
+	"sync/atomic"
 
 	"github.com/andygeiss/cloud-native-utils/event"
 	"github.com/andygeiss/cloud-native-utils/messaging"
@@ -12,18 +14,39 @@ import (
 // It is defined in the domain/indexing package as an outbound port.
 // It uses a messaging dispatcher from the cloud-native-utils package.
 
+// defaultSubscriberBuffer is the channel capacity for each subscriber.
+const defaultSubscriberBuffer = 64
+
 // EventPublisher represents an event publisher.
+// Besides forwarding events to the messaging dispatcher, it delivers them
+// to in-process subscribers registered via Subscribe.
 type EventPublisher struct {
-	dispatcher messaging.Dispatcher
+	dispatcher  messaging.Dispatcher
+	subscribers map[uint64]subscriber
+	dropped     atomic.Uint64
+	mutex       sync.RWMutex
+	nextID      uint64
+}
+
+// subscriber is an in-process event listener for a single topic.
+type subscriber struct {
+	ch    chan event.Event
+	topic string
 }
 
 // NewEventPublisher creates a new event publisher.
 func NewEventPublisher(dispatcher messaging.Dispatcher) *EventPublisher {
 	return &EventPublisher{
-		dispatcher: dispatcher,
+		dispatcher:  dispatcher,
+		subscribers: make(map[uint64]subscriber),
 	}
 }
 
+// Dropped returns the number of events dropped because a subscriber was too slow.
+func (ep *EventPublisher) Dropped() uint64 {
+	return ep.dropped.Load()
+}
+
 // Publish publishes an event.
 func (ep *EventPublisher) Publish(ctx context.Context, e event.Event) error {
 	// Encode the event to JSON.
@@ -32,6 +55,9 @@ func (ep *EventPublisher) Publish(ctx context.Context, e event.Event) error {
 		return err
 	}
 
+	// Deliver the event to in-process subscribers.
+	ep.notifySubscribers(e)
+
 	// Create a new message with the encoded event.
 	msg := messaging.NewMessage(e.Topic(), encoded)
 
@@ -41,3 +67,46 @@ func (ep *EventPublisher) Publish(ctx context.Context, e event.Event) error {
 	}
 	return nil
 }
+
+// Subscribe registers an in-process subscriber for events with the given topic
+// (e.g. agent.TopicTaskStarted). An empty topic subscribes to all events.
+// Delivery is non-blocking: if the subscriber's buffer is full, the event is
+// dropped and counted in Dropped. The returned function unsubscribes and
+// closes the channel; it is safe to call more than once.
+func (ep *EventPublisher) Subscribe(eventType string) (<-chan event.Event, func()) {
+	ep.mutex.Lock()
+	defer ep.mutex.Unlock()
+
+	id := ep.nextID
+	ep.nextID++
+	ch := make(chan event.Event, defaultSubscriberBuffer)
+	ep.subscribers[id] = subscriber{ch: ch, topic: eventType}
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			ep.mutex.Lock()
+			defer ep.mutex.Unlock()
+			delete(ep.subscribers, id)
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// notifySubscribers delivers the event to all matching subscribers without blocking.
+func (ep *EventPublisher) notifySubscribers(e event.Event) {
+	ep.mutex.RLock()
+	defer ep.mutex.RUnlock()
+
+	for _, sub := range ep.subscribers {
+		if sub.topic != "" && sub.topic != e.Topic() {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			ep.dropped.Add(1)
+		}
+	}
+}
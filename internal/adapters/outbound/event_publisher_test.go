@@ -10,6 +10,7 @@ import (
 	"github.com/andygeiss/cloud-native-utils/messaging"
 	"github.com/andygeiss/cloud-native-utils/service"
 	"github.com/andygeiss/go-agent/internal/adapters/outbound"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
 )
 
 func Test_EventPublisher_Publish_With_ValidEvent_Should_Succeed(t *testing.T) {
@@ -110,6 +111,81 @@ func Test_EventPublisher_Publish_With_MultipleEvents_Should_PreserveTopicOrder(t
 	assert.That(t, "second message topic", dispatcher.publishedMessages[1].Topic, "second")
 }
 
+func Test_EventPublisher_Subscribe_Should_ReceiveMatchingEvents(t *testing.T) {
+	// Arrange
+	publisher := outbound.NewEventPublisher(&mockDispatcher{})
+	started, unsubscribeStarted := publisher.Subscribe(agent.TopicTaskStarted)
+	completed, unsubscribeCompleted := publisher.Subscribe(agent.TopicTaskCompleted)
+	t.Cleanup(unsubscribeStarted)
+	t.Cleanup(unsubscribeCompleted)
+	ctx := context.Background()
+
+	// Act
+	_ = publisher.Publish(ctx, agent.NewEventTaskStarted("task-1", "Task"))
+	_ = publisher.Publish(ctx, agent.NewEventTaskCompleted("task-1", "done"))
+
+	// Assert
+	assert.That(t, "started subscriber must receive 1 event", len(started), 1)
+	assert.That(t, "completed subscriber must receive 1 event", len(completed), 1)
+	startedEvt := (<-started).(agent.EventTaskStarted)
+	completedEvt := (<-completed).(agent.EventTaskCompleted)
+	assert.That(t, "started task id must match", startedEvt.TaskID, "task-1")
+	assert.That(t, "completed output must match", completedEvt.Output, "done")
+}
+
+func Test_EventPublisher_Subscribe_With_EmptyTopic_Should_ReceiveAllEvents(t *testing.T) {
+	// Arrange
+	publisher := outbound.NewEventPublisher(&mockDispatcher{})
+	events, unsubscribe := publisher.Subscribe("")
+	t.Cleanup(unsubscribe)
+	ctx := context.Background()
+
+	// Act
+	_ = publisher.Publish(ctx, agent.NewEventTaskStarted("task-1", "Task"))
+	_ = publisher.Publish(ctx, agent.NewEventTaskCompleted("task-1", "done"))
+
+	// Assert
+	assert.That(t, "subscriber must receive 2 events", len(events), 2)
+}
+
+func Test_EventPublisher_Subscribe_With_Unsubscribe_Should_StopDelivery(t *testing.T) {
+	// Arrange
+	publisher := outbound.NewEventPublisher(&mockDispatcher{})
+	events, unsubscribe := publisher.Subscribe(agent.TopicTaskStarted)
+	ctx := context.Background()
+	_ = publisher.Publish(ctx, agent.NewEventTaskStarted("task-1", "Task"))
+
+	// Act
+	unsubscribe()
+	unsubscribe()
+	_ = publisher.Publish(ctx, agent.NewEventTaskStarted("task-2", "Task"))
+
+	// Assert
+	received := 0
+	for range events {
+		received++
+	}
+	assert.That(t, "only the event before unsubscribe must be received", received, 1)
+}
+
+func Test_EventPublisher_Subscribe_With_SlowSubscriber_Should_DropEvents(t *testing.T) {
+	// Arrange
+	publisher := outbound.NewEventPublisher(&mockDispatcher{})
+	events, unsubscribe := publisher.Subscribe(agent.TopicTaskStarted)
+	t.Cleanup(unsubscribe)
+	ctx := context.Background()
+	total := cap(events) + 5
+
+	// Act
+	for range total {
+		_ = publisher.Publish(ctx, agent.NewEventTaskStarted("task-1", "Task"))
+	}
+
+	// Assert
+	assert.That(t, "buffer must be full", len(events), cap(events))
+	assert.That(t, "overflow must be counted as dropped", publisher.Dropped(), uint64(5))
+}
+
 // mockEvent implements event.Event for testing.
 type mockEvent struct {
 	EventTopic string `json:"topic"`
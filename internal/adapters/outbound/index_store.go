@@ -4,6 +4,9 @@ package outbound
 import (
 	"context"
 	"errors"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/andygeiss/cloud-native-utils/resource"
 	"github.com/andygeiss/go-agent/internal/domain/indexing"
@@ -16,11 +19,19 @@ var ErrSnapshotNotFound = errors.New("snapshot not found")
 
 // IndexStore persists snapshots to a JSON file.
 type IndexStore struct {
-	access resource.Access[string, indexing.Snapshot]
+	access       resource.Access[string, indexing.Snapshot]
+	maxSnapshots int
+	mu           sync.Mutex
 }
 
 // NewIndexStore creates a new IndexStore with the given file path.
+// Paths ending in ".gz" are stored gzip-compressed and written atomically.
 func NewIndexStore(path string) *IndexStore {
+	if strings.HasSuffix(path, gzipSuffix) {
+		return &IndexStore{
+			access: NewAtomicJsonFileAccess[string, indexing.Snapshot](path),
+		}
+	}
 	return &IndexStore{
 		access: resource.NewJsonFileAccess[string, indexing.Snapshot](path),
 	}
@@ -33,6 +44,23 @@ func NewInMemoryIndexStore() *IndexStore {
 	}
 }
 
+// WithMaxSnapshots limits the number of retained snapshots.
+// SaveSnapshot evicts the oldest snapshots (by creation time) beyond n;
+// the snapshot just saved is always kept, so GetLatestSnapshot keeps working.
+// Zero or a negative n retains every snapshot (default).
+func (s *IndexStore) WithMaxSnapshots(n int) *IndexStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxSnapshots = n
+	return s
+}
+
+// Close flushes pending writes of the backend.
+// Returns nil if the backend does not need closing.
+func (s *IndexStore) Close(ctx context.Context) error {
+	return closeAccess(ctx, s.access)
+}
+
 // GetLatestSnapshot retrieves the most recent snapshot.
 // Returns an empty snapshot if none exists.
 func (s *IndexStore) GetLatestSnapshot(ctx context.Context) (indexing.Snapshot, error) {
@@ -64,8 +92,69 @@ func (s *IndexStore) GetSnapshot(ctx context.Context, id indexing.SnapshotID) (i
 	return *snapshot, nil
 }
 
+// GetSnapshotByLabel retrieves the snapshot carrying the label.
+// Returns ErrSnapshotNotFound if no snapshot carries it.
+func (s *IndexStore) GetSnapshotByLabel(ctx context.Context, label string) (indexing.Snapshot, error) {
+	if label == "" {
+		return indexing.Snapshot{}, ErrSnapshotNotFound
+	}
+	all, err := s.access.ReadAll(ctx)
+	if err != nil {
+		return indexing.Snapshot{}, err
+	}
+	for _, snapshot := range all {
+		if snapshot.Label == label {
+			return snapshot, nil
+		}
+	}
+	return indexing.Snapshot{}, ErrSnapshotNotFound
+}
+
+// LabelSnapshot sets the label of the snapshot and removes it from any other snapshot,
+// so that a label always names a single checkpoint. An empty label removes the snapshot's label.
+// Returns ErrSnapshotNotFound if the snapshot does not exist.
+func (s *IndexStore) LabelSnapshot(ctx context.Context, id indexing.SnapshotID, label string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.GetSnapshot(ctx, id); err != nil {
+		return err
+	}
+
+	all, err := s.access.ReadAll(ctx)
+	if err != nil {
+		return err
+	}
+	// The latest pointer holds a copy of a stored snapshot, so it is relabeled as well
+	keys := map[string]indexing.Snapshot{}
+	for _, snapshot := range all {
+		keys[string(snapshot.ID)] = snapshot
+	}
+	if latest, err := s.GetLatestSnapshot(ctx); err == nil && latest.ID != "" {
+		keys[latestSnapshotKey] = latest
+	}
+	for key, snapshot := range keys {
+		switch {
+		case snapshot.ID == id:
+			snapshot.Label = label
+		case label != "" && snapshot.Label == label:
+			snapshot.Label = ""
+		default:
+			continue
+		}
+		if err := s.access.Update(ctx, key, snapshot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SaveSnapshot persists a snapshot and updates the latest pointer.
+// If a snapshot limit is set, the oldest snapshots beyond it are evicted.
 func (s *IndexStore) SaveSnapshot(ctx context.Context, snapshot indexing.Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	key := string(snapshot.ID)
 
 	// Save the snapshot by ID
@@ -82,6 +171,50 @@ func (s *IndexStore) SaveSnapshot(ctx context.Context, snapshot indexing.Snapsho
 	if err != nil && err.Error() == resource.ErrorResourceAlreadyExists {
 		err = s.access.Update(ctx, latestSnapshotKey, snapshot)
 	}
+	if err != nil {
+		return err
+	}
+
+	return s.evictSnapshots(ctx, snapshot.ID)
+}
+
+// evictSnapshots deletes the oldest snapshots beyond the limit, keeping the latest one.
+// The caller must hold the mutex.
+func (s *IndexStore) evictSnapshots(ctx context.Context, latest indexing.SnapshotID) error {
+	if s.maxSnapshots <= 0 {
+		return nil
+	}
+
+	all, err := s.access.ReadAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The latest pointer holds a copy of a stored snapshot, so deduplicate by ID
+	seen := make(map[indexing.SnapshotID]bool, len(all))
+	candidates := make([]indexing.Snapshot, 0, len(all))
+	for _, snapshot := range all {
+		if seen[snapshot.ID] || snapshot.ID == latest {
+			continue
+		}
+		seen[snapshot.ID] = true
+		candidates = append(candidates, snapshot)
+	}
 
-	return err
+	excess := len(candidates) + 1 - s.maxSnapshots
+	if excess <= 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].CreatedAt.Equal(candidates[j].CreatedAt) {
+			return candidates[i].ID < candidates[j].ID
+		}
+		return candidates[i].CreatedAt.Before(candidates[j].CreatedAt)
+	})
+	for _, snapshot := range candidates[:min(excess, len(candidates))] {
+		if err := s.access.Delete(ctx, string(snapshot.ID)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
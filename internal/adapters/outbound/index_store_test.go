@@ -2,6 +2,8 @@ package outbound_test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -108,3 +110,139 @@ func Test_IndexStore_SaveSnapshot_With_ExistingID_Should_UpdateSnapshot(t *testi
 	assert.That(t, "get error must be nil", err == nil, true)
 	assert.That(t, "files count must be 2", len(retrieved.Files), 2)
 }
+
+func Test_IndexStore_With_GzipPath_Should_MatchUncompressedRoundTrip(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "index.json")
+	gzipPath := filepath.Join(dir, "index.json.gz")
+	ctx := context.Background()
+	modTime := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	snapshot := indexing.NewSnapshot("snap-1", []indexing.FileInfo{
+		indexing.NewFileInfo("/path/to/file1.go", modTime, 100).WithHash("abc"),
+		indexing.NewFileInfo("/path/to/file2.go", modTime, 200),
+	}).WithRoots([]string{"/path"}, []string{".git"})
+	_ = outbound.NewIndexStore(plainPath).SaveSnapshot(ctx, snapshot)
+	_ = outbound.NewIndexStore(gzipPath).SaveSnapshot(ctx, snapshot)
+
+	// Act
+	plain, plainErr := outbound.NewIndexStore(plainPath).GetSnapshot(ctx, "snap-1")
+	compressed, gzipErr := outbound.NewIndexStore(gzipPath).GetLatestSnapshot(ctx)
+
+	// Assert
+	assert.That(t, "plain err must be nil", plainErr, nil)
+	assert.That(t, "gzip err must be nil", gzipErr, nil)
+	assert.That(t, "files must match", compressed.Files, plain.Files)
+	assert.That(t, "roots must match", compressed.Roots, plain.Roots)
+	raw, _ := os.ReadFile(gzipPath)
+	assert.That(t, "file must start with gzip header", len(raw) > 2 && raw[0] == 0x1f && raw[1] == 0x8b, true)
+}
+
+func Test_IndexStore_WithMaxSnapshots_Should_EvictOldestSnapshots(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryIndexStore().WithMaxSnapshots(2)
+	ctx := context.Background()
+	base := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	ids := []indexing.SnapshotID{"snap-1", "snap-2", "snap-3", "snap-4"}
+
+	// Act
+	for i, id := range ids {
+		snapshot := indexing.NewSnapshot(id, nil)
+		snapshot.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		_ = store.SaveSnapshot(ctx, snapshot)
+	}
+
+	// Assert
+	_, err1 := store.GetSnapshot(ctx, "snap-1")
+	_, err2 := store.GetSnapshot(ctx, "snap-2")
+	_, err3 := store.GetSnapshot(ctx, "snap-3")
+	_, err4 := store.GetSnapshot(ctx, "snap-4")
+	latest, latestErr := store.GetLatestSnapshot(ctx)
+	assert.That(t, "snap-1 must be evicted", err1, outbound.ErrSnapshotNotFound)
+	assert.That(t, "snap-2 must be evicted", err2, outbound.ErrSnapshotNotFound)
+	assert.That(t, "snap-3 must be retained", err3, nil)
+	assert.That(t, "snap-4 must be retained", err4, nil)
+	assert.That(t, "latest err must be nil", latestErr, nil)
+	assert.That(t, "latest must be snap-4", latest.ID, indexing.SnapshotID("snap-4"))
+}
+
+func Test_IndexStore_WithMaxSnapshots_Should_KeepLatestEvenIfOldest(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryIndexStore().WithMaxSnapshots(1)
+	ctx := context.Background()
+	newer := indexing.NewSnapshot("snap-new", nil)
+	older := indexing.NewSnapshot("snap-old", nil)
+	older.CreatedAt = newer.CreatedAt.Add(-time.Hour)
+	_ = store.SaveSnapshot(ctx, newer)
+
+	// Act
+	err := store.SaveSnapshot(ctx, older)
+
+	// Assert
+	_, newErr := store.GetSnapshot(ctx, "snap-new")
+	latest, _ := store.GetLatestSnapshot(ctx)
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "previous snapshot must be evicted", newErr, outbound.ErrSnapshotNotFound)
+	assert.That(t, "saved snapshot must be latest", latest.ID, indexing.SnapshotID("snap-old"))
+}
+
+func Test_IndexStore_LabelSnapshot_Should_FindSnapshotByLabel(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryIndexStore()
+	ctx := context.Background()
+	_ = store.SaveSnapshot(ctx, indexing.NewSnapshot("snap-1", nil))
+	_ = store.SaveSnapshot(ctx, indexing.NewSnapshot("snap-2", nil))
+
+	// Act
+	err := store.LabelSnapshot(ctx, "snap-1", "before-refactor")
+
+	// Assert
+	assert.That(t, "label error must be nil", err == nil, true)
+	labeled, err := store.GetSnapshotByLabel(ctx, "before-refactor")
+	assert.That(t, "get error must be nil", err == nil, true)
+	assert.That(t, "labeled snapshot must match", string(labeled.ID), "snap-1")
+}
+
+func Test_IndexStore_LabelSnapshot_With_LabelInUse_Should_MoveLabel(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryIndexStore()
+	ctx := context.Background()
+	_ = store.SaveSnapshot(ctx, indexing.NewSnapshot("snap-1", nil))
+	_ = store.SaveSnapshot(ctx, indexing.NewSnapshot("snap-2", nil))
+	_ = store.LabelSnapshot(ctx, "snap-1", "checkpoint")
+
+	// Act
+	err := store.LabelSnapshot(ctx, "snap-2", "checkpoint")
+
+	// Assert
+	assert.That(t, "label error must be nil", err == nil, true)
+	labeled, _ := store.GetSnapshotByLabel(ctx, "checkpoint")
+	assert.That(t, "label must name the newer snapshot", string(labeled.ID), "snap-2")
+	first, _ := store.GetSnapshot(ctx, "snap-1")
+	assert.That(t, "label must be removed from the older snapshot", first.Label, "")
+	latest, _ := store.GetLatestSnapshot(ctx)
+	assert.That(t, "latest pointer must carry the label", latest.Label, "checkpoint")
+}
+
+func Test_IndexStore_LabelSnapshot_With_UnknownID_Should_ReturnErrSnapshotNotFound(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryIndexStore()
+
+	// Act
+	err := store.LabelSnapshot(context.Background(), "missing", "checkpoint")
+
+	// Assert
+	assert.That(t, "error must be ErrSnapshotNotFound", err, outbound.ErrSnapshotNotFound)
+}
+
+func Test_IndexStore_GetSnapshotByLabel_With_UnknownLabel_Should_ReturnErrSnapshotNotFound(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryIndexStore()
+	_ = store.SaveSnapshot(context.Background(), indexing.NewSnapshot("snap-1", nil))
+
+	// Act
+	_, err := store.GetSnapshotByLabel(context.Background(), "missing")
+
+	// Assert
+	assert.That(t, "error must be ErrSnapshotNotFound", err, outbound.ErrSnapshotNotFound)
+}
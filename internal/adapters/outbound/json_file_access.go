@@ -0,0 +1,410 @@
+package outbound
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andygeiss/cloud-native-utils/resource"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+)
+
+// File naming and format markers (alphabetically sorted).
+const (
+	backupSuffix = ".bak" // Appended to the target path to name the backup copy
+	gzipSuffix   = ".gz"  // Paths with this suffix are stored gzip-compressed
+)
+
+// gzipMagic are the leading bytes of every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// AtomicJsonFileAccess implements resource.Access backed by a JSON file.
+// Unlike resource.JsonFileAccess, writes are crash-safe: data is serialized
+// to a temporary file in the same directory, fsynced, and renamed over the
+// target (atomic on POSIX). Every successful save also refreshes a ".bak"
+// copy, which is used as a fallback when the main file cannot be decoded.
+//
+// With WithFlushInterval, changes are buffered in memory and written to disk
+// at most once per interval; reads always see buffered changes immediately.
+//
+// With compression, the JSON is gzip-encoded before the atomic write.
+// Reads detect gzip data automatically, so compression can be switched on
+// for an existing uncompressed file.
+type AtomicJsonFileAccess[K comparable, V any] struct {
+	cache         map[K]V
+	flushErr      error
+	flushTimer    *time.Timer
+	logger        *slog.Logger
+	path          string
+	flushInterval time.Duration
+	mutex         sync.Mutex
+	compressed    bool
+	dirty         bool
+}
+
+// NewAtomicJsonFileAccess creates a new AtomicJsonFileAccess for the given path.
+// The file is created on the first write if it does not exist.
+// Paths ending in ".gz" are stored gzip-compressed.
+func NewAtomicJsonFileAccess[K comparable, V any](path string) *AtomicJsonFileAccess[K, V] {
+	return &AtomicJsonFileAccess[K, V]{
+		compressed: strings.HasSuffix(path, gzipSuffix),
+		path:       path,
+	}
+}
+
+// WithCompression stores the file gzip-compressed regardless of its extension.
+func (a *AtomicJsonFileAccess[K, V]) WithCompression() *AtomicJsonFileAccess[K, V] {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.compressed = true
+	return a
+}
+
+// WithLogger sets an optional logger that reports failures to refresh the backup copy.
+func (a *AtomicJsonFileAccess[K, V]) WithLogger(logger *slog.Logger) *AtomicJsonFileAccess[K, V] {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.logger = logger
+	return a
+}
+
+// WithFlushInterval enables buffered persistence.
+// Changes are kept in memory and flushed to disk at most once per interval.
+// Call Flush or Close to force pending changes to disk.
+// Set interval to 0 to write through on every change (default).
+func (a *AtomicJsonFileAccess[K, V]) WithFlushInterval(interval time.Duration) *AtomicJsonFileAccess[K, V] {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.flushInterval = interval
+	return a
+}
+
+// Close flushes any buffered changes to disk.
+func (a *AtomicJsonFileAccess[K, V]) Close(ctx context.Context) error {
+	return a.Flush(ctx)
+}
+
+// closeAccess closes the backend if it implements agent.Closer.
+// Backends without resources to release, like the in-memory access, need no closing.
+func closeAccess(ctx context.Context, access any) error {
+	if closer, ok := access.(agent.Closer); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
+// Flush writes buffered changes to disk immediately.
+// It returns the error of a failed background flush, if any.
+func (a *AtomicJsonFileAccess[K, V]) Flush(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.flushTimer != nil {
+		a.flushTimer.Stop()
+		a.flushTimer = nil
+	}
+	if err := a.flushLocked(); err != nil {
+		return err
+	}
+	err := a.flushErr
+	a.flushErr = nil
+	return err
+}
+
+// Create creates a new resource.
+func (a *AtomicJsonFileAccess[K, V]) Create(ctx context.Context, key K, value V) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	data, err := a.load()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if data == nil {
+		data = make(map[K]V)
+	}
+
+	if _, exists := data[key]; exists {
+		return errors.New(resource.ErrorResourceAlreadyExists)
+	}
+	data[key] = value
+
+	return a.save(data)
+}
+
+// Delete deletes a resource.
+func (a *AtomicJsonFileAccess[K, V]) Delete(ctx context.Context, key K) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	data, err := a.load()
+	if err != nil {
+		return err
+	}
+
+	if _, exists := data[key]; !exists {
+		return errors.New(resource.ErrorResourceNotFound)
+	}
+	delete(data, key)
+
+	return a.save(data)
+}
+
+// Read reads a resource.
+func (a *AtomicJsonFileAccess[K, V]) Read(ctx context.Context, key K) (*V, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	data, err := a.load()
+	if err != nil {
+		return nil, err
+	}
+
+	value, exists := data[key]
+	if !exists {
+		return nil, errors.New(resource.ErrorResourceNotFound)
+	}
+	return &value, nil
+}
+
+// ReadAll reads all resources.
+func (a *AtomicJsonFileAccess[K, V]) ReadAll(ctx context.Context) ([]V, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	data, err := a.load()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]V, 0, len(data))
+	for _, value := range data {
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// Update updates a resource.
+func (a *AtomicJsonFileAccess[K, V]) Update(ctx context.Context, key K, value V) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	data, err := a.load()
+	if err != nil {
+		return err
+	}
+
+	if _, exists := data[key]; !exists {
+		return errors.New(resource.ErrorResourceNotFound)
+	}
+	data[key] = value
+
+	return a.save(data)
+}
+
+// flushInBackground is invoked by the flush timer.
+func (a *AtomicJsonFileAccess[K, V]) flushInBackground() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.flushTimer = nil
+	if err := a.flushLocked(); err != nil {
+		a.flushErr = err
+	}
+}
+
+// flushLocked writes the cache to disk if it has pending changes.
+// The caller must hold the mutex.
+func (a *AtomicJsonFileAccess[K, V]) flushLocked() error {
+	if !a.dirty {
+		return nil
+	}
+	if err := a.write(a.cache); err != nil {
+		return err
+	}
+	a.dirty = false
+	return nil
+}
+
+// load returns the current data, served from the cache when buffering is enabled.
+func (a *AtomicJsonFileAccess[K, V]) load() (map[K]V, error) {
+	if a.cache != nil {
+		return a.cache, nil
+	}
+
+	data, err := a.read()
+	if a.flushInterval <= 0 {
+		return data, err
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if data == nil {
+		data = make(map[K]V)
+	}
+	a.cache = data
+	return a.cache, nil
+}
+
+// read decodes the main file.
+// If the main file exists but is corrupt, the backup copy is used instead.
+func (a *AtomicJsonFileAccess[K, V]) read() (map[K]V, error) {
+	data, err := decodeJsonFile[K, V](a.path)
+	if err == nil || errors.Is(err, os.ErrNotExist) {
+		return data, err
+	}
+
+	// Main file is unreadable or corrupt, try the backup from the last save
+	backup, backupErr := decodeJsonFile[K, V](a.path + backupSuffix)
+	if backupErr != nil {
+		return nil, err
+	}
+	return backup, nil
+}
+
+// save persists the data, or marks it dirty and schedules a flush when buffering.
+func (a *AtomicJsonFileAccess[K, V]) save(data map[K]V) error {
+	if a.flushInterval <= 0 {
+		return a.write(data)
+	}
+
+	a.cache = data
+	a.dirty = true
+	if a.flushTimer == nil {
+		a.flushTimer = time.AfterFunc(a.flushInterval, a.flushInBackground)
+	}
+	return nil
+}
+
+// write encodes the data and atomically replaces the main file and its backup.
+// The backup is refreshed on a best-effort basis: once the main file is replaced the
+// data is persisted, so a failed backup keeps the previous one and does not fail the write.
+// The failure is reported to the logger, if one is set.
+func (a *AtomicJsonFileAccess[K, V]) write(data map[K]V) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if a.compressed {
+		if encoded, err = gzipBytes(encoded); err != nil {
+			return err
+		}
+	}
+	if err := writeFileAtomic(a.path, encoded); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(a.path+backupSuffix, encoded); err != nil && a.logger != nil {
+		a.logger.Warn("backup write failed", "path", a.path+backupSuffix, "error", err)
+	}
+	return nil
+}
+
+// decodeJsonFile reads the file at path and decodes it into a map.
+// Gzip-compressed files are decompressed transparently.
+func decodeJsonFile[K comparable, V any](path string) (map[K]V, error) {
+	raw, err := os.ReadFile(path) //nolint:gosec // path is configured by the caller
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(raw, gzipMagic) {
+		if raw, err = gunzipBytes(raw); err != nil {
+			return nil, err
+		}
+	}
+	var data map[K]V
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// gunzipBytes decompresses gzip-encoded data.
+func gunzipBytes(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+	return io.ReadAll(reader)
+}
+
+// gzipBytes compresses data with gzip.
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFileAtomic writes data to a temporary file in the target directory,
+// syncs it to disk, and renames it over the target path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	// Remove the temp file on any failure before the rename succeeds
+	cleanup := func(err error) error {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return cleanup(err)
+	}
+	if err := os.Chmod(tmpPath, 0o644); err != nil { //nolint:gosec // match resource.JsonFileAccess permissions
+		return cleanup(err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
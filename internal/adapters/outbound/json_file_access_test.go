@@ -0,0 +1,323 @@
+package outbound_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/adapters/outbound"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+)
+
+func Test_AtomicJsonFileAccess_Create_Should_WriteFileAndBackup(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "data.json")
+	access := outbound.NewAtomicJsonFileAccess[string, string](path)
+
+	// Act
+	err := access.Create(context.Background(), "key", "value")
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	_, statErr := os.Stat(path)
+	assert.That(t, "file must exist", statErr, nil)
+	_, bakErr := os.Stat(path + ".bak")
+	assert.That(t, "backup must exist", bakErr, nil)
+}
+
+func Test_AtomicJsonFileAccess_Create_With_UnwritableBackup_Should_PersistAndSucceed(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "data.json")
+	access := outbound.NewAtomicJsonFileAccess[string, string](path)
+	// A directory in place of the backup makes refreshing the backup fail
+	_ = os.MkdirAll(filepath.Join(path+".bak", "blocker"), 0o700)
+
+	// Act
+	err := access.Create(context.Background(), "key", "value")
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	reread := outbound.NewAtomicJsonFileAccess[string, string](path)
+	value, readErr := reread.Read(context.Background(), "key")
+	assert.That(t, "read err must be nil", readErr, nil)
+	assert.That(t, "value must be persisted", *value, "value")
+}
+
+func Test_AtomicJsonFileAccess_Create_With_UnwritableBackupAndLogger_Should_LogFailure(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "data.json")
+	var logs bytes.Buffer
+	access := outbound.NewAtomicJsonFileAccess[string, string](path).
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	// A directory in place of the backup makes refreshing the backup fail
+	_ = os.MkdirAll(filepath.Join(path+".bak", "blocker"), 0o700)
+
+	// Act
+	err := access.Create(context.Background(), "key", "value")
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "failure must be logged", strings.Contains(logs.String(), "backup write failed"), true)
+	assert.That(t, "backup path must be logged", strings.Contains(logs.String(), path+".bak"), true)
+}
+
+func Test_AtomicJsonFileAccess_Create_Should_LeaveNoTempFiles(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	access := outbound.NewAtomicJsonFileAccess[string, string](path)
+	ctx := context.Background()
+
+	// Act
+	_ = access.Create(ctx, "a", "1")
+	_ = access.Update(ctx, "a", "2")
+	_ = access.Create(ctx, "b", "3")
+
+	// Assert
+	entries, _ := os.ReadDir(dir)
+	assert.That(t, "only file and backup must exist", len(entries), 2)
+}
+
+func Test_AtomicJsonFileAccess_Read_With_TruncatedFile_Should_RecoverFromBackup(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "data.json")
+	access := outbound.NewAtomicJsonFileAccess[string, string](path)
+	ctx := context.Background()
+	_ = access.Create(ctx, "key", "value")
+
+	// Simulate a crash that left a truncated main file behind
+	raw, _ := os.ReadFile(path)
+	_ = os.WriteFile(path, raw[:len(raw)/2], 0o600)
+
+	// Act
+	value, err := outbound.NewAtomicJsonFileAccess[string, string](path).Read(ctx, "key")
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "value must be recovered", *value, "value")
+}
+
+func Test_AtomicJsonFileAccess_Read_With_CorruptFileAndBackup_Should_ReturnError(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "data.json")
+	_ = os.WriteFile(path, []byte(`{"key":`), 0o600)
+	_ = os.WriteFile(path+".bak", []byte(`{"key":`), 0o600)
+	access := outbound.NewAtomicJsonFileAccess[string, string](path)
+
+	// Act
+	_, err := access.Read(context.Background(), "key")
+
+	// Assert
+	assert.That(t, "err must not be nil", err != nil, true)
+}
+
+func Test_AtomicJsonFileAccess_Update_With_TruncatedFile_Should_RewriteMainFile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "data.json")
+	access := outbound.NewAtomicJsonFileAccess[string, string](path)
+	ctx := context.Background()
+	_ = access.Create(ctx, "key", "value")
+	_ = os.WriteFile(path, []byte(`{"ke`), 0o600)
+
+	// Act
+	err := access.Update(ctx, "key", "updated")
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	all, readErr := outbound.NewAtomicJsonFileAccess[string, string](path).ReadAll(ctx)
+	assert.That(t, "read err must be nil", readErr, nil)
+	assert.That(t, "must have 1 value", len(all), 1)
+	assert.That(t, "value must be updated", all[0], "updated")
+}
+
+func Test_AtomicJsonFileAccess_Create_With_GzipPath_Should_WriteCompressedFile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "data.json.gz")
+	access := outbound.NewAtomicJsonFileAccess[string, string](path)
+
+	// Act
+	err := access.Create(context.Background(), "key", "value")
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	raw, _ := os.ReadFile(path)
+	assert.That(t, "file must start with gzip header", bytes.HasPrefix(raw, []byte{0x1f, 0x8b}), true)
+	value, readErr := outbound.NewAtomicJsonFileAccess[string, string](path).Read(context.Background(), "key")
+	assert.That(t, "read err must be nil", readErr, nil)
+	assert.That(t, "value must round-trip", *value, "value")
+}
+
+func Test_AtomicJsonFileAccess_WithCompression_Should_ReadExistingUncompressedFile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "data.json")
+	ctx := context.Background()
+	_ = outbound.NewAtomicJsonFileAccess[string, string](path).Create(ctx, "a", "1")
+	access := outbound.NewAtomicJsonFileAccess[string, string](path).WithCompression()
+
+	// Act
+	err := access.Create(ctx, "b", "2")
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	raw, _ := os.ReadFile(path)
+	assert.That(t, "file must be compressed after write", bytes.HasPrefix(raw, []byte{0x1f, 0x8b}), true)
+	all, readErr := outbound.NewAtomicJsonFileAccess[string, string](path).ReadAll(ctx)
+	assert.That(t, "read err must be nil", readErr, nil)
+	assert.That(t, "both values must be kept", len(all), 2)
+}
+
+func Test_MemoryStore_JsonFile_With_GzipPath_Should_MatchUncompressedRoundTrip(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "memory.json")
+	gzipPath := filepath.Join(dir, "memory.json.gz")
+	ctx := context.Background()
+	for _, path := range []string{plainPath, gzipPath} {
+		store := outbound.NewJsonFileMemoryStore(path)
+		_ = store.Write(ctx, agent.NewFactNote("note-1", "Go has goroutines").WithTags("go"))
+		_ = store.Write(ctx, agent.NewPreferenceNote("note-2", "User prefers tabs"))
+	}
+
+	// Act
+	plainNote, plainErr := outbound.NewJsonFileMemoryStore(plainPath).Get(ctx, "note-1")
+	gzipNote, gzipErr := outbound.NewJsonFileMemoryStore(gzipPath).Get(ctx, "note-1")
+	gzipNotes, searchErr := outbound.NewJsonFileMemoryStore(gzipPath).Search(ctx, "", 10, nil)
+
+	// Assert
+	assert.That(t, "plain err must be nil", plainErr, nil)
+	assert.That(t, "gzip err must be nil", gzipErr, nil)
+	assert.That(t, "search err must be nil", searchErr, nil)
+	assert.That(t, "notes must match", gzipNote.RawContent, plainNote.RawContent)
+	assert.That(t, "tags must match", gzipNote.Tags, plainNote.Tags)
+	assert.That(t, "all notes must be reloaded", len(gzipNotes), 2)
+	plainInfo, _ := os.Stat(plainPath)
+	gzipInfo, _ := os.Stat(gzipPath)
+	assert.That(t, "compressed file must differ from plain file", gzipInfo.Size() != plainInfo.Size(), true)
+}
+
+func Test_MemoryStore_JsonFile_With_TruncatedFile_Should_RecoverNotes(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "memory.json")
+	store := outbound.NewJsonFileMemoryStore(path)
+	ctx := context.Background()
+	_ = store.Write(ctx, agent.NewFactNote("note-1", "Go has goroutines"))
+	_ = store.Write(ctx, agent.NewFactNote("note-2", "Go has channels"))
+
+	raw, _ := os.ReadFile(path)
+	_ = os.WriteFile(path, raw[:len(raw)-10], 0o600)
+
+	// Act
+	reloaded := outbound.NewJsonFileMemoryStore(path)
+	notes, err := reloaded.Search(ctx, "Go", 10, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "both notes must be recovered", len(notes), 2)
+}
+
+func Test_MemoryStore_WithFlushInterval_Should_BufferWritesUntilClose(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "memory.json")
+	store := outbound.NewJsonFileMemoryStore(path).WithFlushInterval(time.Hour)
+	ctx := context.Background()
+
+	// Act
+	for i := range 100 {
+		_ = store.Write(ctx, agent.NewFactNote(agent.NoteID(fmt.Sprintf("note-%d", i)), "buffered fact"))
+	}
+	_, statBeforeClose := os.Stat(path)
+	closeErr := store.Close(ctx)
+
+	// Assert
+	assert.That(t, "file must not be written before close", os.IsNotExist(statBeforeClose), true)
+	assert.That(t, "close err must be nil", closeErr, nil)
+	reloaded := outbound.NewJsonFileMemoryStore(path)
+	notes, _ := reloaded.Search(ctx, "buffered", 0, nil)
+	assert.That(t, "all notes must be flushed", len(notes), 100)
+}
+
+func Test_MemoryStore_WithFlushInterval_Should_ServeBufferedWritesToReads(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "memory.json")
+	store := outbound.NewJsonFileMemoryStore(path).WithFlushInterval(time.Hour)
+	ctx := context.Background()
+	t.Cleanup(func() { _ = store.Close(ctx) })
+	_ = store.Write(ctx, agent.NewFactNote("note-1", "Go has goroutines"))
+	_ = store.Write(ctx, agent.NewFactNote("note-1", "Go has channels"))
+
+	// Act
+	note, getErr := store.Get(ctx, "note-1")
+	notes, searchErr := store.Search(ctx, "channels", 10, nil)
+
+	// Assert
+	assert.That(t, "get err must be nil", getErr, nil)
+	assert.That(t, "get must see latest write", note.RawContent, "Go has channels")
+	assert.That(t, "search err must be nil", searchErr, nil)
+	assert.That(t, "search must see buffered write", len(notes), 1)
+}
+
+func Test_MemoryStore_WithFlushInterval_Should_FlushAfterInterval(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "memory.json")
+	store := outbound.NewJsonFileMemoryStore(path).WithFlushInterval(10 * time.Millisecond)
+	ctx := context.Background()
+
+	// Act
+	_ = store.Write(ctx, agent.NewFactNote("note-1", "flushed fact"))
+	time.Sleep(100 * time.Millisecond)
+
+	// Assert
+	reloaded := outbound.NewJsonFileMemoryStore(path)
+	note, err := reloaded.Get(ctx, "note-1")
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "note must be flushed", note.RawContent, "flushed fact")
+}
+
+func Test_MemoryStore_Close_With_InMemoryBackend_Should_ReturnNil(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore().WithFlushInterval(time.Second)
+
+	// Act
+	err := store.Close(context.Background())
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+}
+
+func Test_AtomicJsonFileAccess_Close_With_FlushInterval_Should_FlushPendingWrites(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "data.json")
+	access := outbound.NewAtomicJsonFileAccess[string, string](path).WithFlushInterval(time.Hour)
+	ctx := context.Background()
+	_ = access.Create(ctx, "key", "value")
+	_, statBeforeClose := os.Stat(path)
+	var closer agent.Closer = access
+
+	// Act
+	err := closer.Close(ctx)
+
+	// Assert
+	assert.That(t, "file must not be written before close", os.IsNotExist(statBeforeClose), true)
+	assert.That(t, "err must be nil", err, nil)
+	value, readErr := outbound.NewAtomicJsonFileAccess[string, string](path).Read(ctx, "key")
+	assert.That(t, "read err must be nil", readErr, nil)
+	assert.That(t, "value must be flushed", *value, "value")
+}
+
+func Test_IndexStore_Close_With_InMemoryBackend_Should_ReturnNil(t *testing.T) {
+	// Arrange
+	var store agent.Closer = outbound.NewInMemoryIndexStore()
+
+	// Act
+	err := store.Close(context.Background())
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+}
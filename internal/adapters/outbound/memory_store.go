@@ -3,9 +3,12 @@ package outbound
 import (
 	"context"
 	"errors"
-	"math"
+	"fmt"
+	"path"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/andygeiss/cloud-native-utils/resource"
 	"github.com/andygeiss/cloud-native-utils/slices"
@@ -20,7 +23,12 @@ var ErrMemoryNoteNotFound = errors.New("memory note not found")
 // Search is performed via basic text matching; for production use with embeddings,
 // consider extending with a vector database.
 type MemoryStore struct {
-	access resource.Access[string, agent.MemoryNote]
+	access           resource.Access[string, agent.MemoryNote]
+	indexes          *memoryIndexes // nil unless enabled via WithIndexes
+	embeddingDim     int            // 0 accepts embeddings of any dimension
+	historySize      int            // 0 keeps no earlier versions
+	writeMu          sync.Mutex     // Serializes the read-modify-write of Write and RecordAccess
+	diacriticFolding bool
 }
 
 // NewMemoryStore creates a MemoryStore with the given storage backend.
@@ -33,33 +41,105 @@ func NewMemoryStore(access resource.Access[string, agent.MemoryNote]) *MemorySto
 
 // NewInMemoryMemoryStore creates a MemoryStore backed by in-memory storage.
 // Useful for testing or when persistence is not required.
+// Secondary indexes are enabled, see WithIndexes.
 func NewInMemoryMemoryStore() *MemoryStore {
-	return NewMemoryStore(resource.NewInMemoryAccess[string, agent.MemoryNote]())
+	return NewMemoryStore(resource.NewInMemoryAccess[string, agent.MemoryNote]()).WithIndexes()
 }
 
 // NewJsonFileMemoryStore creates a MemoryStore backed by a JSON file.
-// The file is created if it does not exist.
+// The file is created if it does not exist. Writes are atomic and a backup
+// copy is kept next to the file to recover from corruption.
+// Paths ending in ".gz" are stored gzip-compressed.
 func NewJsonFileMemoryStore(path string) *MemoryStore {
-	return NewMemoryStore(resource.NewJsonFileAccess[string, agent.MemoryNote](path))
+	return NewMemoryStore(NewAtomicJsonFileAccess[string, agent.MemoryNote](path))
+}
+
+// WithDiacriticFolding makes text search ignore diacritics, so that "cafe" matches "Café".
+// Search is always case-insensitive using Unicode case folding.
+func (s *MemoryStore) WithDiacriticFolding() *MemoryStore {
+	s.diacriticFolding = true
+	return s
+}
+
+// WithEmbeddingDimension makes Write reject notes whose embedding does not have exactly dim
+// dimensions with agent.ErrEmbeddingDimensionMismatch. Mixing dimensions, e.g. after switching
+// the embedding model, would otherwise silently yield zero similarity scores.
+// Notes without an embedding are still accepted. Zero accepts any dimension (default).
+func (s *MemoryStore) WithEmbeddingDimension(dim int) *MemoryStore {
+	s.embeddingDim = dim
+	return s
+}
+
+// WithHistory keeps the last size versions of each note's content. When Write overwrites
+// a note with different content or summary, the previous state is appended to the
+// note's History, dropping the oldest versions beyond size; GetHistory returns them.
+// Zero disables version history (default).
+func (s *MemoryStore) WithHistory(size int) *MemoryStore {
+	s.historySize = size
+	return s
+}
+
+// WithIndexes enables secondary indexes by user ID, source type, and exact tag.
+// Searches filtered by any of these only read the notes the indexes point to,
+// instead of scanning every note; the results are identical to a full scan.
+// The indexes are built from the backend on the first filtered search and then
+// maintained on Write and Delete, so every write to the backend must go through this store.
+// Indexes pay off when single reads are cheap, e.g. for in-memory or buffered backends.
+func (s *MemoryStore) WithIndexes() *MemoryStore {
+	s.indexes = newMemoryIndexes()
+	return s
+}
+
+// WithFlushInterval enables buffered persistence for file-backed stores.
+// Writes are kept in memory and flushed to disk at most once per interval,
+// while Get and Search see buffered writes immediately.
+// Call Close to force a final flush. Has no effect on other backends.
+func (s *MemoryStore) WithFlushInterval(interval time.Duration) *MemoryStore {
+	if fileAccess, ok := s.access.(*AtomicJsonFileAccess[string, agent.MemoryNote]); ok {
+		fileAccess.WithFlushInterval(interval)
+	}
+	return s
+}
+
+// Close flushes pending writes and releases resources held by the backend.
+// Returns nil if the backend does not need closing.
+func (s *MemoryStore) Close(ctx context.Context) error {
+	return closeAccess(ctx, s.access)
 }
 
 // Write stores a new memory note.
 // Creates a new record if none exists, or updates the existing one.
+// An update keeps the access count recorded by RecordAccess.
 func (s *MemoryStore) Write(ctx context.Context, note *agent.MemoryNote) error {
+	if s.embeddingDim > 0 && len(note.Embedding) > 0 && len(note.Embedding) != s.embeddingDim {
+		return fmt.Errorf("%w: note %s has %d dimensions, expected %d",
+			agent.ErrEmbeddingDimensionMismatch, note.ID, len(note.Embedding), s.embeddingDim)
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
 	key := string(note.ID)
 
 	// Try to create new note first (handles non-existent files)
 	err := s.access.Create(ctx, key, *note)
 	if err != nil && err.Error() == resource.ErrorResourceAlreadyExists {
-		// Update existing note
-		return s.access.Update(ctx, key, *note)
+		// Update existing note, keeping the state only the store maintains
+		note, err = s.withStoredState(ctx, note)
+		if err != nil {
+			return err
+		}
+		err = s.access.Update(ctx, key, *note)
+	}
+	if err == nil && s.indexes != nil {
+		s.indexes.add(note)
 	}
 	return err
 }
 
 // Search retrieves notes matching the query and filters.
 // This implementation performs basic text matching on SearchableText.
-// Results are sorted by importance when no embedding is provided.
+// Results are sorted by text relevance, then importance, and carry their relevance as Score.
 // For semantic search with embeddings, use SearchWithEmbedding.
 func (s *MemoryStore) Search(ctx context.Context, query string, limit int, opts *agent.MemorySearchOptions) ([]*agent.MemoryNote, error) {
 	return s.searchWithEmbedding(ctx, query, nil, limit, opts)
@@ -73,34 +153,92 @@ func (s *MemoryStore) SearchWithEmbedding(ctx context.Context, query string, que
 }
 
 // collectCandidates filters notes and computes similarity scores.
-func collectCandidates(allNotes []agent.MemoryNote, query string, queryEmbedding agent.Embedding, opts *agent.MemorySearchOptions) []scoredNote {
-	queryLower := strings.ToLower(query)
+// The query and note text are normalized with fold before matching.
+func collectCandidates(allNotes []agent.MemoryNote, query string, queryEmbedding agent.Embedding, opts *agent.MemorySearchOptions, fold func(string) string) []scoredNote {
+	queryFolded := fold(query)
+	queryTerms := tokenize(queryFolded)
+	wholeWord := opts != nil && opts.WholeWord
+	queryNormalized := queryEmbedding.IsNormalized()
 	candidates := make([]scoredNote, 0, len(allNotes))
 
 	for i := range allNotes {
 		note := &allNotes[i]
 
-		if !matchesFilters(note, opts) || !matchesQuery(note, queryLower) {
+		if !matchesFilters(note, opts) || !matchesQuery(note, queryFolded, fold, wholeWord) {
 			continue
 		}
 
-		score := computeSimilarityScore(queryEmbedding, note.Embedding)
+		var score float64
+		if len(queryEmbedding) > 0 {
+			score = computeSimilarityScore(queryEmbedding, note.Embedding, queryNormalized && note.EmbeddingNormalized)
+		} else {
+			score = computeRelevanceScore(note, queryTerms, fold, wholeWord)
+		}
 		noteCopy := *note
+		noteCopy.Score = score
 		candidates = append(candidates, scoredNote{note: &noteCopy, score: score})
 	}
 
 	return candidates
 }
 
+// Field weights for text relevance scoring: a match in the summary counts
+// more than one in the raw content, which counts more than one in the context.
+const (
+	relevanceWeightContext = 1
+	relevanceWeightRaw     = 2
+	relevanceWeightSummary = 3
+)
+
+// computeRelevanceScore counts the occurrences of each query term in the note's
+// summary, raw content, and context description, weighted by field.
+func computeRelevanceScore(note *agent.MemoryNote, terms []string, fold func(string) string, wholeWord bool) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	count := countTerms
+	if wholeWord {
+		count = countWholeWordTerms
+	}
+	return float64(relevanceWeightSummary*count(fold(note.Summary), terms) +
+		relevanceWeightRaw*count(fold(note.RawContent), terms) +
+		relevanceWeightContext*count(fold(note.ContextDescription), terms))
+}
+
+// countTerms counts the substring occurrences of all terms in text.
+func countTerms(text string, terms []string) int {
+	total := 0
+	for _, term := range terms {
+		total += strings.Count(text, term)
+	}
+	return total
+}
+
+// countWholeWordTerms counts the words of text that equal one of the terms.
+func countWholeWordTerms(text string, terms []string) int {
+	total := 0
+	for _, word := range tokenize(text) {
+		if slices.Contains(terms, word) {
+			total++
+		}
+	}
+	return total
+}
+
 // computeSimilarityScore returns cosine similarity if both embeddings exist, otherwise 0.
-func computeSimilarityScore(queryEmbedding, noteEmbedding agent.Embedding) float32 {
+// When both embeddings are unit vectors, the cheaper dot product is used.
+func computeSimilarityScore(queryEmbedding, noteEmbedding agent.Embedding, normalized bool) float64 {
+	if normalized {
+		return agent.DotProduct(queryEmbedding, noteEmbedding)
+	}
 	if len(queryEmbedding) > 0 && len(noteEmbedding) > 0 {
-		return cosineSimilarity(queryEmbedding, noteEmbedding)
+		return agent.CosineSimilarity(queryEmbedding, noteEmbedding)
 	}
 	return 0
 }
 
-// sortCandidates sorts by semantic score if embeddings were used, otherwise by importance.
+// sortCandidates sorts by semantic score if embeddings were used,
+// otherwise by text relevance and then by importance.
 func sortCandidates(candidates []scoredNote, queryEmbedding agent.Embedding) {
 	if len(queryEmbedding) > 0 {
 		sort.Slice(candidates, func(i, j int) bool {
@@ -108,6 +246,9 @@ func sortCandidates(candidates []scoredNote, queryEmbedding agent.Embedding) {
 		})
 	} else {
 		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].score != candidates[j].score {
+				return candidates[i].score > candidates[j].score
+			}
 			return candidates[i].note.Importance > candidates[j].note.Importance
 		})
 	}
@@ -132,17 +273,35 @@ func matchesFilters(note *agent.MemoryNote, opts *agent.MemorySearchOptions) boo
 	if opts == nil {
 		return true
 	}
-	return matchesImportance(note, opts) &&
+	return matchesCreated(note, opts) &&
+		matchesImportance(note, opts) &&
+		matchesKeywordFilter(note, opts) &&
 		matchesScope(note, opts) &&
 		matchesSourceTypes(note, opts) &&
 		matchesTags(note, opts)
 }
 
-// matchesImportance checks if note meets minimum importance requirement.
+// matchesCreated checks if note was created within the required time range.
+func matchesCreated(note *agent.MemoryNote, opts *agent.MemorySearchOptions) bool {
+	if !opts.CreatedAfter.IsZero() && !note.CreatedAt.After(opts.CreatedAfter) {
+		return false
+	}
+	return opts.CreatedBefore.IsZero() || note.CreatedAt.Before(opts.CreatedBefore)
+}
+
+// matchesImportance checks if note meets the minimum and maximum importance requirements.
 func matchesImportance(note *agent.MemoryNote, opts *agent.MemorySearchOptions) bool {
+	if opts.MaxImportance > 0 && note.Importance > opts.MaxImportance {
+		return false
+	}
 	return opts.MinImportance <= 0 || note.Importance >= opts.MinImportance
 }
 
+// matchesKeywordFilter checks if note has any of the required keywords.
+func matchesKeywordFilter(note *agent.MemoryNote, opts *agent.MemorySearchOptions) bool {
+	return len(opts.Keywords) == 0 || slices.ContainsAny(note.Keywords, opts.Keywords)
+}
+
 // matchesScope checks if note matches user/session/task scope filters.
 func matchesScope(note *agent.MemoryNote, opts *agent.MemorySearchOptions) bool {
 	if opts.UserID != "" && note.UserID != opts.UserID {
@@ -162,9 +321,45 @@ func matchesSourceTypes(note *agent.MemoryNote, opts *agent.MemorySearchOptions)
 	return len(opts.SourceTypes) == 0 || hasAnySourceType(note, opts.SourceTypes)
 }
 
-// matchesTags checks if note has any of the required tags.
+// matchesTags checks if note has any (or, with RequireAllTags, every) of the required tags,
+// using the configured match mode.
 func matchesTags(note *agent.MemoryNote, opts *agent.MemorySearchOptions) bool {
-	return len(opts.Tags) == 0 || hasAnyTag(note, opts.Tags)
+	if len(opts.Tags) == 0 {
+		return true
+	}
+	match := tagMatcher(opts.TagMatch)
+	if opts.RequireAllTags {
+		return hasAllTagsMatching(note, opts.Tags, match)
+	}
+	return hasAnyTagMatching(note, opts.Tags, match)
+}
+
+// tagMatcher returns the comparison function for the given match mode.
+func tagMatcher(mode agent.TagMatch) func(tag, pattern string) bool {
+	switch mode {
+	case agent.TagMatchGlob:
+		return matchesTagGlob
+	case agent.TagMatchPrefix:
+		return matchesTagPrefix
+	default:
+		return matchesTagExact
+	}
+}
+
+// matchesTagExact checks if tag equals the filter.
+func matchesTagExact(tag, filter string) bool {
+	return tag == filter
+}
+
+// matchesTagGlob checks if tag matches the shell pattern. Malformed patterns never match.
+func matchesTagGlob(tag, pattern string) bool {
+	matched, err := path.Match(pattern, tag)
+	return err == nil && matched
+}
+
+// matchesTagPrefix checks if tag starts with prefix, ignoring a trailing "*" on the prefix.
+func matchesTagPrefix(tag, prefix string) bool {
+	return strings.HasPrefix(tag, strings.TrimSuffix(prefix, "*"))
 }
 
 // hasAnySourceType checks if the note's source type matches any of the specified types.
@@ -172,10 +367,29 @@ func hasAnySourceType(note *agent.MemoryNote, sourceTypes []agent.SourceType) bo
 	return slices.Contains(sourceTypes, note.SourceType)
 }
 
-// matchesQuery checks if the note content matches the search query.
-func matchesQuery(note *agent.MemoryNote, queryLower string) bool {
-	searchText := strings.ToLower(note.SearchableText())
-	return strings.Contains(searchText, queryLower) || matchesKeywords(note, queryLower)
+// matchesQuery checks if the note content matches the folded search query.
+// In whole-word mode every query term must equal a word of the note's searchable text.
+func matchesQuery(note *agent.MemoryNote, queryFolded string, fold func(string) string, wholeWord bool) bool {
+	searchText := fold(note.SearchableText())
+	if wholeWord {
+		return matchesWholeWords(searchText, queryFolded)
+	}
+	return strings.Contains(searchText, queryFolded) || matchesKeywords(note, queryFolded, fold)
+}
+
+// matchesWholeWords checks if every term of the query is a word of the text.
+// Words are maximal runs of letters and digits.
+func matchesWholeWords(text, query string) bool {
+	words := make(map[string]struct{})
+	for _, word := range tokenize(text) {
+		words[word] = struct{}{}
+	}
+	for _, term := range tokenize(query) {
+		if _, ok := words[term]; !ok {
+			return false
+		}
+	}
+	return true
 }
 
 // Get retrieves a specific note by ID.
@@ -192,41 +406,165 @@ func (s *MemoryStore) Get(ctx context.Context, id agent.NoteID) (*agent.MemoryNo
 	return note, nil
 }
 
+// GetHistory returns the earlier versions of a note, oldest first.
+// The history is only recorded if enabled via WithHistory.
+// Returns ErrMemoryNoteNotFound if the note is not found.
+func (s *MemoryStore) GetHistory(ctx context.Context, id agent.NoteID) ([]agent.NoteVersion, error) {
+	note, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return note.History, nil
+}
+
 // Delete removes a note by ID.
 // Returns nil if the note does not exist.
 func (s *MemoryStore) Delete(ctx context.Context, id agent.NoteID) error {
 	key := string(id)
 	err := s.access.Delete(ctx, key)
+	if s.indexes != nil && (err == nil || err.Error() == resource.ErrorResourceNotFound) {
+		s.indexes.remove(id)
+	}
 	if err != nil && err.Error() == resource.ErrorResourceNotFound {
 		return nil // Not an error if note doesn't exist
 	}
 	return err
 }
 
+// RecordAccess increments the access count of a note.
+// The note is updated in place, so neither its UpdatedAt nor its history change.
+// Returns ErrMemoryNoteNotFound if the note is not found.
+func (s *MemoryStore) RecordAccess(ctx context.Context, id agent.NoteID) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	note, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	note.AccessCount++
+	return s.access.Update(ctx, string(id), *note)
+}
+
+// withStoredState returns a copy of the note carrying the access count of the stored note.
+// With WithHistory, the copy also carries the history of the stored note, extended by
+// the stored version if the content changed and capped to historySize.
+// A note that is not stored yet is returned unchanged.
+func (s *MemoryStore) withStoredState(ctx context.Context, note *agent.MemoryNote) (*agent.MemoryNote, error) {
+	previous, err := s.access.Read(ctx, string(note.ID))
+	if err != nil {
+		if err.Error() == resource.ErrorResourceNotFound {
+			return note, nil
+		}
+		return nil, err
+	}
+
+	stored := *note
+	stored.AccessCount = previous.AccessCount
+	if s.historySize > 0 {
+		stored.History = s.extendHistory(previous, note)
+	}
+	return &stored, nil
+}
+
+// extendHistory returns the history of the previous note, extended by its version
+// if the content of the note differs from it and capped to historySize.
+func (s *MemoryStore) extendHistory(previous, note *agent.MemoryNote) []agent.NoteVersion {
+	history := append([]agent.NoteVersion(nil), previous.History...)
+	if previous.RawContent != note.RawContent || previous.Summary != note.Summary {
+		updatedAt := previous.UpdatedAt
+		if updatedAt.IsZero() {
+			updatedAt = previous.CreatedAt
+		}
+		history = append(history, agent.NoteVersion{
+			RawContent: previous.RawContent,
+			Summary:    previous.Summary,
+			UpdatedAt:  updatedAt,
+		})
+	}
+	if len(history) > s.historySize {
+		history = history[len(history)-s.historySize:]
+	}
+	return history
+}
+
 // searchWithEmbedding is the internal implementation for search with optional embedding support.
 func (s *MemoryStore) searchWithEmbedding(ctx context.Context, query string, queryEmbedding agent.Embedding, limit int, opts *agent.MemorySearchOptions) ([]*agent.MemoryNote, error) {
-	allNotes, err := s.access.ReadAll(ctx)
+	allNotes, err := s.readSearchScope(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	candidates := collectCandidates(allNotes, query, queryEmbedding, opts)
+	candidates := collectCandidates(allNotes, query, queryEmbedding, opts, s.textFolder())
 	sortCandidates(candidates, queryEmbedding)
 	return extractResults(candidates, limit), nil
 }
 
-// hasAnyTag checks if the note has any of the specified tags.
-func hasAnyTag(note *agent.MemoryNote, tags []string) bool {
-	return slices.ContainsAny(note.Tags, tags)
+// readSearchScope returns the notes a search has to consider.
+// With indexes and an indexed filter, only the candidate notes are read; otherwise all notes.
+func (s *MemoryStore) readSearchScope(ctx context.Context, opts *agent.MemorySearchOptions) ([]agent.MemoryNote, error) {
+	if s.indexes == nil {
+		return s.access.ReadAll(ctx)
+	}
+	if err := s.indexes.build(ctx, s.access.ReadAll); err != nil {
+		return nil, err
+	}
+	ids, ok := s.indexes.candidates(opts)
+	if !ok {
+		return s.access.ReadAll(ctx)
+	}
+
+	notes := make([]agent.MemoryNote, 0, len(ids))
+	for _, id := range ids {
+		note, err := s.access.Read(ctx, string(id))
+		if err != nil {
+			if err.Error() == resource.ErrorResourceNotFound {
+				continue // Deleted concurrently
+			}
+			return nil, err
+		}
+		notes = append(notes, *note)
+	}
+	return notes, nil
+}
+
+// hasAllTagsMatching checks if every pattern matches at least one of the note's tags.
+func hasAllTagsMatching(note *agent.MemoryNote, patterns []string, match func(tag, pattern string) bool) bool {
+	for _, pattern := range patterns {
+		if !hasAnyTagMatching(note, []string{pattern}, match) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasAnyTagMatching checks if any of the note's tags matches any of the patterns.
+func hasAnyTagMatching(note *agent.MemoryNote, patterns []string, match func(tag, pattern string) bool) bool {
+	for _, tag := range note.Tags {
+		for _, pattern := range patterns {
+			if match(tag, pattern) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// textFolder returns the normalization applied to queries and note text before matching.
+func (s *MemoryStore) textFolder() func(string) string {
+	if s.diacriticFolding {
+		return foldDiacritics
+	}
+	return foldCase
 }
 
-// matchesKeywords checks if the query matches any of the note's keywords.
-func matchesKeywords(note *agent.MemoryNote, queryLower string) bool {
-	queryWords := strings.Fields(queryLower)
+// matchesKeywords checks if the folded query matches any of the note's keywords.
+func matchesKeywords(note *agent.MemoryNote, queryFolded string, fold func(string) string) bool {
+	queryWords := strings.Fields(queryFolded)
 	for _, keyword := range note.Keywords {
-		keywordLower := strings.ToLower(keyword)
+		keywordFolded := fold(keyword)
 		for _, queryWord := range queryWords {
-			if strings.Contains(keywordLower, queryWord) || strings.Contains(queryWord, keywordLower) {
+			if strings.Contains(keywordFolded, queryWord) || strings.Contains(queryWord, keywordFolded) {
 				return true
 			}
 		}
@@ -234,32 +572,9 @@ func matchesKeywords(note *agent.MemoryNote, queryLower string) bool {
 	return false
 }
 
-// scoredNote pairs a memory note with its semantic similarity score.
+// scoredNote pairs a memory note with its semantic similarity or text relevance score.
 // Used for sorting search results by relevance.
 type scoredNote struct {
 	note  *agent.MemoryNote
-	score float32
-}
-
-// cosineSimilarity computes the cosine similarity between two embeddings.
-// Returns 0 if either embedding is nil, empty, or if lengths don't match.
-// Returns a value between -1 and 1, where 1 indicates identical direction.
-func cosineSimilarity(a, b agent.Embedding) float32 {
-	if len(a) == 0 || len(b) == 0 || len(a) != len(b) {
-		return 0
-	}
-
-	var dot, na, nb float32
-	for i := range a {
-		v1 := a[i]
-		v2 := b[i]
-		dot += v1 * v2
-		na += v1 * v1
-		nb += v2 * v2
-	}
-	if na == 0 || nb == 0 {
-		return 0
-	}
-
-	return dot / (float32(math.Sqrt(float64(na))) * float32(math.Sqrt(float64(nb))))
+	score float64
 }
@@ -0,0 +1,198 @@
+package outbound
+
+import (
+	"context"
+	"sync"
+
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+)
+
+// noteSet is a set of note IDs.
+type noteSet map[agent.NoteID]struct{}
+
+// noteIndex is an inverted index from keys (e.g. a user ID or a tag) to the notes carrying them.
+type noteIndex struct {
+	notes map[string]noteSet
+	keys  map[agent.NoteID][]string
+}
+
+// newNoteIndex creates an empty noteIndex.
+func newNoteIndex() *noteIndex {
+	return &noteIndex{
+		notes: make(map[string]noteSet),
+		keys:  make(map[agent.NoteID][]string),
+	}
+}
+
+// add records the keys of the note, replacing any previously recorded keys.
+func (i *noteIndex) add(id agent.NoteID, keys ...string) {
+	i.remove(id)
+	for _, key := range keys {
+		if i.notes[key] == nil {
+			i.notes[key] = make(noteSet)
+		}
+		i.notes[key][id] = struct{}{}
+	}
+	i.keys[id] = append([]string(nil), keys...)
+}
+
+// intersection returns the notes carrying every key.
+func (i *noteIndex) intersection(keys []string) noteSet {
+	result := i.union(keys[:1])
+	for _, key := range keys[1:] {
+		result = intersect(result, i.notes[key])
+	}
+	return result
+}
+
+// remove forgets the note.
+func (i *noteIndex) remove(id agent.NoteID) {
+	for _, key := range i.keys[id] {
+		delete(i.notes[key], id)
+		if len(i.notes[key]) == 0 {
+			delete(i.notes, key)
+		}
+	}
+	delete(i.keys, id)
+}
+
+// union returns the notes carrying any of the keys.
+func (i *noteIndex) union(keys []string) noteSet {
+	result := make(noteSet)
+	for _, key := range keys {
+		for id := range i.notes[key] {
+			result[id] = struct{}{}
+		}
+	}
+	return result
+}
+
+// memoryIndexes holds the secondary indexes of a MemoryStore.
+// They narrow down the notes a filtered search has to read; the filters are still
+// applied to every candidate, so results are identical to a full scan.
+// The indexes are built from the backend on first use and then maintained on
+// Write and Delete, so they are only correct if every write goes through the store.
+type memoryIndexes struct {
+	bySourceType *noteIndex
+	byTag        *noteIndex
+	byUser       *noteIndex
+	built        bool
+	mu           sync.RWMutex
+}
+
+// newMemoryIndexes creates empty, not yet built indexes.
+func newMemoryIndexes() *memoryIndexes {
+	return &memoryIndexes{
+		bySourceType: newNoteIndex(),
+		byTag:        newNoteIndex(),
+		byUser:       newNoteIndex(),
+	}
+}
+
+// add indexes the note, replacing its previous entries.
+func (x *memoryIndexes) add(note *agent.MemoryNote) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.addLocked(note)
+}
+
+// addLocked indexes the note. The caller must hold the mutex.
+func (x *memoryIndexes) addLocked(note *agent.MemoryNote) {
+	x.bySourceType.add(note.ID, string(note.SourceType))
+	x.byTag.add(note.ID, note.Tags...)
+	x.byUser.add(note.ID, note.UserID)
+}
+
+// build indexes all notes returned by readAll, unless the indexes are already built.
+func (x *memoryIndexes) build(ctx context.Context, readAll func(context.Context) ([]agent.MemoryNote, error)) error {
+	x.mu.RLock()
+	built := x.built
+	x.mu.RUnlock()
+	if built {
+		return nil
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.built {
+		return nil
+	}
+	notes, err := readAll(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range notes {
+		x.addLocked(&notes[i])
+	}
+	x.built = true
+	return nil
+}
+
+// candidates returns the IDs of the notes that may match the filters.
+// Returns false if no filter can be answered by an index, in which case all notes must be scanned.
+func (x *memoryIndexes) candidates(opts *agent.MemorySearchOptions) ([]agent.NoteID, bool) {
+	if opts == nil {
+		return nil, false
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var sets []noteSet
+	if opts.UserID != "" {
+		sets = append(sets, x.byUser.union([]string{opts.UserID}))
+	}
+	if len(opts.SourceTypes) > 0 {
+		keys := make([]string, len(opts.SourceTypes))
+		for i, sourceType := range opts.SourceTypes {
+			keys[i] = string(sourceType)
+		}
+		sets = append(sets, x.bySourceType.union(keys))
+	}
+	// Only exact tags can be looked up; glob and prefix patterns need a scan
+	if len(opts.Tags) > 0 && (opts.TagMatch == "" || opts.TagMatch == agent.TagMatchExact) {
+		if opts.RequireAllTags {
+			sets = append(sets, x.byTag.intersection(opts.Tags))
+		} else {
+			sets = append(sets, x.byTag.union(opts.Tags))
+		}
+	}
+	if len(sets) == 0 {
+		return nil, false
+	}
+
+	result := sets[0]
+	for _, set := range sets[1:] {
+		result = intersect(result, set)
+	}
+	ids := make([]agent.NoteID, 0, len(result))
+	for id := range result {
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// remove forgets the note.
+func (x *memoryIndexes) remove(id agent.NoteID) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.bySourceType.remove(id)
+	x.byTag.remove(id)
+	x.byUser.remove(id)
+}
+
+// intersect returns the notes contained in both sets.
+func intersect(a, b noteSet) noteSet {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	result := make(noteSet, len(a))
+	for id := range a {
+		if _, ok := b[id]; ok {
+			result[id] = struct{}{}
+		}
+	}
+	return result
+}
@@ -3,9 +3,14 @@ package outbound_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/cloud-native-utils/resource"
 	"github.com/andygeiss/go-agent/internal/adapters/outbound"
 	"github.com/andygeiss/go-agent/internal/domain/agent"
 )
@@ -47,6 +52,227 @@ func Test_MemoryStore_Write_Should_UpdateExistingNote(t *testing.T) {
 	assert.That(t, "content must be updated", retrieved.RawContent, "Updated content")
 }
 
+func Test_MemoryStore_RecordAccess_Should_IncrementAccessCount(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore().WithHistory(5)
+	note := agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithRawContent("Go is fun")
+	_ = store.Write(context.Background(), note)
+	before, _ := store.Get(context.Background(), "note-1")
+	updatedAt := before.UpdatedAt
+
+	// Act
+	var err error
+	for range 3 {
+		err = store.RecordAccess(context.Background(), "note-1")
+	}
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	retrieved, _ := store.Get(context.Background(), "note-1")
+	assert.That(t, "access count must be incremented", retrieved.AccessCount, 3)
+	assert.That(t, "updated at must not change", retrieved.UpdatedAt, updatedAt)
+	assert.That(t, "history must not change", len(retrieved.History), 0)
+}
+
+func Test_MemoryStore_RecordAccess_With_UnknownNote_Should_ReturnNotFound(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+
+	// Act
+	err := store.RecordAccess(context.Background(), "missing")
+
+	// Assert
+	assert.That(t, "error must be not found", errors.Is(err, outbound.ErrMemoryNoteNotFound), true)
+}
+
+func Test_MemoryStore_Write_With_AccessedNote_Should_KeepAccessCount(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+	ctx := context.Background()
+	_ = store.Write(ctx, agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithRawContent("Original"))
+	_ = store.RecordAccess(ctx, "note-1")
+	_ = store.RecordAccess(ctx, "note-1")
+
+	// Act
+	err := store.Write(ctx, agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithRawContent("Updated"))
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	retrieved, _ := store.Get(ctx, "note-1")
+	assert.That(t, "content must be updated", retrieved.RawContent, "Updated")
+	assert.That(t, "access count must be kept", retrieved.AccessCount, 2)
+}
+
+// pausingAccess is a resource.Access that pauses the first read after being armed,
+// so a concurrent writer can run between the read and the following update.
+type pausingAccess struct {
+	resource.Access[string, agent.MemoryNote]
+
+	reading chan struct{}
+	once    sync.Once
+	armed   bool
+}
+
+func (a *pausingAccess) Read(ctx context.Context, key string) (*agent.MemoryNote, error) {
+	note, err := a.Access.Read(ctx, key)
+	if a.armed {
+		a.once.Do(func() {
+			close(a.reading)
+			time.Sleep(50 * time.Millisecond)
+		})
+	}
+	return note, err
+}
+
+func Test_MemoryStore_RecordAccess_With_ConcurrentWrite_Should_KeepBothUpdates(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	access := &pausingAccess{
+		Access:  resource.NewInMemoryAccess[string, agent.MemoryNote](),
+		reading: make(chan struct{}),
+	}
+	store := outbound.NewMemoryStore(access)
+	_ = store.Write(ctx, agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithRawContent("Original"))
+	access.armed = true
+
+	// Act
+	var wg sync.WaitGroup
+	wg.Go(func() { _ = store.RecordAccess(ctx, "note-1") })
+	<-access.reading
+	err := store.Write(ctx, agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithRawContent("Updated"))
+	wg.Wait()
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	retrieved, _ := store.Get(ctx, "note-1")
+	assert.That(t, "content must be updated", retrieved.RawContent, "Updated")
+	assert.That(t, "access must be counted", retrieved.AccessCount, 1)
+}
+
+func Test_MemoryStore_GetHistory_With_History_Should_ReturnEarlierVersionsOldestFirst(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore().WithHistory(5)
+	for _, content := range []string{"v1", "v2", "v3"} {
+		_ = store.Write(context.Background(), agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithRawContent(content))
+	}
+
+	// Act
+	history, err := store.GetHistory(context.Background(), "note-1")
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	contents := make([]string, len(history))
+	for i, version := range history {
+		contents[i] = version.RawContent
+	}
+	assert.That(t, "history must hold the earlier versions", contents, []string{"v1", "v2"})
+	note, _ := store.Get(context.Background(), "note-1")
+	assert.That(t, "note must hold the latest content", note.RawContent, "v3")
+}
+
+func Test_MemoryStore_GetHistory_With_MoreUpdatesThanSize_Should_KeepLatestVersions(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore().WithHistory(2)
+	for _, content := range []string{"v1", "v2", "v3", "v4", "v5"} {
+		_ = store.Write(context.Background(), agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithRawContent(content))
+	}
+
+	// Act
+	history, err := store.GetHistory(context.Background(), "note-1")
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "history must be capped", len(history), 2)
+	assert.That(t, "oldest kept version must be v3", history[0].RawContent, "v3")
+	assert.That(t, "newest kept version must be v4", history[1].RawContent, "v4")
+}
+
+func Test_MemoryStore_GetHistory_With_UnchangedContent_Should_NotRecordVersion(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore().WithHistory(5)
+	note := agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithRawContent("same")
+	_ = store.Write(context.Background(), note)
+	_ = store.Write(context.Background(), note.WithImportance(5))
+
+	// Act
+	history, err := store.GetHistory(context.Background(), "note-1")
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "history must be empty", len(history), 0)
+}
+
+func Test_MemoryStore_GetHistory_Without_History_Should_ReturnNoVersions(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+	_ = store.Write(context.Background(), agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithRawContent("v1"))
+	_ = store.Write(context.Background(), agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithRawContent("v2"))
+
+	// Act
+	history, err := store.GetHistory(context.Background(), "note-1")
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "history must be empty", len(history), 0)
+}
+
+func Test_MemoryStore_GetHistory_With_UnknownNote_Should_ReturnNotFound(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore().WithHistory(5)
+
+	// Act
+	_, err := store.GetHistory(context.Background(), "missing")
+
+	// Assert
+	assert.That(t, "err must be ErrMemoryNoteNotFound", errors.Is(err, outbound.ErrMemoryNoteNotFound), true)
+}
+
+func Test_MemoryStore_Write_With_MatchingEmbeddingDimension_Should_StoreNote(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore().WithEmbeddingDimension(3)
+	note := agent.NewMemoryNote("note-123", agent.SourceTypeFact).
+		WithRawContent("Go is fun").
+		WithEmbedding(agent.Embedding{0.1, 0.2, 0.3})
+
+	// Act
+	err := store.Write(context.Background(), note)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	retrieved, _ := store.Get(context.Background(), "note-123")
+	assert.That(t, "note must be retrievable", retrieved != nil, true)
+}
+
+func Test_MemoryStore_Write_With_MismatchingEmbeddingDimension_Should_RejectNote(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore().WithEmbeddingDimension(3)
+	note := agent.NewMemoryNote("note-123", agent.SourceTypeFact).
+		WithRawContent("Go is fun").
+		WithEmbedding(agent.Embedding{0.1, 0.2})
+
+	// Act
+	err := store.Write(context.Background(), note)
+
+	// Assert
+	assert.That(t, "error must be ErrEmbeddingDimensionMismatch", errors.Is(err, agent.ErrEmbeddingDimensionMismatch), true)
+	_, getErr := store.Get(context.Background(), "note-123")
+	assert.That(t, "note must not be stored", getErr != nil, true)
+}
+
+func Test_MemoryStore_Write_Without_EmbeddingDimension_Should_AcceptAnyDimension(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+	ctx := context.Background()
+
+	// Act
+	err1 := store.Write(ctx, agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithEmbedding(agent.Embedding{0.1, 0.2}))
+	err2 := store.Write(ctx, agent.NewMemoryNote("note-2", agent.SourceTypeFact).WithEmbedding(agent.Embedding{0.1, 0.2, 0.3}))
+
+	// Assert
+	assert.That(t, "first write must succeed", err1, nil)
+	assert.That(t, "second write must succeed", err2, nil)
+}
+
 func Test_MemoryStore_Get_Should_ReturnNote(t *testing.T) {
 	// Arrange
 	store := outbound.NewInMemoryMemoryStore()
@@ -177,6 +403,352 @@ func Test_MemoryStore_Search_Should_FilterByTags(t *testing.T) {
 	assert.That(t, "result must have language tag", results[0].HasTag("language"), true)
 }
 
+func newTaggedMemoryStore() *outbound.MemoryStore {
+	store := outbound.NewInMemoryMemoryStore()
+	for id, tags := range map[agent.NoteID][]string{
+		"note-config":    {"config"},
+		"note-config-db": {"config-db", "database"},
+		"note-myconfig":  {"myconfig"},
+		"note-api":       {"api"},
+	} {
+		_ = store.Write(context.Background(), agent.NewMemoryNote(id, agent.SourceTypeFact).
+			WithRawContent("content").WithTags(tags...))
+	}
+	return store
+}
+
+func searchTaggedIDs(t *testing.T, store *outbound.MemoryStore, opts *agent.MemorySearchOptions) []string {
+	t.Helper()
+	return searchQueryIDs(t, store, "content", opts)
+}
+
+func searchQueryIDs(t *testing.T, store *outbound.MemoryStore, query string, opts *agent.MemorySearchOptions) []string {
+	t.Helper()
+	results, err := store.Search(context.Background(), query, 10, opts)
+	assert.That(t, "error must be nil", err, nil)
+	ids := make([]string, 0, len(results))
+	for _, note := range results {
+		ids = append(ids, string(note.ID))
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func Test_MemoryStore_Search_With_TagMatchExact_Should_MatchWholeTags(t *testing.T) {
+	// Arrange
+	store := newTaggedMemoryStore()
+	opts := &agent.MemorySearchOptions{Tags: []string{"config"}, TagMatch: agent.TagMatchExact}
+
+	// Act
+	ids := searchTaggedIDs(t, store, opts)
+
+	// Assert
+	assert.That(t, "only the exact tag must match", ids, []string{"note-config"})
+}
+
+func Test_MemoryStore_Search_With_DefaultTagMatch_Should_MatchExactly(t *testing.T) {
+	// Arrange
+	store := newTaggedMemoryStore()
+	opts := &agent.MemorySearchOptions{Tags: []string{"config*"}}
+
+	// Act
+	ids := searchTaggedIDs(t, store, opts)
+
+	// Assert
+	assert.That(t, "patterns must not be interpreted by default", ids, []string{})
+}
+
+func Test_MemoryStore_Search_With_TagMatchPrefix_Should_MatchTagsStartingWithPrefix(t *testing.T) {
+	// Arrange
+	store := newTaggedMemoryStore()
+	opts := &agent.MemorySearchOptions{Tags: []string{"config*"}, TagMatch: agent.TagMatchPrefix}
+
+	// Act
+	ids := searchTaggedIDs(t, store, opts)
+
+	// Assert
+	assert.That(t, "config and config-db must match", ids, []string{"note-config", "note-config-db"})
+}
+
+func Test_MemoryStore_Search_With_TagMatchGlob_Should_MatchShellPatterns(t *testing.T) {
+	// Arrange
+	store := newTaggedMemoryStore()
+	opts := &agent.MemorySearchOptions{Tags: []string{"*config", "data?ase"}, TagMatch: agent.TagMatchGlob}
+
+	// Act
+	ids := searchTaggedIDs(t, store, opts)
+
+	// Assert
+	assert.That(t, "glob patterns must match", ids, []string{"note-config", "note-config-db", "note-myconfig"})
+}
+
+func Test_MemoryStore_Search_With_MalformedGlob_Should_MatchNothing(t *testing.T) {
+	// Arrange
+	store := newTaggedMemoryStore()
+	opts := &agent.MemorySearchOptions{Tags: []string{"[config"}, TagMatch: agent.TagMatchGlob}
+
+	// Act
+	ids := searchTaggedIDs(t, store, opts)
+
+	// Assert
+	assert.That(t, "malformed pattern must not match", ids, []string{})
+}
+
+func newOverlappingTagsMemoryStore() *outbound.MemoryStore {
+	store := outbound.NewInMemoryMemoryStore()
+	for id, tags := range map[agent.NoteID][]string{
+		"note-go-api":  {"go", "api"},
+		"note-go":      {"go"},
+		"note-api":     {"api", "rest"},
+		"note-go-test": {"go", "api", "test"},
+	} {
+		_ = store.Write(context.Background(), agent.NewMemoryNote(id, agent.SourceTypeFact).
+			WithRawContent("content").WithTags(tags...))
+	}
+	return store
+}
+
+func Test_MemoryStore_Search_With_AnyTags_Should_ReturnUnion(t *testing.T) {
+	// Arrange
+	store := newOverlappingTagsMemoryStore()
+	opts := &agent.MemorySearchOptions{Tags: []string{"go", "api"}}
+
+	// Act
+	ids := searchTaggedIDs(t, store, opts)
+
+	// Assert
+	assert.That(t, "all notes with either tag must match", ids, []string{"note-api", "note-go", "note-go-api", "note-go-test"})
+}
+
+func Test_MemoryStore_Search_With_RequireAllTags_Should_ReturnIntersection(t *testing.T) {
+	// Arrange
+	store := newOverlappingTagsMemoryStore()
+	opts := &agent.MemorySearchOptions{Tags: []string{"go", "api"}, RequireAllTags: true}
+
+	// Act
+	ids := searchTaggedIDs(t, store, opts)
+
+	// Assert
+	assert.That(t, "only notes with both tags must match", ids, []string{"note-go-api", "note-go-test"})
+}
+
+func Test_MemoryStore_Search_With_RequireAllTags_And_Prefix_Should_MatchEachPattern(t *testing.T) {
+	// Arrange
+	store := newOverlappingTagsMemoryStore()
+	opts := &agent.MemorySearchOptions{Tags: []string{"go", "te*"}, TagMatch: agent.TagMatchPrefix, RequireAllTags: true}
+
+	// Act
+	ids := searchTaggedIDs(t, store, opts)
+
+	// Assert
+	assert.That(t, "every pattern must match a tag", ids, []string{"note-go-test"})
+}
+
+func Test_MemoryStore_Search_With_KeywordFilter_Should_ComposeWithQueryAndSourceTypes(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+	notes := []*agent.MemoryNote{
+		agent.NewMemoryNote("pref-db", agent.SourceTypePreference).
+			WithRawContent("use connection pooling").WithKeywords("postgres", "pool"),
+		agent.NewMemoryNote("fact-db", agent.SourceTypeFact).
+			WithRawContent("connection limit is 100").WithKeywords("postgres"),
+		agent.NewMemoryNote("pref-cache", agent.SourceTypePreference).
+			WithRawContent("use connection reuse").WithKeywords("redis"),
+		agent.NewMemoryNote("pref-other", agent.SourceTypePreference).
+			WithRawContent("tabs over spaces").WithKeywords("postgres"),
+	}
+	for _, note := range notes {
+		_ = store.Write(context.Background(), note)
+	}
+	opts := &agent.MemorySearchOptions{
+		Keywords:    []string{"postgres", "mysql"},
+		SourceTypes: []agent.SourceType{agent.SourceTypePreference},
+	}
+
+	// Act
+	ids := searchQueryIDs(t, store, "connection", opts)
+
+	// Assert
+	assert.That(t, "only the matching preference must be returned", ids, []string{"pref-db"})
+}
+
+func Test_MemoryStore_Search_Without_KeywordFilter_Should_NotRestrictResults(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+	_ = store.Write(context.Background(), agent.NewMemoryNote("with", agent.SourceTypeFact).
+		WithRawContent("content").WithKeywords("postgres"))
+	_ = store.Write(context.Background(), agent.NewMemoryNote("without", agent.SourceTypeFact).
+		WithRawContent("content"))
+
+	// Act
+	ids := searchQueryIDs(t, store, "content", &agent.MemorySearchOptions{})
+
+	// Assert
+	assert.That(t, "all notes must be returned", ids, []string{"with", "without"})
+}
+
+func Test_MemoryStore_Search_With_MixedCaseUnicode_Should_MatchCaseInsensitively(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+	_ = store.Write(context.Background(), agent.NewMemoryNote("greek", agent.SourceTypeFact).
+		WithRawContent("ΟΔΥΣΣΕΥΣ sailed home"))
+	_ = store.Write(context.Background(), agent.NewMemoryNote("german", agent.SourceTypeFact).
+		WithRawContent("Die ÜBERSICHT ist fertig"))
+
+	// Act
+	greek := searchQueryIDs(t, store, "οδυσσευς", nil)
+	german := searchQueryIDs(t, store, "übersicht", nil)
+
+	// Assert
+	assert.That(t, "greek text must match regardless of case and final sigma", greek, []string{"greek"})
+	assert.That(t, "german umlaut must match regardless of case", german, []string{"german"})
+}
+
+func Test_MemoryStore_Search_Without_DiacriticFolding_Should_RespectAccents(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+	_ = store.Write(context.Background(), agent.NewMemoryNote("cafe", agent.SourceTypeFact).
+		WithRawContent("Meet at the Café"))
+
+	// Act
+	ids := searchQueryIDs(t, store, "cafe", nil)
+
+	// Assert
+	assert.That(t, "accented content must not match unaccented query", ids, []string{})
+}
+
+func Test_MemoryStore_Search_With_DiacriticFolding_Should_IgnoreAccents(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore().WithDiacriticFolding()
+	_ = store.Write(context.Background(), agent.NewMemoryNote("cafe", agent.SourceTypeFact).
+		WithRawContent("Meet at the Café"))
+	_ = store.Write(context.Background(), agent.NewMemoryNote("resume", agent.SourceTypeFact).
+		WithRawContent("updated resume").WithKeywords("Résumé"))
+	_ = store.Write(context.Background(), agent.NewMemoryNote("decomposed", agent.SourceTypeFact).
+		WithRawContent("Crème brûle\u0301e recipe"))
+
+	// Act
+	cafe := searchQueryIDs(t, store, "CAFE", nil)
+	accentedQuery := searchQueryIDs(t, store, "café", nil)
+	decomposed := searchQueryIDs(t, store, "creme brulee", nil)
+
+	// Assert
+	assert.That(t, "unaccented query must match accented content", cafe, []string{"cafe"})
+	assert.That(t, "accented query must match too", accentedQuery, []string{"cafe"})
+	assert.That(t, "combining marks must be stripped", decomposed, []string{"decomposed"})
+}
+
+func newWordsMemoryStore() *outbound.MemoryStore {
+	store := outbound.NewInMemoryMemoryStore()
+	_ = store.Write(context.Background(), agent.NewMemoryNote("start", agent.SourceTypeFact).
+		WithRawContent("How to start the server"))
+	_ = store.Write(context.Background(), agent.NewMemoryNote("art", agent.SourceTypeFact).
+		WithRawContent("Pixel art, generated on demand"))
+	return store
+}
+
+func Test_MemoryStore_Search_Without_WholeWord_Should_MatchSubstrings(t *testing.T) {
+	// Arrange
+	store := newWordsMemoryStore()
+
+	// Act
+	ids := searchQueryIDs(t, store, "art", nil)
+
+	// Assert
+	assert.That(t, "substring must match both notes", ids, []string{"art", "start"})
+}
+
+func Test_MemoryStore_Search_With_WholeWord_Should_MatchWholeTokensOnly(t *testing.T) {
+	// Arrange
+	store := newWordsMemoryStore()
+	opts := &agent.MemorySearchOptions{WholeWord: true}
+
+	// Act
+	ids := searchQueryIDs(t, store, "Art", opts)
+
+	// Assert
+	assert.That(t, "art must not match start", ids, []string{"art"})
+}
+
+func Test_MemoryStore_Search_With_WholeWord_Should_RequireEveryTerm(t *testing.T) {
+	// Arrange
+	store := newWordsMemoryStore()
+	opts := &agent.MemorySearchOptions{WholeWord: true}
+
+	// Act
+	both := searchQueryIDs(t, store, "pixel art", opts)
+	missing := searchQueryIDs(t, store, "pixel server", opts)
+
+	// Assert
+	assert.That(t, "all terms present must match", both, []string{"art"})
+	assert.That(t, "a missing term must not match", missing, []string{})
+}
+
+func Test_MemoryStore_Search_With_RepeatedMatches_Should_RankByRelevance(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+	_ = store.Write(context.Background(), agent.NewMemoryNote("once", agent.SourceTypeFact).
+		WithRawContent("deploy with docker").WithImportance(3))
+	_ = store.Write(context.Background(), agent.NewMemoryNote("twice", agent.SourceTypeFact).
+		WithRawContent("docker build, then docker push").WithImportance(3))
+
+	// Act
+	results, err := store.Search(context.Background(), "docker", 10, nil)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "must find 2 results", len(results), 2)
+	assert.That(t, "note matching twice must rank first", string(results[0].ID), "twice")
+	assert.That(t, "score must be exposed", results[0].Score > results[1].Score, true)
+}
+
+func Test_MemoryStore_Search_With_SummaryMatch_Should_OutrankRawContentMatch(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+	_ = store.Write(context.Background(), agent.NewMemoryNote("raw", agent.SourceTypeFact).
+		WithRawContent("notes about kafka"))
+	_ = store.Write(context.Background(), agent.NewMemoryNote("summary", agent.SourceTypeFact).
+		WithRawContent("notes").WithSummary("kafka"))
+
+	// Act
+	results, err := store.Search(context.Background(), "kafka", 10, nil)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "summary match must rank first", string(results[0].ID), "summary")
+}
+
+func Test_MemoryStore_Search_With_EqualRelevance_Should_RankByImportance(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+	_ = store.Write(context.Background(), agent.NewMemoryNote("low", agent.SourceTypeFact).
+		WithRawContent("redis cache").WithImportance(1))
+	_ = store.Write(context.Background(), agent.NewMemoryNote("high", agent.SourceTypeFact).
+		WithRawContent("redis queue").WithImportance(5))
+
+	// Act
+	results, err := store.Search(context.Background(), "redis", 10, nil)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "more important note must rank first", string(results[0].ID), "high")
+}
+
+func Test_MemoryStore_Search_Should_NotPersistScore(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+	_ = store.Write(context.Background(), agent.NewMemoryNote("note-1", agent.SourceTypeFact).
+		WithRawContent("docker"))
+	_, _ = store.Search(context.Background(), "docker", 10, nil)
+
+	// Act
+	note, err := store.Get(context.Background(), "note-1")
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "stored note must have no score", note.Score, 0.0)
+}
+
 func Test_MemoryStore_Search_Should_MatchKeywords(t *testing.T) {
 	// Arrange
 	store := outbound.NewInMemoryMemoryStore()
@@ -313,6 +885,43 @@ func Test_MemoryStore_Search_Should_FilterByMinImportance(t *testing.T) {
 	assert.That(t, "should find 2 results with importance >= 3", len(results), 2)
 }
 
+func Test_MemoryStore_Search_Should_FilterByMaxImportance(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+	for i, importance := range []int{1, 3, 5} {
+		note := agent.NewMemoryNote(agent.NoteID(fmt.Sprintf("note-%d", i)), agent.SourceTypeFact).
+			WithRawContent("importance").WithImportance(importance)
+		_ = store.Write(context.Background(), note)
+	}
+
+	// Act
+	results, err := store.Search(context.Background(), "", 0, &agent.MemorySearchOptions{MaxImportance: 3})
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "should find 2 results with importance <= 3", len(results), 2)
+}
+
+func Test_MemoryStore_Search_Should_FilterByCreationTime(t *testing.T) {
+	// Arrange
+	store := outbound.NewInMemoryMemoryStore()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		note := agent.NewMemoryNote(agent.NoteID(fmt.Sprintf("note-%d", i)), agent.SourceTypeFact).WithRawContent("dated")
+		note.CreatedAt = base.AddDate(0, 0, i)
+		_ = store.Write(context.Background(), note)
+	}
+	opts := &agent.MemorySearchOptions{CreatedAfter: base, CreatedBefore: base.AddDate(0, 0, 2)}
+
+	// Act
+	results, err := store.Search(context.Background(), "", 0, opts)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "should find only the note created in between", len(results), 1)
+	assert.That(t, "note must be the middle one", results[0].ID, agent.NoteID("note-1"))
+}
+
 func Test_MemoryStore_Search_Should_CombineSourceTypeAndMinImportance(t *testing.T) {
 	// Arrange
 	store := outbound.NewInMemoryMemoryStore()
@@ -555,3 +1164,159 @@ func Test_MemoryStore_SearchWithEmbedding_With_MismatchedLengths_Should_TreatAsZ
 	// Note with matching embedding should rank higher due to positive similarity score
 	assert.That(t, "matching note should rank first", results[0].ID, agent.NoteID("matching"))
 }
+
+func Test_MemoryStore_SearchWithEmbedding_With_NormalizedEmbeddings_Should_MatchUnnormalizedRanking(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	plain := outbound.NewInMemoryMemoryStore()
+	normalized := outbound.NewInMemoryMemoryStore()
+	embeddings := []agent.Embedding{
+		{0.9, 0.1, 0.3},
+		{-1.0, 2.0, 0.5},
+		{4.0, 0.2, -0.7},
+		{0.1, 0.1, 5.0},
+		{2.0, 2.0, 2.0},
+	}
+	for i, e := range embeddings {
+		id := agent.NoteID(fmt.Sprintf("note-%d", i))
+		_ = plain.Write(ctx, agent.NewFactNote(id, "content").WithEmbedding(e))
+		_ = normalized.Write(ctx, agent.NewFactNote(id, "content").WithNormalizedEmbedding(e))
+	}
+	query := agent.Embedding{3.0, 0.5, 0.2}
+
+	// Act
+	plainResults, _ := plain.SearchWithEmbedding(ctx, "content", query, 0, nil)
+	normalizedResults, _ := normalized.SearchWithEmbedding(ctx, "content", query.Normalize(), 0, nil)
+
+	// Assert
+	assert.That(t, "result counts must match", len(normalizedResults), len(plainResults))
+	for i := range plainResults {
+		assert.That(t, fmt.Sprintf("rank %d must match", i), normalizedResults[i].ID, plainResults[i].ID)
+	}
+}
+
+// noteIDsOf returns the sorted IDs of the given notes.
+func noteIDsOf(notes []*agent.MemoryNote) []string {
+	ids := make([]string, len(notes))
+	for i, note := range notes {
+		ids[i] = string(note.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func Test_MemoryStore_Search_With_Indexes_Should_MatchUnindexedResults(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	indexed := outbound.NewInMemoryMemoryStore()
+	unindexed := outbound.NewMemoryStore(resource.NewInMemoryAccess[string, agent.MemoryNote]())
+	users := []string{"alice", "bob", "carol", ""}
+	sourceTypes := []agent.SourceType{agent.SourceTypeDecision, agent.SourceTypeFact, agent.SourceTypePreference}
+	tags := [][]string{{"go"}, {"go", "test"}, {"ops"}, nil, {"test", "ops"}}
+	for i := range 60 {
+		note := agent.NewMemoryNote(agent.NoteID(fmt.Sprintf("note-%d", i)), sourceTypes[i%len(sourceTypes)]).
+			WithRawContent(fmt.Sprintf("note %d about go and testing", i)).
+			WithImportance(i%5 + 1).
+			WithTags(tags[i%len(tags)]...).
+			WithUserID(users[i%len(users)]).
+			WithSessionID(fmt.Sprintf("session-%d", i%3))
+		_ = indexed.Write(ctx, note)
+		_ = unindexed.Write(ctx, note)
+	}
+	// Move a note to another user, source type, and tags, and delete some notes
+	moved := agent.NewMemoryNote("note-0", agent.SourceTypePlanStep).WithRawContent("moved go note").WithTags("ops").WithUserID("bob")
+	_ = indexed.Write(ctx, moved)
+	_ = unindexed.Write(ctx, moved)
+	for _, id := range []agent.NoteID{"note-1", "note-4", "missing"} {
+		_ = indexed.Delete(ctx, id)
+		_ = unindexed.Delete(ctx, id)
+	}
+	optionSets := []*agent.MemorySearchOptions{
+		nil,
+		{UserID: "alice"},
+		{UserID: "bob"},
+		{UserID: "bob", SessionID: "session-1"},
+		{UserID: "carol", MinImportance: 3},
+		{UserID: "nobody"},
+		{SourceTypes: []agent.SourceType{agent.SourceTypeFact}},
+		{SourceTypes: []agent.SourceType{agent.SourceTypeDecision, agent.SourceTypePlanStep}},
+		{Tags: []string{"go"}},
+		{Tags: []string{"go", "ops"}},
+		{Tags: []string{"test", "ops"}, RequireAllTags: true},
+		{Tags: []string{"o*"}, TagMatch: agent.TagMatchGlob},
+		{UserID: "alice", SourceTypes: []agent.SourceType{agent.SourceTypePreference}, Tags: []string{"test"}},
+	}
+
+	for _, opts := range optionSets {
+		// Act
+		want, wantErr := unindexed.Search(ctx, "go", 0, opts)
+		got, gotErr := indexed.Search(ctx, "go", 0, opts)
+
+		// Assert
+		assert.That(t, "unindexed error must be nil", wantErr, nil)
+		assert.That(t, "indexed error must be nil", gotErr, nil)
+		assert.That(t, fmt.Sprintf("results must match for %+v", opts), noteIDsOf(got), noteIDsOf(want))
+	}
+}
+
+func Test_MemoryStore_Search_With_Indexes_Should_ExcludeMovedNotes(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := outbound.NewInMemoryMemoryStore()
+	_ = store.Write(ctx, agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithRawContent("go").WithUserID("alice"))
+	_ = store.Write(ctx, agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithRawContent("go").WithUserID("bob"))
+
+	// Act
+	aliceNotes, _ := store.Search(ctx, "go", 10, &agent.MemorySearchOptions{UserID: "alice"})
+	bobNotes, _ := store.Search(ctx, "go", 10, &agent.MemorySearchOptions{UserID: "bob"})
+
+	// Assert
+	assert.That(t, "alice must have no notes", len(aliceNotes), 0)
+	assert.That(t, "bob must have the moved note", len(bobNotes), 1)
+}
+
+// countingAccess is a resource.Access that counts the notes read from it.
+type countingAccess struct {
+	resource.Access[string, agent.MemoryNote]
+
+	read int
+}
+
+func (a *countingAccess) Read(ctx context.Context, key string) (*agent.MemoryNote, error) {
+	note, err := a.Access.Read(ctx, key)
+	if err == nil {
+		a.read++
+	}
+	return note, err
+}
+
+func (a *countingAccess) ReadAll(ctx context.Context) ([]agent.MemoryNote, error) {
+	notes, err := a.Access.ReadAll(ctx)
+	a.read += len(notes)
+	return notes, err
+}
+
+func Test_MemoryStore_Search_With_Indexes_Should_ReadOnlyCandidateNotes(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	access := &countingAccess{Access: resource.NewInMemoryAccess[string, agent.MemoryNote]()}
+	store := outbound.NewMemoryStore(access).WithIndexes()
+	for i := range 100 {
+		note := agent.NewMemoryNote(agent.NoteID(fmt.Sprintf("note-%d", i)), agent.SourceTypeFact).
+			WithRawContent("go note")
+		if i%10 == 0 {
+			note = note.WithTags("rare")
+		}
+		_ = store.Write(ctx, note)
+	}
+	_, _ = store.Search(ctx, "go", 0, nil) // Full scan without filters
+	access.read = 0
+
+	// Act
+	results, err := store.Search(ctx, "go", 0, &agent.MemorySearchOptions{Tags: []string{"rare"}})
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "all tagged notes must be found", len(results), 10)
+	assert.That(t, "only tagged notes must be read", access.read, 10)
+}
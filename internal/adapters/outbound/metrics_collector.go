@@ -0,0 +1,136 @@
+package outbound
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultDurationBuckets are the upper bounds used for duration histograms.
+var defaultDurationBuckets = []time.Duration{
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+	time.Minute,
+}
+
+// DurationHistogram counts observed durations in non-cumulative buckets.
+// Counts[i] holds observations <= Buckets[i] (and > Buckets[i-1]);
+// the last entry of Counts holds observations above the largest bucket.
+type DurationHistogram struct {
+	Buckets []time.Duration `json:"buckets"`
+	Counts  []int           `json:"counts"`
+	Count   int             `json:"count"`
+	Sum     time.Duration   `json:"sum"`
+}
+
+// newDurationHistogram creates an empty histogram with the default buckets.
+func newDurationHistogram() DurationHistogram {
+	return DurationHistogram{
+		Buckets: defaultDurationBuckets,
+		Counts:  make([]int, len(defaultDurationBuckets)+1),
+	}
+}
+
+// observe adds a duration to the histogram.
+func (h *DurationHistogram) observe(d time.Duration) {
+	idx := len(h.Buckets)
+	for i, upper := range h.Buckets {
+		if d <= upper {
+			idx = i
+			break
+		}
+	}
+	h.Counts[idx]++
+	h.Count++
+	h.Sum += d
+}
+
+// clone returns a deep copy of the histogram.
+func (h DurationHistogram) clone() DurationHistogram {
+	h.Counts = append([]int(nil), h.Counts...)
+	return h
+}
+
+// ToolMetrics holds the aggregated metrics for a single tool.
+type ToolMetrics struct {
+	Durations DurationHistogram `json:"durations"`
+	Calls     int               `json:"calls"`
+	Errors    int               `json:"errors"`
+}
+
+// MetricsSnapshot is a point-in-time copy of the collected metrics.
+type MetricsSnapshot struct {
+	Tools          map[string]ToolMetrics `json:"tools"`
+	TaskDurations  DurationHistogram      `json:"task_durations"`
+	TasksTotal     int                    `json:"tasks_total"`
+	TasksSucceeded int                    `json:"tasks_succeeded"`
+	TasksFailed    int                    `json:"tasks_failed"`
+	Iterations     int                    `json:"iterations"`
+	ToolCalls      int                    `json:"tool_calls"`
+}
+
+// InMemoryMetricsCollector implements agent.MetricsCollector with in-memory counters.
+// It is safe for concurrent use.
+type InMemoryMetricsCollector struct {
+	snapshot MetricsSnapshot
+	mutex    sync.Mutex
+}
+
+// NewInMemoryMetricsCollector creates a new InMemoryMetricsCollector.
+func NewInMemoryMetricsCollector() *InMemoryMetricsCollector {
+	return &InMemoryMetricsCollector{
+		snapshot: MetricsSnapshot{
+			Tools:         make(map[string]ToolMetrics),
+			TaskDurations: newDurationHistogram(),
+		},
+	}
+}
+
+// RecordTask records a finished task.
+func (c *InMemoryMetricsCollector) RecordTask(duration time.Duration, iterations, toolCalls int, success bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.snapshot.TasksTotal++
+	if success {
+		c.snapshot.TasksSucceeded++
+	} else {
+		c.snapshot.TasksFailed++
+	}
+	c.snapshot.Iterations += iterations
+	c.snapshot.ToolCalls += toolCalls
+	c.snapshot.TaskDurations.observe(duration)
+}
+
+// RecordToolCall records a single tool execution.
+func (c *InMemoryMetricsCollector) RecordToolCall(name string, duration time.Duration, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	tm, exists := c.snapshot.Tools[name]
+	if !exists {
+		tm.Durations = newDurationHistogram()
+	}
+	tm.Calls++
+	if err != nil {
+		tm.Errors++
+	}
+	tm.Durations.observe(duration)
+	c.snapshot.Tools[name] = tm
+}
+
+// Snapshot returns a copy of the current metrics.
+func (c *InMemoryMetricsCollector) Snapshot() MetricsSnapshot {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	snapshot := c.snapshot
+	snapshot.TaskDurations = c.snapshot.TaskDurations.clone()
+	snapshot.Tools = make(map[string]ToolMetrics, len(c.snapshot.Tools))
+	for name, tm := range c.snapshot.Tools {
+		tm.Durations = tm.Durations.clone()
+		snapshot.Tools[name] = tm
+	}
+	return snapshot
+}
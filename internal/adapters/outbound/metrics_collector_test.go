@@ -0,0 +1,60 @@
+package outbound_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/adapters/outbound"
+)
+
+func Test_InMemoryMetricsCollector_RecordTask_Should_AggregateCounters(t *testing.T) {
+	// Arrange
+	collector := outbound.NewInMemoryMetricsCollector()
+
+	// Act
+	collector.RecordTask(50*time.Millisecond, 2, 3, true)
+	collector.RecordTask(2*time.Minute, 5, 0, false)
+	snapshot := collector.Snapshot()
+
+	// Assert
+	assert.That(t, "tasks total must be 2", snapshot.TasksTotal, 2)
+	assert.That(t, "tasks succeeded must be 1", snapshot.TasksSucceeded, 1)
+	assert.That(t, "tasks failed must be 1", snapshot.TasksFailed, 1)
+	assert.That(t, "iterations must be summed", snapshot.Iterations, 7)
+	assert.That(t, "tool calls must be summed", snapshot.ToolCalls, 3)
+	assert.That(t, "histogram count must be 2", snapshot.TaskDurations.Count, 2)
+	assert.That(t, "50ms must land in the 100ms bucket", snapshot.TaskDurations.Counts[1], 1)
+	assert.That(t, "2m must land in the overflow bucket", snapshot.TaskDurations.Counts[len(snapshot.TaskDurations.Counts)-1], 1)
+}
+
+func Test_InMemoryMetricsCollector_RecordToolCall_Should_TrackCallsAndErrors(t *testing.T) {
+	// Arrange
+	collector := outbound.NewInMemoryMetricsCollector()
+
+	// Act
+	collector.RecordToolCall("search", 5*time.Millisecond, nil)
+	collector.RecordToolCall("search", 15*time.Millisecond, errors.New("boom"))
+	snapshot := collector.Snapshot()
+
+	// Assert
+	search := snapshot.Tools["search"]
+	assert.That(t, "calls must be 2", search.Calls, 2)
+	assert.That(t, "errors must be 1", search.Errors, 1)
+	assert.That(t, "duration sum must match", search.Durations.Sum, 20*time.Millisecond)
+}
+
+func Test_InMemoryMetricsCollector_Snapshot_Should_BeIndependentCopy(t *testing.T) {
+	// Arrange
+	collector := outbound.NewInMemoryMetricsCollector()
+	collector.RecordToolCall("search", time.Millisecond, nil)
+	snapshot := collector.Snapshot()
+
+	// Act
+	collector.RecordToolCall("search", time.Millisecond, nil)
+
+	// Assert
+	assert.That(t, "snapshot calls must not change", snapshot.Tools["search"].Calls, 1)
+	assert.That(t, "snapshot histogram must not change", snapshot.Tools["search"].Durations.Counts[0], 1)
+}
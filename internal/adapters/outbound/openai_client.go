@@ -1,6 +1,7 @@
 package outbound
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -9,6 +10,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/andygeiss/cloud-native-utils/service"
@@ -24,6 +26,7 @@ const (
 	defaultDebouncePeriod = 0                 // Debounce disabled by default (0 = no debounce)
 	defaultHTTPTimeout    = 60 * time.Second  // HTTP client timeout
 	defaultLLMTimeout     = 120 * time.Second // LLM call timeout (longer for complex prompts)
+	defaultMaxRespBytes   = 32 << 20          // Response body cap (32 MiB)
 	defaultRetryAttempts  = 3                 // Number of retry attempts
 	defaultRetryDelay     = 2 * time.Second   // Delay between retries
 	defaultThrottlePeriod = time.Second       // Refill period
@@ -31,6 +34,21 @@ const (
 	defaultThrottleTokens = 0                 // Throttle disabled by default (0 = no limit)
 )
 
+// Request logging configuration (alphabetically sorted).
+const (
+	maxLoggedBodyBytes = 4096         // Bodies longer than this are truncated in logs
+	redactedValue      = "[REDACTED]" // Replaces secret header values in logs
+)
+
+// ErrModelNotAvailable is returned by Ping when the endpoint is reachable but does not serve the configured model.
+var ErrModelNotAvailable = errors.New("model not available")
+
+// ErrResponseTooLarge is returned when a response body exceeds the configured maximum size.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// ErrTooManyStopSequences is returned when more stop sequences are configured than the API accepts.
+var ErrTooManyStopSequences = fmt.Errorf("too many stop sequences (max %d)", openai.MaxStopSequences)
+
 // The adapter translates between domain types (agent.Message, agent.ToolCall)
 // and the OpenAI chat payload that LM Studio expects.
 
@@ -38,18 +56,32 @@ const (
 // It communicates with LM Studio using the OpenAI-compatible API.
 // It wraps LLM calls with resilience patterns (timeout, retry, circuit breaker, throttle).
 type OpenAIClient struct {
-	httpClient     *http.Client
-	logger         *slog.Logger
-	baseURL        string
-	model          string
-	debouncePeriod time.Duration
-	llmTimeout     time.Duration
-	retryDelay     time.Duration
-	throttlePeriod time.Duration
-	breakerThresh  int
-	retryAttempts  int
-	throttleRefill uint
-	throttleTokens uint
+	httpClient      *http.Client
+	logger          *slog.Logger
+	toolCallIDs     agent.IDGenerator
+	seed            *int
+	streamCallback  func(delta string)
+	headers         http.Header
+	secretHeaders   map[string]bool
+	baseURL         string
+	model           string
+	stop            []string
+	toolChoice      agent.ToolChoice
+	debouncePeriod  time.Duration
+	requestLogLevel slog.Level
+	llmTimeout      time.Duration
+	retryDelay      time.Duration
+	throttlePeriod  time.Duration
+	maxRespBytes    int64
+	breakerThresh   int
+	candidates      int
+	contextWindow   int
+	retryAttempts   int
+	throttleRefill  uint
+	throttleTokens  uint
+	developerRole   bool
+	promptCaching   bool
+	requestLogging  bool
 }
 
 // NewOpenAIClient creates a new OpenAIClient instance with sensible defaults.
@@ -60,36 +92,93 @@ type OpenAIClient struct {
 // - Circuit breaker: opens after 5 consecutive failures.
 // - Throttle: disabled by default (set via WithThrottle).
 // - Debounce: disabled by default (set via WithDebounce).
+// - Maximum response size: 32 MiB.
 func NewOpenAIClient(baseURL, model string) *OpenAIClient {
 	return &OpenAIClient{
 		httpClient: &http.Client{
 			Timeout: defaultHTTPTimeout,
 		},
+		headers:        make(http.Header),
+		secretHeaders:  make(map[string]bool),
 		baseURL:        baseURL,
 		model:          model,
 		debouncePeriod: defaultDebouncePeriod,
 		llmTimeout:     defaultLLMTimeout,
+		maxRespBytes:   defaultMaxRespBytes,
 		retryDelay:     defaultRetryDelay,
 		throttlePeriod: defaultThrottlePeriod,
 		breakerThresh:  defaultBreakerThresh,
 		retryAttempts:  defaultRetryAttempts,
 		throttleRefill: defaultThrottleRefill,
 		throttleTokens: defaultThrottleTokens,
+		toolCallIDs:    agent.NewUUIDGenerator("call"),
 	}
 }
 
+// WithDeveloperRole controls how developer messages are sent.
+// When enabled, RoleDeveloper is sent as "developer"; otherwise (the default)
+// it falls back to "system" for servers that don't support the developer role.
+func (c *OpenAIClient) WithDeveloperRole(enabled bool) *OpenAIClient {
+	c.developerRole = enabled
+	return c
+}
+
+// WithPromptCaching controls whether cache markers are sent.
+// When enabled, messages marked with agent.Message.WithCacheControl are sent as
+// content arrays whose last part carries "cache_control": {"type": "ephemeral"},
+// as understood by providers with prompt caching (e.g. Anthropic-compatible APIs).
+// Disabled by default, since other servers may reject the unknown field.
+func (c *OpenAIClient) WithPromptCaching(enabled bool) *OpenAIClient {
+	c.promptCaching = enabled
+	return c
+}
+
 // WithHTTPClient sets a custom HTTP client for the OpenAIClient.
+// It replaces the whole client, so it discards settings from earlier
+// WithTimeout or WithTransport calls; later calls apply on top of it.
 func (c *OpenAIClient) WithHTTPClient(httpClient *http.Client) *OpenAIClient {
 	c.httpClient = httpClient
 	return c
 }
 
+// WithTimeout sets the overall HTTP request timeout (default: 60s),
+// covering connecting, sending the request, and reading the response.
+// The client is copied, so an HTTP client passed to WithHTTPClient is not modified.
+func (c *OpenAIClient) WithTimeout(timeout time.Duration) *OpenAIClient {
+	httpClient := *c.httpClient
+	httpClient.Timeout = timeout
+	c.httpClient = &httpClient
+	return c
+}
+
+// WithTransport sets the HTTP transport, e.g. to tune connection pooling,
+// keep-alive, or dial timeouts. The request timeout set via WithTimeout is kept.
+// The client is copied, so an HTTP client passed to WithHTTPClient is not modified.
+func (c *OpenAIClient) WithTransport(transport *http.Transport) *OpenAIClient {
+	httpClient := *c.httpClient
+	httpClient.Transport = transport
+	c.httpClient = &httpClient
+	return c
+}
+
 // WithLLMTimeout sets the timeout for LLM calls.
 func (c *OpenAIClient) WithLLMTimeout(timeout time.Duration) *OpenAIClient {
 	c.llmTimeout = timeout
 	return c
 }
 
+// WithMaxResponseBytes caps the size of a response body.
+// Reading stops at the limit and the call fails with ErrResponseTooLarge,
+// so a misbehaving server cannot exhaust memory.
+// A value of 0 or less restores the default cap of 32 MiB.
+func (c *OpenAIClient) WithMaxResponseBytes(n int64) *OpenAIClient {
+	if n <= 0 {
+		n = defaultMaxRespBytes
+	}
+	c.maxRespBytes = n
+	return c
+}
+
 // WithRetry configures retry behavior for transient failures.
 func (c *OpenAIClient) WithRetry(attempts int, delay time.Duration) *OpenAIClient {
 	c.retryAttempts = attempts
@@ -103,6 +192,19 @@ func (c *OpenAIClient) WithCircuitBreaker(threshold int) *OpenAIClient {
 	return c
 }
 
+// WithContextWindow sets the model's context window size in tokens.
+// The TaskService uses it to check the conversation size before each call.
+// Set tokens to 0 to disable the check (default).
+func (c *OpenAIClient) WithContextWindow(tokens int) *OpenAIClient {
+	c.contextWindow = tokens
+	return c
+}
+
+// ContextWindow returns the configured context window size in tokens.
+func (c *OpenAIClient) ContextWindow() int {
+	return c.contextWindow
+}
+
 // WithLogger sets an optional structured logger for the client.
 // When set, the client logs LLM requests and responses at debug level.
 func (c *OpenAIClient) WithLogger(logger *slog.Logger) *OpenAIClient {
@@ -134,6 +236,73 @@ func (c *OpenAIClient) WithDebounce(period time.Duration) *OpenAIClient {
 	return c
 }
 
+// WithCandidates requests n completions per call.
+// Run still answers with the first choice; all choices are exposed on
+// LLMResponse.Candidates so a caller can pick a different one.
+func (c *OpenAIClient) WithCandidates(n int) *OpenAIClient {
+	c.candidates = n
+	return c
+}
+
+// WithSeed sets the sampling seed for reproducible completions.
+// Only backends that support seeding honor it; compare
+// LLMResponse.SystemFingerprint to detect backend changes between runs.
+func (c *OpenAIClient) WithSeed(seed int) *OpenAIClient {
+	c.seed = &seed
+	return c
+}
+
+// WithStop sets the sequences at which the model stops generating.
+// At most four sequences are allowed; otherwise ErrTooManyStopSequences is returned
+// and the client is left unchanged.
+func (c *OpenAIClient) WithStop(sequences ...string) (*OpenAIClient, error) {
+	if len(sequences) > openai.MaxStopSequences {
+		return c, ErrTooManyStopSequences
+	}
+	c.stop = sequences
+	return c, nil
+}
+
+// WithStreamCallback streams completions and calls callback with each content delta
+// of the first choice, in arrival order, e.g. to drive a progress indicator.
+// Run still returns the assembled response, marked as streamed; a retried request
+// streams its deltas again. A nil callback disables streaming (default).
+func (c *OpenAIClient) WithStreamCallback(callback func(delta string)) *OpenAIClient {
+	c.streamCallback = callback
+	return c
+}
+
+// WithToolChoice sets whether the model may, must, or must not call tools, or which tool it must call.
+// A task can override it with Task.WithToolChoice. The choice is only sent with requests that offer tools.
+func (c *OpenAIClient) WithToolChoice(choice agent.ToolChoice) *OpenAIClient {
+	c.toolChoice = choice
+	return c
+}
+
+// WithHeader sets an HTTP header sent with every request.
+// The Authorization header is always redacted in request logs.
+func (c *OpenAIClient) WithHeader(key, value string) *OpenAIClient {
+	c.headers.Set(key, value)
+	return c
+}
+
+// WithSecretHeader sets an HTTP header sent with every request,
+// whose value is redacted in request logs (e.g. an API key header).
+func (c *OpenAIClient) WithSecretHeader(key, value string) *OpenAIClient {
+	c.headers.Set(key, value)
+	c.secretHeaders[http.CanonicalHeaderKey(key)] = true
+	return c
+}
+
+// WithRequestLogging logs the full request and response bodies at the given level.
+// Authorization and secret headers are redacted, and bodies longer than 4 KiB
+// are truncated. It requires a logger set via WithLogger.
+func (c *OpenAIClient) WithRequestLogging(level slog.Level) *OpenAIClient {
+	c.requestLogging = true
+	c.requestLogLevel = level
+	return c
+}
+
 // llmInput bundles the inputs for an LLM call.
 type llmInput struct {
 	messages []agent.Message
@@ -160,14 +329,9 @@ func (c *OpenAIClient) Run(ctx context.Context, messages []agent.Message, tools
 	// Create the base function that performs the actual LLM call
 	baseFn := func(ctx context.Context, in llmInput) (agent.LLMResponse, error) {
 		apiMessages := c.convertToAPIMessages(in.messages)
-		apiTools := c.convertToAPITools(in.tools)
-
-		respPayload, err := c.sendRequest(ctx, apiMessages, apiTools)
-		if err != nil {
-			return agent.LLMResponse{}, err
-		}
+		apiTools := agent.OpenAITools(in.tools)
 
-		return c.convertToResponse(respPayload)
+		return c.sendRequest(ctx, apiMessages, apiTools)
 	}
 
 	// Wrap with stability patterns (innermost to outermost):
@@ -210,22 +374,40 @@ func (c *OpenAIClient) Run(ctx context.Context, messages []agent.Message, tools
 	return response, err
 }
 
+// Close releases the idle connections held by the HTTP client.
+func (c *OpenAIClient) Close(_ context.Context) error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// Ping checks that the endpoint is reachable and serves the configured model.
+// It lists the models via GET /v1/models, which is cheap and needs no completion.
+func (c *OpenAIClient) Ping(ctx context.Context) error {
+	return pingModels(ctx, c.httpClient, c.baseURL, c.model, c.headers, c.maxRespBytes)
+}
+
 // convertToAPIMessages converts domain messages to API format.
 func (c *OpenAIClient) convertToAPIMessages(messages []agent.Message) []openai.Message {
 	apiMessages := make([]openai.Message, len(messages))
 	for i, msg := range messages {
 		apiMessages[i] = openai.Message{
-			Role:       string(msg.Role),
+			Role:       c.convertToAPIRole(msg.Role),
 			Content:    msg.Content,
 			ToolCallID: string(msg.ToolCallID),
 		}
+		if parts := msg.ContentParts(); len(parts) > 0 {
+			apiMessages[i].Parts = convertToAPIParts(parts)
+		}
+		if c.promptCaching && msg.CacheControl {
+			apiMessages[i].Parts = withCacheControl(apiMessages[i])
+		}
 		if len(msg.ToolCalls) > 0 {
 			apiMessages[i].ToolCalls = make([]openai.ToolCall, len(msg.ToolCalls))
 			for j, tc := range msg.ToolCalls {
 				apiMessages[i].ToolCalls[j] = openai.NewToolCall(
 					string(tc.ID),
 					tc.Name,
-					tc.Arguments,
+					normalizeArguments(tc.Arguments),
 				)
 			}
 		}
@@ -233,38 +415,250 @@ func (c *OpenAIClient) convertToAPIMessages(messages []agent.Message) []openai.M
 	return apiMessages
 }
 
-// sendRequest sends the chat completion request to LM Studio.
-func (c *OpenAIClient) sendRequest(ctx context.Context, apiMessages []openai.Message, apiTools []openai.Tool) (*openai.ChatCompletionResponse, error) {
-	reqPayload := openai.NewChatCompletionRequest(c.model, apiMessages).WithTools(apiTools)
+// convertToAPIParts converts domain content parts to API content parts.
+func convertToAPIParts(parts []agent.ContentPart) []openai.ContentPart {
+	apiParts := make([]openai.ContentPart, len(parts))
+	for i, part := range parts {
+		if part.Type == agent.ContentPartTypeImageURL {
+			apiParts[i] = openai.NewImageURLPart(part.ImageURL, part.Detail)
+			continue
+		}
+		apiParts[i] = openai.NewTextPart(part.Text)
+	}
+	return apiParts
+}
+
+// withCacheControl returns the content of the message as parts with a cache
+// marker on the last part. A plain text message is converted to a single text part.
+func withCacheControl(msg openai.Message) []openai.ContentPart {
+	parts := msg.Parts
+	if len(parts) == 0 {
+		parts = []openai.ContentPart{openai.NewTextPart(msg.Content)}
+	}
+	parts[len(parts)-1] = parts[len(parts)-1].WithCacheControl()
+	return parts
+}
+
+// convertToAPIRole converts a domain role to the API role string.
+func (c *OpenAIClient) convertToAPIRole(role agent.Role) string {
+	if role == agent.RoleDeveloper && !c.developerRole {
+		return string(agent.RoleSystem)
+	}
+	return string(role)
+}
+
+// sendRequest sends the chat completion request to LM Studio and converts the response to domain types.
+func (c *OpenAIClient) sendRequest(ctx context.Context, apiMessages []openai.Message, apiTools []openai.Tool) (agent.LLMResponse, error) {
+	reqPayload := openai.NewChatCompletionRequest(c.model, apiMessages).
+		WithN(c.candidates).
+		WithSeed(c.seed).
+		WithStop(c.stop).
+		WithStream(c.streamCallback != nil).
+		WithToolChoice(c.convertToAPIToolChoice(ctx, apiTools)).
+		WithTools(apiTools)
 
 	reqBody, err := json.Marshal(reqPayload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return agent.LLMResponse{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/chat/completions", bytes.NewReader(reqBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return agent.LLMResponse{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range c.headers {
+		req.Header[key] = values
 	}
 	req.Header.Set("Content-Type", "application/json")
+	c.logRequest(ctx, req, reqBody)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return agent.LLMResponse{}, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if c.streamCallback != nil && resp.StatusCode == http.StatusOK {
+		c.logResponse(ctx, resp.StatusCode, []byte("(streamed)"))
+		acc, err := c.readStream(resp.Body)
+		if err != nil {
+			return agent.LLMResponse{}, err
+		}
+		return c.ConvertStreamedResponse(acc)
+	}
+
+	body, err := readLimited(resp.Body, c.maxRespBytes)
+	if err != nil {
+		return agent.LLMResponse{}, err
+	}
+	c.logResponse(ctx, resp.StatusCode, body)
+
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("LM Studio returned status %d: %s", resp.StatusCode, string(body))
+		return agent.LLMResponse{}, parseAPIError(resp.StatusCode, body)
 	}
 
 	var respPayload openai.ChatCompletionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&respPayload); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := json.Unmarshal(body, &respPayload); err != nil {
+		return agent.LLMResponse{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return c.convertToResponse(&respPayload)
+}
+
+// readStream reads the server-sent events of a streamed completion until "[DONE]" or the
+// end of the body, passing each content delta of the first choice to the stream callback,
+// and returns the accumulated chunks. The stream is capped like a response body.
+func (c *OpenAIClient) readStream(body io.Reader) (*openai.StreamAccumulator, error) {
+	limited := &io.LimitedReader{R: body, N: c.maxRespBytes + 1}
+	reader := bufio.NewReader(limited)
+	acc := openai.NewStreamAccumulator()
+	for {
+		line, err := reader.ReadString('\n')
+		if limited.N <= 0 {
+			return nil, fmt.Errorf("%w: exceeds %d bytes", ErrResponseTooLarge, c.maxRespBytes)
+		}
+		data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+		data = strings.TrimSpace(data)
+		if ok && data == "[DONE]" {
+			break
+		}
+		if ok && data != "" {
+			var chunk openai.ChatCompletionChunk
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				return nil, fmt.Errorf("failed to decode stream chunk: %w", err)
+			}
+			acc.Add(chunk)
+			for _, choice := range chunk.Choices {
+				if choice.Index == 0 && choice.Delta.Content != "" {
+					c.streamCallback(choice.Delta.Content)
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stream: %w", err)
+		}
+	}
+
+	return acc, nil
+}
+
+// logRequest logs the outgoing request with secret headers redacted, if enabled.
+func (c *OpenAIClient) logRequest(ctx context.Context, req *http.Request, body []byte) {
+	if !c.requestLogging || c.logger == nil {
+		return
+	}
+	c.logger.Log(ctx, c.requestLogLevel, "llm http request",
+		"method", req.Method,
+		"url", req.URL.String(),
+		"headers", c.redactHeaders(req.Header),
+		"body", truncateBody(body),
+	)
+}
+
+// logResponse logs the response status and body, if enabled.
+func (c *OpenAIClient) logResponse(ctx context.Context, status int, body []byte) {
+	if !c.requestLogging || c.logger == nil {
+		return
+	}
+	c.logger.Log(ctx, c.requestLogLevel, "llm http response",
+		"status", status,
+		"body", truncateBody(body),
+	)
+}
+
+// redactHeaders flattens the headers for logging, replacing secret values.
+func (c *OpenAIClient) redactHeaders(header http.Header) map[string]string {
+	redacted := make(map[string]string, len(header))
+	for key, values := range header {
+		if key == "Authorization" || key == "Proxy-Authorization" || c.secretHeaders[key] {
+			redacted[key] = redactedValue
+			continue
+		}
+		redacted[key] = strings.Join(values, ", ")
+	}
+	return redacted
+}
+
+// normalizeArguments returns "{}" for empty tool call arguments,
+// since the API requires the arguments to be a JSON object string.
+func normalizeArguments(arguments string) string {
+	if strings.TrimSpace(arguments) == "" {
+		return "{}"
+	}
+	return arguments
+}
+
+// parseAPIError converts an error response into an agent.APIError if the body
+// contains the OpenAI error envelope, or a generic error otherwise.
+func parseAPIError(statusCode int, body []byte) error {
+	var envelope openai.ErrorResponse
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Error.Message == "" {
+		return fmt.Errorf("LM Studio returned status %d: %s", statusCode, string(body))
+	}
+	detail := envelope.Error
+	return agent.NewAPIError(statusCode, detail.Type, detail.CodeString(), detail.Message)
+}
+
+// pingModels requests the model list of an OpenAI-compatible endpoint.
+// If model is not empty, it must be contained in the list.
+func pingModels(ctx context.Context, httpClient *http.Client, baseURL, model string, header http.Header, maxBytes int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/v1/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
 	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := readLimited(resp.Body, maxBytes)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return parseAPIError(resp.StatusCode, body)
+	}
+	if model == "" {
+		return nil
+	}
+
+	var list openai.ModelList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !list.HasModel(model) {
+		return fmt.Errorf("%w: %s", ErrModelNotAvailable, model)
+	}
+	return nil
+}
+
+// readLimited reads at most limit bytes from r.
+// Returns ErrResponseTooLarge if r holds more than limit bytes.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", ErrResponseTooLarge, limit)
+	}
+	return body, nil
+}
 
-	return &respPayload, nil
+// truncateBody returns the body as a string, cut to maxLoggedBodyBytes.
+func truncateBody(body []byte) string {
+	if len(body) <= maxLoggedBodyBytes {
+		return string(body)
+	}
+	return fmt.Sprintf("%s... (%d bytes truncated)", body[:maxLoggedBodyBytes], len(body)-maxLoggedBodyBytes)
 }
 
 // convertToResponse converts the API response to domain types.
@@ -274,60 +668,73 @@ func (c *OpenAIClient) convertToResponse(respPayload *openai.ChatCompletionRespo
 		return agent.LLMResponse{}, errors.New("no choices in response")
 	}
 
-	domainMessage := agent.NewMessage(agent.Role(choice.Message.Role), choice.Message.Content)
+	candidates := slices.Map(respPayload.Choices, func(candidate openai.ChatCompletionChoice) agent.Message {
+		return c.convertToDomainMessage(candidate.Message)
+	})
+	domainMessage := candidates[0]
+
+	return agent.NewLLMResponse(domainMessage, choice.FinishReason).
+		WithCandidates(candidates).
+		WithSystemFingerprint(respPayload.SystemFingerprint).
+		WithToolCalls(domainMessage.ToolCalls).
+		WithUsage(agent.TokenUsage{
+			CompletionTokens: respPayload.Usage.CompletionTokens,
+			PromptTokens:     respPayload.Usage.PromptTokens,
+			TotalTokens:      respPayload.Usage.TotalTokens,
+		}), nil
+}
 
-	var domainToolCalls []agent.ToolCall
-	if len(choice.Message.ToolCalls) > 0 {
-		domainToolCalls = make([]agent.ToolCall, len(choice.Message.ToolCalls))
-		for i, tc := range choice.Message.ToolCalls {
-			domainToolCalls[i] = agent.NewToolCall(
-				agent.ToolCallID(tc.ID),
-				tc.Function.Name,
-				tc.Function.Arguments,
-			)
-		}
-		domainMessage = domainMessage.WithToolCalls(domainToolCalls)
+// ConvertStreamedResponse converts the response assembled from a streamed completion
+// to domain types. The result equals the response of Run for the same completion,
+// except that it is marked as streamed.
+func (c *OpenAIClient) ConvertStreamedResponse(acc *openai.StreamAccumulator) (agent.LLMResponse, error) {
+	respPayload, err := acc.Response()
+	if err != nil {
+		return agent.LLMResponse{}, err
 	}
-
-	return agent.NewLLMResponse(domainMessage, choice.FinishReason).WithToolCalls(domainToolCalls), nil
+	response, err := c.convertToResponse(&respPayload)
+	if err != nil {
+		return agent.LLMResponse{}, err
+	}
+	return response.WithStreamed(), nil
 }
 
-// convertToAPITools converts domain tool definitions to API format.
-func (c *OpenAIClient) convertToAPITools(tools []agent.ToolDefinition) []openai.Tool {
-	if len(tools) == 0 {
-		return nil
+// convertToDomainMessage converts an API message, including its tool calls, to a domain message.
+func (c *OpenAIClient) convertToDomainMessage(msg openai.Message) agent.Message {
+	domainMessage := agent.NewMessage(agent.Role(msg.Role), msg.Content)
+	if len(msg.ToolCalls) == 0 {
+		return domainMessage
 	}
-	return slices.Map(tools, func(tool agent.ToolDefinition) openai.Tool {
-		properties := make(map[string]openai.PropertyDefinition)
-		for _, param := range tool.Parameters {
-			prop := openai.PropertyDefinition{
-				Type:        string(param.Type),
-				Description: param.Description,
-			}
-			if len(param.Enum) > 0 {
-				prop.Enum = param.Enum
-			}
-			properties[param.Name] = prop
-		}
 
-		requiredParams := slices.Filter(tool.Parameters, func(p agent.ParameterDefinition) bool {
-			return p.Required
-		})
-		required := slices.Map(requiredParams, func(p agent.ParameterDefinition) string {
-			return p.Name
-		})
-
-		return openai.Tool{
-			Type: "function",
-			Function: openai.FunctionDefinition{
-				Name:        tool.Name,
-				Description: tool.Description,
-				Parameters: openai.ParametersDefinition{
-					Type:       "object",
-					Properties: properties,
-					Required:   required,
-				},
-			},
+	// Tool results are matched to their call by ID when the turn is replayed,
+	// so calls without an ID (as returned by some local models) get a unique one.
+	domainToolCalls := make([]agent.ToolCall, len(msg.ToolCalls))
+	for i, tc := range msg.ToolCalls {
+		id := tc.ID
+		if id == "" {
+			id = c.toolCallIDs.NewID()
 		}
-	})
+		domainToolCalls[i] = agent.NewToolCall(
+			agent.ToolCallID(id),
+			tc.Function.Name,
+			tc.Function.Arguments,
+		)
+	}
+	return domainMessage.WithToolCalls(domainToolCalls)
+}
+
+// convertToAPIToolChoice returns the tool choice of the request: the override of the context
+// or else the configured one. Returns nil if no tools are offered or no choice is set.
+func (c *OpenAIClient) convertToAPIToolChoice(ctx context.Context, apiTools []openai.Tool) any {
+	choice, ok := agent.ToolChoiceFromContext(ctx)
+	if !ok {
+		choice = c.toolChoice
+	}
+	if len(apiTools) == 0 || choice.IsZero() {
+		return nil
+	}
+	if choice.Mode == agent.ToolChoiceFunction {
+		return openai.NewNamedToolChoice(choice.Name)
+	}
+	return string(choice.Mode)
 }
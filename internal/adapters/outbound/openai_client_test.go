@@ -1,11 +1,20 @@
 package outbound_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/andygeiss/cloud-native-utils/assert"
 	"github.com/andygeiss/go-agent/internal/adapters/outbound"
@@ -37,6 +46,18 @@ func Test_OpenAIClient_WithHTTPClient_Should_SetCustomClient(t *testing.T) {
 	assert.That(t, "must return client for chaining", result != nil, true)
 }
 
+func Test_OpenAIClient_WithContextWindow_Should_ExposeContextWindow(t *testing.T) {
+	// Arrange
+	client := outbound.NewOpenAIClient("http://localhost:1234", "test-model")
+
+	// Act
+	client.WithContextWindow(8192)
+
+	// Assert
+	var provider agent.ContextWindowProvider = client
+	assert.That(t, "context window must match", provider.ContextWindow(), 8192)
+}
+
 // -----------------------------------------------------------------------------
 // Run tests with mock HTTP server
 // -----------------------------------------------------------------------------
@@ -129,6 +150,151 @@ func Test_OpenAIClient_Run_With_ToolCalls_Should_ReturnToolCalls(t *testing.T) {
 	assert.That(t, "finish reason must be tool_calls", result.FinishReason, "tool_calls")
 }
 
+// newStreamServer serves the content deltas as a server-sent event stream and records the request body.
+func newStreamServer(deltas []string, requestBody *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*requestBody = string(body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i, delta := range deltas {
+			chunk := openai.ChatCompletionChunk{
+				Choices: []openai.ChatCompletionChunkChoice{{Delta: openai.ChatCompletionDelta{Content: delta}}},
+				ID:      "chatcmpl-1",
+				Model:   "test-model",
+			}
+			if i == 0 {
+				chunk.Choices[0].Delta.Role = "assistant"
+			}
+			data, _ := json.Marshal(chunk)
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		_, _ = fmt.Fprint(w, "data: {\"id\":\"chatcmpl-1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+		if strings.Contains(*requestBody, `"include_usage":true`) {
+			_, _ = fmt.Fprint(w, "data: {\"id\":\"chatcmpl-1\",\"choices\":[],\"usage\":{\"prompt_tokens\":7,\"completion_tokens\":4,\"total_tokens\":11}}\n\n")
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+}
+
+func Test_OpenAIClient_Run_With_StreamCallback_Should_PassDeltasInOrder(t *testing.T) {
+	// Arrange
+	var requestBody string
+	server := newStreamServer([]string{"Hel", "lo, ", "world", "!"}, &requestBody)
+	defer server.Close()
+	var deltas []string
+	client := outbound.NewOpenAIClient(server.URL, "test-model").
+		WithStreamCallback(func(delta string) { deltas = append(deltas, delta) })
+
+	// Act
+	response, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "hi")}, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "request must ask for a stream", strings.Contains(requestBody, `"stream":true`), true)
+	assert.That(t, "deltas must arrive in order", deltas, []string{"Hel", "lo, ", "world", "!"})
+	assert.That(t, "content must be the concatenation", response.Message.Content, strings.Join(deltas, ""))
+	assert.That(t, "finish reason must be assembled", response.FinishReason, "stop")
+	assert.That(t, "response must be marked as streamed", response.IsStreamed, true)
+}
+
+func Test_OpenAIClient_Run_With_StreamCallback_Should_ReportUsageFromFinalChunk(t *testing.T) {
+	// Arrange
+	var requestBody string
+	server := newStreamServer([]string{"Hello"}, &requestBody)
+	defer server.Close()
+	client := outbound.NewOpenAIClient(server.URL, "test-model").
+		WithStreamCallback(func(string) {})
+
+	// Act
+	response, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "hi")}, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "usage must match the final chunk", response.Usage, agent.TokenUsage{CompletionTokens: 4, PromptTokens: 7, TotalTokens: 11})
+}
+
+func Test_OpenAIClient_Run_Without_StreamCallback_Should_NotRequestStream(t *testing.T) {
+	// Arrange
+	var requestBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requestBody = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{FinishReason: "stop", Message: openai.NewMessage("assistant", "Hello")}},
+		})
+	}))
+	defer server.Close()
+	client := outbound.NewOpenAIClient(server.URL, "test-model")
+
+	// Act
+	response, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "hi")}, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "request must not ask for a stream", strings.Contains(requestBody, `"stream"`), false)
+	assert.That(t, "content must match", response.Message.Content, "Hello")
+	assert.That(t, "response must not be marked as streamed", response.IsStreamed, false)
+}
+
+func Test_OpenAIClient_Run_With_StreamCallbackAndOversizedStream_Should_ReturnError(t *testing.T) {
+	// Arrange
+	var requestBody string
+	server := newStreamServer([]string{strings.Repeat("x", 256)}, &requestBody)
+	defer server.Close()
+	client := outbound.NewOpenAIClient(server.URL, "test-model").
+		WithMaxResponseBytes(64).
+		WithRetry(1, 0).
+		WithStreamCallback(func(string) {})
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "hi")}, nil)
+
+	// Assert
+	assert.That(t, "err must be ErrResponseTooLarge", errors.Is(err, outbound.ErrResponseTooLarge), true)
+}
+
+func Test_OpenAIClient_ConvertStreamedResponse_With_ToolCallDeltas_Should_EqualRunResult(t *testing.T) {
+	// Arrange
+	response := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{
+			FinishReason: "tool_calls",
+			Message: openai.NewMessage("assistant", "Checking.").WithToolCalls([]openai.ToolCall{
+				openai.NewToolCall("call_1", "read_file", `{"path":"a.go"}`),
+			}),
+		}},
+		ID:    "chatcmpl-1",
+		Model: "test-model",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+	client := outbound.NewOpenAIClient(server.URL, "test-model")
+	expected, _ := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "hi")}, nil)
+
+	acc := openai.NewStreamAccumulator()
+	for _, delta := range []openai.ChatCompletionDelta{
+		{Content: "Check", Role: "assistant"},
+		{ToolCalls: []openai.ToolCallDelta{{Function: openai.FunctionCall{Arguments: `{"pa`, Name: "read_file"}, ID: "call_1", Type: "function"}}},
+		{Content: "ing."},
+		{ToolCalls: []openai.ToolCallDelta{{Function: openai.FunctionCall{Arguments: `th":"a.go"}`}}}},
+	} {
+		acc.Add(openai.ChatCompletionChunk{Choices: []openai.ChatCompletionChunkChoice{{Delta: delta}}, ID: "chatcmpl-1"})
+	}
+	acc.Add(openai.ChatCompletionChunk{Choices: []openai.ChatCompletionChunkChoice{{FinishReason: "tool_calls"}}})
+
+	// Act
+	result, err := client.ConvertStreamedResponse(acc)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "response must be marked as streamed", result.IsStreamed, true)
+	result.IsStreamed = false
+	assert.That(t, "response must equal the non-streamed response", result, expected)
+}
+
 func Test_OpenAIClient_Run_With_EmptyChoices_Should_ReturnError(t *testing.T) {
 	// Arrange
 	response := openai.ChatCompletionResponse{
@@ -282,6 +448,112 @@ func Test_OpenAIClient_Run_With_Tools_Should_IncludeToolsInRequest(t *testing.T)
 	assert.That(t, "second tool name must match", receivedRequest.Tools[1].Function.Name, "calculate")
 }
 
+func Test_OpenAIClient_Run_With_SchemaTool_Should_RoundTripSchema(t *testing.T) {
+	// Arrange
+	schema := `{"type":"object","properties":{"mode":{"type":"string","description":"Search mode","enum":["exact","fuzzy"]},"tags":{"type":"array","description":"Tags","items":{"type":"string"}}},"required":["mode"]}`
+	var receivedRequest openai.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&receivedRequest)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{FinishReason: "stop", Message: openai.NewMessage("assistant", "OK")}},
+		})
+	}))
+	defer server.Close()
+	client := outbound.NewOpenAIClient(server.URL, "test-model")
+	tool, _ := agent.NewToolDefinitionFromSchema("search", "Search notes", schema)
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "hi")}, []agent.ToolDefinition{tool})
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	params := receivedRequest.Tools[0].Function.Parameters
+	assert.That(t, "required must match", params.Required, []string{"mode"})
+	assert.That(t, "enum must match", params.Properties["mode"].Enum, []string{"exact", "fuzzy"})
+	assert.That(t, "array type must match", params.Properties["tags"].Type, "array")
+	assert.That(t, "items must match", params.Properties["tags"].Items.Type, "string")
+}
+
+// toolChoiceServer records the raw tool_choice field of each request.
+func toolChoiceServer(t *testing.T, received *json.RawMessage) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ToolChoice json.RawMessage `json:"tool_choice"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		*received = body.ToolChoice
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{FinishReason: "stop", Message: openai.NewMessage("assistant", "OK")}},
+		})
+	}))
+}
+
+func Test_OpenAIClient_WithToolChoice_Should_SendToolChoice(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		choice   agent.ToolChoice
+	}{
+		{name: "auto", choice: agent.NewToolChoice(agent.ToolChoiceAuto), expected: `"auto"`},
+		{name: "none", choice: agent.NewToolChoice(agent.ToolChoiceNone), expected: `"none"`},
+		{name: "required", choice: agent.NewToolChoice(agent.ToolChoiceRequired), expected: `"required"`},
+		{name: "named", choice: agent.NewNamedToolChoice("get_time"), expected: `{"function":{"name":"get_time"},"type":"function"}`},
+		{name: "unset", choice: agent.ToolChoice{}, expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			var received json.RawMessage
+			server := toolChoiceServer(t, &received)
+			defer server.Close()
+			client := outbound.NewOpenAIClient(server.URL, "test-model").WithToolChoice(tt.choice)
+			tools := []agent.ToolDefinition{agent.NewToolDefinition("get_time", "Get the current time")}
+
+			// Act
+			_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "hi")}, tools)
+
+			// Assert
+			assert.That(t, "must not return error", err, nil)
+			assert.That(t, "tool_choice must match", string(received), tt.expected)
+		})
+	}
+}
+
+func Test_OpenAIClient_Run_With_ToolChoiceInContext_Should_OverrideClientChoice(t *testing.T) {
+	// Arrange
+	var received json.RawMessage
+	server := toolChoiceServer(t, &received)
+	defer server.Close()
+	client := outbound.NewOpenAIClient(server.URL, "test-model").WithToolChoice(agent.NewToolChoice(agent.ToolChoiceAuto))
+	tools := []agent.ToolDefinition{agent.NewToolDefinition("get_time", "Get the current time")}
+	ctx := agent.ContextWithToolChoice(context.Background(), agent.NewToolChoice(agent.ToolChoiceNone))
+
+	// Act
+	_, err := client.Run(ctx, []agent.Message{agent.NewMessage(agent.RoleUser, "hi")}, tools)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "tool_choice must be the override", string(received), `"none"`)
+}
+
+func Test_OpenAIClient_Run_With_ToolChoiceWithoutTools_Should_OmitToolChoice(t *testing.T) {
+	// Arrange
+	var received json.RawMessage
+	server := toolChoiceServer(t, &received)
+	defer server.Close()
+	client := outbound.NewOpenAIClient(server.URL, "test-model").WithToolChoice(agent.NewToolChoice(agent.ToolChoiceRequired))
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "hi")}, nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "tool_choice must be omitted", len(received), 0)
+}
+
 func Test_OpenAIClient_Run_With_ContextCanceled_Should_ReturnError(t *testing.T) {
 	// Arrange
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -303,3 +575,800 @@ func Test_OpenAIClient_Run_With_ContextCanceled_Should_ReturnError(t *testing.T)
 	// Assert
 	assert.That(t, "must return error", err != nil, true)
 }
+
+func Test_OpenAIClient_Run_With_Stop_Should_IncludeStopArrayInRequest(t *testing.T) {
+	// Arrange
+	response := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{FinishReason: "stop", Message: openai.Message{Role: "assistant", Content: "OK"}},
+		},
+	}
+
+	var receivedBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&receivedBody)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client, configErr := outbound.NewOpenAIClient(server.URL, "test-model").WithStop("END", "\n\n")
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	assert.That(t, "config err must be nil", configErr, nil)
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "stop must be an array", receivedBody["stop"], any([]any{"END", "\n\n"}))
+}
+
+func Test_OpenAIClient_WithStop_With_FiveSequences_Should_ReturnError(t *testing.T) {
+	// Arrange
+	client := outbound.NewOpenAIClient("http://localhost:1234", "test-model")
+
+	// Act
+	_, err := client.WithStop("a", "b", "c", "d", "e")
+
+	// Assert
+	assert.That(t, "err must be ErrTooManyStopSequences", errors.Is(err, outbound.ErrTooManyStopSequences), true)
+}
+
+func Test_OpenAIClient_Run_With_Seed_Should_IncludeSeedInRequest(t *testing.T) {
+	// Arrange
+	response := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{FinishReason: "stop", Message: openai.Message{Role: "assistant", Content: "OK"}},
+		},
+	}
+
+	var receivedBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&receivedBody)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model").WithSeed(42)
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "seed must be sent", receivedBody["seed"], any(float64(42)))
+}
+
+func Test_OpenAIClient_Run_Without_Seed_Should_OmitSeedFromRequest(t *testing.T) {
+	// Arrange
+	response := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{FinishReason: "stop", Message: openai.Message{Role: "assistant", Content: "OK"}},
+		},
+	}
+
+	var receivedBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&receivedBody)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model")
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	_, hasSeed := receivedBody["seed"]
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "seed must be omitted", hasSeed, false)
+}
+
+// runCapturingMessages runs the client against a test server and returns the messages it received.
+func runCapturingMessages(t *testing.T, configure func(*outbound.OpenAIClient), messages []agent.Message) []map[string]any {
+	t.Helper()
+	response := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{FinishReason: "stop", Message: openai.Message{Role: "assistant", Content: "OK"}},
+		},
+	}
+	var receivedBody struct {
+		Messages []map[string]any `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&receivedBody)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model")
+	configure(client)
+	if _, err := client.Run(context.Background(), messages, nil); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	return receivedBody.Messages
+}
+
+func Test_OpenAIClient_Run_With_PromptCaching_Should_SendCacheMarker(t *testing.T) {
+	// Arrange
+	messages := []agent.Message{
+		agent.NewMessage(agent.RoleSystem, "Long system prompt").WithCacheControl(),
+		agent.NewMessage(agent.RoleUser, "Hi"),
+	}
+
+	// Act
+	received := runCapturingMessages(t, func(c *outbound.OpenAIClient) { c.WithPromptCaching(true) }, messages)
+
+	// Assert
+	assert.That(t, "system prompt must carry the cache marker", received[0]["content"], any([]any{
+		map[string]any{"cache_control": map[string]any{"type": "ephemeral"}, "text": "Long system prompt", "type": "text"},
+	}))
+	assert.That(t, "unmarked message must stay a string", received[1]["content"], any("Hi"))
+}
+
+func Test_OpenAIClient_Run_Without_PromptCaching_Should_OmitCacheMarker(t *testing.T) {
+	// Arrange
+	messages := []agent.Message{
+		agent.NewMessage(agent.RoleSystem, "Long system prompt").WithCacheControl(),
+	}
+
+	// Act
+	received := runCapturingMessages(t, func(*outbound.OpenAIClient) {}, messages)
+
+	// Assert
+	assert.That(t, "system prompt must stay a string", received[0]["content"], any("Long system prompt"))
+}
+
+func Test_OpenAIClient_Run_With_ImagePart_Should_SendContentArray(t *testing.T) {
+	// Arrange
+	response := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{FinishReason: "stop", Message: openai.Message{Role: "assistant", Content: "A diagram"}},
+		},
+	}
+
+	var receivedBody struct {
+		Messages []map[string]any `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&receivedBody)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model")
+	messages := []agent.Message{
+		agent.NewMessage(agent.RoleSystem, "You describe images."),
+		agent.NewMessage(agent.RoleUser, "What is this?").WithImageURL("https://example.com/diagram.png", "low"),
+	}
+
+	// Act
+	_, err := client.Run(context.Background(), messages, nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "text message content must stay a string", receivedBody.Messages[0]["content"], any("You describe images."))
+	assert.That(t, "image message content must be an array", receivedBody.Messages[1]["content"], any([]any{
+		map[string]any{"text": "What is this?", "type": "text"},
+		map[string]any{"image_url": map[string]any{"detail": "low", "url": "https://example.com/diagram.png"}, "type": "image_url"},
+	}))
+}
+
+func Test_OpenAIClient_Run_With_SystemFingerprint_Should_SurfaceFingerprint(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","system_fingerprint":"fp_44709d6fcb","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"OK"}}]}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model").WithSeed(7)
+
+	// Act
+	result, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "fingerprint must match", result.SystemFingerprint, "fp_44709d6fcb")
+}
+
+func Test_OpenAIClient_Run_With_Usage_Should_SurfaceTokenUsage(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"OK"}}],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model")
+
+	// Act
+	result, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "usage must match", result.Usage, agent.TokenUsage{CompletionTokens: 3, PromptTokens: 12, TotalTokens: 15})
+}
+
+func Test_OpenAIClient_Run_With_Candidates_Should_ReturnFirstChoiceAndExposeAll(t *testing.T) {
+	// Arrange
+	response := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Index: 0, FinishReason: "stop", Message: openai.Message{Role: "assistant", Content: "first"}},
+			{Index: 1, FinishReason: "stop", Message: openai.Message{Role: "assistant", Content: "second"}},
+			{
+				Index:        2,
+				FinishReason: "tool_calls",
+				Message: openai.Message{
+					Role:      "assistant",
+					ToolCalls: []openai.ToolCall{openai.NewToolCall("call-1", "search", `{"query":"go"}`)},
+				},
+			},
+		},
+	}
+
+	var receivedBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&receivedBody)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model").WithCandidates(3)
+
+	// Act
+	result, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "n must be sent", receivedBody["n"], any(float64(3)))
+	assert.That(t, "message must be the first choice", result.Message.Content, "first")
+	assert.That(t, "must expose 3 candidates", len(result.Candidates), 3)
+	assert.That(t, "third candidate must carry tool calls", len(result.Candidates[2].ToolCalls), 1)
+}
+
+func Test_OpenAIClient_Run_With_Candidates_And_Selection_Should_PickIntendedIndex(t *testing.T) {
+	// Arrange
+	response := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Index: 0, FinishReason: "stop", Message: openai.Message{Role: "assistant", Content: "first"}},
+			{
+				Index:        1,
+				FinishReason: "tool_calls",
+				Message: openai.Message{
+					Role:      "assistant",
+					ToolCalls: []openai.ToolCall{openai.NewToolCall("call-1", "search", `{"query":"go"}`)},
+				},
+			},
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model").WithCandidates(2)
+	result, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Act
+	selected := result.SelectCandidate(1)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "selected response must have tool calls", selected.HasToolCalls(), true)
+	assert.That(t, "tool call name must match", selected.ToolCalls[0].Name, "search")
+}
+
+func captureRoles(t *testing.T, client func(baseURL string) *outbound.OpenAIClient, messages []agent.Message) []string {
+	t.Helper()
+	response := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{FinishReason: "stop", Message: openai.Message{Role: "assistant", Content: "OK"}},
+		},
+	}
+
+	var received openai.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	_, err := client(server.URL).Run(context.Background(), messages, nil)
+	assert.That(t, "must not return error", err, nil)
+
+	roles := make([]string, len(received.Messages))
+	for i, msg := range received.Messages {
+		roles[i] = msg.Role
+	}
+	return roles
+}
+
+func Test_OpenAIClient_Run_With_DeveloperRoleEnabled_Should_SendDeveloperRole(t *testing.T) {
+	// Arrange
+	messages := []agent.Message{
+		agent.NewMessage(agent.RoleSystem, "You are helpful."),
+		agent.NewMessage(agent.RoleDeveloper, "Answer in JSON."),
+		agent.NewMessage(agent.RoleUser, "Hi"),
+	}
+
+	// Act
+	roles := captureRoles(t, func(baseURL string) *outbound.OpenAIClient {
+		return outbound.NewOpenAIClient(baseURL, "test-model").WithDeveloperRole(true)
+	}, messages)
+
+	// Assert
+	assert.That(t, "roles must be translated", roles, []string{"system", "developer", "user"})
+}
+
+func Test_OpenAIClient_Run_Without_DeveloperRole_Should_FallBackToSystem(t *testing.T) {
+	// Arrange
+	messages := []agent.Message{
+		agent.NewMessage(agent.RoleSystem, "You are helpful."),
+		agent.NewMessage(agent.RoleDeveloper, "Answer in JSON."),
+		agent.NewMessage(agent.RoleUser, "Hi"),
+	}
+
+	// Act
+	roles := captureRoles(t, func(baseURL string) *outbound.OpenAIClient {
+		return outbound.NewOpenAIClient(baseURL, "test-model")
+	}, messages)
+
+	// Assert
+	assert.That(t, "developer must fall back to system", roles, []string{"system", "system", "user"})
+}
+
+func Test_OpenAIClient_Run_With_RequestLogging_Should_LogRedactedRequestAndResponse(t *testing.T) {
+	// Arrange
+	var gotAuth, gotKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotKey = r.Header.Get("X-Api-Key")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Pong"}}]}`))
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	client := outbound.NewOpenAIClient(server.URL, "test-model").
+		WithHeader("Authorization", "Bearer sk-secret-token").
+		WithSecretHeader("X-Api-Key", "key-secret-value").
+		WithHeader("X-Request-Source", "cli").
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))).
+		WithRequestLogging(slog.LevelInfo)
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Ping")}, nil)
+
+	// Assert
+	output := logs.String()
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "authorization must be sent", gotAuth, "Bearer sk-secret-token")
+	assert.That(t, "secret header must be sent", gotKey, "key-secret-value")
+	assert.That(t, "authorization must be redacted", strings.Contains(output, "sk-secret-token"), false)
+	assert.That(t, "secret header must be redacted", strings.Contains(output, "key-secret-value"), false)
+	assert.That(t, "redaction marker must appear", strings.Contains(output, "[REDACTED]"), true)
+	assert.That(t, "plain header must appear", strings.Contains(output, "cli"), true)
+	assert.That(t, "model must appear", strings.Contains(output, "test-model"), true)
+	assert.That(t, "message must appear", strings.Contains(output, "Ping"), true)
+	assert.That(t, "response must appear", strings.Contains(output, "Pong"), true)
+}
+
+func Test_OpenAIClient_Run_With_RequestLogging_Should_TruncateLargeBodies(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"OK"}}]}`))
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	client := outbound.NewOpenAIClient(server.URL, "test-model").
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))).
+		WithRequestLogging(slog.LevelInfo)
+	large := strings.Repeat("a", 10000)
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, large)}, nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "full body must not be logged", strings.Contains(logs.String(), large), false)
+	assert.That(t, "truncation must be noted", strings.Contains(logs.String(), "bytes truncated"), true)
+}
+
+func Test_OpenAIClient_Run_Without_RequestLogging_Should_NotLogBodies(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"OK"}}]}`))
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	client := outbound.NewOpenAIClient(server.URL, "test-model").
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Ping")}, nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "request body must not be logged", strings.Contains(logs.String(), "llm http request"), false)
+}
+
+func Test_OpenAIClient_Run_With_Timeout_Should_FailOnHangingServer(t *testing.T) {
+	// Arrange
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		// Never respond until the test is done
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model").
+		WithRetry(1, time.Millisecond).
+		WithTimeout(50 * time.Millisecond)
+	start := time.Now()
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	assert.That(t, "must return error", err != nil, true)
+	assert.That(t, "timeout must fire quickly", time.Since(start) < 5*time.Second, true)
+}
+
+func Test_OpenAIClient_WithTimeout_Should_NotModifyCustomHTTPClient(t *testing.T) {
+	// Arrange
+	customHTTPClient := &http.Client{Timeout: time.Minute}
+
+	// Act
+	outbound.NewOpenAIClient("http://localhost:1234", "test-model").
+		WithHTTPClient(customHTTPClient).
+		WithTimeout(time.Second)
+
+	// Assert
+	assert.That(t, "custom client timeout must be unchanged", customHTTPClient.Timeout, time.Minute)
+}
+
+func Test_OpenAIClient_Run_With_Transport_Should_UseTransport(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"OK"}}]}`))
+	}))
+	defer server.Close()
+
+	var dials atomic.Int32
+	dialer := &net.Dialer{}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dials.Add(1)
+			return dialer.DialContext(ctx, network, addr)
+		},
+		MaxIdleConnsPerHost: 4,
+	}
+	client := outbound.NewOpenAIClient(server.URL, "test-model").
+		WithTransport(transport).
+		WithTimeout(5 * time.Second)
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "transport must be used", dials.Load(), int32(1))
+}
+
+func Test_OpenAIClient_Run_With_StructuredErrorBody_Should_ReturnAPIError(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"This model's maximum context length is 4096 tokens","type":"invalid_request_error","code":"context_length_exceeded"}}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model").WithRetry(1, 0)
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	var apiErr *agent.APIError
+	assert.That(t, "error must be an APIError", errors.As(err, &apiErr), true)
+	assert.That(t, "status code must match", apiErr.StatusCode, http.StatusBadRequest)
+	assert.That(t, "type must match", apiErr.Type, "invalid_request_error")
+	assert.That(t, "code must match", apiErr.Code, "context_length_exceeded")
+	assert.That(t, "message must match", apiErr.Message, "This model's maximum context length is 4096 tokens")
+}
+
+func Test_OpenAIClient_Run_With_RateLimitErrorBody_Should_ReturnAPIError(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"message":"Rate limit reached","type":"requests","code":null}}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model").WithRetry(1, 0)
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	var apiErr *agent.APIError
+	assert.That(t, "error must be an APIError", errors.As(err, &apiErr), true)
+	assert.That(t, "status code must match", apiErr.StatusCode, http.StatusTooManyRequests)
+	assert.That(t, "type must match", apiErr.Type, "requests")
+	assert.That(t, "code must be empty", apiErr.Code, "")
+}
+
+func Test_OpenAIClient_Run_With_PlainErrorBody_Should_NotReturnAPIError(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = w.Write([]byte("Bad Gateway"))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model").WithRetry(1, 0)
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	var apiErr *agent.APIError
+	assert.That(t, "must return error", err != nil, true)
+	assert.That(t, "error must not be an APIError", errors.As(err, &apiErr), false)
+	assert.That(t, "error must contain the body", strings.Contains(err.Error(), "Bad Gateway"), true)
+}
+
+func Test_OpenAIClient_Ping_With_ModelListed_Should_Succeed(t *testing.T) {
+	// Arrange
+	var method, path, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, auth = r.Method, r.URL.Path, r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"test-model","object":"model"}]}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model").WithHeader("Authorization", "Bearer token")
+
+	// Act
+	err := client.Ping(context.Background())
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "method must be GET", method, http.MethodGet)
+	assert.That(t, "path must be the models endpoint", path, "/v1/models")
+	assert.That(t, "custom headers must be sent", auth, "Bearer token")
+}
+
+func Test_OpenAIClient_Ping_With_ResponseExceedingMaxBytes_Should_ReturnErrResponseTooLarge(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"` + strings.Repeat("x", 4096) + `","object":"model"}]}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model").WithMaxResponseBytes(1024)
+
+	// Act
+	err := client.Ping(context.Background())
+
+	// Assert
+	assert.That(t, "error must be ErrResponseTooLarge", errors.Is(err, outbound.ErrResponseTooLarge), true)
+}
+
+func Test_OpenAIClient_Ping_With_ModelMissing_Should_ReturnErrModelNotAvailable(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"other-model","object":"model"}]}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model")
+
+	// Act
+	err := client.Ping(context.Background())
+
+	// Assert
+	assert.That(t, "error must be ErrModelNotAvailable", errors.Is(err, outbound.ErrModelNotAvailable), true)
+}
+
+func Test_OpenAIClient_Ping_With_ServerError_Should_ReturnError(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("Internal Server Error"))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model")
+
+	// Act
+	err := client.Ping(context.Background())
+
+	// Assert
+	assert.That(t, "must return error", err != nil, true)
+	assert.That(t, "error must contain the status", strings.Contains(err.Error(), "500"), true)
+}
+
+func Test_OpenAIClient_Ping_With_UnreachableServer_Should_ReturnError(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	client := outbound.NewOpenAIClient(url, "test-model")
+
+	// Act
+	err := client.Ping(context.Background())
+
+	// Assert
+	assert.That(t, "must return error", err != nil, true)
+}
+
+func Test_OpenAIClient_Run_With_ResponseExceedingMaxBytes_Should_ReturnErrResponseTooLarge(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"`))
+		_, _ = w.Write([]byte(strings.Repeat("x", 4096)))
+		_, _ = w.Write([]byte(`"}}]}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model").
+		WithMaxResponseBytes(1024).
+		WithRetry(1, 0)
+
+	// Act
+	_, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	assert.That(t, "error must be ErrResponseTooLarge", errors.Is(err, outbound.ErrResponseTooLarge), true)
+}
+
+func Test_OpenAIClient_Run_With_ResponseWithinMaxBytes_Should_Succeed(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model").
+		WithMaxResponseBytes(1024).
+		WithRetry(1, 0)
+
+	// Act
+	resp, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "content must match", resp.Message.Content, "Hello")
+}
+
+func Test_OpenAIClient_Run_With_NonPositiveMaxBytes_Should_KeepDefaultLimit(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Hello"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model").
+		WithMaxResponseBytes(0).
+		WithRetry(1, 0)
+
+	// Act
+	resp, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "content must match", resp.Message.Content, "Hello")
+}
+
+func Test_OpenAIClient_Run_With_ToolCallTurn_Should_SendWellFormedMessages(t *testing.T) {
+	// Arrange
+	var raw struct {
+		Messages []map[string]json.RawMessage `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&raw)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Done"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model")
+	messages := []agent.Message{
+		agent.NewMessage(agent.RoleUser, "What time is it in Berlin and Tokyo?"),
+		agent.NewMessage(agent.RoleAssistant, "").WithToolCalls([]agent.ToolCall{
+			agent.NewToolCall("call_1", "get_time", `{"city":"Berlin"}`),
+			agent.NewToolCall("call_2", "get_time", ""),
+		}),
+		agent.NewMessage(agent.RoleTool, "12:00").WithToolCallID("call_1"),
+		agent.NewMessage(agent.RoleTool, "19:00").WithToolCallID("call_2"),
+	}
+
+	// Act
+	_, err := client.Run(context.Background(), messages, nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "must send 4 messages", len(raw.Messages), 4)
+	assistant := raw.Messages[1]
+	assert.That(t, "assistant content must be null", string(assistant["content"]), "null")
+	var toolCalls []openai.ToolCall
+	_ = json.Unmarshal(assistant["tool_calls"], &toolCalls)
+	assert.That(t, "assistant must carry both tool calls", len(toolCalls), 2)
+	assert.That(t, "tool call type must be function", toolCalls[0].Type, "function")
+	assert.That(t, "tool call arguments must be kept", toolCalls[0].Function.Arguments, `{"city":"Berlin"}`)
+	assert.That(t, "empty arguments must be an empty object", toolCalls[1].Function.Arguments, "{}")
+	for i, id := range []string{`"call_1"`, `"call_2"`} {
+		tool := raw.Messages[2+i]
+		assert.That(t, "tool message role must be tool", string(tool["role"]), `"tool"`)
+		assert.That(t, "tool_call_id must match the call", string(tool["tool_call_id"]), id)
+		assert.That(t, "tool message must not carry tool_calls", tool["tool_calls"] == nil, true)
+	}
+}
+
+func Test_OpenAIClient_Run_With_ToolCallWithoutID_Should_AssignID(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"","tool_calls":[{"type":"function","function":{"name":"get_time","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model")
+
+	// Act
+	resp, err := client.Run(context.Background(), []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}, nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "must have one tool call", len(resp.ToolCalls), 1)
+	assert.That(t, "tool call must get an ID", strings.HasPrefix(string(resp.ToolCalls[0].ID), "call-"), true)
+}
+
+func Test_OpenAIClient_Run_With_ToolCallsWithoutIDInTwoTurns_Should_AssignDistinctIDs(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"","tool_calls":[` +
+			`{"type":"function","function":{"name":"get_time","arguments":"{}"}},` +
+			`{"type":"function","function":{"name":"get_date","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIClient(server.URL, "test-model")
+	messages := []agent.Message{agent.NewMessage(agent.RoleUser, "Hi")}
+
+	// Act
+	first, firstErr := client.Run(context.Background(), messages, nil)
+	second, secondErr := client.Run(context.Background(), append(messages, first.Message), nil)
+
+	// Assert
+	assert.That(t, "first turn must not return error", firstErr, nil)
+	assert.That(t, "second turn must not return error", secondErr, nil)
+	ids := make(map[agent.ToolCallID]bool)
+	for _, tc := range append(first.ToolCalls, second.ToolCalls...) {
+		ids[tc.ID] = true
+	}
+	assert.That(t, "every tool call must get a distinct ID", len(ids), 4)
+}
@@ -69,7 +69,9 @@ func (c *OpenAIEmbeddingClient) WithModel(model string) *OpenAIEmbeddingClient {
 	return c
 }
 
-// WithRetry configures retry behavior for transient failures.
+// WithRetry configures retry behavior for transient failures, like OpenAIClient.WithRetry.
+// A failed Embed call is retried up to attempts times, waiting delay before each retry;
+// a canceled context stops the retries immediately. Zero disables retries.
 func (c *OpenAIEmbeddingClient) WithRetry(attempts int, delay time.Duration) *OpenAIEmbeddingClient {
 	c.retryAttempts = attempts
 	c.retryDelay = delay
@@ -101,45 +103,102 @@ func (c *OpenAIEmbeddingClient) Embed(ctx context.Context, text string) (agent.E
 	return fn(ctx, text)
 }
 
+// EmbedBatch generates the embedding vectors for several texts in one API call.
+// The embeddings are returned in the order of the texts. It uses the same timeout,
+// retry, and circuit breaker configuration as Embed.
+func (c *OpenAIEmbeddingClient) EmbedBatch(ctx context.Context, texts []string) ([]agent.Embedding, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	var fn service.Function[[]string, []agent.Embedding] = c.doEmbedBatch
+	fn = stability.Timeout(fn, c.timeout)
+	fn = stability.Retry(fn, c.retryAttempts, c.retryDelay)
+	fn = stability.Breaker(fn, c.breakerThresh)
+
+	return fn(ctx, texts)
+}
+
+// Close releases the idle connections held by the HTTP client.
+func (c *OpenAIEmbeddingClient) Close(_ context.Context) error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// Ping checks that the endpoint is reachable and serves the configured model.
+func (c *OpenAIEmbeddingClient) Ping(ctx context.Context) error {
+	return pingModels(ctx, c.httpClient, c.baseURL, c.model, nil, defaultMaxRespBytes)
+}
+
 // doEmbed performs the actual embedding API call.
 func (c *OpenAIEmbeddingClient) doEmbed(ctx context.Context, text string) (agent.Embedding, error) {
-	request := openai.NewEmbeddingRequest(c.model, text)
+	response, err := c.postEmbeddings(ctx, openai.NewEmbeddingRequest(c.model, text))
+	if err != nil {
+		return nil, err
+	}
+
+	embedding := response.GetFirstEmbedding()
+	if embedding == nil {
+		return nil, errors.New("empty embedding response")
+	}
+
+	c.logSuccess(len(embedding), response.Usage.TotalTokens)
+
+	return embedding, nil
+}
+
+// doEmbedBatch performs the actual embedding API call for several texts.
+func (c *OpenAIEmbeddingClient) doEmbedBatch(ctx context.Context, texts []string) ([]agent.Embedding, error) {
+	response, err := c.postEmbeddings(ctx, openai.NewEmbeddingBatchRequest(c.model, texts))
+	if err != nil {
+		return nil, err
+	}
+
+	data := response.GetEmbeddings(len(texts))
+	if data == nil {
+		return nil, fmt.Errorf("incomplete embedding response: got %d embeddings for %d texts", len(response.Data), len(texts))
+	}
+
+	embeddings := make([]agent.Embedding, len(data))
+	for i, embedding := range data {
+		embeddings[i] = embedding
+	}
+
+	c.logSuccess(len(embeddings[0]), response.Usage.TotalTokens)
+
+	return embeddings, nil
+}
+
+// postEmbeddings sends an embedding request and decodes the response.
+func (c *OpenAIEmbeddingClient) postEmbeddings(ctx context.Context, request any) (openai.EmbeddingResponse, error) {
+	var response openai.EmbeddingResponse
 	body, err := json.Marshal(request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return response, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	url := c.baseURL + "/v1/embeddings"
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return response, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return response, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("embedding API error: status %d, body: %s", resp.StatusCode, string(respBody))
+		return response, fmt.Errorf("embedding API error: status %d, body: %s", resp.StatusCode, string(respBody))
 	}
 
-	var response openai.EmbeddingResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return response, fmt.Errorf("failed to decode response: %w", err)
 	}
-
-	embedding := response.GetFirstEmbedding()
-	if embedding == nil {
-		return nil, errors.New("empty embedding response")
-	}
-
-	c.logSuccess(len(embedding), response.Usage.TotalTokens)
-
-	return embedding, nil
+	return response, nil
 }
 
 // logSuccess logs a successful embedding request.
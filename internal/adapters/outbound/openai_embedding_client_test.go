@@ -3,13 +3,16 @@ package outbound_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/andygeiss/cloud-native-utils/assert"
 	"github.com/andygeiss/go-agent/internal/adapters/outbound"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
 	"github.com/andygeiss/go-agent/internal/domain/openai"
 )
 
@@ -184,6 +187,66 @@ func Test_OpenAIEmbeddingClient_Embed_With_ContextCanceled_Should_ReturnError(t
 	assert.That(t, "result must be nil", result == nil, true)
 }
 
+// newFlakyEmbeddingServer fails the first failures requests with a server error and then returns an embedding.
+func newFlakyEmbeddingServer(failures int32, calls *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": [{"embedding": [0.1, 0.2], "index": 0}], "usage": {"total_tokens": 2}}`))
+	}))
+}
+
+func Test_OpenAIEmbeddingClient_Embed_With_TransientFailures_Should_Retry(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := newFlakyEmbeddingServer(2, &calls)
+	defer server.Close()
+	client := outbound.NewOpenAIEmbeddingClient(server.URL).WithRetry(2, time.Millisecond)
+
+	// Act
+	result, err := client.Embed(context.Background(), "Hello, world!")
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "embedding must be returned", result, agent.Embedding{0.1, 0.2})
+	assert.That(t, "request must be retried until it succeeds", calls.Load(), int32(3))
+}
+
+func Test_OpenAIEmbeddingClient_Embed_With_RetriesExhausted_Should_ReturnError(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := newFlakyEmbeddingServer(5, &calls)
+	defer server.Close()
+	client := outbound.NewOpenAIEmbeddingClient(server.URL).WithRetry(2, time.Millisecond)
+
+	// Act
+	_, err := client.Embed(context.Background(), "Hello, world!")
+
+	// Assert
+	assert.That(t, "err must not be nil", err != nil, true)
+	assert.That(t, "request must be tried once plus the retries", calls.Load(), int32(3))
+}
+
+func Test_OpenAIEmbeddingClient_Embed_With_ContextCanceledDuringRetry_Should_StopRetrying(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := newFlakyEmbeddingServer(5, &calls)
+	defer server.Close()
+	client := outbound.NewOpenAIEmbeddingClient(server.URL).WithRetry(5, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Act
+	_, err := client.Embed(ctx, "Hello, world!")
+
+	// Assert
+	assert.That(t, "err must be the context error", errors.Is(err, context.DeadlineExceeded), true)
+	assert.That(t, "request must not be retried", calls.Load(), int32(1))
+}
+
 func Test_OpenAIEmbeddingClient_Embed_Should_SendCorrectRequest(t *testing.T) {
 	// Arrange
 	var receivedRequest openai.EmbeddingRequest
@@ -211,3 +274,90 @@ func Test_OpenAIEmbeddingClient_Embed_Should_SendCorrectRequest(t *testing.T) {
 	assert.That(t, "input must match", receivedRequest.Input, "test input")
 	assert.That(t, "model must match", receivedRequest.Model, "custom-model")
 }
+
+// -----------------------------------------------------------------------------
+// Ping tests
+// -----------------------------------------------------------------------------
+
+func Test_OpenAIEmbeddingClient_EmbedBatch_Should_SendInputsAndOrderEmbeddings(t *testing.T) {
+	// Arrange
+	var request openai.EmbeddingBatchRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(openai.EmbeddingResponse{
+			Data: []openai.EmbeddingData{
+				{Embedding: []float32{0.2}, Index: 1},
+				{Embedding: []float32{0.1}, Index: 0},
+			},
+		})
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIEmbeddingClient(server.URL).WithModel("test-model")
+
+	// Act
+	result, err := client.EmbedBatch(context.Background(), []string{"first", "second"})
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "inputs must be sent in one request", request.Input, []string{"first", "second"})
+	assert.That(t, "model must be sent", request.Model, "test-model")
+	assert.That(t, "embeddings must follow the input order", result, []agent.Embedding{{0.1}, {0.2}})
+}
+
+func Test_OpenAIEmbeddingClient_EmbedBatch_With_MissingEmbedding_Should_ReturnError(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(openai.EmbeddingResponse{
+			Data: []openai.EmbeddingData{{Embedding: []float32{0.1}, Index: 0}},
+		})
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIEmbeddingClient(server.URL).
+		WithRetry(0, 0) // Disable retries for fast test execution
+
+	// Act
+	result, err := client.EmbedBatch(context.Background(), []string{"first", "second"})
+
+	// Assert
+	assert.That(t, "must return error", err != nil, true)
+	assert.That(t, "result must be nil", result == nil, true)
+}
+
+func Test_OpenAIEmbeddingClient_Ping_With_ModelListed_Should_Succeed(t *testing.T) {
+	// Arrange
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"nomic-embed","object":"model"}]}`))
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIEmbeddingClient(server.URL).WithModel("nomic-embed")
+
+	// Act
+	err := client.Ping(context.Background())
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "path must be the models endpoint", path, "/v1/models")
+}
+
+func Test_OpenAIEmbeddingClient_Ping_With_ServerError_Should_ReturnError(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := outbound.NewOpenAIEmbeddingClient(server.URL)
+
+	// Act
+	err := client.Ping(context.Background())
+
+	// Assert
+	assert.That(t, "must return error", err != nil, true)
+}
@@ -0,0 +1,56 @@
+package outbound
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+)
+
+// ErrSessionNotFound is returned by SessionStore.Load if no session has been saved yet.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore persists the state of a single agent (conversation, tasks, and statistics)
+// in a JSON file, so that a session can be resumed after a restart.
+// Writes are atomic: a crash during Save never leaves a truncated file behind.
+type SessionStore struct {
+	path string
+}
+
+// NewSessionStore creates a SessionStore for the given file path.
+// The file is created on the first Save.
+func NewSessionStore(path string) *SessionStore {
+	return &SessionStore{path: path}
+}
+
+// Load restores the saved session into the agent.
+// Returns ErrSessionNotFound if the file does not exist and agent.ErrInvalidState
+// if it cannot be decoded; the agent is left unchanged in both cases.
+func (s *SessionStore) Load(ag *agent.Agent) error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, s.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read session: %w", err)
+	}
+	return ag.UnmarshalState(data)
+}
+
+// Path returns the path of the session file.
+func (s *SessionStore) Path() string {
+	return s.path
+}
+
+// Save writes the current state of the agent to the session file.
+func (s *SessionStore) Save(ag *agent.Agent) error {
+	data, err := ag.MarshalState()
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return nil
+}
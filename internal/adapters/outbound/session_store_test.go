@@ -0,0 +1,66 @@
+package outbound_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/adapters/outbound"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+)
+
+func Test_SessionStore_Load_With_SavedSession_Should_RestoreMessagesAndStats(t *testing.T) {
+	// Arrange
+	store := outbound.NewSessionStore(filepath.Join(t.TempDir(), "session.json"))
+	original := agent.NewAgent("agent-1", "prompt")
+	original.AddMessage(agent.NewMessage(agent.RoleUser, "Hello"))
+	original.AddMessage(agent.NewMessage(agent.RoleAssistant, "Hi there!"))
+	task := agent.NewTask("task-1", "chat", "Hello")
+	task.Status = agent.TaskStatusCompleted
+	original.AddTask(task)
+	original.RecordResult(agent.NewResult(task.ID, true, "Hi there!").
+		WithIterationCount(1).
+		WithTokens(agent.TokenUsage{TotalTokens: 42}).
+		WithToolCallCount(3))
+	_ = store.Save(&original)
+	restored := agent.NewAgent("agent-1", "prompt")
+
+	// Act
+	err := store.Load(&restored)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "message count must carry over", restored.MessageCount(), 2)
+	assert.That(t, "task count must carry over", restored.TaskCount(), 1)
+	assert.That(t, "completed tasks must carry over", restored.CompletedTaskCount(), 1)
+	assert.That(t, "totals must carry over", restored.Totals, original.Totals)
+}
+
+func Test_SessionStore_Load_With_MissingFile_Should_ReturnErrSessionNotFound(t *testing.T) {
+	// Arrange
+	store := outbound.NewSessionStore(filepath.Join(t.TempDir(), "missing.json"))
+	ag := agent.NewAgent("agent-1", "prompt")
+
+	// Act
+	err := store.Load(&ag)
+
+	// Assert
+	assert.That(t, "err must be ErrSessionNotFound", errors.Is(err, outbound.ErrSessionNotFound), true)
+	assert.That(t, "agent must be unchanged", ag.MessageCount(), 0)
+}
+
+func Test_SessionStore_Load_With_CorruptFile_Should_ReturnErrInvalidState(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "session.json")
+	_ = os.WriteFile(path, []byte("not json"), 0o600)
+	store := outbound.NewSessionStore(path)
+	ag := agent.NewAgent("agent-1", "prompt")
+
+	// Act
+	err := store.Load(&ag)
+
+	// Assert
+	assert.That(t, "err must be ErrInvalidState", errors.Is(err, agent.ErrInvalidState), true)
+}
@@ -2,10 +2,14 @@ package outbound
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/andygeiss/cloud-native-utils/slices"
 	"github.com/andygeiss/cloud-native-utils/stability"
 	"github.com/andygeiss/go-agent/internal/domain/agent"
 )
@@ -15,14 +19,24 @@ const (
 	defaultToolTimeout = 30 * time.Second // Maximum time for a tool to execute
 )
 
+// Errors returned by the ToolExecutor (alphabetically sorted).
+var (
+	ErrArgumentsTooLarge = errors.New("tool call arguments too large")
+	ErrToolAliasConflict = errors.New("tool alias conflicts with a registered tool")
+)
+
 // ToolExecutor implements the agent.ToolExecutor interface.
 // It provides tool registration and execution with timeout protection.
 // Tool execution is wrapped with timeout to prevent runaway tools.
 type ToolExecutor struct {
 	logger      *slog.Logger
+	aliases     map[string]string
 	tools       map[string]agent.ToolFunc
+	toolContext func(context.Context) context.Context
 	definitions []agent.ToolDefinition
 	toolTimeout time.Duration
+	maxArgBytes int
+	validate    bool
 }
 
 // NewToolExecutor creates a new ToolExecutor without any registered tools.
@@ -30,6 +44,7 @@ type ToolExecutor struct {
 // Tool execution is wrapped with a default 30s timeout.
 func NewToolExecutor() *ToolExecutor {
 	return &ToolExecutor{
+		aliases:     make(map[string]string),
 		definitions: make([]agent.ToolDefinition, 0),
 		tools:       make(map[string]agent.ToolFunc),
 		toolTimeout: defaultToolTimeout,
@@ -38,13 +53,35 @@ func NewToolExecutor() *ToolExecutor {
 
 // Execute runs the specified tool with the given input arguments.
 // Execution is wrapped with a timeout to prevent runaway tools.
+// The tool receives ctx (decorated by WithDefaultToolContext, if set), so deadlines
+// and values of the caller propagate to outbound calls made by the tool.
+// Unknown tools return agent.ErrUnknownTool with the list of available tools,
+// so the LLM can correct itself in the next iteration.
 func (e *ToolExecutor) Execute(ctx context.Context, toolName string, arguments string) (string, error) {
+	toolName = e.resolve(toolName)
 	fn, ok := e.tools[toolName]
 	if !ok {
 		if e.logger != nil {
 			e.logger.Warn("tool not found", "tool", toolName)
 		}
-		return "", fmt.Errorf("tool not found: %s", toolName)
+		return "", e.unknownToolError(toolName)
+	}
+
+	if e.maxArgBytes > 0 && len(arguments) > e.maxArgBytes {
+		return "", agent.NewToolError(toolName,
+			fmt.Sprintf("arguments are %d bytes, the limit is %d; pass a reference such as a file path instead of the content", len(arguments), e.maxArgBytes),
+			ErrArgumentsTooLarge,
+		).WithUserFacing()
+	}
+
+	if e.validate {
+		if err := e.validateArgs(toolName, arguments); err != nil {
+			return "", err
+		}
+	}
+
+	if e.toolContext != nil {
+		ctx = e.toolContext(ctx)
 	}
 
 	start := time.Now()
@@ -83,6 +120,7 @@ func (e *ToolExecutor) Execute(ctx context.Context, toolName string, arguments s
 }
 
 // GetAvailableTools returns the list of available tool names.
+// Aliases are not listed; they resolve to the tools they point to.
 func (e *ToolExecutor) GetAvailableTools() []string {
 	names := make([]string, 0, len(e.tools))
 	for name := range e.tools {
@@ -97,11 +135,28 @@ func (e *ToolExecutor) GetToolDefinitions() []agent.ToolDefinition {
 }
 
 // HasTool returns true if the specified tool is available.
+// Aliases registered via RegisterAlias are resolved to their target.
 func (e *ToolExecutor) HasTool(toolName string) bool {
-	_, ok := e.tools[toolName]
+	_, ok := e.tools[e.resolve(toolName)]
 	return ok
 }
 
+// RegisterAlias registers an alternative name for a registered tool, for models
+// that call tools by a different naming convention (e.g. "memory.search" for
+// "memory_search"). Aliases are resolved on execution and are not sent to the model.
+// Returns agent.ErrToolNotFound if the target is not registered and
+// ErrToolAliasConflict if the alias is itself a registered tool name.
+func (e *ToolExecutor) RegisterAlias(alias, target string) error {
+	if _, ok := e.tools[target]; !ok {
+		return fmt.Errorf("%w: %s", agent.ErrToolNotFound, target)
+	}
+	if _, ok := e.tools[alias]; ok {
+		return fmt.Errorf("%w: %s", ErrToolAliasConflict, alias)
+	}
+	e.aliases[alias] = target
+	return nil
+}
+
 // RegisterTool registers a new tool function with the executor.
 func (e *ToolExecutor) RegisterTool(name string, fn agent.ToolFunc) {
 	e.tools[name] = fn
@@ -112,6 +167,37 @@ func (e *ToolExecutor) RegisterToolDefinition(def agent.ToolDefinition) {
 	e.definitions = append(e.definitions, def)
 }
 
+// UnregisterTool removes a tool, its definition, and all aliases pointing to it.
+// Unregistering an unknown tool is a no-op.
+func (e *ToolExecutor) UnregisterTool(name string) {
+	delete(e.tools, name)
+	e.definitions = slices.Filter(e.definitions, func(def agent.ToolDefinition) bool {
+		return def.Name != name
+	})
+	for alias, target := range e.aliases {
+		if target == name {
+			delete(e.aliases, alias)
+		}
+	}
+}
+
+// WithArgumentValidation validates the arguments of each call against the tool's
+// registered definition before the tool runs. Invalid arguments fail the call with
+// an *agent.ValidationError naming the offending fields; tools without a registered
+// definition are executed unvalidated.
+func (e *ToolExecutor) WithArgumentValidation() *ToolExecutor {
+	e.validate = true
+	return e
+}
+
+// WithDefaultToolContext sets a decorator that derives the context passed to every tool.
+// Use it to inject cross-cutting deadlines or values centrally instead of in each tool.
+// The decorator runs before the tool timeout is applied, so the earlier deadline wins.
+func (e *ToolExecutor) WithDefaultToolContext(decorate func(ctx context.Context) context.Context) *ToolExecutor {
+	e.toolContext = decorate
+	return e
+}
+
 // WithLogger sets an optional structured logger for the executor.
 // When set, the executor logs tool executions at debug level.
 func (e *ToolExecutor) WithLogger(logger *slog.Logger) *ToolExecutor {
@@ -119,8 +205,49 @@ func (e *ToolExecutor) WithLogger(logger *slog.Logger) *ToolExecutor {
 	return e
 }
 
+// WithMaxArgumentBytes rejects tool calls whose arguments exceed n bytes before they
+// are decoded or passed to the tool. The call fails with a user-facing error wrapping
+// ErrArgumentsTooLarge, so the model learns to pass references instead of content.
+// A limit of zero or less disables the check.
+func (e *ToolExecutor) WithMaxArgumentBytes(n int) *ToolExecutor {
+	e.maxArgBytes = n
+	return e
+}
+
 // WithToolTimeout sets the timeout for tool execution.
 func (e *ToolExecutor) WithToolTimeout(timeout time.Duration) *ToolExecutor {
 	e.toolTimeout = timeout
 	return e
 }
+
+// resolve returns the tool name an alias points to, or the name itself.
+func (e *ToolExecutor) resolve(toolName string) string {
+	if target, ok := e.aliases[toolName]; ok {
+		return target
+	}
+	return toolName
+}
+
+// unknownToolError builds a model-friendly error listing the available tools.
+func (e *ToolExecutor) unknownToolError(toolName string) error {
+	available := e.GetAvailableTools()
+	if len(available) == 0 {
+		return fmt.Errorf("%w: %s (no tools are available)", agent.ErrUnknownTool, toolName)
+	}
+	sort.Strings(available)
+	return fmt.Errorf("%w: %s (available tools: %s)", agent.ErrUnknownTool, toolName, strings.Join(available, ", "))
+}
+
+// validateArgs validates the arguments against the definition of the tool, if any.
+// Empty arguments are validated as an empty object.
+func (e *ToolExecutor) validateArgs(toolName, arguments string) error {
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	for _, def := range e.definitions {
+		if def.Name == toolName {
+			return agent.ValidateArgs(def, arguments)
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/andygeiss/cloud-native-utils/assert"
 	"github.com/andygeiss/go-agent/internal/adapters/outbound"
@@ -69,6 +70,32 @@ func Test_ToolExecutor_Execute_With_UnknownTool_Should_ReturnError(t *testing.T)
 	assert.That(t, "must return error for unknown tool", err != nil, true)
 }
 
+func Test_ToolExecutor_Execute_With_UnknownTool_Should_ReturnErrUnknownTool(t *testing.T) {
+	// Arrange
+	executor := newToolExecutorWithMockTools()
+	ctx := context.Background()
+
+	// Act
+	_, err := executor.Execute(ctx, "unknown_tool", "{}")
+
+	// Assert
+	assert.That(t, "err must be ErrUnknownTool", errors.Is(err, agent.ErrUnknownTool), true)
+	assert.That(t, "err must be ErrToolNotFound", errors.Is(err, agent.ErrToolNotFound), true)
+}
+
+func Test_ToolExecutor_Execute_With_UnknownTool_Should_ListAvailableTools(t *testing.T) {
+	// Arrange
+	executor := newToolExecutorWithMockTools()
+	ctx := context.Background()
+
+	// Act
+	_, err := executor.Execute(ctx, "unknown_tool", "{}")
+
+	// Assert
+	assert.That(t, "err must list available tools", err.Error(),
+		"tool not found: unknown_tool (available tools: another_tool, mock_tool)")
+}
+
 func Test_ToolExecutor_GetAvailableTools_Should_ContainMockTool(t *testing.T) {
 	// Arrange
 	executor := newToolExecutorWithMockTools()
@@ -145,3 +172,227 @@ func Test_ToolExecutor_HasTool_With_NonexistentTool_Should_ReturnFalse(t *testin
 	// Assert
 	assert.That(t, "must not have nonexistent tool", hasTool, false)
 }
+
+func Test_ToolExecutor_RegisterAlias_With_Alias_Should_DispatchToTarget(t *testing.T) {
+	// Arrange
+	executor := outbound.NewToolExecutor()
+	var called string
+	executor.RegisterTool("memory_search", func(_ context.Context, args string) (string, error) {
+		called = args
+		return "found", nil
+	})
+	err := executor.RegisterAlias("memory.search", "memory_search")
+
+	// Act
+	result, execErr := executor.Execute(context.Background(), "memory.search", `{"query":"go"}`)
+
+	// Assert
+	assert.That(t, "register error must be nil", err, nil)
+	assert.That(t, "execute error must be nil", execErr, nil)
+	assert.That(t, "result must come from target", result, "found")
+	assert.That(t, "target must receive arguments", called, `{"query":"go"}`)
+}
+
+func Test_ToolExecutor_RegisterAlias_With_Alias_Should_BeKnownToHasTool(t *testing.T) {
+	// Arrange
+	executor := newToolExecutorWithMockTools()
+	_ = executor.RegisterAlias("mock.tool", "mock_tool")
+
+	// Act
+	hasTool := executor.HasTool("mock.tool")
+
+	// Assert
+	assert.That(t, "alias must resolve to mock_tool", hasTool, true)
+}
+
+func Test_ToolExecutor_RegisterAlias_With_Alias_Should_NotDuplicateDefinitions(t *testing.T) {
+	// Arrange
+	executor := newToolExecutorWithMockTools()
+	_ = executor.RegisterAlias("mock.tool", "mock_tool")
+
+	// Act
+	definitions := executor.GetToolDefinitions()
+	tools := executor.GetAvailableTools()
+
+	// Assert
+	assert.That(t, "definitions must not include alias", len(definitions), 2)
+	assert.That(t, "available tools must not include alias", len(tools), 2)
+}
+
+func Test_ToolExecutor_RegisterAlias_With_NonexistentTarget_Should_ReturnError(t *testing.T) {
+	// Arrange
+	executor := newToolExecutorWithMockTools()
+
+	// Act
+	err := executor.RegisterAlias("alias", "nonexistent")
+
+	// Assert
+	assert.That(t, "error must be ErrToolNotFound", errors.Is(err, agent.ErrToolNotFound), true)
+	assert.That(t, "alias must not be registered", executor.HasTool("alias"), false)
+}
+
+func Test_ToolExecutor_RegisterAlias_With_RegisteredToolName_Should_ReturnError(t *testing.T) {
+	// Arrange
+	executor := newToolExecutorWithMockTools()
+
+	// Act
+	err := executor.RegisterAlias("another_tool", "mock_tool")
+
+	// Assert
+	assert.That(t, "error must be ErrToolAliasConflict", errors.Is(err, outbound.ErrToolAliasConflict), true)
+}
+
+func Test_ToolExecutor_UnregisterTool_Should_RemoveToolDefinitionAndAliases(t *testing.T) {
+	// Arrange
+	executor := newToolExecutorWithMockTools()
+	_ = executor.RegisterAlias("mock.tool", "mock_tool")
+
+	// Act
+	executor.UnregisterTool("mock_tool")
+
+	// Assert
+	definitions := executor.GetToolDefinitions()
+	assert.That(t, "tool must be removed", executor.HasTool("mock_tool"), false)
+	assert.That(t, "alias must be removed", executor.HasTool("mock.tool"), false)
+	assert.That(t, "one definition must remain", len(definitions), 1)
+	assert.That(t, "remaining definition must be another_tool", definitions[0].Name, "another_tool")
+}
+
+func Test_ToolExecutor_UnregisterTool_With_UnknownTool_Should_DoNothing(t *testing.T) {
+	// Arrange
+	executor := newToolExecutorWithMockTools()
+
+	// Act
+	executor.UnregisterTool("nonexistent")
+
+	// Assert
+	assert.That(t, "tools must be unchanged", len(executor.GetAvailableTools()), 2)
+	assert.That(t, "definitions must be unchanged", len(executor.GetToolDefinitions()), 2)
+}
+
+func Test_ToolExecutor_Execute_With_DefaultToolContext_Should_PassDeadlineToTool(t *testing.T) {
+	// Arrange
+	deadline := time.Now().Add(5 * time.Second)
+	var observed time.Time
+	var hasDeadline bool
+	executor := outbound.NewToolExecutor().
+		WithDefaultToolContext(func(ctx context.Context) context.Context {
+			ctx, cancel := context.WithDeadline(ctx, deadline)
+			t.Cleanup(cancel)
+			return ctx
+		})
+	executor.RegisterTool("deadline_tool", func(ctx context.Context, _ string) (string, error) {
+		observed, hasDeadline = ctx.Deadline()
+		return "ok", nil
+	})
+
+	// Act
+	_, err := executor.Execute(context.Background(), "deadline_tool", `{}`)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "tool must observe a deadline", hasDeadline, true)
+	assert.That(t, "tool must observe the decorator's deadline", observed.Equal(deadline), true)
+}
+
+type toolContextKey struct{}
+
+func Test_ToolExecutor_Execute_With_DefaultToolContext_Should_KeepCallerValues(t *testing.T) {
+	// Arrange
+	var value any
+	executor := outbound.NewToolExecutor().
+		WithDefaultToolContext(func(ctx context.Context) context.Context {
+			return context.WithValue(ctx, toolContextKey{}, "injected")
+		})
+	executor.RegisterTool("value_tool", func(ctx context.Context, _ string) (string, error) {
+		value = ctx.Value(toolContextKey{})
+		return "ok", nil
+	})
+
+	// Act
+	_, err := executor.Execute(context.Background(), "value_tool", `{}`)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "tool must observe the injected value", value, any("injected"))
+}
+
+func Test_ToolExecutor_Execute_With_ExpiredDefaultToolContext_Should_CancelTool(t *testing.T) {
+	// Arrange
+	executor := outbound.NewToolExecutor().
+		WithDefaultToolContext(func(ctx context.Context) context.Context {
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+			t.Cleanup(cancel)
+			return ctx
+		})
+	executor.RegisterTool("slow_tool", func(ctx context.Context, _ string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+
+	// Act
+	_, err := executor.Execute(context.Background(), "slow_tool", `{}`)
+
+	// Assert
+	assert.That(t, "must return error", err != nil, true)
+}
+
+func Test_ToolExecutor_Execute_With_ArgumentValidation_Should_RejectInvalidArguments(t *testing.T) {
+	// Arrange
+	executor := outbound.NewToolExecutor().WithArgumentValidation()
+	executor.RegisterTool("search", mockTool)
+	executor.RegisterToolDefinition(agent.NewToolDefinition("search", "Search").
+		WithParameterDef(agent.NewParameterDefinition("query", agent.ParamTypeString).WithRequired()))
+
+	// Act
+	_, err := executor.Execute(context.Background(), "search", `{}`)
+
+	// Assert
+	var validationErr *agent.ValidationError
+	assert.That(t, "err must be a validation error", errors.As(err, &validationErr), true)
+	assert.That(t, "missing field must be reported", validationErr.Errors["query"], "required parameter missing")
+}
+
+func Test_ToolExecutor_Execute_With_ArgumentValidation_Should_RunValidCall(t *testing.T) {
+	// Arrange
+	executor := newToolExecutorWithMockTools().WithArgumentValidation()
+
+	// Act
+	result, err := executor.Execute(context.Background(), "mock_tool", "")
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "result must match", result, "mock_result")
+}
+
+func Test_ToolExecutor_Execute_With_ArgumentsOverLimit_Should_RejectCall(t *testing.T) {
+	// Arrange
+	called := false
+	executor := outbound.NewToolExecutor().WithMaxArgumentBytes(16)
+	executor.RegisterTool("search", func(_ context.Context, _ string) (string, error) {
+		called = true
+		return "result", nil
+	})
+
+	// Act
+	_, err := executor.Execute(context.Background(), "search", `{"query":"a very long query"}`)
+
+	// Assert
+	assert.That(t, "err must wrap ErrArgumentsTooLarge", errors.Is(err, outbound.ErrArgumentsTooLarge), true)
+	var toolErr *agent.ToolError
+	assert.That(t, "err must be a tool error", errors.As(err, &toolErr), true)
+	assert.That(t, "err must be user-facing", toolErr.UserFacing, true)
+	assert.That(t, "tool must not be called", called, false)
+}
+
+func Test_ToolExecutor_Execute_With_ArgumentsWithinLimit_Should_RunTool(t *testing.T) {
+	// Arrange
+	executor := newToolExecutorWithMockTools().WithMaxArgumentBytes(64)
+
+	// Act
+	result, err := executor.Execute(context.Background(), "mock_tool", `{"query":"short"}`)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "result must match", result, "mock_result")
+}
@@ -1,6 +1,11 @@
 package agent
 
-import "github.com/andygeiss/cloud-native-utils/slices"
+import (
+	"sort"
+	"strings"
+
+	"github.com/andygeiss/cloud-native-utils/slices"
+)
 
 // Metadata holds arbitrary key-value pairs for agent context.
 type Metadata map[string]string
@@ -8,17 +13,53 @@ type Metadata map[string]string
 // Option is a functional option for configuring an Agent.
 type Option func(*Agent)
 
+// Trim strategies reported with EventMessagesTrimmed (alphabetically sorted).
+const (
+	TrimStrategyCount  = "count"  // MaxMessages was exceeded
+	TrimStrategyTokens = "tokens" // the context window was exceeded
+)
+
+// MessageTrim records how many messages a trim strategy dropped from the history.
+type MessageTrim struct {
+	Strategy string
+	Removed  int
+}
+
+// SystemSegment is a prioritized part of the system prompt.
+// Segments are assembled in ascending priority order; the base SystemPrompt has priority 0.
+type SystemSegment struct {
+	Text     string
+	Priority int
+}
+
+// TaskTotals holds cumulative counters across all tasks executed by an agent.
+// Unlike the task list, the totals are constant in size and never trimmed.
+type TaskTotals struct {
+	Iterations int // Sum of agent loop iterations
+	Tasks      int // Number of finished tasks (completed or failed)
+	Tokens     int // Sum of total tokens used
+	ToolCalls  int // Sum of executed tool calls
+}
+
 // Agent is the aggregate root that coordinates task execution.
 // It maintains conversation state and manages the agent loop lifecycle.
 type Agent struct {
-	Metadata      Metadata
-	SystemPrompt  string
-	ID            AgentID
-	Messages      []Message
-	Tasks         []*Task
-	Iteration     int
-	MaxIterations int
-	MaxMessages   int
+	Metadata       Metadata
+	SystemPrompt   string
+	ID             AgentID
+	Examples       []Message // few-shot examples, never trimmed
+	Messages       []Message
+	SystemSegments []SystemSegment
+	Tasks          []*Task
+	Totals         TaskTotals
+	Iteration      int
+	MaxIterations  int
+	MaxMessages    int
+	toolDescriber  ToolExecutor  // lists its tools in the system prompt if set
+	history        []TaskSummary // ring buffer of finished tasks
+	historyMax     int
+	historyNext    int
+	trims          []MessageTrim // trims not yet taken by TakeMessageTrims
 }
 
 // NewAgent creates a new Agent with the given ID and system prompt.
@@ -40,6 +81,15 @@ func NewAgent(id AgentID, systemPrompt string, opts ...Option) Agent {
 	return ag
 }
 
+// WithExamples returns an Option that sets few-shot example messages.
+// Examples are sent after the system prompt and before the conversation,
+// but are not part of Messages and never count toward MaxMessages.
+func WithExamples(msgs ...Message) Option {
+	return func(a *Agent) {
+		a.Examples = append([]Message(nil), msgs...)
+	}
+}
+
 // WithMaxIterations returns an Option that sets the maximum iterations per task.
 func WithMaxIterations(maxIter int) Option {
 	return func(a *Agent) {
@@ -48,7 +98,7 @@ func WithMaxIterations(maxIter int) Option {
 }
 
 // WithMaxMessages returns an Option that sets the maximum messages to retain.
-// When exceeded, older messages are trimmed (keeping system prompt context).
+// When exceeded, older messages are trimmed (keeping system prompt context and pinned messages).
 func WithMaxMessages(maxMsg int) Option {
 	return func(a *Agent) {
 		a.MaxMessages = maxMsg
@@ -62,6 +112,28 @@ func WithMetadata(meta Metadata) Option {
 	}
 }
 
+// WithTaskHistory returns an Option that retains summaries of the last max finished tasks.
+// The history is a fixed-size ring buffer; older entries are overwritten.
+// History is disabled by default (max <= 0).
+func WithTaskHistory(maxTasks int) Option {
+	return func(a *Agent) {
+		a.historyMax = maxTasks
+		a.history = make([]TaskSummary, 0, max(maxTasks, 0))
+		a.historyNext = 0
+	}
+}
+
+// WithToolDescriptionsInPrompt returns an Option that lists the names and descriptions
+// of the executor's tools at the end of the system prompt, in addition to the function
+// schema sent to the LLM. Many local models call tools more reliably with such a list.
+// The list is rendered whenever the messages for the LLM are built, so it follows tools
+// being registered or removed. Disabled by default, since strong models do not need it.
+func WithToolDescriptionsInPrompt(executor ToolExecutor) Option {
+	return func(a *Agent) {
+		a.toolDescriber = executor
+	}
+}
+
 // AddMessage appends a message to the conversation history.
 // If MaxMessages is set and exceeded, older messages are trimmed.
 func (a *Agent) AddMessage(msg Message) {
@@ -69,21 +141,152 @@ func (a *Agent) AddMessage(msg Message) {
 	a.trimMessagesIfNeeded()
 }
 
+// AddSystemSegment adds a prioritized segment to the system prompt.
+// Segments with negative priority precede the base prompt, positive ones follow it.
+// Segments apply to the next task only; they are cleared when that task finishes.
+func (a *Agent) AddSystemSegment(priority int, text string) {
+	a.SystemSegments = append(a.SystemSegments, SystemSegment{Priority: priority, Text: text})
+}
+
 // AddTask adds a task to the queue.
 func (a *Agent) AddTask(task *Task) {
 	a.Tasks = append(a.Tasks, task)
 }
 
+// AverageIterationsPerTask returns the mean number of iterations per finished task.
+// Returns 0 if no task has finished yet.
+func (a *Agent) AverageIterationsPerTask() float64 {
+	if a.Totals.Tasks == 0 {
+		return 0
+	}
+	return float64(a.Totals.Iterations) / float64(a.Totals.Tasks)
+}
+
+// BuildSystemPrompt combines the base prompt and all segments into a single prompt.
+// Segments are sorted by ascending priority; equal priorities keep insertion order.
+func (a *Agent) BuildSystemPrompt() string {
+	if len(a.SystemSegments) == 0 {
+		return a.SystemPrompt
+	}
+
+	segments := make([]SystemSegment, 0, len(a.SystemSegments)+1)
+	segments = append(segments, SystemSegment{Priority: 0, Text: a.SystemPrompt})
+	segments = append(segments, a.SystemSegments...)
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].Priority < segments[j].Priority
+	})
+
+	parts := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment.Text != "" {
+			parts = append(parts, segment.Text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// toolDescriptionsPrompt renders the tools of the executor set by WithToolDescriptionsInPrompt
+// as a list for the system prompt. Returns "" if the option is not set or there are no tools.
+func (a *Agent) toolDescriptionsPrompt() string {
+	if a.toolDescriber == nil {
+		return ""
+	}
+	definitions := a.toolDescriber.GetToolDefinitions()
+	if len(definitions) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Available tools:")
+	for _, def := range definitions {
+		b.WriteString("\n- " + def.Name)
+		if def.Description != "" {
+			b.WriteString(": " + def.Description)
+		}
+	}
+	return b.String()
+}
+
 // CanContinue returns true if the agent has not exceeded max iterations.
 func (a *Agent) CanContinue() bool {
 	return a.Iteration < a.MaxIterations
 }
 
+// CanContinueTask returns true if the agent has not exceeded the iteration cap for the task.
+// A non-zero Task.MaxIterations takes precedence over the agent's MaxIterations.
+func (a *Agent) CanContinueTask(task *Task) bool {
+	if task != nil && task.MaxIterations > 0 {
+		return a.Iteration < task.MaxIterations
+	}
+	return a.CanContinue()
+}
+
+// Clone returns a deep copy of the agent for branching a conversation.
+// Messages (including their tool calls), examples, metadata, system segments, tasks
+// (including their tool call records), and task history are copied, so mutating the
+// clone never affects the original. The iteration counter of the clone is reset and
+// trims pending on the original are not carried over.
+func (a *Agent) Clone() *Agent {
+	clone := *a
+	clone.Iteration = 0
+	clone.trims = nil
+
+	clone.Messages = make([]Message, len(a.Messages))
+	for i, msg := range a.Messages {
+		if msg.ToolCalls != nil {
+			toolCalls := make([]ToolCall, len(msg.ToolCalls))
+			copy(toolCalls, msg.ToolCalls)
+			msg.ToolCalls = toolCalls
+		}
+		clone.Messages[i] = msg
+	}
+
+	clone.Metadata = make(Metadata, len(a.Metadata))
+	for key, value := range a.Metadata {
+		clone.Metadata[key] = value
+	}
+
+	clone.Tasks = make([]*Task, len(a.Tasks))
+	for i, task := range a.Tasks {
+		taskCopy := *task
+		if task.ToolCalls != nil {
+			taskCopy.ToolCalls = append([]ToolCallRecord(nil), task.ToolCalls...)
+		}
+		clone.Tasks[i] = &taskCopy
+	}
+
+	if a.Examples != nil {
+		clone.Examples = append([]Message(nil), a.Examples...)
+	}
+	if a.SystemSegments != nil {
+		clone.SystemSegments = append([]SystemSegment(nil), a.SystemSegments...)
+	}
+	if a.history != nil {
+		clone.history = append(make([]TaskSummary, 0, cap(a.history)), a.history...)
+	}
+	return &clone
+}
+
+// ClearConversation drops the dialogue but keeps the conversation's context:
+// system and pinned messages are retained and the iteration counter is reset.
+// The system prompt and examples are not part of Messages and are unaffected.
+func (a *Agent) ClearConversation() {
+	a.Messages = slices.Filter(a.Messages, func(msg Message) bool {
+		return msg.Pinned || msg.Role == RoleSystem
+	})
+	a.Iteration = 0
+}
+
 // ClearMessages removes all messages from the conversation history.
 func (a *Agent) ClearMessages() {
 	a.Messages = make([]Message, 0)
 }
 
+// ClearSystemSegments removes all system prompt segments, keeping the base prompt.
+func (a *Agent) ClearSystemSegments() {
+	a.SystemSegments = nil
+}
+
 // CompletedTaskCount returns the number of completed tasks.
 func (a *Agent) CompletedTaskCount() int {
 	return len(slices.Filter(a.Tasks, func(t *Task) bool {
@@ -133,6 +336,27 @@ func (a *Agent) MessageCount() int {
 	return len(a.Messages)
 }
 
+// RecordResult adds the outcome of a finished task to the cumulative totals.
+func (a *Agent) RecordResult(result Result) {
+	a.Totals.Iterations += result.IterationCount
+	a.Totals.Tasks++
+	a.Totals.Tokens += result.Tokens.TotalTokens
+	a.Totals.ToolCalls += result.ToolCallCount
+}
+
+// RecordTask adds a finished task to the task history, if enabled.
+func (a *Agent) RecordTask(task *Task) {
+	if a.historyMax <= 0 {
+		return
+	}
+	if len(a.history) < a.historyMax {
+		a.history = append(a.history, task.Summary())
+		return
+	}
+	a.history[a.historyNext] = task.Summary()
+	a.historyNext = (a.historyNext + 1) % a.historyMax
+}
+
 // ResetIteration sets the iteration counter back to zero.
 func (a *Agent) ResetIteration() {
 	a.Iteration = 0
@@ -150,6 +374,22 @@ func (a *Agent) SetMetadata(key, value string) {
 	a.Metadata[key] = value
 }
 
+// TaskHistory returns the retained task summaries, oldest first.
+func (a *Agent) TaskHistory() []TaskSummary {
+	history := make([]TaskSummary, 0, len(a.history))
+	history = append(history, a.history[a.historyNext:]...)
+	history = append(history, a.history[:a.historyNext]...)
+	return history
+}
+
+// TakeMessageTrims returns the trims recorded since the last call and clears them.
+// The TaskService publishes them as EventMessagesTrimmed.
+func (a *Agent) TakeMessageTrims() []MessageTrim {
+	trims := a.trims
+	a.trims = nil
+	return trims
+}
+
 // TaskCount returns the number of tasks in the queue.
 func (a *Agent) TaskCount() int {
 	return len(a.Tasks)
@@ -157,11 +397,32 @@ func (a *Agent) TaskCount() int {
 
 // trimMessagesIfNeeded removes oldest messages if MaxMessages limit is exceeded.
 // It preserves the most recent messages to maintain conversation context.
+// Pinned messages are never removed, so they may keep the history above the limit.
 func (a *Agent) trimMessagesIfNeeded() {
-	if a.MaxMessages <= 0 || len(a.Messages) <= a.MaxMessages {
+	if a.MaxMessages <= 0 {
+		return
+	}
+	removed := 0
+	for len(a.Messages) > a.MaxMessages {
+		i := oldestUnpinnedIndex(a.Messages)
+		if i < 0 {
+			break
+		}
+		a.Messages = append(a.Messages[:i:i], a.Messages[i+1:]...)
+		removed++
+	}
+	a.recordTrim(TrimStrategyCount, removed)
+}
+
+// recordTrim remembers that the strategy dropped removed messages.
+// Consecutive trims of the same strategy are merged.
+func (a *Agent) recordTrim(strategy string, removed int) {
+	if removed <= 0 {
+		return
+	}
+	if n := len(a.trims); n > 0 && a.trims[n-1].Strategy == strategy {
+		a.trims[n-1].Removed += removed
 		return
 	}
-	// Keep only the most recent MaxMessages
-	excess := len(a.Messages) - a.MaxMessages
-	a.Messages = a.Messages[excess:]
+	a.trims = append(a.trims, MessageTrim{Strategy: strategy, Removed: removed})
 }
@@ -1,6 +1,7 @@
 package agent_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/andygeiss/cloud-native-utils/assert"
@@ -98,6 +99,19 @@ func Test_Agent_CanContinue_With_MaxIteration_Should_ReturnFalse(t *testing.T) {
 	assert.That(t, "agent must not be able to continue", canContinue, false)
 }
 
+func Test_Agent_CanContinueTask_With_TaskMaxIterations_Should_UseTaskCap(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxIterations(2))
+	ag.Iteration = 3
+	task := agent.NewTask("task-1", "test", "input").WithMaxIterations(4)
+
+	// Act
+	canContinue := ag.CanContinueTask(task)
+
+	// Assert
+	assert.That(t, "task cap must take precedence", canContinue, true)
+}
+
 func Test_Agent_ClearMessages_Should_ClearHistory(t *testing.T) {
 	// Arrange
 	ag := agent.NewAgent("agent-1", "prompt")
@@ -111,6 +125,27 @@ func Test_Agent_ClearMessages_Should_ClearHistory(t *testing.T) {
 	assert.That(t, "agent must have no messages", len(ag.GetMessages()), 0)
 }
 
+func Test_Agent_ClearConversation_Should_KeepSystemAndPinnedMessages(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt")
+	ag.AddMessage(agent.NewMessage(agent.RoleSystem, "context"))
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "My name is Alice.").WithPinned())
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "hello"))
+	ag.AddMessage(agent.NewMessage(agent.RoleAssistant, "hi"))
+	ag.AddMessage(agent.NewMessage(agent.RoleTool, "result"))
+	ag.IncrementIteration()
+
+	// Act
+	ag.ClearConversation()
+
+	// Assert
+	messages := ag.GetMessages()
+	assert.That(t, "two messages must remain", len(messages), 2)
+	assert.That(t, "system message must be kept", messages[0].Content, "context")
+	assert.That(t, "pinned message must be kept", messages[1].Content, "My name is Alice.")
+	assert.That(t, "iteration must be reset", ag.Iteration, 0)
+}
+
 func Test_Agent_GetMessages_With_Messages_Should_ReturnAll(t *testing.T) {
 	// Arrange
 	ag := agent.NewAgent("agent-1", "prompt")
@@ -212,6 +247,75 @@ func Test_Agent_AddMessage_With_MaxMessages_Should_TrimOldMessages(t *testing.T)
 	assert.That(t, "second message must be third", messages[1].Content, "third")
 }
 
+func Test_Agent_TakeMessageTrims_With_MaxMessagesExceeded_Should_ReturnRemovedCount(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxMessages(2))
+	for _, content := range []string{"first", "second", "third", "fourth"} {
+		ag.AddMessage(agent.NewMessage(agent.RoleUser, content))
+	}
+
+	// Act
+	trims := ag.TakeMessageTrims()
+
+	// Assert
+	assert.That(t, "trims must be merged into one", len(trims), 1)
+	assert.That(t, "strategy must be count", trims[0].Strategy, agent.TrimStrategyCount)
+	assert.That(t, "two messages must be removed", trims[0].Removed, 2)
+	assert.That(t, "trims must be cleared", len(ag.TakeMessageTrims()), 0)
+}
+
+func Test_Agent_TakeMessageTrims_With_LimitNotExceeded_Should_ReturnNothing(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxMessages(2))
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "first"))
+
+	// Act
+	trims := ag.TakeMessageTrims()
+
+	// Assert
+	assert.That(t, "no trims must be recorded", len(trims), 0)
+}
+
+func Test_Agent_AddMessage_With_PinnedMessages_Should_KeepThemWhenTrimming(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxMessages(2))
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "pinned-1").WithPinned())
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "first"))
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "pinned-2").WithPinned())
+
+	// Act
+	for _, content := range []string{"second", "third", "fourth"} {
+		ag.AddMessage(agent.NewMessage(agent.RoleUser, content))
+	}
+
+	// Assert
+	messages := ag.GetMessages()
+	assert.That(t, "agent must keep pinned messages and the latest one", len(messages), 3)
+	assert.That(t, "first pinned message must be kept", messages[0].Content, "pinned-1")
+	assert.That(t, "second pinned message must be kept", messages[1].Content, "pinned-2")
+	assert.That(t, "latest message must be kept", messages[2].Content, "fourth")
+}
+
+func Test_Agent_WithExamples_Option_Should_NotCountTowardMaxMessages(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt",
+		agent.WithMaxMessages(2),
+		agent.WithExamples(
+			agent.NewMessage(agent.RoleUser, "example question"),
+			agent.NewMessage(agent.RoleAssistant, "example answer"),
+		),
+	)
+
+	// Act
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "first"))
+	ag.AddMessage(agent.NewMessage(agent.RoleAssistant, "second"))
+
+	// Assert
+	assert.That(t, "agent must keep 2 messages", ag.MessageCount(), 2)
+	assert.That(t, "agent must keep 2 examples", len(ag.Examples), 2)
+	assert.That(t, "first message must be kept", ag.GetMessages()[0].Content, "first")
+}
+
 func Test_Agent_SetMetadata_Should_StoreValue(t *testing.T) {
 	// Arrange
 	ag := agent.NewAgent("agent-1", "prompt")
@@ -334,3 +438,172 @@ func Test_LLMResponse_HasToolCalls_With_ToolCalls_Should_ReturnTrue(t *testing.T
 	// Assert
 	assert.That(t, "response must have tool calls", hasToolCalls, true)
 }
+
+func Test_Agent_RecordTask_With_HistoryDisabled_Should_KeepNothing(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "chat", "input")
+	task.Complete("output")
+
+	// Act
+	ag.RecordTask(task)
+
+	// Assert
+	assert.That(t, "history must be empty", len(ag.TaskHistory()), 0)
+}
+
+func Test_Agent_RecordTask_With_HistoryFull_Should_KeepNewestTasks(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithTaskHistory(3))
+
+	// Act
+	for i := range 5 {
+		task := agent.NewTask(agent.TaskID(fmt.Sprintf("task-%d", i)), "chat", "input")
+		task.Start()
+		task.IncrementIterations()
+		task.Complete("output")
+		ag.RecordTask(task)
+	}
+	history := ag.TaskHistory()
+
+	// Assert
+	assert.That(t, "history must have 3 entries", len(history), 3)
+	assert.That(t, "oldest entry must be task-2", history[0].ID, agent.TaskID("task-2"))
+	assert.That(t, "newest entry must be task-4", history[2].ID, agent.TaskID("task-4"))
+	assert.That(t, "status must be completed", history[2].Status, agent.TaskStatusCompleted)
+	assert.That(t, "iterations must be recorded", history[2].Iterations, 1)
+}
+
+func Test_Agent_BuildSystemPrompt_With_Segments_Should_OrderByPriority(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "base")
+	ag.AddSystemSegment(10, "memories")
+	ag.AddSystemSegment(-5, "preamble")
+	ag.AddSystemSegment(10, "more memories")
+	ag.AddSystemSegment(1, "context")
+
+	// Act
+	prompt := ag.BuildSystemPrompt()
+
+	// Assert
+	assert.That(t, "segments must be ordered by priority", prompt, "preamble\n\nbase\n\ncontext\n\nmemories\n\nmore memories")
+}
+
+func Test_Agent_BuildSystemPrompt_With_NoSegments_Should_ReturnBasePrompt(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "base")
+
+	// Act
+	prompt := ag.BuildSystemPrompt()
+
+	// Assert
+	assert.That(t, "prompt must be the base prompt", prompt, "base")
+}
+
+func Test_Agent_ClearSystemSegments_Should_KeepBasePrompt(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "base")
+	ag.AddSystemSegment(1, "context")
+
+	// Act
+	ag.ClearSystemSegments()
+
+	// Assert
+	assert.That(t, "prompt must be the base prompt", ag.BuildSystemPrompt(), "base")
+}
+
+func Test_Agent_Clone_Should_CopyConversationAndConfig(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxIterations(7), agent.WithMetadata(agent.Metadata{"model": "gpt"}))
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "hello"))
+	ag.IncrementIteration()
+
+	// Act
+	clone := ag.Clone()
+
+	// Assert
+	assert.That(t, "messages must be copied", clone.Messages, ag.Messages)
+	assert.That(t, "metadata must be copied", clone.GetMetadata("model"), "gpt")
+	assert.That(t, "max iterations must be copied", clone.MaxIterations, 7)
+	assert.That(t, "system prompt must be copied", clone.SystemPrompt, "prompt")
+	assert.That(t, "iteration must be reset", clone.Iteration, 0)
+	assert.That(t, "original iteration must be kept", ag.Iteration, 1)
+}
+
+func Test_Agent_Clone_With_MutatedClone_Should_LeaveOriginalUnchanged(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxMessages(10))
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "hello"))
+	ag.AddMessage(agent.NewMessage(agent.RoleAssistant, "").WithToolCalls([]agent.ToolCall{
+		agent.NewToolCall("tc-1", "search", `{}`),
+	}))
+	ag.AddTask(agent.NewTask("task-1", "chat", "hello"))
+	clone := ag.Clone()
+
+	// Act
+	clone.AddMessage(agent.NewMessage(agent.RoleUser, "branch"))
+	clone.Messages[0].Content = "changed"
+	clone.Messages[1].ToolCalls[0].Complete("done")
+	clone.SetMetadata("branch", "b")
+	clone.Tasks[0].Start()
+	clone.AddSystemSegment(1, "branch only")
+
+	// Assert
+	assert.That(t, "original must keep 2 messages", ag.MessageCount(), 2)
+	assert.That(t, "original message must be unchanged", ag.Messages[0].Content, "hello")
+	assert.That(t, "original tool call must be unchanged", ag.Messages[1].ToolCalls[0].Status, agent.ToolCallStatusPending)
+	assert.That(t, "original metadata must be unchanged", ag.GetMetadata("branch"), "")
+	assert.That(t, "original task must be unchanged", ag.Tasks[0].Status, agent.TaskStatusPending)
+	assert.That(t, "original segments must be unchanged", len(ag.SystemSegments), 0)
+}
+
+func Test_Agent_Clone_With_PendingTrims_Should_NotShareTrims(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxMessages(2))
+	for _, content := range []string{"first", "second", "third"} {
+		ag.AddMessage(agent.NewMessage(agent.RoleUser, content))
+	}
+	clone := ag.Clone()
+
+	// Act
+	clone.AddMessage(agent.NewMessage(agent.RoleUser, "branch"))
+
+	// Assert
+	cloneTrims := clone.TakeMessageTrims()
+	assert.That(t, "clone must only report its own trim", cloneTrims, []agent.MessageTrim{{Strategy: agent.TrimStrategyCount, Removed: 1}})
+	trims := ag.TakeMessageTrims()
+	assert.That(t, "original trims must be unchanged", trims, []agent.MessageTrim{{Strategy: agent.TrimStrategyCount, Removed: 1}})
+}
+
+func Test_Agent_Clone_With_TaskToolCalls_Should_CopyToolCallRecords(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "chat", "hello")
+	task.ToolCalls = []agent.ToolCallRecord{{ToolCall: agent.NewToolCall("tc-1", "search", `{}`), Iteration: 1}}
+	ag.AddTask(task)
+	clone := ag.Clone()
+
+	// Act
+	clone.Tasks[0].ToolCalls[0].Name = "changed"
+
+	// Assert
+	assert.That(t, "original tool call record must be unchanged", ag.Tasks[0].ToolCalls[0].Name, "search")
+}
+
+func Test_Agent_Clone_With_TaskHistory_Should_CopyHistory(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithTaskHistory(2))
+	first := agent.NewTask("task-1", "first", "input")
+	first.Complete("ok")
+	ag.RecordTask(first)
+	clone := ag.Clone()
+
+	// Act
+	second := agent.NewTask("task-2", "second", "input")
+	second.Complete("ok")
+	clone.RecordTask(second)
+
+	// Assert
+	assert.That(t, "clone must have 2 entries", len(clone.TaskHistory()), 2)
+	assert.That(t, "original must keep 1 entry", len(ag.TaskHistory()), 1)
+}
@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"sync"
+)
+
+// BatchRunner runs many independent tasks with bounded concurrency,
+// e.g. to evaluate an agent over a dataset.
+// Each task gets its own agent instance, so no conversation state is shared.
+type BatchRunner struct {
+	runner TaskRunner
+}
+
+// NewBatchRunner creates a new BatchRunner that executes tasks with the given runner.
+func NewBatchRunner(runner TaskRunner) *BatchRunner {
+	return &BatchRunner{runner: runner}
+}
+
+// Run executes the tasks with at most concurrency tasks in flight (values below 1 run them sequentially).
+// agentFactory is called once per task and must return a new agent each time.
+// Results are returned in the order of tasks. A failed task yields a result with
+// Success false and the error message; tasks not started before ctx is canceled
+// fail with ErrContextCanceled.
+func (b *BatchRunner) Run(ctx context.Context, agentFactory func() *Agent, tasks []*Task, concurrency int) []Result {
+	results := make([]Result, len(tasks))
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+
+	for i, task := range tasks {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			results[i] = NewResult(task.ID, false, "").WithError(ErrContextCanceled.Error())
+			continue
+		}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			results[i] = b.runTask(ctx, agentFactory(), task)
+		})
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runTask runs a single task and converts a runner error into a failed result.
+func (b *BatchRunner) runTask(ctx context.Context, agent *Agent, task *Task) Result {
+	result, err := b.runner.RunTask(ctx, agent, task)
+	if err != nil {
+		result.Success = false
+		if result.Error == "" {
+			result = result.WithError(err.Error())
+		}
+	}
+	result.TaskID = task.ID
+	return result
+}
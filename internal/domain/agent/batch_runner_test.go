@@ -0,0 +1,127 @@
+package agent_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/cloud-native-utils/event"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+)
+
+// echoLLMClient answers with the last user message and tracks how many calls run at once.
+type echoLLMClient struct {
+	delay       time.Duration
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (m *echoLLMClient) Run(ctx context.Context, messages []agent.Message, _ []agent.ToolDefinition) (agent.LLMResponse, error) {
+	current := m.inFlight.Add(1)
+	defer m.inFlight.Add(-1)
+	for {
+		peak := m.maxInFlight.Load()
+		if current <= peak || m.maxInFlight.CompareAndSwap(peak, current) {
+			break
+		}
+	}
+	select {
+	case <-time.After(m.delay):
+	case <-ctx.Done():
+		return agent.LLMResponse{}, ctx.Err()
+	}
+	// Each agent must see only its own conversation
+	if len(messages) != 2 {
+		return agent.LLMResponse{}, fmt.Errorf("expected 2 messages, got %d", len(messages))
+	}
+	last := messages[len(messages)-1]
+	return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "echo: "+last.Content), "stop"), nil
+}
+
+// nopEventPublisher discards events; it is safe for concurrent use.
+type nopEventPublisher struct{}
+
+func (nopEventPublisher) Publish(_ context.Context, _ event.Event) error { return nil }
+
+// newBatchTasks creates n tasks with inputs "input-0" to "input-n-1".
+func newBatchTasks(n int) []*agent.Task {
+	tasks := make([]*agent.Task, n)
+	for i := range n {
+		tasks[i] = agent.NewTask(agent.TaskID(fmt.Sprintf("task-%d", i)), "batch", fmt.Sprintf("input-%d", i))
+	}
+	return tasks
+}
+
+// newBatchAgentFactory returns a factory creating a new agent per call.
+func newBatchAgentFactory() func() *agent.Agent {
+	return func() *agent.Agent {
+		ag := agent.NewAgent("batch-agent", "You are helpful")
+		return &ag
+	}
+}
+
+func Test_BatchRunner_Run_Should_CompleteAllTasksInInputOrder(t *testing.T) {
+	// Arrange
+	llm := &echoLLMClient{delay: time.Millisecond}
+	sut := agent.NewBatchRunner(agent.NewTaskService(llm, &mockToolExecutor{}, nopEventPublisher{}))
+	tasks := newBatchTasks(20)
+
+	// Act
+	results := sut.Run(context.Background(), newBatchAgentFactory(), tasks, 4)
+
+	// Assert
+	assert.That(t, "must return one result per task", len(results), 20)
+	for i, result := range results {
+		assert.That(t, "result must be successful", result.Success, true)
+		assert.That(t, "result must belong to its task", result.TaskID, tasks[i].ID)
+		assert.That(t, "output must match the task input", result.Output, fmt.Sprintf("echo: input-%d", i))
+	}
+}
+
+func Test_BatchRunner_Run_Should_BoundConcurrency(t *testing.T) {
+	// Arrange
+	llm := &echoLLMClient{delay: 10 * time.Millisecond}
+	sut := agent.NewBatchRunner(agent.NewTaskService(llm, &mockToolExecutor{}, nopEventPublisher{}))
+
+	// Act
+	_ = sut.Run(context.Background(), newBatchAgentFactory(), newBatchTasks(12), 3)
+
+	// Assert
+	peak := llm.maxInFlight.Load()
+	assert.That(t, "at most 3 tasks must run at once", peak <= 3, true)
+	assert.That(t, "tasks must run concurrently", peak > 1, true)
+}
+
+func Test_BatchRunner_Run_With_ZeroConcurrency_Should_RunSequentially(t *testing.T) {
+	// Arrange
+	llm := &echoLLMClient{delay: time.Millisecond}
+	sut := agent.NewBatchRunner(agent.NewTaskService(llm, &mockToolExecutor{}, nopEventPublisher{}))
+
+	// Act
+	results := sut.Run(context.Background(), newBatchAgentFactory(), newBatchTasks(5), 0)
+
+	// Assert
+	assert.That(t, "must return one result per task", len(results), 5)
+	assert.That(t, "only one task must run at a time", llm.maxInFlight.Load(), int32(1))
+}
+
+func Test_BatchRunner_Run_With_CanceledContext_Should_FailRemainingTasks(t *testing.T) {
+	// Arrange
+	llm := &echoLLMClient{delay: time.Millisecond}
+	sut := agent.NewBatchRunner(agent.NewTaskService(llm, &mockToolExecutor{}, nopEventPublisher{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	results := sut.Run(ctx, newBatchAgentFactory(), newBatchTasks(3), 2)
+
+	// Assert
+	assert.That(t, "must return one result per task", len(results), 3)
+	for _, result := range results {
+		assert.That(t, "result must fail", result.Success, false)
+		assert.That(t, "error must mention cancellation", result.Error, agent.ErrContextCanceled.Error())
+	}
+}
@@ -144,3 +144,26 @@ func Benchmark_Event_Create(b *testing.B) {
 		_ = agent.NewEventTaskStarted("task-1", "TaskName")
 	}
 }
+
+func Benchmark_CosineSimilarity_1536(b *testing.B) {
+	x, y := benchmarkEmbedding(1), benchmarkEmbedding(2)
+	for b.Loop() {
+		_ = agent.CosineSimilarity(x, y)
+	}
+}
+
+func Benchmark_DotProduct_1536_Normalized(b *testing.B) {
+	x, y := benchmarkEmbedding(1).Normalize(), benchmarkEmbedding(2).Normalize()
+	for b.Loop() {
+		_ = agent.DotProduct(x, y)
+	}
+}
+
+// benchmarkEmbedding returns a deterministic 1536-dimensional embedding.
+func benchmarkEmbedding(seed int) agent.Embedding {
+	e := make(agent.Embedding, 1536)
+	for i := range e {
+		e[i] = float32((i*seed)%97) / 97
+	}
+	return e
+}
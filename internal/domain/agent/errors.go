@@ -1,25 +1,60 @@
 package agent
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // Sentinel errors for common failure conditions (alphabetically sorted).
 var (
 	// ErrContextCanceled is returned when the context is canceled during execution.
 	ErrContextCanceled = errors.New("context canceled")
 
+	// ErrContextWindowExceeded is returned when the messages do not fit into the model's context window.
+	ErrContextWindowExceeded = errors.New("context window exceeded")
+
+	// ErrEmbeddingDimensionMismatch is returned when an embedding does not have the expected number of dimensions,
+	// e.g. because the embedding model was switched.
+	ErrEmbeddingDimensionMismatch = errors.New("embedding dimension mismatch")
+
 	// ErrInvalidArguments is returned when tool arguments are malformed.
 	ErrInvalidArguments = errors.New("invalid tool arguments")
 
+	// ErrInvalidSchema is returned when a tool's JSON Schema cannot be converted to parameter definitions.
+	ErrInvalidSchema = errors.New("invalid tool schema")
+
+	// ErrInvalidState is returned when a serialized agent state cannot be restored.
+	ErrInvalidState = errors.New("invalid agent state")
+
 	// ErrMaxIterationsReached is returned when the agent exceeds the maximum allowed iterations.
 	ErrMaxIterationsReached = errors.New("max iterations reached")
 
 	// ErrNoResponse is returned when the LLM returns an empty response.
 	ErrNoResponse = errors.New("no response from LLM")
 
+	// ErrToolLoopDetected is returned when the LLM keeps repeating the same tool calls without new results.
+	ErrToolLoopDetected = errors.New("tool call loop detected")
+
 	// ErrToolNotFound is returned when trying to execute an unknown tool.
 	ErrToolNotFound = errors.New("tool not found")
+
+	// ErrToolResultMissing is reported for a tool call whose result was lost during parallel execution.
+	ErrToolResultMissing = errors.New("tool call result missing")
+
+	// ErrUnknownTool is returned when the LLM requests a tool that is not registered.
+	// It is the same error as ErrToolNotFound, so errors.Is matches either name.
+	ErrUnknownTool = ErrToolNotFound
 )
 
+// APIError is a structured error returned by an OpenAI-compatible API.
+// Callers can branch on Type or Code, e.g. "context_length_exceeded" or "invalid_api_key".
+type APIError struct {
+	Code       string
+	Message    string
+	Type       string
+	StatusCode int
+}
+
 // LLMError wraps errors from the LLM client with additional context.
 type LLMError struct {
 	Cause   error
@@ -34,10 +69,25 @@ type TaskError struct {
 }
 
 // ToolError wraps errors from tool execution with additional context.
+// Tools return it to tell the model how to react to the failure:
+// UserFacing errors are caused by the arguments and can be fixed by the model,
+// Retryable errors are transient; any other ToolError should not be retried.
 type ToolError struct {
-	Cause    error
-	Message  string
-	ToolName string
+	Cause      error
+	Message    string
+	ToolName   string
+	Retryable  bool
+	UserFacing bool
+}
+
+// NewAPIError creates a new APIError with the given HTTP status and error details.
+func NewAPIError(statusCode int, errType, code, message string) *APIError {
+	return &APIError{
+		Code:       code,
+		Message:    message,
+		StatusCode: statusCode,
+		Type:       errType,
+	}
 }
 
 // NewLLMError creates a new LLMError with the given message and cause.
@@ -66,6 +116,30 @@ func NewToolError(toolName, message string, cause error) *ToolError {
 	}
 }
 
+// WithRetryable marks the failure as transient, so the call may succeed if retried.
+func (e *ToolError) WithRetryable() *ToolError {
+	e.Retryable = true
+	return e
+}
+
+// WithUserFacing marks the failure as caused by invalid arguments the model can fix.
+func (e *ToolError) WithUserFacing() *ToolError {
+	e.UserFacing = true
+	return e
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	base := fmt.Sprintf("API error %d", e.StatusCode)
+	if e.Type != "" {
+		base += " " + e.Type
+	}
+	if e.Code != "" {
+		base += " (" + e.Code + ")"
+	}
+	return base + ": " + e.Message
+}
+
 // Error implements the error interface.
 func (e *LLMError) Error() string {
 	if e.Cause != nil {
@@ -106,3 +180,28 @@ func (e *TaskError) Unwrap() error {
 func (e *ToolError) Unwrap() error {
 	return e.Cause
 }
+
+// isInvalidArguments reports whether err wraps ErrInvalidArguments or a ValidationError.
+func isInvalidArguments(err error) bool {
+	var validationErr *ValidationError
+	return errors.Is(err, ErrInvalidArguments) || errors.As(err, &validationErr)
+}
+
+// toolErrorKind classifies a tool execution error for the model.
+// Errors wrapping ErrInvalidArguments and ValidationErrors are treated as user errors;
+// errors without a ToolError carry no classification.
+func toolErrorKind(err error) ToolErrorKind {
+	var toolErr *ToolError
+	switch {
+	case errors.As(err, &toolErr) && toolErr.UserFacing:
+		return ToolErrorKindUser
+	case isInvalidArguments(err):
+		return ToolErrorKindUser
+	case toolErr != nil && toolErr.Retryable:
+		return ToolErrorKindTemporary
+	case toolErr != nil:
+		return ToolErrorKindSystem
+	default:
+		return ""
+	}
+}
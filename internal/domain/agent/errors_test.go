@@ -16,6 +16,17 @@ func Test_ErrMaxIterationsReached_Error_Should_ReturnMessage(t *testing.T) {
 	assert.That(t, "error message must match", msg, "max iterations reached")
 }
 
+func Test_APIError_Error_Should_IncludeStatusTypeAndCode(t *testing.T) {
+	// Arrange
+	err := agent.NewAPIError(400, "invalid_request_error", "context_length_exceeded", "too many tokens")
+
+	// Act
+	msg := err.Error()
+
+	// Assert
+	assert.That(t, "error message must include details", msg, "API error 400 invalid_request_error (context_length_exceeded): too many tokens")
+}
+
 func Test_LLMError_Error_With_Cause_Should_IncludeCause(t *testing.T) {
 	// Arrange
 	cause := errors.New("connection timeout")
@@ -2,12 +2,60 @@ package agent
 
 // Event topic constants for messaging (alphabetically sorted).
 const (
-	TopicTaskCompleted    = "agent.task.completed"
-	TopicTaskFailed       = "agent.task.failed"
-	TopicTaskStarted      = "agent.task.started"
-	TopicToolCallExecuted = "agent.toolcall.executed"
+	TopicIterationCompleted = "agent.iteration.completed"
+	TopicMessagesTrimmed    = "agent.messages.trimmed"
+	TopicTaskCompleted      = "agent.task.completed"
+	TopicTaskFailed         = "agent.task.failed"
+	TopicTaskStarted        = "agent.task.started"
+	TopicToolCallExecuted   = "agent.toolcall.executed"
 )
 
+// EventIterationCompleted is emitted after each iteration of the agent loop,
+// once the tool calls requested in that iteration have been executed.
+type EventIterationCompleted struct {
+	FinishReason string `json:"finish_reason"`
+	TaskID       string `json:"task_id"`
+	Iteration    int    `json:"iteration"`
+	ToolCalls    int    `json:"tool_calls"`
+}
+
+// NewEventIterationCompleted creates a new iteration completed event.
+func NewEventIterationCompleted(taskID string, iteration int, finishReason string, toolCalls int) EventIterationCompleted {
+	return EventIterationCompleted{
+		FinishReason: finishReason,
+		Iteration:    iteration,
+		TaskID:       taskID,
+		ToolCalls:    toolCalls,
+	}
+}
+
+// Topic returns the event topic for messaging.
+func (e EventIterationCompleted) Topic() string {
+	return TopicIterationCompleted
+}
+
+// EventMessagesTrimmed is emitted when messages are dropped from the conversation history,
+// either because MaxMessages was exceeded or to fit the context window.
+type EventMessagesTrimmed struct {
+	AgentID  string `json:"agent_id"`
+	Strategy string `json:"strategy"`
+	Removed  int    `json:"removed"`
+}
+
+// NewEventMessagesTrimmed creates a new messages trimmed event.
+func NewEventMessagesTrimmed(agentID string, removed int, strategy string) EventMessagesTrimmed {
+	return EventMessagesTrimmed{
+		AgentID:  agentID,
+		Removed:  removed,
+		Strategy: strategy,
+	}
+}
+
+// Topic returns the event topic for messaging.
+func (e EventMessagesTrimmed) Topic() string {
+	return TopicMessagesTrimmed
+}
+
 // EventTaskCompleted is emitted when a task finishes successfully.
 type EventTaskCompleted struct {
 	Output string `json:"output"`
@@ -7,6 +7,49 @@ import (
 	"github.com/andygeiss/go-agent/internal/domain/agent"
 )
 
+func Test_EventIterationCompleted_Topic_Should_ReturnCorrectTopic(t *testing.T) {
+	// Arrange
+	event := agent.NewEventIterationCompleted("task-1", 2, "tool_calls", 3)
+
+	// Act
+	topic := event.Topic()
+
+	// Assert
+	assert.That(t, "topic must match", topic, agent.TopicIterationCompleted)
+}
+
+func Test_EventIterationCompleted_Fields_Should_MatchInputs(t *testing.T) {
+	// Arrange & Act
+	event := agent.NewEventIterationCompleted("task-1", 2, "tool_calls", 3)
+
+	// Assert
+	assert.That(t, "task ID must match", event.TaskID, "task-1")
+	assert.That(t, "iteration must match", event.Iteration, 2)
+	assert.That(t, "finish reason must match", event.FinishReason, "tool_calls")
+	assert.That(t, "tool calls must match", event.ToolCalls, 3)
+}
+
+func Test_EventMessagesTrimmed_Topic_Should_ReturnCorrectTopic(t *testing.T) {
+	// Arrange
+	event := agent.NewEventMessagesTrimmed("agent-1", 3, agent.TrimStrategyCount)
+
+	// Act
+	topic := event.Topic()
+
+	// Assert
+	assert.That(t, "topic must match", topic, agent.TopicMessagesTrimmed)
+}
+
+func Test_EventMessagesTrimmed_Fields_Should_MatchInputs(t *testing.T) {
+	// Arrange & Act
+	event := agent.NewEventMessagesTrimmed("agent-1", 3, agent.TrimStrategyTokens)
+
+	// Assert
+	assert.That(t, "agent ID must match", event.AgentID, "agent-1")
+	assert.That(t, "removed must match", event.Removed, 3)
+	assert.That(t, "strategy must match", event.Strategy, agent.TrimStrategyTokens)
+}
+
 func Test_EventTaskStarted_Topic_Should_ReturnCorrectTopic(t *testing.T) {
 	// Arrange
 	event := agent.NewEventTaskStarted("task-1", "Test Task")
@@ -0,0 +1,14 @@
+package agent
+
+import "context"
+
+// CollectToolCallResults feeds the results for the given indices to the parallel
+// result collection, so tests can simulate lost or duplicate results.
+func (s *TaskService) CollectToolCallResults(ctx context.Context, agent *Agent, toolCalls []ToolCall, indices []int) int {
+	outCh := make(chan toolCallOutput, len(indices))
+	for _, i := range indices {
+		outCh <- toolCallOutput{tc: &toolCalls[i], index: i}
+	}
+	close(outCh)
+	return s.collectAndPublishResults(ctx, agent, toolCalls, outCh, make(chan error))
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// maxIDSequence is the largest sequence number that fits into the 12 bits
+// following the version nibble of a generated ID.
+const maxIDSequence = 0x0fff
+
+// UUIDGenerator generates time-ordered IDs in the UUIDv7 layout.
+// Each ID is a 48-bit millisecond timestamp, a 12-bit sequence that is
+// incremented for IDs within the same millisecond, and 62 random bits.
+// IDs of one generator are strictly increasing, even under rapid calls
+// or when the clock goes backwards, so they never collide.
+type UUIDGenerator struct {
+	prefix     string
+	lastMillis int64
+	sequence   int
+	mu         sync.Mutex
+}
+
+// NewUUIDGenerator creates a new UUIDGenerator.
+// A non-empty prefix is prepended to each ID, separated by a dash (e.g. "note-0192...").
+func NewUUIDGenerator(prefix string) *UUIDGenerator {
+	return &UUIDGenerator{prefix: prefix}
+}
+
+// NewID returns the next unique ID.
+func (g *UUIDGenerator) NewID() string {
+	millis, sequence := g.next()
+
+	var b [16]byte
+	for i := range 6 {
+		b[i] = byte(millis >> (8 * (5 - i)))
+	}
+	b[6] = 0x70 | byte(sequence>>8)&0x0f
+	b[7] = byte(sequence)
+	_, _ = rand.Read(b[8:])
+	b[8] = b[8]&0x3f | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+
+	if g.prefix == "" {
+		return string(buf[:])
+	}
+	return g.prefix + "-" + string(buf[:])
+}
+
+// next advances the generator state and returns the timestamp and sequence of the next ID.
+// If the sequence overflows within a millisecond, the timestamp is advanced instead.
+func (g *UUIDGenerator) next() (int64, int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	millis := time.Now().UnixMilli()
+	switch {
+	case millis > g.lastMillis:
+		g.lastMillis = millis
+		g.sequence = 0
+	case g.sequence < maxIDSequence:
+		g.sequence++
+	default:
+		g.lastMillis++
+		g.sequence = 0
+	}
+	return g.lastMillis, g.sequence
+}
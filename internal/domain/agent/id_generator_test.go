@@ -0,0 +1,100 @@
+package agent_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+)
+
+// UUIDGenerator tests
+
+func Test_UUIDGenerator_NewID_With_Prefix_Should_ReturnPrefixedUUID(t *testing.T) {
+	// Arrange
+	gen := agent.NewUUIDGenerator("note")
+
+	// Act
+	id := gen.NewID()
+
+	// Assert
+	assert.That(t, "ID must start with the prefix", strings.HasPrefix(id, "note-"), true)
+	uuid := strings.TrimPrefix(id, "note-")
+	assert.That(t, "UUID must have 36 characters", len(uuid), 36)
+	assert.That(t, "UUID must have five groups", len(strings.Split(uuid, "-")), 5)
+	assert.That(t, "UUID version must be 7", uuid[14], byte('7'))
+}
+
+func Test_UUIDGenerator_NewID_With_EmptyPrefix_Should_ReturnPlainUUID(t *testing.T) {
+	// Arrange
+	gen := agent.NewUUIDGenerator("")
+
+	// Act
+	id := gen.NewID()
+
+	// Assert
+	assert.That(t, "ID must be a plain UUID", len(id), 36)
+}
+
+func Test_UUIDGenerator_NewID_With_TightLoop_Should_ReturnUniqueIncreasingIDs(t *testing.T) {
+	// Arrange
+	gen := agent.NewUUIDGenerator("id")
+	const count = 100000
+	seen := make(map[string]bool, count)
+	previous := ""
+	increasing := true
+
+	// Act
+	for range count {
+		id := gen.NewID()
+		seen[id] = true
+		if id <= previous {
+			increasing = false
+		}
+		previous = id
+	}
+
+	// Assert
+	assert.That(t, "all IDs must be unique", len(seen), count)
+	assert.That(t, "IDs must be strictly increasing", increasing, true)
+}
+
+func Test_UUIDGenerator_NewID_With_ConcurrentCalls_Should_ReturnUniqueIDs(t *testing.T) {
+	// Arrange
+	gen := agent.NewUUIDGenerator("id")
+	const workers, perWorker = 8, 5000
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[string]bool, workers*perWorker)
+
+	// Act
+	for range workers {
+		wg.Go(func() {
+			ids := make([]string, 0, perWorker)
+			for range perWorker {
+				ids = append(ids, gen.NewID())
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range ids {
+				seen[id] = true
+			}
+		})
+	}
+	wg.Wait()
+
+	// Assert
+	assert.That(t, "all IDs must be unique", len(seen), workers*perWorker)
+}
+
+func Test_IDGeneratorFunc_NewID_Should_CallFunction(t *testing.T) {
+	// Arrange
+	gen := agent.IDGeneratorFunc(func() string { return "fixed" })
+
+	// Act
+	id := gen.NewID()
+
+	// Assert
+	assert.That(t, "ID must come from the function", id, "fixed")
+}
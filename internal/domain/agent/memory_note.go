@@ -1,6 +1,8 @@
 package agent
 
 import (
+	"math"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,6 +17,9 @@ type NoteID string
 // It is a slice of float32 values, typically generated by an embedding model.
 type Embedding []float32
 
+// normalizedTolerance is the allowed deviation from magnitude 1 for a unit vector.
+const normalizedTolerance = 1e-4
+
 // SourceType categorizes what created a memory note.
 type SourceType string
 
@@ -84,11 +89,112 @@ type MemoryNote struct {
 	Summary    string     `json:"summary"`
 
 	// Semantic enrichment
-	ContextDescription string    `json:"context_description"`
-	Embedding          Embedding `json:"embedding,omitempty"`
-	Keywords           []string  `json:"keywords"`
-	Tags               []string  `json:"tags"`
-	Importance         int       `json:"importance"` // 1-5 scale
+	ContextDescription  string    `json:"context_description"`
+	Embedding           Embedding `json:"embedding,omitempty"`
+	Keywords            []string  `json:"keywords"`
+	Tags                []string  `json:"tags"`
+	Importance          int       `json:"importance"`                     // 1-5 scale
+	EmbeddingNormalized bool      `json:"embedding_normalized,omitempty"` // Embedding is a unit vector
+
+	// Number of retrievals, counted by stores implementing AccessRecorder
+	AccessCount int `json:"access_count,omitempty"`
+
+	// Earlier versions, oldest first, kept by stores with version history enabled
+	History []NoteVersion `json:"history,omitempty"`
+
+	// Search metadata, set on the copies returned by a search and never persisted
+	Score float64 `json:"-"` // Relevance or similarity to the query
+}
+
+// SearchableWeights sets how often each field of a note appears in SearchableTextWeighted.
+// A weight of zero omits the field; higher weights repeat the field and move it to the front,
+// so embedding models give it more emphasis.
+type SearchableWeights struct {
+	ContextDescription int
+	Keywords           int
+	RawContent         int
+	Summary            int
+	Tags               int
+}
+
+// DefaultSearchableWeights returns the weights that include every field once, like SearchableText.
+func DefaultSearchableWeights() SearchableWeights {
+	return SearchableWeights{ContextDescription: 1, Keywords: 1, RawContent: 1, Summary: 1, Tags: 1}
+}
+
+// NoteVersion is an earlier state of a memory note's content, kept when the note is overwritten.
+type NoteVersion struct {
+	UpdatedAt  time.Time `json:"updated_at"`
+	RawContent string    `json:"raw_content"`
+	Summary    string    `json:"summary"`
+}
+
+// CosineSimilarity computes the cosine similarity between two embeddings.
+// The result ranges from -1 (opposite) to 1 (identical direction).
+// Returns 0 if either embedding is empty, the lengths differ,
+// or either vector has zero magnitude.
+func CosineSimilarity(a, b Embedding) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var dot, na, nb float64
+	for i := range a {
+		v1 := float64(a[i])
+		v2 := float64(b[i])
+		dot += v1 * v2
+		na += v1 * v1
+		nb += v2 * v2
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// DotProduct computes the dot product of two embeddings.
+// For unit vectors this equals their cosine similarity.
+// Returns 0 if the lengths differ.
+func DotProduct(a, b Embedding) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+// IsNormalized returns true if the embedding is a unit vector.
+func (e Embedding) IsNormalized() bool {
+	return len(e) > 0 && math.Abs(e.magnitude()-1) < normalizedTolerance
+}
+
+// Normalize returns a unit vector copy of the embedding.
+// Zero-magnitude embeddings are returned as an unchanged copy.
+func (e Embedding) Normalize() Embedding {
+	normalized := make(Embedding, len(e))
+	m := e.magnitude()
+	if m == 0 {
+		copy(normalized, e)
+		return normalized
+	}
+	for i, v := range e {
+		normalized[i] = float32(float64(v) / m)
+	}
+	return normalized
+}
+
+// magnitude returns the Euclidean length of the embedding.
+func (e Embedding) magnitude() float64 {
+	var sum float64
+	for _, v := range e {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum)
 }
 
 // NewMemoryNote creates a new MemoryNote with the given ID and source type.
@@ -275,6 +381,17 @@ func (n *MemoryNote) WithContextDescription(desc string) *MemoryNote {
 // Embeddings enable semantic similarity search using cosine similarity.
 func (n *MemoryNote) WithEmbedding(e Embedding) *MemoryNote {
 	n.Embedding = e
+	n.EmbeddingNormalized = false
+	n.UpdatedAt = time.Now()
+	return n
+}
+
+// WithNormalizedEmbedding stores the embedding as a unit vector.
+// Searches with a normalized query embedding can then rank the note
+// with a plain dot product instead of a full cosine similarity.
+func (n *MemoryNote) WithNormalizedEmbedding(e Embedding) *MemoryNote {
+	n.Embedding = e.Normalize()
+	n.EmbeddingNormalized = n.Embedding.IsNormalized()
 	n.UpdatedAt = time.Now()
 	return n
 }
@@ -335,3 +452,33 @@ func (n *MemoryNote) SearchableText() string {
 	}
 	return text
 }
+
+// SearchableTextWeighted returns the searchable text with each field repeated according to
+// its weight. Fields are ordered by descending weight; equal weights keep the order of
+// SearchableText, so DefaultSearchableWeights yields the same fields in the same order.
+func (n *MemoryNote) SearchableTextWeighted(weights SearchableWeights) string {
+	fields := []struct {
+		text   string
+		weight int
+	}{
+		{n.RawContent, weights.RawContent},
+		{n.Summary, weights.Summary},
+		{n.ContextDescription, weights.ContextDescription},
+		{strings.Join(n.Tags, " "), weights.Tags},
+		{strings.Join(n.Keywords, " "), weights.Keywords},
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].weight > fields[j].weight
+	})
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field.text == "" {
+			continue
+		}
+		for range field.weight {
+			parts = append(parts, field.text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
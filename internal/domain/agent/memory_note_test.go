@@ -1,6 +1,7 @@
 package agent_test
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -209,6 +210,40 @@ func Test_MemoryNote_SearchableText_WithPartialFields_Should_ReturnAvailableText
 	assert.That(t, "searchable text must be raw content only", text, "Just raw content")
 }
 
+func Test_MemoryNote_SearchableTextWeighted_With_SummaryWeight_Should_EmphasizeSummary(t *testing.T) {
+	// Arrange
+	note := agent.NewMemoryNote("note-123", agent.SourceTypePreference).
+		WithRawContent("User prefers German").
+		WithSummary("Language preference").
+		WithTags("language")
+	weights := agent.DefaultSearchableWeights()
+	weights.Summary = 2
+	weights.Tags = 0
+
+	// Act
+	text := note.SearchableTextWeighted(weights)
+
+	// Assert
+	assert.That(t, "summary must come first and be repeated", text, "Language preference Language preference User prefers German")
+}
+
+func Test_MemoryNote_SearchableTextWeighted_With_DefaultWeights_Should_MatchSearchableText(t *testing.T) {
+	// Arrange
+	note := agent.NewMemoryNote("note-123", agent.SourceTypePreference).
+		WithRawContent("User prefers German").
+		WithSummary("Language preference").
+		WithContextDescription("Apply to all responses").
+		WithTags("language", "preference").
+		WithKeywords("german", "locale")
+
+	// Act
+	text := note.SearchableTextWeighted(agent.DefaultSearchableWeights())
+
+	// Assert
+	assert.That(t, "default weights must reproduce SearchableText", text, note.SearchableText())
+	assert.That(t, "SearchableText must be unchanged", note.SearchableText(), "User prefers German Language preference Apply to all responses language preference german locale")
+}
+
 func Test_MemoryNote_MethodChaining_Should_Work(t *testing.T) {
 	// Arrange & Act
 	note := agent.NewMemoryNote("note-123", agent.SourceTypePreference).
@@ -395,3 +430,129 @@ func Test_NewSummaryNote_Should_CreateWithSourceReferences(t *testing.T) {
 	assert.That(t, "should have summary tag", note.HasTag("summary"), true)
 	assert.That(t, "context should reference sources", note.ContextDescription != "", true)
 }
+
+func Test_CosineSimilarity_With_IdenticalVectors_Should_ReturnOne(t *testing.T) {
+	// Arrange
+	a := agent.Embedding{1, 2, 3}
+
+	// Act
+	similarity := agent.CosineSimilarity(a, agent.Embedding{1, 2, 3})
+
+	// Assert
+	assert.That(t, "similarity must be 1", math.Abs(similarity-1) < 1e-9, true)
+}
+
+func Test_CosineSimilarity_With_OrthogonalVectors_Should_ReturnZero(t *testing.T) {
+	// Arrange
+	a := agent.Embedding{1, 0}
+	b := agent.Embedding{0, 1}
+
+	// Act
+	similarity := agent.CosineSimilarity(a, b)
+
+	// Assert
+	assert.That(t, "similarity must be 0", similarity, 0.0)
+}
+
+func Test_CosineSimilarity_With_OppositeVectors_Should_ReturnMinusOne(t *testing.T) {
+	// Arrange
+	a := agent.Embedding{1, -2, 3}
+	b := agent.Embedding{-1, 2, -3}
+
+	// Act
+	similarity := agent.CosineSimilarity(a, b)
+
+	// Assert
+	assert.That(t, "similarity must be -1", math.Abs(similarity+1) < 1e-9, true)
+}
+
+func Test_CosineSimilarity_With_MismatchedLengths_Should_ReturnZero(t *testing.T) {
+	// Arrange
+	a := agent.Embedding{1, 2, 3}
+	b := agent.Embedding{1, 2}
+
+	// Act
+	similarity := agent.CosineSimilarity(a, b)
+
+	// Assert
+	assert.That(t, "similarity must be 0", similarity, 0.0)
+}
+
+func Test_CosineSimilarity_With_ZeroVector_Should_ReturnZero(t *testing.T) {
+	// Arrange
+	a := agent.Embedding{0, 0, 0}
+	b := agent.Embedding{1, 2, 3}
+
+	// Act
+	similarity := agent.CosineSimilarity(a, b)
+
+	// Assert
+	assert.That(t, "similarity must be 0", similarity, 0.0)
+}
+
+func Test_CosineSimilarity_With_EmptyVectors_Should_ReturnZero(t *testing.T) {
+	// Act
+	similarity := agent.CosineSimilarity(nil, agent.Embedding{})
+
+	// Assert
+	assert.That(t, "similarity must be 0", similarity, 0.0)
+}
+
+func Test_Embedding_Normalize_Should_ReturnUnitVector(t *testing.T) {
+	// Arrange
+	e := agent.Embedding{3, 4}
+
+	// Act
+	normalized := e.Normalize()
+
+	// Assert
+	assert.That(t, "normalized must be a unit vector", normalized.IsNormalized(), true)
+	assert.That(t, "original must be unchanged", e[0], float32(3))
+	assert.That(t, "direction must be preserved", math.Abs(agent.CosineSimilarity(e, normalized)-1) < 1e-6, true)
+}
+
+func Test_Embedding_Normalize_With_ZeroVector_Should_NotBeNormalized(t *testing.T) {
+	// Arrange
+	e := agent.Embedding{0, 0}
+
+	// Act
+	normalized := e.Normalize()
+
+	// Assert
+	assert.That(t, "zero vector must not be normalized", normalized.IsNormalized(), false)
+}
+
+func Test_MemoryNote_WithNormalizedEmbedding_Should_StoreUnitVector(t *testing.T) {
+	// Arrange
+	note := agent.NewFactNote("note-1", "content")
+
+	// Act
+	note.WithNormalizedEmbedding(agent.Embedding{1, 2, 2})
+
+	// Assert
+	assert.That(t, "flag must be set", note.EmbeddingNormalized, true)
+	assert.That(t, "embedding must be a unit vector", note.Embedding.IsNormalized(), true)
+}
+
+func Test_MemoryNote_WithEmbedding_Should_ClearNormalizedFlag(t *testing.T) {
+	// Arrange
+	note := agent.NewFactNote("note-1", "content").WithNormalizedEmbedding(agent.Embedding{1, 0})
+
+	// Act
+	note.WithEmbedding(agent.Embedding{2, 0})
+
+	// Assert
+	assert.That(t, "flag must be cleared", note.EmbeddingNormalized, false)
+}
+
+func Test_DotProduct_With_UnitVectors_Should_EqualCosineSimilarity(t *testing.T) {
+	// Arrange
+	a := agent.Embedding{1, 2, 3}
+	b := agent.Embedding{-2, 0.5, 4}
+
+	// Act
+	dot := agent.DotProduct(a.Normalize(), b.Normalize())
+
+	// Assert
+	assert.That(t, "dot product must equal cosine similarity", math.Abs(dot-agent.CosineSimilarity(a, b)) < 1e-6, true)
+}
@@ -1,11 +1,43 @@
 package agent
 
+// Token estimation heuristics (alphabetically sorted).
+const (
+	charsPerToken         = 4  // Average characters per token for English text
+	imagePartTokens       = 85 // Tokens for a low-detail image
+	messageOverheadTokens = 4  // Tokens for role and message framing
+)
+
+// EstimateTokens returns a rough token count for the given messages.
+// It uses a characters-per-token heuristic plus a fixed overhead per message,
+// which is good enough for pre-flight context window checks.
+func EstimateTokens(messages []Message) int {
+	total := 0
+	for _, msg := range messages {
+		chars := len(msg.Content)
+		for _, tc := range msg.ToolCalls {
+			chars += len(tc.Name) + len(tc.Arguments)
+		}
+		for _, part := range msg.Parts {
+			chars += len(part.Text)
+			if part.Type == ContentPartTypeImageURL {
+				total += imagePartTokens
+			}
+		}
+		total += messageOverheadTokens + (chars+charsPerToken-1)/charsPerToken
+	}
+	return total
+}
+
 // LLMResponse represents the response from an LLM.
 // It contains the assistant message and any tool calls requested.
 type LLMResponse struct {
-	FinishReason string     // Why the LLM stopped (e.g., "stop", "tool_calls")
-	Message      Message    // The response message from the LLM
-	ToolCalls    []ToolCall // Tool calls requested by the LLM
+	FinishReason      string     // Why the LLM stopped (e.g., "stop", "tool_calls")
+	SystemFingerprint string     // Backend configuration fingerprint, if reported
+	Candidates        []Message  // All candidate messages when several completions were requested
+	Message           Message    // The response message from the LLM
+	ToolCalls         []ToolCall // Tool calls requested by the LLM
+	Usage             TokenUsage // Token usage reported by the backend, if any
+	IsStreamed        bool       // Whether the response was assembled from a stream
 }
 
 // NewLLMResponse creates a new LLMResponse with the given message and finish reason.
@@ -22,19 +54,68 @@ func (r LLMResponse) HasToolCalls() bool {
 	return len(r.ToolCalls) > 0
 }
 
+// SelectCandidate returns a copy of the response using the candidate at index
+// as its message and tool calls. An out-of-range index returns the response unchanged.
+func (r LLMResponse) SelectCandidate(index int) LLMResponse {
+	if index < 0 || index >= len(r.Candidates) {
+		return r
+	}
+	r.Message = r.Candidates[index]
+	r.ToolCalls = r.Message.ToolCalls
+	return r
+}
+
+// WithCandidates sets the candidate messages on the response.
+func (r LLMResponse) WithCandidates(candidates []Message) LLMResponse {
+	r.Candidates = candidates
+	return r
+}
+
+// WithStreamed marks the response as assembled from a stream.
+func (r LLMResponse) WithStreamed() LLMResponse {
+	r.IsStreamed = true
+	return r
+}
+
+// WithSystemFingerprint sets the backend fingerprint on the response.
+func (r LLMResponse) WithSystemFingerprint(fingerprint string) LLMResponse {
+	r.SystemFingerprint = fingerprint
+	return r
+}
+
 // WithToolCalls sets the tool calls on the response.
 func (r LLMResponse) WithToolCalls(toolCalls []ToolCall) LLMResponse {
 	r.ToolCalls = toolCalls
 	return r
 }
 
+// WithUsage sets the token usage on the response.
+func (r LLMResponse) WithUsage(usage TokenUsage) LLMResponse {
+	r.Usage = usage
+	return r
+}
+
+// ContentPart is a part of a multi-modal message: either text or an image.
+type ContentPart struct {
+	Detail   string          `json:"detail,omitempty"`    // Image detail level: low, high, or auto
+	ImageURL string          `json:"image_url,omitempty"` // Image URL or base64 data URL
+	Text     string          `json:"text,omitempty"`      // Text of a text part
+	Type     ContentPartType `json:"type"`                // Kind of the part
+}
+
 // Message represents a single message in a conversation.
 // It follows the OpenAI chat completion message format.
+// Parts carry additional content such as images; Content stays the text of the message.
+// Pinned messages are never removed when the conversation is trimmed.
+// CacheControl hints that the prompt up to this message may be cached by the provider.
 type Message struct {
-	Content    string     `json:"content"`
-	Role       Role       `json:"role"`
-	ToolCallID ToolCallID `json:"tool_call_id,omitempty"`
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	Content      string        `json:"content"`
+	Role         Role          `json:"role"`
+	ToolCallID   ToolCallID    `json:"tool_call_id,omitempty"`
+	Parts        []ContentPart `json:"parts,omitempty"`
+	ToolCalls    []ToolCall    `json:"tool_calls,omitempty"`
+	CacheControl bool          `json:"cache_control,omitempty"`
+	Pinned       bool          `json:"pinned,omitempty"`
 }
 
 // NewMessage creates a new Message with the given role and content.
@@ -46,6 +127,44 @@ func NewMessage(role Role, content string) Message {
 	}
 }
 
+// ContentParts returns the content as parts: the text of Content (if any)
+// followed by Parts. Returns nil for plain text messages without parts.
+func (m Message) ContentParts() []ContentPart {
+	if len(m.Parts) == 0 {
+		return nil
+	}
+	parts := make([]ContentPart, 0, len(m.Parts)+1)
+	if m.Content != "" {
+		parts = append(parts, ContentPart{Text: m.Content, Type: ContentPartTypeText})
+	}
+	return append(parts, m.Parts...)
+}
+
+// WithCacheControl marks the message as the end of a prompt prefix the provider may cache.
+// It is only a hint: clients without prompt caching support ignore it.
+func (m Message) WithCacheControl() Message {
+	m.CacheControl = true
+	return m
+}
+
+// WithImageURL attaches an image to the message.
+// The url may be a web URL or a base64 data URL; detail is low, high, or auto
+// (empty lets the model decide).
+func (m Message) WithImageURL(url, detail string) Message {
+	m.Parts = append(append([]ContentPart(nil), m.Parts...), ContentPart{
+		Detail:   detail,
+		ImageURL: url,
+		Type:     ContentPartTypeImageURL,
+	})
+	return m
+}
+
+// WithPinned marks the message as pinned, so trimming never removes it.
+func (m Message) WithPinned() Message {
+	m.Pinned = true
+	return m
+}
+
 // WithToolCallID sets the tool call ID for tool response messages.
 func (m Message) WithToolCallID(id ToolCallID) Message {
 	m.ToolCallID = id
@@ -58,15 +177,29 @@ func (m Message) WithToolCalls(toolCalls []ToolCall) Message {
 	return m
 }
 
+// oldestUnpinnedIndex returns the index of the oldest message that may be trimmed,
+// or -1 if there is none. Pinned messages and the latest message are never trimmed.
+func oldestUnpinnedIndex(messages []Message) int {
+	for i := range len(messages) - 1 {
+		if !messages[i].Pinned {
+			return i
+		}
+	}
+	return -1
+}
+
 // ToolCall represents a tool invocation requested by the LLM.
 // It tracks the tool name, arguments, and execution result.
 type ToolCall struct {
-	Arguments string         `json:"arguments"`        // JSON-encoded arguments
-	Error     string         `json:"error,omitempty"`  // Error message if failed
-	ID        ToolCallID     `json:"id"`               // Unique identifier for this call
-	Name      string         `json:"name"`             // Name of the tool to execute
-	Result    string         `json:"result,omitempty"` // Execution result
-	Status    ToolCallStatus `json:"status,omitempty"` // Current execution state
+	Arguments string         `json:"arguments"`            // JSON-encoded arguments
+	Error     string         `json:"error,omitempty"`      // Error message if failed
+	ErrorKind ToolErrorKind  `json:"error_kind,omitempty"` // Classification of the failure, if known
+	ID        ToolCallID     `json:"id"`                   // Unique identifier for this call
+	Name      string         `json:"name"`                 // Name of the tool to execute
+	Result    string         `json:"result,omitempty"`     // Execution result
+	Status    ToolCallStatus `json:"status,omitempty"`     // Current execution state
+
+	invalidArguments bool // Failed with ErrInvalidArguments or a ValidationError
 }
 
 // NewToolCall creates a new ToolCall with the given ID, name, and arguments.
@@ -79,12 +212,31 @@ func NewToolCall(id ToolCallID, name string, arguments string) ToolCall {
 	}
 }
 
+// ArgumentsMap decodes the JSON arguments into a map for dynamic access.
+// Returns ErrInvalidArguments if the JSON is malformed or not an object.
+func (tc *ToolCall) ArgumentsMap() (map[string]any, error) {
+	var args map[string]any
+	if err := DecodeArgs(tc.Arguments, &args); err != nil {
+		return nil, err
+	}
+	if args == nil {
+		args = make(map[string]any)
+	}
+	return args, nil
+}
+
 // Complete marks the tool call as successfully completed with the given result.
 func (tc *ToolCall) Complete(result string) {
 	tc.Result = result
 	tc.Status = ToolCallStatusCompleted
 }
 
+// DecodeArguments decodes the JSON arguments into dst.
+// Returns ErrInvalidArguments if the JSON is malformed.
+func (tc *ToolCall) DecodeArguments(dst any) error {
+	return DecodeArgs(tc.Arguments, dst)
+}
+
 // Execute marks the tool call as currently executing.
 func (tc *ToolCall) Execute() {
 	tc.Status = ToolCallStatusExecuting
@@ -93,14 +245,35 @@ func (tc *ToolCall) Execute() {
 // Fail marks the tool call as failed with the given error message.
 func (tc *ToolCall) Fail(errMsg string) {
 	tc.Error = errMsg
+	tc.ErrorKind = ""
 	tc.Status = ToolCallStatusFailed
+	tc.invalidArguments = false
+}
+
+// FailWithError marks the tool call as failed with the given error.
+// A ToolError (or ErrInvalidArguments) additionally classifies the failure,
+// which ToMessage turns into a hint on how the model should react.
+func (tc *ToolCall) FailWithError(err error) {
+	tc.Fail(err.Error())
+	tc.ErrorKind = toolErrorKind(err)
+	tc.invalidArguments = isInvalidArguments(err)
 }
 
 // ToMessage converts the tool call result to a tool response message.
+// Failures are rendered with a hint matching their ErrorKind.
 func (tc *ToolCall) ToMessage() Message {
 	content := tc.Result
 	if tc.Status == ToolCallStatusFailed {
-		content = "Error: " + tc.Error
+		switch tc.ErrorKind {
+		case ToolErrorKindSystem:
+			content = "System failure: " + tc.Error + " (do not retry this call)"
+		case ToolErrorKindTemporary:
+			content = "Temporary failure: " + tc.Error + " (the call may succeed if retried)"
+		case ToolErrorKindUser:
+			content = "Invalid arguments: " + tc.Error + " (fix the arguments and call the tool again)"
+		default:
+			content = "Error: " + tc.Error
+		}
 	}
 	return NewMessage(RoleTool, content).WithToolCallID(tc.ID)
 }
@@ -1,6 +1,9 @@
 package agent_test
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/andygeiss/cloud-native-utils/assert"
@@ -22,6 +25,18 @@ func Test_Message_NewMessage_With_ValidParams_Should_ReturnMessage(t *testing.T)
 	assert.That(t, "message content must match", msg.Content, content)
 }
 
+func Test_Message_WithPinned_Should_MarkPinned(t *testing.T) {
+	// Arrange
+	msg := agent.NewMessage(agent.RoleUser, "remember this")
+
+	// Act
+	pinned := msg.WithPinned()
+
+	// Assert
+	assert.That(t, "message must be pinned", pinned.Pinned, true)
+	assert.That(t, "original message must not be pinned", msg.Pinned, false)
+}
+
 func Test_Message_WithToolCallID_Should_SetToolCallID(t *testing.T) {
 	// Arrange
 	msg := agent.NewMessage(agent.RoleTool, "result")
@@ -127,3 +142,176 @@ func Test_ToolCall_ToMessage_With_FailedToolCall_Should_ReturnErrorMessage(t *te
 	assert.That(t, "message role must be tool", msg.Role, agent.RoleTool)
 	assert.That(t, "message content must contain error", msg.Content, "Error: tool failed")
 }
+
+func Test_ToolCall_ToMessage_With_UserFacingToolError_Should_AskToFixArguments(t *testing.T) {
+	// Arrange
+	tc := agent.NewToolCall("tc-1", "search", `{}`)
+	tc.FailWithError(agent.NewToolError("search", "query is required", nil).WithUserFacing())
+
+	// Act
+	msg := tc.ToMessage()
+
+	// Assert
+	assert.That(t, "error kind must be user", tc.ErrorKind, agent.ToolErrorKindUser)
+	assert.That(t, "message content must ask to fix arguments", msg.Content,
+		"Invalid arguments: tool search: query is required (fix the arguments and call the tool again)")
+}
+
+func Test_ToolCall_ToMessage_With_RetryableToolError_Should_AllowRetry(t *testing.T) {
+	// Arrange
+	tc := agent.NewToolCall("tc-1", "search", `{}`)
+	tc.FailWithError(agent.NewToolError("search", "rate limited", nil).WithRetryable())
+
+	// Act
+	msg := tc.ToMessage()
+
+	// Assert
+	assert.That(t, "error kind must be temporary", tc.ErrorKind, agent.ToolErrorKindTemporary)
+	assert.That(t, "message content must allow retry", msg.Content,
+		"Temporary failure: tool search: rate limited (the call may succeed if retried)")
+}
+
+func Test_ToolCall_ToMessage_With_SystemToolError_Should_ForbidRetry(t *testing.T) {
+	// Arrange
+	tc := agent.NewToolCall("tc-1", "search", `{}`)
+	tc.FailWithError(agent.NewToolError("search", "backend down", nil))
+
+	// Act
+	msg := tc.ToMessage()
+
+	// Assert
+	assert.That(t, "error kind must be system", tc.ErrorKind, agent.ToolErrorKindSystem)
+	assert.That(t, "message content must forbid retry", msg.Content,
+		"System failure: tool search: backend down (do not retry this call)")
+}
+
+func Test_ToolCall_ToMessage_With_InvalidArgumentsError_Should_AskToFixArguments(t *testing.T) {
+	// Arrange
+	tc := agent.NewToolCall("tc-1", "search", `{`)
+	err := tc.DecodeArguments(&struct{}{})
+	tc.FailWithError(fmt.Errorf("failed to parse arguments: %w", err))
+
+	// Act
+	msg := tc.ToMessage()
+
+	// Assert
+	assert.That(t, "error kind must be user", tc.ErrorKind, agent.ToolErrorKindUser)
+	assert.That(t, "message content must start with hint", strings.HasPrefix(msg.Content, "Invalid arguments: "), true)
+}
+
+func Test_ToolCall_ToMessage_With_PlainError_Should_ReturnErrorMessage(t *testing.T) {
+	// Arrange
+	tc := agent.NewToolCall("tc-1", "search", `{}`)
+	tc.FailWithError(errors.New("tool failed"))
+
+	// Act
+	msg := tc.ToMessage()
+
+	// Assert
+	assert.That(t, "error kind must be empty", tc.ErrorKind, agent.ToolErrorKind(""))
+	assert.That(t, "message content must contain error", msg.Content, "Error: tool failed")
+}
+
+func Test_Message_WithImageURL_Should_AppendImagePart(t *testing.T) {
+	// Arrange
+	msg := agent.NewMessage(agent.RoleUser, "What is this?")
+
+	// Act
+	withImage := msg.WithImageURL("https://example.com/a.png", "high")
+
+	// Assert
+	assert.That(t, "original must be unchanged", len(msg.Parts), 0)
+	assert.That(t, "content parts must start with the text", withImage.ContentParts(), []agent.ContentPart{
+		{Text: "What is this?", Type: agent.ContentPartTypeText},
+		{Detail: "high", ImageURL: "https://example.com/a.png", Type: agent.ContentPartTypeImageURL},
+	})
+}
+
+func Test_Message_ContentParts_With_PlainText_Should_ReturnNil(t *testing.T) {
+	// Arrange
+	msg := agent.NewMessage(agent.RoleUser, "Hello")
+
+	// Act
+	parts := msg.ContentParts()
+
+	// Assert
+	assert.That(t, "parts must be nil", parts == nil, true)
+}
+
+func Test_EstimateTokens_With_ImagePart_Should_CountImage(t *testing.T) {
+	// Arrange
+	plain := agent.NewMessage(agent.RoleUser, "What is this?")
+	withImage := plain.WithImageURL("https://example.com/a.png", "")
+
+	// Act
+	plainTokens := agent.EstimateTokens([]agent.Message{plain})
+	imageTokens := agent.EstimateTokens([]agent.Message{withImage})
+
+	// Assert
+	assert.That(t, "image must add tokens", imageTokens > plainTokens, true)
+}
+
+func Test_ToolCall_DecodeArguments_With_ValidJSON_Should_FillStruct(t *testing.T) {
+	// Arrange
+	tc := agent.NewToolCall("tc-1", "search", `{"query": "go", "limit": 5}`)
+	var args struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+
+	// Act
+	err := tc.DecodeArguments(&args)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "query must match", args.Query, "go")
+	assert.That(t, "limit must match", args.Limit, 5)
+}
+
+func Test_ToolCall_DecodeArguments_With_InvalidJSON_Should_ReturnErrInvalidArguments(t *testing.T) {
+	// Arrange
+	tc := agent.NewToolCall("tc-1", "search", `{"query": `)
+	var args struct{}
+
+	// Act
+	err := tc.DecodeArguments(&args)
+
+	// Assert
+	assert.That(t, "err must be ErrInvalidArguments", errors.Is(err, agent.ErrInvalidArguments), true)
+}
+
+func Test_ToolCall_ArgumentsMap_With_ValidJSON_Should_ReturnMap(t *testing.T) {
+	// Arrange
+	tc := agent.NewToolCall("tc-1", "search", `{"query": "go", "tags": ["a"]}`)
+
+	// Act
+	args, err := tc.ArgumentsMap()
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "query must match", args["query"], any("go"))
+	assert.That(t, "tags must match", args["tags"], any([]any{"a"}))
+}
+
+func Test_ToolCall_ArgumentsMap_With_Null_Should_ReturnEmptyMap(t *testing.T) {
+	// Arrange
+	tc := agent.NewToolCall("tc-1", "search", `null`)
+
+	// Act
+	args, err := tc.ArgumentsMap()
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "map must be empty", len(args), 0)
+}
+
+func Test_ToolCall_ArgumentsMap_With_InvalidJSON_Should_ReturnErrInvalidArguments(t *testing.T) {
+	// Arrange
+	tc := agent.NewToolCall("tc-1", "search", `["not", "an", "object"]`)
+
+	// Act
+	_, err := tc.ArgumentsMap()
+
+	// Assert
+	assert.That(t, "err must be ErrInvalidArguments", errors.Is(err, agent.ErrInvalidArguments), true)
+}
@@ -2,10 +2,31 @@ package agent
 
 import (
 	"context"
+	"time"
 
 	"github.com/andygeiss/cloud-native-utils/event"
 )
 
+// AccessRecorder is optionally implemented by memory stores that count how often notes are retrieved.
+type AccessRecorder interface {
+	// RecordAccess increments the access count of a note.
+	RecordAccess(ctx context.Context, id NoteID) error
+}
+
+// Closer is optionally implemented by stores and clients that hold resources,
+// such as buffered writes or open connections, which must be released on shutdown.
+type Closer interface {
+	// Close flushes pending writes and releases held resources.
+	Close(ctx context.Context) error
+}
+
+// ContextWindowProvider is optionally implemented by LLM clients that know
+// the size of the model's context window in tokens.
+type ContextWindowProvider interface {
+	// ContextWindow returns the maximum number of tokens per request (0 = unknown).
+	ContextWindow() int
+}
+
 // ConversationStore is the interface for persisting conversation history.
 // Implementations can use in-memory, JSON file, or database storage.
 type ConversationStore interface {
@@ -30,6 +51,21 @@ type EventPublisher interface {
 	Publish(ctx context.Context, e event.Event) error
 }
 
+// IDGenerator is the interface for generating unique entity IDs.
+// Implementations must be safe for concurrent use.
+type IDGenerator interface {
+	// NewID returns an ID that differs from every previously returned ID.
+	NewID() string
+}
+
+// IDGeneratorFunc adapts an ordinary function to the IDGenerator interface.
+type IDGeneratorFunc func() string
+
+// NewID calls f().
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
 // LLMClient is the interface for communicating with a language model.
 // Implementations translate between domain types and LLM-specific APIs.
 type LLMClient interface {
@@ -39,12 +75,35 @@ type LLMClient interface {
 
 // MemorySearchOptions configures the search behavior.
 type MemorySearchOptions struct {
-	SessionID     string       // Filter by session ID
-	TaskID        string       // Filter by task ID
-	UserID        string       // Filter by user ID
-	SourceTypes   []SourceType // Filter by source types (any match)
-	Tags          []string     // Filter by tags (any match)
-	MinImportance int          // Filter by minimum importance (1-5, 0 = no filter)
+	CreatedAfter   time.Time    // Filter by creation after this time (zero = no filter)
+	CreatedBefore  time.Time    // Filter by creation before this time (zero = no filter)
+	SessionID      string       // Filter by session ID
+	TaskID         string       // Filter by task ID
+	UserID         string       // Filter by user ID
+	Keywords       []string     // Filter by keywords (any match)
+	SourceTypes    []SourceType // Filter by source types (any match)
+	Tags           []string     // Filter by tags (any match)
+	TagMatch       TagMatch     // How Tags are matched (default: exact)
+	MaxImportance  int          // Filter by maximum importance (1-5, 0 = no filter)
+	MinImportance  int          // Filter by minimum importance (1-5, 0 = no filter)
+	RequireAllTags bool         // Require every tag instead of any (default: false)
+	WholeWord      bool         // Match query terms against whole words instead of substrings
+}
+
+// HasFilters reports whether the options restrict which notes match.
+// TagMatch, RequireAllTags, and WholeWord only change how other filters match.
+func (o *MemorySearchOptions) HasFilters() bool {
+	return o != nil && (!o.CreatedAfter.IsZero() || !o.CreatedBefore.IsZero() ||
+		o.SessionID != "" || o.TaskID != "" || o.UserID != "" ||
+		len(o.Keywords) > 0 || len(o.SourceTypes) > 0 || len(o.Tags) > 0 ||
+		o.MaxImportance > 0 || o.MinImportance > 0)
+}
+
+// MemorySearcher is the interface for retrieving the notes most relevant to a query.
+// Implementations choose the search method themselves, e.g. by embedding the query.
+type MemorySearcher interface {
+	// SmartSearch retrieves the topK notes most relevant to the query.
+	SmartSearch(ctx context.Context, query string, topK int, opts *MemorySearchOptions) ([]*MemoryNote, error)
 }
 
 // MemoryStore is the interface for persisting and retrieving memory notes.
@@ -60,6 +119,15 @@ type MemoryStore interface {
 	Write(ctx context.Context, note *MemoryNote) error
 }
 
+// MetricsCollector is the interface for recording task execution metrics.
+// Implementations must be safe for concurrent use, since tool calls may run in parallel.
+type MetricsCollector interface {
+	// RecordTask records a finished task with its duration, iterations, tool calls, and outcome.
+	RecordTask(duration time.Duration, iterations, toolCalls int, success bool)
+	// RecordToolCall records a single tool execution with its duration and error, if any.
+	RecordToolCall(name string, duration time.Duration, err error)
+}
+
 // TaskRunner executes tasks for an agent.
 type TaskRunner interface {
 	// RunTask executes a task and returns the result.
@@ -85,4 +153,6 @@ type ToolExecutor interface {
 
 // ToolFunc is a function type for tool implementations.
 // It receives a context and JSON arguments string, returning a result or error.
+// Tools must honor the context: outbound calls (e.g. HTTP requests) should be
+// made with it, so the deadline and cancellation of the task propagate.
 type ToolFunc func(ctx context.Context, arguments string) (string, error)
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// PromptTemplate is a system prompt with placeholders, rendered with text/template.
+// Variables are accessed by key, e.g. "Today is {{.date}}." for vars["date"].
+// Rendering fails if the template references a variable that is not provided,
+// so typos in variable names surface instead of producing a silently broken prompt.
+// A variable that is provided but empty renders as an empty string.
+type PromptTemplate struct {
+	tmpl *template.Template
+}
+
+// NewPromptTemplate parses the template text.
+// Returns an error if the text is not a valid text/template.
+func NewPromptTemplate(tmpl string) (*PromptTemplate, error) {
+	parsed, err := template.New("prompt").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+	return &PromptTemplate{tmpl: parsed}, nil
+}
+
+// Render executes the template with the given variables.
+func (t *PromptTemplate) Render(vars map[string]any) (string, error) {
+	if vars == nil {
+		vars = map[string]any{}
+	}
+	var sb strings.Builder
+	if err := t.tmpl.Execute(&sb, vars); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// WithSystemPromptTemplate returns an Option that renders the template with vars
+// and uses the result as the system prompt, replacing the one passed to NewAgent.
+// Like regexp.MustCompile, it panics if the template cannot be parsed or rendered;
+// use NewPromptTemplate and Render to handle these errors instead.
+func WithSystemPromptTemplate(tmpl string, vars map[string]any) Option {
+	return func(a *Agent) {
+		t, err := NewPromptTemplate(tmpl)
+		if err != nil {
+			panic(err)
+		}
+		prompt, err := t.Render(vars)
+		if err != nil {
+			panic(err)
+		}
+		a.SystemPrompt = prompt
+	}
+}
@@ -0,0 +1,90 @@
+package agent_test
+
+import (
+	"testing"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+)
+
+// PromptTemplate tests
+
+func Test_PromptTemplate_Render_With_Variables_Should_SubstituteValues(t *testing.T) {
+	// Arrange
+	tmpl, _ := agent.NewPromptTemplate("Hello {{.user}}, today is {{.date}}.{{range .tools}} [{{.}}]{{end}}")
+
+	// Act
+	prompt, err := tmpl.Render(map[string]any{
+		"date":  "2026-01-15",
+		"tools": []string{"memory_get", "memory_search"},
+		"user":  "Alice",
+	})
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "prompt must match", prompt, "Hello Alice, today is 2026-01-15. [memory_get] [memory_search]")
+}
+
+func Test_PromptTemplate_Render_With_MissingVariable_Should_ReturnError(t *testing.T) {
+	// Arrange
+	tmpl, _ := agent.NewPromptTemplate("Hello {{.user}}")
+
+	// Act
+	_, err := tmpl.Render(map[string]any{"name": "Alice"})
+
+	// Assert
+	assert.That(t, "must return error", err != nil, true)
+}
+
+func Test_PromptTemplate_Render_With_EmptyVariable_Should_RenderEmpty(t *testing.T) {
+	// Arrange
+	tmpl, _ := agent.NewPromptTemplate("Hello {{.user}}!")
+
+	// Act
+	prompt, err := tmpl.Render(map[string]any{"user": ""})
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "prompt must match", prompt, "Hello !")
+}
+
+func Test_PromptTemplate_Render_With_NilVariables_Should_RenderStaticText(t *testing.T) {
+	// Arrange
+	tmpl, _ := agent.NewPromptTemplate("You are helpful.")
+
+	// Act
+	prompt, err := tmpl.Render(nil)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "prompt must match", prompt, "You are helpful.")
+}
+
+func Test_NewPromptTemplate_With_InvalidSyntax_Should_ReturnError(t *testing.T) {
+	// Arrange & Act
+	_, err := agent.NewPromptTemplate("Hello {{.user")
+
+	// Assert
+	assert.That(t, "must return error", err != nil, true)
+}
+
+func Test_WithSystemPromptTemplate_Should_SetRenderedSystemPrompt(t *testing.T) {
+	// Arrange & Act
+	ag := agent.NewAgent("agent-1", "ignored",
+		agent.WithSystemPromptTemplate("You assist {{.user}}.", map[string]any{"user": "Alice"}),
+	)
+
+	// Assert
+	assert.That(t, "system prompt must be rendered", ag.SystemPrompt, "You assist Alice.")
+}
+
+func Test_WithSystemPromptTemplate_With_MissingVariable_Should_Panic(t *testing.T) {
+	// Arrange
+	defer func() {
+		// Assert
+		assert.That(t, "must panic", recover() != nil, true)
+	}()
+
+	// Act
+	_ = agent.NewAgent("agent-1", "", agent.WithSystemPromptTemplate("You assist {{.user}}.", nil))
+}
@@ -2,11 +2,14 @@ package agent
 
 import (
 	"context"
-	"sync"
+	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/andygeiss/cloud-native-utils/efficiency"
 	"github.com/andygeiss/cloud-native-utils/service"
+	"github.com/andygeiss/cloud-native-utils/slices"
 )
 
 // Hook represents a function that can be called at specific points during task execution.
@@ -81,14 +84,36 @@ func (h Hooks) WithBeforeToolCall(hook func(ctx context.Context, agent *Agent, t
 	return h
 }
 
+// Continuation of answers cut off by the token limit.
+const (
+	continuePrompt     = "Continue exactly where you left off."
+	finishReasonLength = "length"
+)
+
+// reflectPrompt asks the LLM to verify an answer given before the minimum iterations.
+const reflectPrompt = "Before finalizing, reflect on your answer and verify it. " +
+	"Use the tools to check anything you are unsure about, then give your final answer."
+
 // TaskService orchestrates the agent loop for task execution.
 // It coordinates between the LLM, tools, and event publishing.
 type TaskService struct {
-	eventPublisher EventPublisher
-	llmClient      LLMClient
-	toolExecutor   ToolExecutor
-	hooks          Hooks
-	parallelTools  bool
+	candidateSelector func([]Message) int
+	eventPublisher    EventPublisher
+	llmClient         LLMClient
+	logger            *slog.Logger
+	metrics           MetricsCollector
+	toolExecutor      ToolExecutor
+	toolTimeouts      map[string]time.Duration
+	hooks             Hooks
+	terminalTool      string
+	toolRetryBackoff  time.Duration
+	toolRetryAttempts int
+	loopWindow        int
+	maxContinues      int
+	minIterations     int
+	validationRetries int
+	contextTrimming   bool
+	parallelTools     bool
 }
 
 // NewTaskService creates a new TaskService with the given dependencies.
@@ -110,7 +135,7 @@ func (s *TaskService) RunTask(ctx context.Context, agent *Agent, task *Task) (Re
 	agent.ResetIteration()
 
 	if err := s.runBeforeTaskHook(ctx, agent, task); err != nil {
-		return s.failTask(ctx, task, err.Error(), state)
+		return s.failTask(ctx, agent, task, err.Error(), state)
 	}
 
 	_ = s.eventPublisher.Publish(ctx, NewEventTaskStarted(string(task.ID), task.Name))
@@ -119,12 +144,70 @@ func (s *TaskService) RunTask(ctx context.Context, agent *Agent, task *Task) (Re
 	return s.runAgentLoop(ctx, agent, task, state)
 }
 
+// WithCandidateSelector sets a function that picks one of several candidate
+// completions returned by the LLM. It receives all candidates and returns the
+// index of the one to continue with; an out-of-range index keeps the first.
+// The selector is only consulted when the response has more than one candidate.
+func (s *TaskService) WithCandidateSelector(selector func([]Message) int) *TaskService {
+	s.candidateSelector = selector
+	return s
+}
+
+// WithContextTrimming trims the oldest messages when the conversation exceeds
+// the LLM client's context window, instead of failing the task.
+// It only applies to clients implementing ContextWindowProvider.
+func (s *TaskService) WithContextTrimming() *TaskService {
+	s.contextTrimming = true
+	return s
+}
+
+// WithContinueOnLength continues answers that were cut off by the token limit.
+// When the LLM stops with finish reason "length" and no tool calls, a user
+// message asks it to continue, up to maxContinues times per task. The pieces
+// are concatenated into the task output. Disabled by default.
+func (s *TaskService) WithContinueOnLength(maxContinues int) *TaskService {
+	s.maxContinues = maxContinues
+	return s
+}
+
 // WithHooks sets the hooks for the task service.
 func (s *TaskService) WithHooks(hooks Hooks) *TaskService {
 	s.hooks = hooks
 	return s
 }
 
+// WithLogger sets the logger used to report anomalies such as lost tool call results.
+func (s *TaskService) WithLogger(logger *slog.Logger) *TaskService {
+	s.logger = logger
+	return s
+}
+
+// WithLoopDetection fails a task early with ErrToolLoopDetected when the LLM requests
+// the same tool calls (name and arguments) with the same results in window consecutive
+// iterations, instead of burning the remaining iteration budget. Windows below 2 disable
+// the guard (default).
+func (s *TaskService) WithLoopDetection(window int) *TaskService {
+	s.loopWindow = window
+	return s
+}
+
+// WithMetrics sets the metrics collector for the task service.
+// The collector is invoked once per finished task and once per executed tool call.
+func (s *TaskService) WithMetrics(m MetricsCollector) *TaskService {
+	s.metrics = m
+	return s
+}
+
+// WithMinIterations requires at least n iterations per task before an answer is final.
+// If the LLM answers without tool calls earlier, the answer is discarded, a system
+// message asks it to reflect on and verify the answer, and the loop continues.
+// Iterations driven by tool calls count toward the minimum, and the nudge is skipped
+// once the iteration limit leaves no room for another iteration. Disabled by default.
+func (s *TaskService) WithMinIterations(n int) *TaskService {
+	s.minIterations = n
+	return s
+}
+
 // WithParallelToolExecution enables parallel execution of tool calls.
 // When enabled, multiple tool calls from a single LLM response are
 // executed concurrently using a worker pool. This can significantly
@@ -135,10 +218,108 @@ func (s *TaskService) WithParallelToolExecution() *TaskService {
 	return s
 }
 
+// WithTerminalTool names a tool that ends the task when it is called.
+// Once the tool succeeds, its result becomes the task output and the loop
+// completes without another LLM round. Other tool calls in the same batch
+// are executed before the terminal tool. A failing terminal tool call is
+// reported back to the LLM like any other tool error.
+func (s *TaskService) WithTerminalTool(name string) *TaskService {
+	s.terminalTool = name
+	return s
+}
+
+// WithToolRetry retries failing tool calls before marking them as failed.
+// Each tool call is attempted up to maxAttempts times, waiting backoff between attempts.
+// Only temporary failures (ToolErrors marked WithRetryable) and errors without a
+// ToolError classification are retried; user and system failures are reported
+// after the first attempt. Successful results (even empty ones) are returned immediately.
+// Retrying stops early when the context is canceled.
+func (s *TaskService) WithToolRetry(maxAttempts int, backoff time.Duration) *TaskService {
+	s.toolRetryAttempts = maxAttempts
+	s.toolRetryBackoff = backoff
+	return s
+}
+
+// WithValidationRetry lets the LLM fix tool arguments that failed validation.
+// When a tool call fails with invalid arguments (an error wrapping ErrInvalidArguments
+// or a ValidationError from a validating executor), the error is reported back as the
+// tool message and the LLM is re-invoked without using up an iteration; the failed
+// calls are not counted as tool calls. Other user-facing failures are not refunded.
+// At most maxRetries such rounds are granted per task.
+// Disabled by default.
+func (s *TaskService) WithValidationRetry(maxRetries int) *TaskService {
+	s.validationRetries = maxRetries
+	return s
+}
+
+// WithToolTimeout limits the execution time of a single tool.
+// Each tool call runs in its own child context, so a call that times out
+// fails on its own without canceling sibling calls running in parallel.
+// The timeout covers all retry attempts of the call.
+func (s *TaskService) WithToolTimeout(toolName string, timeout time.Duration) *TaskService {
+	if s.toolTimeouts == nil {
+		s.toolTimeouts = make(map[string]time.Duration)
+	}
+	s.toolTimeouts[toolName] = timeout
+	return s
+}
+
 // taskState holds mutable state during task execution.
 type taskState struct {
-	startTime     time.Time
-	toolCallCount int
+	startTime         time.Time
+	loopSignature     string // Tool calls and results of the previous iteration
+	partialOutput     strings.Builder
+	tokens            TokenUsage // Sum of the usage reported for every LLM call
+	continues         int
+	loopRepeats       int
+	toolCallCount     int
+	validationRetries int
+}
+
+// detectLoop records the tool calls of the iteration and reports whether the same calls
+// with the same results were made in loopWindow consecutive iterations.
+func (s *TaskService) detectLoop(toolCalls []ToolCall, state *taskState) bool {
+	if s.loopWindow < 2 {
+		return false
+	}
+	var b strings.Builder
+	for _, tc := range toolCalls {
+		fmt.Fprintf(&b, "%s\x00%s\x00%s\x00%s\x00", tc.Name, tc.Arguments, tc.Result, tc.Error)
+	}
+	signature := b.String()
+	if signature == state.loopSignature {
+		state.loopRepeats++
+	} else {
+		state.loopSignature = signature
+		state.loopRepeats = 1
+	}
+	return state.loopRepeats >= s.loopWindow
+}
+
+// toolCallNames lists the distinct names of the tool calls in order.
+func toolCallNames(toolCalls []ToolCall) string {
+	names := make([]string, 0, len(toolCalls))
+	for _, tc := range toolCalls {
+		if !slices.Contains(names, tc.Name) {
+			names = append(names, tc.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// dropOldestMessage removes the oldest unpinned message together with any tool results
+// that would be left without the assistant message that requested them.
+// The latest message is always kept. Returns false if no message could be removed.
+func dropOldestMessage(messages []Message) ([]Message, bool) {
+	i := oldestUnpinnedIndex(messages)
+	if i < 0 {
+		return messages, false
+	}
+	end := i + 1
+	for end < len(messages)-1 && messages[end].Role == RoleTool && !messages[end].Pinned {
+		end++
+	}
+	return append(messages[:i:i], messages[end:]...), true
 }
 
 // toolCallInput bundles the data needed for parallel tool execution.
@@ -153,10 +334,17 @@ type toolCallOutput struct {
 	index int
 }
 
-// buildMessages constructs the message list with system prompt.
+// buildMessages constructs the message list with system prompt and few-shot examples.
 func (s *TaskService) buildMessages(agent *Agent) []Message {
-	messages := make([]Message, 0, len(agent.Messages)+1)
-	messages = append(messages, NewMessage(RoleSystem, agent.SystemPrompt))
+	prompt := agent.BuildSystemPrompt()
+	if tools := agent.toolDescriptionsPrompt(); tools != "" {
+		prompt = strings.TrimSpace(prompt + "\n\n" + tools)
+	}
+
+	messages := make([]Message, 0, len(agent.Examples)+len(agent.Messages)+1)
+	// The system prompt is the largest stable prefix, so it is marked for prompt caching
+	messages = append(messages, NewMessage(RoleSystem, prompt).WithCacheControl())
+	messages = append(messages, agent.Examples...)
 	messages = append(messages, agent.Messages...)
 	return messages
 }
@@ -169,34 +357,29 @@ func (s *TaskService) collectAndPublishResults(
 	outCh <-chan toolCallOutput,
 	errCh <-chan error,
 ) int {
-	// Collect results
-	results := make([]toolCallOutput, 0, len(toolCalls))
-	var mu sync.Mutex
-
-	// Drain output channel
-	for out := range outCh {
-		mu.Lock()
-		results = append(results, out)
-		mu.Unlock()
-	}
-
-	// Check for errors (non-blocking)
-	select {
-	case err := <-errCh:
-		_ = err // Individual tool failures are handled in the processor
-	default:
-	}
-
-	// Sort results by original index and add messages in order
+	// Place results at their original index until the output channel is closed.
+	// Errors are drained as well, since a worker blocks until its error is received.
 	sortedResults := make([]*ToolCall, len(toolCalls))
-	for _, r := range results {
-		sortedResults[r.index] = r.tc
+	for outCh != nil {
+		select {
+		case out, ok := <-outCh:
+			if !ok {
+				outCh = nil
+				continue
+			}
+			s.placeResult(sortedResults, out)
+		case err := <-errCh:
+			s.logError("tool call processing failed", "error", err)
+		}
 	}
 
+	// Every tool call needs exactly one tool message, so a lost result is reported as failed
 	count := 0
-	for _, tc := range sortedResults {
+	for i, tc := range sortedResults {
 		if tc == nil {
-			continue
+			tc = &toolCalls[i]
+			s.logError("tool call result missing", "tool", tc.Name, "tool_call_id", string(tc.ID), "index", i)
+			tc.FailWithError(ErrToolResultMissing)
 		}
 
 		// Publish tool call executed event
@@ -214,9 +397,29 @@ func (s *TaskService) collectAndPublishResults(
 	return count
 }
 
+// placeResult stores the result at its index; invalid and duplicate results are logged and dropped.
+func (s *TaskService) placeResult(results []*ToolCall, out toolCallOutput) {
+	switch {
+	case out.tc == nil || out.index < 0 || out.index >= len(results):
+		s.logError("invalid tool call result", "index", out.index)
+	case results[out.index] != nil:
+		s.logError("duplicate tool call result", "tool", out.tc.Name, "index", out.index)
+	default:
+		results[out.index] = out.tc
+	}
+}
+
+// logError logs an anomaly if a logger is configured.
+func (s *TaskService) logError(msg string, args ...any) {
+	if s.logger != nil {
+		s.logger.Error(msg, args...)
+	}
+}
+
 // completeTask marks the task as completed and publishes the event.
 func (s *TaskService) completeTask(ctx context.Context, agent *Agent, task *Task, output string, state *taskState) (Result, error) {
 	task.Complete(output)
+	s.publishMessagesTrimmed(ctx, agent)
 
 	if s.hooks.AfterTask != nil {
 		_ = s.hooks.AfterTask(ctx, agent, task)
@@ -224,10 +427,20 @@ func (s *TaskService) completeTask(ctx context.Context, agent *Agent, task *Task
 
 	_ = s.eventPublisher.Publish(ctx, NewEventTaskCompleted(string(task.ID), task.Output))
 
-	return NewResult(task.ID, true, task.Output).
+	if s.metrics != nil {
+		s.metrics.RecordTask(time.Since(state.startTime), task.Iterations, state.toolCallCount, true)
+	}
+
+	result := NewResult(task.ID, true, task.Output).
 		WithDuration(time.Since(state.startTime)).
-		WithIterationCount(agent.Iteration).
-		WithToolCallCount(state.toolCallCount), nil
+		WithIterationCount(task.Iterations).
+		WithTokens(state.tokens).
+		WithToolCallCount(state.toolCallCount)
+	agent.RecordResult(result)
+	agent.RecordTask(task)
+	agent.ClearSystemSegments()
+
+	return result, nil
 }
 
 // createToolCallProcessor returns a function that processes a single tool call.
@@ -238,19 +451,12 @@ func (s *TaskService) createToolCallProcessor(ctx context.Context, agent *Agent)
 		// Run before tool call hook
 		if s.hooks.BeforeToolCall != nil {
 			if err := s.hooks.BeforeToolCall(ctx, agent, tc); err != nil {
-				tc.Fail(err.Error())
+				tc.FailWithError(err)
 				return toolCallOutput{tc: tc, index: input.index}, nil
 			}
 		}
 
-		tc.Execute()
-
-		result, err := s.toolExecutor.Execute(ctx, tc.Name, tc.Arguments)
-		if err != nil {
-			tc.Fail(err.Error())
-		} else {
-			tc.Complete(result)
-		}
+		s.runToolCall(ctx, tc)
 
 		// Run after tool call hook
 		if s.hooks.AfterToolCall != nil {
@@ -261,6 +467,24 @@ func (s *TaskService) createToolCallProcessor(ctx context.Context, agent *Agent)
 	}
 }
 
+// deferTerminalTool moves calls to the terminal tool to the end of the batch,
+// keeping the relative order of all other calls.
+func (s *TaskService) deferTerminalTool(toolCalls []ToolCall) []ToolCall {
+	if s.terminalTool == "" {
+		return toolCalls
+	}
+	ordered := make([]ToolCall, 0, len(toolCalls))
+	terminal := make([]ToolCall, 0, 1)
+	for _, tc := range toolCalls {
+		if tc.Name == s.terminalTool {
+			terminal = append(terminal, tc)
+			continue
+		}
+		ordered = append(ordered, tc)
+	}
+	return append(ordered, terminal...)
+}
+
 // executeIteration runs a single iteration of the agent loop.
 func (s *TaskService) executeIteration(ctx context.Context, agent *Agent, task *Task) (LLMResponse, error) {
 	if s.hooks.BeforeLLMCall != nil {
@@ -269,12 +493,24 @@ func (s *TaskService) executeIteration(ctx context.Context, agent *Agent, task *
 		}
 	}
 
-	messages := s.buildMessages(agent)
+	messages, err := s.fitContextWindow(agent)
+	s.publishMessagesTrimmed(ctx, agent)
+	if err != nil {
+		return LLMResponse{}, err
+	}
+
+	if !task.ToolChoice.IsZero() && task.Iterations == 1 {
+		ctx = ContextWithToolChoice(ctx, task.ToolChoice)
+	}
 	response, err := s.llmClient.Run(ctx, messages, s.toolExecutor.GetToolDefinitions())
 	if err != nil {
 		return LLMResponse{}, err
 	}
 
+	if s.candidateSelector != nil && len(response.Candidates) > 1 {
+		response = response.SelectCandidate(s.candidateSelector(response.Candidates))
+	}
+
 	if s.hooks.AfterLLMCall != nil {
 		if err := s.hooks.AfterLLMCall(ctx, agent, task); err != nil {
 			return LLMResponse{}, err
@@ -316,21 +552,14 @@ func (s *TaskService) executeToolCallsSequential(ctx context.Context, agent *Age
 		// Run before tool call hook
 		if s.hooks.BeforeToolCall != nil {
 			if err := s.hooks.BeforeToolCall(ctx, agent, tc); err != nil {
-				tc.Fail(err.Error())
+				tc.FailWithError(err)
 				agent.AddMessage(tc.ToMessage())
 				count++
 				continue
 			}
 		}
 
-		tc.Execute()
-
-		result, err := s.toolExecutor.Execute(ctx, tc.Name, tc.Arguments)
-		if err != nil {
-			tc.Fail(err.Error())
-		} else {
-			tc.Complete(result)
-		}
+		s.runToolCall(ctx, tc)
 
 		// Run after tool call hook
 		if s.hooks.AfterToolCall != nil {
@@ -354,11 +583,13 @@ func (s *TaskService) executeToolCallsSequential(ctx context.Context, agent *Age
 // failTask marks the task as failed and publishes the event.
 func (s *TaskService) failTask(
 	ctx context.Context,
+	agent *Agent,
 	task *Task,
 	errMsg string,
 	state *taskState,
 ) (Result, error) {
 	task.Fail(errMsg)
+	s.publishMessagesTrimmed(ctx, agent)
 
 	// Run after task hook even on failure
 	if s.hooks.AfterTask != nil {
@@ -368,11 +599,51 @@ func (s *TaskService) failTask(
 	// Publish task failed event
 	_ = s.eventPublisher.Publish(ctx, NewEventTaskFailed(string(task.ID), errMsg))
 
-	return NewResult(task.ID, false, "").
+	if s.metrics != nil {
+		s.metrics.RecordTask(time.Since(state.startTime), task.Iterations, state.toolCallCount, false)
+	}
+
+	result := NewResult(task.ID, false, "").
 		WithError(errMsg).
 		WithDuration(time.Since(state.startTime)).
 		WithIterationCount(task.Iterations).
-		WithToolCallCount(state.toolCallCount), nil
+		WithTokens(state.tokens).
+		WithToolCallCount(state.toolCallCount)
+	agent.RecordResult(result)
+	agent.RecordTask(task)
+	agent.ClearSystemSegments()
+
+	return result, nil
+}
+
+// fitContextWindow builds the messages and checks them against the context window.
+// If trimming is enabled, the oldest messages are dropped until the estimate fits,
+// always keeping the latest message and pinned messages. Otherwise ErrContextWindowExceeded is returned.
+func (s *TaskService) fitContextWindow(agent *Agent) ([]Message, error) {
+	messages := s.buildMessages(agent)
+
+	provider, ok := s.llmClient.(ContextWindowProvider)
+	if !ok || provider.ContextWindow() <= 0 {
+		return messages, nil
+	}
+	window := provider.ContextWindow()
+
+	estimated := EstimateTokens(messages)
+	for s.contextTrimming && estimated > window {
+		trimmed, ok := dropOldestMessage(agent.Messages)
+		if !ok {
+			break
+		}
+		agent.recordTrim(TrimStrategyTokens, len(agent.Messages)-len(trimmed))
+		agent.Messages = trimmed
+		messages = s.buildMessages(agent)
+		estimated = EstimateTokens(messages)
+	}
+
+	if estimated > window {
+		return nil, fmt.Errorf("%w: estimated %d tokens, allowed %d", ErrContextWindowExceeded, estimated, window)
+	}
+	return messages, nil
 }
 
 // prepareToolCallInputs creates indexed inputs for parallel processing.
@@ -384,11 +655,23 @@ func (s *TaskService) prepareToolCallInputs(toolCalls []ToolCall) []toolCallInpu
 	return inputs
 }
 
+// publishIterationCompleted publishes the progress of the task's current iteration.
+func (s *TaskService) publishIterationCompleted(ctx context.Context, task *Task, finishReason string, toolCalls int) {
+	_ = s.eventPublisher.Publish(ctx, NewEventIterationCompleted(string(task.ID), task.Iterations, finishReason, toolCalls))
+}
+
+// publishMessagesTrimmed publishes the trims the agent recorded since the last call.
+func (s *TaskService) publishMessagesTrimmed(ctx context.Context, agent *Agent) {
+	for _, trim := range agent.TakeMessageTrims() {
+		_ = s.eventPublisher.Publish(ctx, NewEventMessagesTrimmed(string(agent.ID), trim.Removed, trim.Strategy))
+	}
+}
+
 // runAgentLoop executes the main agent loop until completion or failure.
 func (s *TaskService) runAgentLoop(ctx context.Context, agent *Agent, task *Task, state *taskState) (Result, error) {
-	for agent.CanContinue() {
+	for agent.CanContinueTask(task) {
 		if ctx.Err() != nil {
-			return s.failTask(ctx, task, ErrContextCanceled.Error(), state)
+			return s.failTask(ctx, agent, task, ErrContextCanceled.Error(), state)
 		}
 
 		agent.IncrementIteration()
@@ -396,20 +679,94 @@ func (s *TaskService) runAgentLoop(ctx context.Context, agent *Agent, task *Task
 
 		response, err := s.executeIteration(ctx, agent, task)
 		if err != nil {
-			return s.failTask(ctx, task, err.Error(), state)
+			return s.failTask(ctx, agent, task, err.Error(), state)
 		}
+		state.tokens = state.tokens.Add(response.Usage)
 
 		agent.AddMessage(response.Message)
 
 		if response.HasToolCalls() {
-			state.toolCallCount += s.executeToolCalls(ctx, agent, response.ToolCalls)
+			toolCalls := s.deferTerminalTool(response.ToolCalls)
+			state.toolCallCount += s.executeToolCalls(ctx, agent, toolCalls)
+			task.RecordToolCalls(toolCalls)
+			s.publishIterationCompleted(ctx, task, response.FinishReason, len(toolCalls))
+			if output, ok := s.terminalToolResult(toolCalls); ok {
+				return s.completeTask(ctx, agent, task, output, state)
+			}
+			if s.detectLoop(toolCalls, state) {
+				errMsg := fmt.Sprintf("%s: %s repeated in %d iterations", ErrToolLoopDetected, toolCallNames(toolCalls), state.loopRepeats)
+				return s.failTask(ctx, agent, task, errMsg, state)
+			}
+			s.grantValidationRetry(agent, task, toolCalls, state)
+			continue
+		}
+
+		state.partialOutput.WriteString(response.Message.Content)
+		s.publishIterationCompleted(ctx, task, response.FinishReason, 0)
+		if response.FinishReason == finishReasonLength && state.continues < s.maxContinues {
+			state.continues++
+			agent.AddMessage(NewMessage(RoleUser, continuePrompt))
+			continue
+		}
+		if task.Iterations < s.minIterations && agent.CanContinueTask(task) {
+			state.partialOutput.Reset()
+			agent.AddMessage(NewMessage(RoleSystem, reflectPrompt))
 			continue
 		}
 
-		return s.completeTask(ctx, agent, task, response.Message.Content, state)
+		return s.finishTask(ctx, agent, task, state)
+	}
+
+	return s.failTask(ctx, agent, task, ErrMaxIterationsReached.Error(), state)
+}
+
+// finishTask completes the task with the collected answer. If the LLM answered with
+// an empty turn, the last tool result becomes the output instead; without one, the
+// task fails with ErrNoResponse rather than succeeding with an empty output.
+func (s *TaskService) finishTask(ctx context.Context, agent *Agent, task *Task, state *taskState) (Result, error) {
+	output := state.partialOutput.String()
+	if strings.TrimSpace(output) != "" {
+		return s.completeTask(ctx, agent, task, output, state)
+	}
+	if result, ok := lastToolResult(task); ok {
+		return s.completeTask(ctx, agent, task, result, state)
+	}
+	return s.failTask(ctx, agent, task, ErrNoResponse.Error(), state)
+}
+
+// lastToolResult returns the result of the task's most recent successful tool call, if any.
+func lastToolResult(task *Task) (string, bool) {
+	for i := len(task.ToolCalls) - 1; i >= 0; i-- {
+		tc := task.ToolCalls[i]
+		if tc.Status == ToolCallStatusCompleted && strings.TrimSpace(tc.Result) != "" {
+			return tc.Result, true
+		}
 	}
+	return "", false
+}
 
-	return s.failTask(ctx, task, ErrMaxIterationsReached.Error(), state)
+// grantValidationRetry refunds the iteration and the failed tool calls if a tool call
+// failed with invalid arguments and the validation retry budget is not used up.
+// The iteration is refunded on both the agent and the task, so the iteration cap,
+// the minimum iterations, and the reported iteration count all ignore it.
+// Other user-facing failures (e.g. a missing confirmation) use up the iteration as usual.
+func (s *TaskService) grantValidationRetry(agent *Agent, task *Task, toolCalls []ToolCall, state *taskState) {
+	if state.validationRetries >= s.validationRetries {
+		return
+	}
+	failed := 0
+	for _, tc := range toolCalls {
+		if tc.Status == ToolCallStatusFailed && tc.invalidArguments {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return
+	}
+	state.validationRetries++
+	state.toolCallCount -= failed
+	agent.Iteration--
+	task.Iterations--
 }
 
 // runBeforeTaskHook executes the before task hook if configured.
@@ -419,3 +776,75 @@ func (s *TaskService) runBeforeTaskHook(ctx context.Context, agent *Agent, task
 	}
 	return nil
 }
+
+// executeToolCall executes the tool call, retrying it if WithToolRetry is configured.
+// Only temporary failures and unclassified errors are retried; user and system
+// failures are returned after the first attempt. A canceled context is returned
+// without executing the tool.
+func (s *TaskService) executeToolCall(ctx context.Context, tc *ToolCall) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	for attempt := 1; ; attempt++ {
+		result, err := s.toolExecutor.Execute(ctx, tc.Name, tc.Arguments)
+		if err == nil || attempt >= s.toolRetryAttempts || !isRetryableToolError(err) {
+			return result, err
+		}
+		timer := time.NewTimer(s.toolRetryBackoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		}
+	}
+}
+
+// isRetryableToolError reports whether a failed tool call may succeed if executed again.
+func isRetryableToolError(err error) bool {
+	kind := toolErrorKind(err)
+	return kind == ToolErrorKindTemporary || kind == ""
+}
+
+// runToolCall executes a single tool call and records its outcome.
+// The call runs in a child context derived from ctx, so canceling ctx
+// stops every call while a per-tool timeout only affects this one.
+func (s *TaskService) runToolCall(ctx context.Context, tc *ToolCall) {
+	ctx, cancel := s.toolCallContext(ctx, tc.Name)
+	defer cancel()
+
+	tc.Execute()
+
+	start := time.Now()
+	result, err := s.executeToolCall(ctx, tc)
+	if s.metrics != nil {
+		s.metrics.RecordToolCall(tc.Name, time.Since(start), err)
+	}
+
+	if err != nil {
+		tc.FailWithError(err)
+	} else {
+		tc.Complete(result)
+	}
+}
+
+// terminalToolResult returns the result of the first successful terminal tool call.
+func (s *TaskService) terminalToolResult(toolCalls []ToolCall) (string, bool) {
+	if s.terminalTool == "" {
+		return "", false
+	}
+	for _, tc := range toolCalls {
+		if tc.Name == s.terminalTool && tc.Status == ToolCallStatusCompleted {
+			return tc.Result, true
+		}
+	}
+	return "", false
+}
+
+// toolCallContext derives the context for a single tool call.
+func (s *TaskService) toolCallContext(ctx context.Context, toolName string) (context.Context, context.CancelFunc) {
+	if timeout, ok := s.toolTimeouts[toolName]; ok && timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
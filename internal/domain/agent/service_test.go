@@ -3,7 +3,12 @@ package agent_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/andygeiss/cloud-native-utils/assert"
 	"github.com/andygeiss/cloud-native-utils/event"
@@ -138,6 +143,47 @@ func Test_TaskService_RunTask_With_ToolCall_Should_ExecuteToolAndComplete(t *tes
 	assert.That(t, "LLM must be called twice", callCount, 2)
 }
 
+func Test_TaskService_RunTask_With_ToolCall_Should_PublishEventPerIteration(t *testing.T) {
+	// Arrange
+	callCount := 0
+	mockLLM := &mockLLMClient{
+		responseFn: func(_ []agent.Message) agent.LLMResponse {
+			callCount++
+			if callCount == 1 {
+				return agent.NewLLMResponse(
+					agent.NewMessage(agent.RoleAssistant, ""),
+					"tool_calls",
+				).WithToolCalls([]agent.ToolCall{
+					agent.NewToolCall("tc-1", "search", `{"query":"a"}`),
+					agent.NewToolCall("tc-2", "search", `{"query":"b"}`),
+				})
+			}
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "answer"), "stop")
+		},
+	}
+	mockPublisher := &mockEventPublisher{}
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{result: "search result"}, mockPublisher)
+	ag := agent.NewAgent("agent-1", "You are helpful")
+	task := agent.NewTask("task-1", "Search Task", "Find something")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	var iterations []agent.EventIterationCompleted
+	for _, e := range mockPublisher.events {
+		if it, ok := e.(agent.EventIterationCompleted); ok {
+			iterations = append(iterations, it)
+		}
+	}
+	assert.That(t, "one event per iteration must be published", len(iterations), result.IterationCount)
+	assert.That(t, "events must carry the iterations and finish reasons", iterations, []agent.EventIterationCompleted{
+		agent.NewEventIterationCompleted("task-1", 1, "tool_calls", 2),
+		agent.NewEventIterationCompleted("task-1", 2, "stop", 0),
+	})
+}
+
 func Test_TaskService_RunTask_With_MaxIterations_Should_Fail(t *testing.T) {
 	// Arrange
 	mockLLM := &mockLLMClient{
@@ -168,6 +214,300 @@ func Test_TaskService_RunTask_With_MaxIterations_Should_Fail(t *testing.T) {
 	assert.That(t, "error must indicate max iterations", result.Error, "max iterations reached")
 }
 
+func Test_TaskService_RunTask_With_EmptyTurn_Should_FailWithNoResponse(t *testing.T) {
+	// Arrange
+	mockLLM := &mockLLMClient{
+		response: agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, ""), "stop"),
+	}
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{}, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Empty", "say something")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "result must not be successful", result.Success, false)
+	assert.That(t, "error must indicate no response", result.Error, agent.ErrNoResponse.Error())
+}
+
+func Test_TaskService_RunTask_With_EmptyTurnAfterToolCall_Should_CompleteWithLastToolResult(t *testing.T) {
+	// Arrange
+	callCount := 0
+	mockLLM := &mockLLMClient{
+		responseFn: func(_ []agent.Message) agent.LLMResponse {
+			callCount++
+			if callCount == 1 {
+				return agent.NewLLMResponse(
+					agent.NewMessage(agent.RoleAssistant, ""),
+					"tool_calls",
+				).WithToolCalls([]agent.ToolCall{
+					agent.NewToolCall("tc-1", "search", `{"query":"test"}`),
+				})
+			}
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "  "), "stop")
+		},
+	}
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{result: "search result"}, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Search", "find something")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "result must be successful", result.Success, true)
+	assert.That(t, "output must be the last tool result", result.Output, "search result")
+	assert.That(t, "LLM must not be called again", callCount, 2)
+}
+
+func newLoopingLLM() *mockLLMClient {
+	return &mockLLMClient{
+		responseFn: func(_ []agent.Message) agent.LLMResponse {
+			return agent.NewLLMResponse(
+				agent.NewMessage(agent.RoleAssistant, ""),
+				"tool_calls",
+			).WithToolCalls([]agent.ToolCall{
+				agent.NewToolCall("tc-1", "loop_tool", `{}`),
+			})
+		},
+	}
+}
+
+func Test_TaskService_RunTask_With_LoopDetection_Should_FailBeforeMaxIterations(t *testing.T) {
+	// Arrange
+	mockLLM := newLoopingLLM()
+	calls := 0
+	loop := mockLLM.responseFn
+	mockLLM.responseFn = func(messages []agent.Message) agent.LLMResponse {
+		calls++
+		return loop(messages)
+	}
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{result: "loop result"}, &mockEventPublisher{}).
+		WithLoopDetection(3)
+	ag := agent.NewAgent("agent-1", "prompt")
+	ag.SetMaxIterations(10)
+	task := agent.NewTask("task-1", "Loop", "loop")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "result must not be successful", result.Success, false)
+	assert.That(t, "error must name the loop and tool", result.Error, "tool call loop detected: loop_tool repeated in 3 iterations")
+	assert.That(t, "loop must stop after the window", calls, 3)
+}
+
+func Test_TaskService_RunTask_With_LoopDetectionAndChangingResults_Should_NotFail(t *testing.T) {
+	// Arrange
+	callCount := 0
+	mockLLM := &mockLLMClient{
+		responseFn: func(_ []agent.Message) agent.LLMResponse {
+			callCount++
+			if callCount < 4 {
+				return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, ""), "tool_calls").
+					WithToolCalls([]agent.ToolCall{agent.NewToolCall("tc-1", "search", `{"query":"next"}`)})
+			}
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+		},
+	}
+	executor := &countingToolExecutor{}
+	sut := agent.NewTaskService(mockLLM, executor, &mockEventPublisher{}).WithLoopDetection(2)
+	ag := agent.NewAgent("agent-1", "prompt")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, agent.NewTask("task-1", "Paging", "page"))
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "result must be successful", result.Success, true)
+}
+
+// countingToolExecutor returns a different result for every call.
+type countingToolExecutor struct {
+	mockToolExecutor
+	calls int
+}
+
+func (m *countingToolExecutor) Execute(_ context.Context, _ string, _ string) (string, error) {
+	m.calls++
+	return fmt.Sprintf("page %d", m.calls), nil
+}
+
+func Test_TaskService_RunTask_With_TaskMaxIterations_Should_OverrideAgentDefault(t *testing.T) {
+	// Arrange
+	sut := agent.NewTaskService(newLoopingLLM(), &mockToolExecutor{result: "loop result"}, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxIterations(2))
+	task := agent.NewTask("task-1", "Long Task", "loop").WithMaxIterations(5)
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "task must run the per-task number of iterations", result.IterationCount, 5)
+	assert.That(t, "agent default must not change", ag.MaxIterations, 2)
+}
+
+func Test_TaskService_RunTask_Without_TaskMaxIterations_Should_UseAgentDefault(t *testing.T) {
+	// Arrange
+	sut := agent.NewTaskService(newLoopingLLM(), &mockToolExecutor{result: "loop result"}, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxIterations(2))
+	task := agent.NewTask("task-1", "Short Task", "loop")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "task must run the agent's number of iterations", result.IterationCount, 2)
+}
+
+// newTruncatingLLM returns a client that answers in pieces, finishing with
+// "length" until the last piece, which finishes with "stop".
+func newTruncatingLLM(pieces ...string) *mockLLMClient {
+	call := 0
+	return &mockLLMClient{
+		responseFn: func(_ []agent.Message) agent.LLMResponse {
+			piece := pieces[call]
+			call++
+			finishReason := "length"
+			if call == len(pieces) {
+				finishReason = "stop"
+			}
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, piece), finishReason)
+		},
+	}
+}
+
+func Test_TaskService_RunTask_WithContinueOnLength_Should_ConcatenatePieces(t *testing.T) {
+	// Arrange
+	mockLLM := newTruncatingLLM("The quick brown ", "fox jumps.")
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{}, &mockEventPublisher{}).
+		WithContinueOnLength(2)
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Long Answer", "tell me")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "result must be successful", result.Success, true)
+	assert.That(t, "output must be the stitched answer", result.Output, "The quick brown fox jumps.")
+	assert.That(t, "task must take two iterations", result.IterationCount, 2)
+	assert.That(t, "continue prompt must be added", ag.Messages[2].Role, agent.RoleUser)
+}
+
+func Test_TaskService_RunTask_WithContinueOnLength_Should_StopAfterMaxContinues(t *testing.T) {
+	// Arrange
+	mockLLM := newTruncatingLLM("one ", "two ", "three")
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{}, &mockEventPublisher{}).
+		WithContinueOnLength(1)
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Long Answer", "tell me")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "output must contain the allowed pieces", result.Output, "one two ")
+}
+
+// newAnsweringLLM returns a client that answers each call with the next answer, finishing with "stop".
+func newAnsweringLLM(answers ...string) *mockLLMClient {
+	call := 0
+	return &mockLLMClient{
+		responseFn: func(_ []agent.Message) agent.LLMResponse {
+			answer := answers[min(call, len(answers)-1)]
+			call++
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, answer), "stop")
+		},
+	}
+}
+
+func Test_TaskService_RunTask_WithMinIterations_Should_ForceReflection(t *testing.T) {
+	// Arrange
+	mockLLM := newAnsweringLLM("first guess", "verified answer")
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{}, &mockEventPublisher{}).
+		WithMinIterations(2)
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Hard Task", "solve it")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "result must be successful", result.Success, true)
+	assert.That(t, "output must be the reflected answer", result.Output, "verified answer")
+	assert.That(t, "task must take two iterations", result.IterationCount, 2)
+	assert.That(t, "reflection nudge must be a system message", ag.Messages[2].Role, agent.RoleSystem)
+}
+
+func Test_TaskService_RunTask_WithMinIterations_With_ToolCalls_Should_CountTowardMinimum(t *testing.T) {
+	// Arrange
+	call := 0
+	mockLLM := &mockLLMClient{
+		responseFn: func(_ []agent.Message) agent.LLMResponse {
+			call++
+			if call == 1 {
+				return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, ""), "tool_calls").
+					WithToolCalls([]agent.ToolCall{agent.NewToolCall("tc-1", "search", `{}`)})
+			}
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "answer"), "stop")
+		},
+	}
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{result: "found"}, &mockEventPublisher{}).
+		WithMinIterations(2)
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Tool Task", "search it")
+
+	// Act
+	result, _ := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "output must be the answer", result.Output, "answer")
+	assert.That(t, "no reflection must be needed", result.IterationCount, 2)
+}
+
+func Test_TaskService_RunTask_WithMinIterations_AboveMaxIterations_Should_CompleteAtLimit(t *testing.T) {
+	// Arrange
+	mockLLM := newAnsweringLLM("only answer")
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{}, &mockEventPublisher{}).
+		WithMinIterations(5)
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxIterations(2))
+	task := agent.NewTask("task-1", "Capped Task", "solve it")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "result must be successful", result.Success, true)
+	assert.That(t, "task must stop at the iteration limit", result.IterationCount, 2)
+}
+
+func Test_TaskService_RunTask_WithoutContinueOnLength_Should_CompleteOnLength(t *testing.T) {
+	// Arrange
+	mockLLM := newTruncatingLLM("The quick brown ", "fox jumps.")
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{}, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Long Answer", "tell me")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "output must be the truncated answer", result.Output, "The quick brown ")
+	assert.That(t, "task must take one iteration", result.IterationCount, 1)
+}
+
 func Test_TaskService_RunTask_With_LLMError_Should_FailTask(t *testing.T) {
 	// Arrange
 	mockLLM := &mockLLMClient{
@@ -356,3 +696,1206 @@ func Test_TaskService_WithHooks_BeforeToolCall_Should_BeCalled(t *testing.T) {
 	assert.That(t, "beforeToolCall must be called", beforeToolCallCalled, true)
 	assert.That(t, "afterToolCall must be called", afterToolCallCalled, true)
 }
+
+// mockMetricsCollector implements agent.MetricsCollector for testing.
+type mockMetricsCollector struct {
+	toolCalls []recordedToolCall
+	tasks     []recordedTask
+	mu        sync.Mutex
+}
+
+type recordedTask struct {
+	duration   time.Duration
+	iterations int
+	toolCalls  int
+	success    bool
+}
+
+type recordedToolCall struct {
+	err      error
+	name     string
+	duration time.Duration
+}
+
+func (m *mockMetricsCollector) RecordTask(duration time.Duration, iterations, toolCalls int, success bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tasks = append(m.tasks, recordedTask{duration: duration, iterations: iterations, toolCalls: toolCalls, success: success})
+}
+
+func (m *mockMetricsCollector) RecordToolCall(name string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.toolCalls = append(m.toolCalls, recordedToolCall{name: name, duration: duration, err: err})
+}
+
+func Test_TaskService_WithMetrics_Should_RecordTaskAndToolCalls(t *testing.T) {
+	// Arrange
+	callCount := 0
+	mockLLM := &mockLLMClient{
+		responseFn: func(_ []agent.Message) agent.LLMResponse {
+			callCount++
+			if callCount == 1 {
+				return agent.NewLLMResponse(
+					agent.NewMessage(agent.RoleAssistant, ""),
+					"tool_calls",
+				).WithToolCalls([]agent.ToolCall{
+					agent.NewToolCall("tc-1", "search", `{"query":"a"}`),
+					agent.NewToolCall("tc-2", "search", `{"query":"b"}`),
+				})
+			}
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+		},
+	}
+	metrics := &mockMetricsCollector{}
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{result: "ok"}, &mockEventPublisher{}).
+		WithMetrics(metrics)
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Metrics Test", "input")
+
+	// Act
+	_, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "one task must be recorded", len(metrics.tasks), 1)
+	assert.That(t, "task must be successful", metrics.tasks[0].success, true)
+	assert.That(t, "task iterations must match", metrics.tasks[0].iterations, 2)
+	assert.That(t, "task tool calls must match", metrics.tasks[0].toolCalls, 2)
+	assert.That(t, "task duration must be positive", metrics.tasks[0].duration > 0, true)
+	assert.That(t, "two tool calls must be recorded", len(metrics.toolCalls), 2)
+	assert.That(t, "tool name must match", metrics.toolCalls[0].name, "search")
+	assert.That(t, "tool err must be nil", metrics.toolCalls[0].err == nil, true)
+}
+
+func Test_TaskService_WithMetrics_With_FailingTool_Should_RecordToolError(t *testing.T) {
+	// Arrange
+	mockLLM := &mockLLMClient{
+		response: agent.NewLLMResponse(
+			agent.NewMessage(agent.RoleAssistant, ""),
+			"tool_calls",
+		).WithToolCalls([]agent.ToolCall{
+			agent.NewToolCall("tc-1", "loop_tool", `{}`),
+		}),
+	}
+	metrics := &mockMetricsCollector{}
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{err: errors.New("boom")}, &mockEventPublisher{}).
+		WithMetrics(metrics)
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxIterations(2))
+	task := agent.NewTask("task-1", "Metrics Test", "input")
+
+	// Act
+	_, _ = sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "one task must be recorded", len(metrics.tasks), 1)
+	assert.That(t, "task must be failed", metrics.tasks[0].success, false)
+	assert.That(t, "task iterations must match", metrics.tasks[0].iterations, 2)
+	assert.That(t, "two tool calls must be recorded", len(metrics.toolCalls), 2)
+	assert.That(t, "tool err must be recorded", metrics.toolCalls[0].err != nil, true)
+}
+
+// flakyToolExecutor fails a fixed number of times before succeeding.
+type flakyToolExecutor struct {
+	mockToolExecutor
+	err      error
+	failures int
+	attempts int
+}
+
+func (m *flakyToolExecutor) Execute(_ context.Context, _ string, _ string) (string, error) {
+	m.attempts++
+	if m.attempts <= m.failures {
+		if m.err != nil {
+			return "", m.err
+		}
+		return "", errors.New("transient failure")
+	}
+	return "recovered", nil
+}
+
+func singleToolCallLLM() *mockLLMClient {
+	callCount := 0
+	return &mockLLMClient{
+		responseFn: func(_ []agent.Message) agent.LLMResponse {
+			callCount++
+			if callCount == 1 {
+				return agent.NewLLMResponse(
+					agent.NewMessage(agent.RoleAssistant, ""),
+					"tool_calls",
+				).WithToolCalls([]agent.ToolCall{
+					agent.NewToolCall("tc-1", "search", `{"query":"test"}`),
+				})
+			}
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+		},
+	}
+}
+
+func Test_TaskService_WithToolRetry_With_TransientFailures_Should_Succeed(t *testing.T) {
+	// Arrange
+	executor := &flakyToolExecutor{failures: 2}
+	publisher := &mockEventPublisher{}
+	sut := agent.NewTaskService(singleToolCallLLM(), executor, publisher).
+		WithToolRetry(3, time.Millisecond)
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Retry Test", "input")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "result must be successful", result.Success, true)
+	assert.That(t, "tool must be attempted 3 times", executor.attempts, 3)
+	executed := publisher.events[1].(agent.EventToolCallExecuted)
+	assert.That(t, "tool result must be recovered", executed.Result, "recovered")
+	assert.That(t, "tool error must be empty", executed.Error, "")
+}
+
+func Test_TaskService_WithToolRetry_With_PermanentFailure_Should_FailToolCall(t *testing.T) {
+	// Arrange
+	executor := &flakyToolExecutor{failures: 100}
+	publisher := &mockEventPublisher{}
+	sut := agent.NewTaskService(singleToolCallLLM(), executor, publisher).
+		WithToolRetry(3, time.Millisecond)
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Retry Test", "input")
+
+	// Act
+	_, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "tool must be attempted 3 times", executor.attempts, 3)
+	executed := publisher.events[1].(agent.EventToolCallExecuted)
+	assert.That(t, "tool error must be set", executed.Error, "transient failure")
+}
+
+func Test_TaskService_WithToolRetry_With_UserFacingError_Should_NotRetry(t *testing.T) {
+	// Arrange
+	executor := &flakyToolExecutor{
+		err:      agent.NewToolError("search", "query is required", nil).WithUserFacing(),
+		failures: 100,
+	}
+	sut := agent.NewTaskService(singleToolCallLLM(), executor, &mockEventPublisher{}).
+		WithToolRetry(3, time.Millisecond)
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Retry Test", "input")
+
+	// Act
+	_, _ = sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "tool must be attempted once", executor.attempts, 1)
+}
+
+func Test_TaskService_WithToolRetry_With_SystemError_Should_NotRetry(t *testing.T) {
+	// Arrange
+	executor := &flakyToolExecutor{
+		err:      agent.NewToolError("search", "index is corrupt", nil),
+		failures: 100,
+	}
+	sut := agent.NewTaskService(singleToolCallLLM(), executor, &mockEventPublisher{}).
+		WithToolRetry(3, time.Millisecond)
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Retry Test", "input")
+
+	// Act
+	_, _ = sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "tool must be attempted once", executor.attempts, 1)
+}
+
+func Test_TaskService_WithToolRetry_With_RetryableError_Should_Retry(t *testing.T) {
+	// Arrange
+	executor := &flakyToolExecutor{
+		err:      agent.NewToolError("search", "backend unavailable", nil).WithRetryable(),
+		failures: 2,
+	}
+	sut := agent.NewTaskService(singleToolCallLLM(), executor, &mockEventPublisher{}).
+		WithToolRetry(3, time.Millisecond)
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Retry Test", "input")
+
+	// Act
+	result, _ := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "tool must be attempted 3 times", executor.attempts, 3)
+	assert.That(t, "result must be successful", result.Success, true)
+}
+
+func Test_TaskService_WithToolRetry_With_Success_Should_NotRetry(t *testing.T) {
+	// Arrange
+	executor := &flakyToolExecutor{}
+	sut := agent.NewTaskService(singleToolCallLLM(), executor, &mockEventPublisher{}).
+		WithToolRetry(3, time.Millisecond)
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Retry Test", "input")
+
+	// Act
+	_, _ = sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "tool must be attempted once", executor.attempts, 1)
+}
+
+func Test_TaskService_WithToolRetry_With_CanceledContext_Should_StopRetrying(t *testing.T) {
+	// Arrange
+	executor := &flakyToolExecutor{failures: 100}
+	sut := agent.NewTaskService(singleToolCallLLM(), executor, &mockEventPublisher{}).
+		WithToolRetry(5, time.Hour)
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Retry Test", "input")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// Act
+	result, _ := sut.RunTask(ctx, &ag, task)
+
+	// Assert
+	assert.That(t, "tool must be attempted once", executor.attempts, 1)
+	assert.That(t, "result must not be successful", result.Success, false)
+}
+
+func Test_TaskService_RunTask_With_ContextCanceledBeforeToolCall_Should_NotExecuteTool(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	llm := &mockLLMClient{
+		responseFn: func([]agent.Message) agent.LLMResponse {
+			cancel()
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, ""), "tool_calls").
+				WithToolCalls([]agent.ToolCall{agent.NewToolCall("tc-1", "search", `{}`)})
+		},
+	}
+	executor := &flakyToolExecutor{}
+	sut := agent.NewTaskService(llm, executor, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Cancel Test", "input")
+
+	// Act
+	result, _ := sut.RunTask(ctx, &ag, task)
+
+	// Assert
+	assert.That(t, "tool must not be executed", executor.attempts, 0)
+	assert.That(t, "result must not be successful", result.Success, false)
+}
+
+func Test_TaskService_RunTask_With_SeveralTasks_Should_AccumulateAgentTotals(t *testing.T) {
+	// Arrange
+	publisher := &mockEventPublisher{}
+	ag := agent.NewAgent("agent-1", "prompt")
+	ctx := context.Background()
+
+	// Act
+	for i := range 3 {
+		sut := agent.NewTaskService(singleToolCallLLM(), &mockToolExecutor{result: "ok"}, publisher)
+		_, _ = sut.RunTask(ctx, &ag, agent.NewTask(agent.TaskID(fmt.Sprintf("task-%d", i)), "Totals Test", "input"))
+	}
+	failing := agent.NewTaskService(&mockLLMClient{err: errors.New("llm down")}, &mockToolExecutor{}, publisher)
+	_, _ = failing.RunTask(ctx, &ag, agent.NewTask("task-failed", "Totals Test", "input"))
+
+	// Assert
+	assert.That(t, "tasks must be 4", ag.Totals.Tasks, 4)
+	assert.That(t, "tool calls must be 3", ag.Totals.ToolCalls, 3)
+	assert.That(t, "iterations must be 7", ag.Totals.Iterations, 7)
+	assert.That(t, "average iterations must be 1.75", ag.AverageIterationsPerTask(), 1.75)
+}
+
+func Test_TaskService_RunTask_With_ReportedUsage_Should_SumTokensOfEveryLLMCall(t *testing.T) {
+	// Arrange
+	usage := agent.TokenUsage{CompletionTokens: 5, PromptTokens: 20, TotalTokens: 25}
+	llm := singleToolCallLLM()
+	responseFn := llm.responseFn
+	llm.responseFn = func(messages []agent.Message) agent.LLMResponse {
+		return responseFn(messages).WithUsage(usage)
+	}
+	sut := agent.NewTaskService(llm, &mockToolExecutor{result: "ok"}, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "prompt")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, agent.NewTask("task-1", "Usage Test", "input"))
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "tokens must be summed over both calls", result.Tokens, agent.TokenUsage{CompletionTokens: 10, PromptTokens: 40, TotalTokens: 50})
+	assert.That(t, "agent totals must include the tokens", ag.Totals.Tokens, 50)
+}
+
+func Test_TaskService_RunTask_With_TaskHistory_Should_RecordCompletedAndFailedTasks(t *testing.T) {
+	// Arrange
+	publisher := &mockEventPublisher{}
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithTaskHistory(10))
+	ctx := context.Background()
+	succeeding := agent.NewTaskService(singleToolCallLLM(), &mockToolExecutor{result: "ok"}, publisher)
+	failing := agent.NewTaskService(&mockLLMClient{err: errors.New("llm down")}, &mockToolExecutor{}, publisher)
+
+	// Act
+	_, _ = succeeding.RunTask(ctx, &ag, agent.NewTask("task-1", "Succeeds", "input"))
+	_, _ = failing.RunTask(ctx, &ag, agent.NewTask("task-2", "Fails", "input"))
+	history := ag.TaskHistory()
+
+	// Assert
+	assert.That(t, "history must have 2 entries", len(history), 2)
+	assert.That(t, "first task must be completed", history[0].Status, agent.TaskStatusCompleted)
+	assert.That(t, "first task name must match", history[0].Name, "Succeeds")
+	assert.That(t, "first task iterations must be 2", history[0].Iterations, 2)
+	assert.That(t, "second task must be failed", history[1].Status, agent.TaskStatusFailed)
+	assert.That(t, "second task id must match", history[1].ID, agent.TaskID("task-2"))
+}
+
+func Test_TaskService_RunTask_With_SystemSegments_Should_SendSingleCombinedSystemMessage(t *testing.T) {
+	// Arrange
+	var sent []agent.Message
+	mockLLM := &mockLLMClient{
+		responseFn: func(messages []agent.Message) agent.LLMResponse {
+			sent = messages
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+		},
+	}
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{}, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "You are helpful")
+	ag.AddSystemSegment(10, "Relevant memories: none")
+	task := agent.NewTask("task-1", "Segments Test", "input")
+
+	// Act
+	_, _ = sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	systemMessages := 0
+	for _, msg := range sent {
+		if msg.Role == agent.RoleSystem {
+			systemMessages++
+		}
+	}
+	assert.That(t, "must send exactly one system message", systemMessages, 1)
+	assert.That(t, "system message must combine segments", sent[0].Content, "You are helpful\n\nRelevant memories: none")
+	assert.That(t, "segments must be cleared after the task", len(ag.SystemSegments), 0)
+}
+
+func Test_TaskService_RunTask_With_UnknownTool_Should_FailToolCallAndContinue(t *testing.T) {
+	// Arrange
+	var sent []agent.Message
+	callCount := 0
+	mockLLM := &mockLLMClient{
+		responseFn: func(messages []agent.Message) agent.LLMResponse {
+			callCount++
+			sent = messages
+			if callCount == 1 {
+				return agent.NewLLMResponse(
+					agent.NewMessage(agent.RoleAssistant, ""),
+					"tool_calls",
+				).WithToolCalls([]agent.ToolCall{
+					agent.NewToolCall("tc-1", "made_up_tool", `{}`),
+				})
+			}
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "recovered"), "stop")
+		},
+	}
+	executor := &mockToolExecutor{err: fmt.Errorf("%w: made_up_tool (available tools: search)", agent.ErrUnknownTool)}
+	sut := agent.NewTaskService(mockLLM, executor, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Unknown Tool", "input")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "task must complete", result.Success, true)
+	toolMessage := sent[len(sent)-1]
+	assert.That(t, "tool message must carry the error", strings.Contains(toolMessage.Content, "available tools: search"), true)
+}
+
+// blockingToolExecutor blocks the "slow" tool until its context is done.
+type blockingToolExecutor struct {
+	mockToolExecutor
+}
+
+func (m *blockingToolExecutor) Execute(ctx context.Context, toolName string, _ string) (string, error) {
+	if toolName == "slow" {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return toolName + " result", nil
+}
+
+func parallelToolCallsLLM(names ...string) *mockLLMClient {
+	callCount := 0
+	return &mockLLMClient{
+		responseFn: func(_ []agent.Message) agent.LLMResponse {
+			callCount++
+			if callCount == 1 {
+				toolCalls := make([]agent.ToolCall, 0, len(names))
+				for i, name := range names {
+					toolCalls = append(toolCalls, agent.NewToolCall(agent.ToolCallID(fmt.Sprintf("tc-%d", i)), name, `{}`))
+				}
+				return agent.NewLLMResponse(
+					agent.NewMessage(agent.RoleAssistant, ""),
+					"tool_calls",
+				).WithToolCalls(toolCalls)
+			}
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+		},
+	}
+}
+
+func Test_TaskService_WithToolTimeout_Should_TimeOutSingleCallWithoutCancelingSiblings(t *testing.T) {
+	// Arrange
+	publisher := &mockEventPublisher{}
+	sut := agent.NewTaskService(parallelToolCallsLLM("fast", "slow", "other"), &blockingToolExecutor{}, publisher).
+		WithParallelToolExecution().
+		WithToolTimeout("slow", 20*time.Millisecond)
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Timeout Test", "input")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "task must complete", result.Success, true)
+	outcomes := make(map[string]agent.EventToolCallExecuted)
+	for _, e := range publisher.events {
+		if executed, ok := e.(agent.EventToolCallExecuted); ok {
+			outcomes[executed.ToolName] = executed
+		}
+	}
+	assert.That(t, "slow call must time out", outcomes["slow"].Error, context.DeadlineExceeded.Error())
+	assert.That(t, "fast call must succeed", outcomes["fast"].Result, "fast result")
+	assert.That(t, "other call must succeed", outcomes["other"].Result, "other result")
+}
+
+func Test_TaskService_WithParallelToolExecution_With_CanceledContext_Should_CancelAllCalls(t *testing.T) {
+	// Arrange
+	publisher := &mockEventPublisher{}
+	sut := agent.NewTaskService(parallelToolCallsLLM("slow", "slow"), &blockingToolExecutor{}, publisher).
+		WithParallelToolExecution()
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Cancel Test", "input")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// Act
+	result, _ := sut.RunTask(ctx, &ag, task)
+
+	// Assert
+	failed := 0
+	for _, e := range publisher.events {
+		if executed, ok := e.(agent.EventToolCallExecuted); ok && executed.Error != "" {
+			failed++
+		}
+	}
+	assert.That(t, "all calls must be canceled", failed, 2)
+	assert.That(t, "task must fail", result.Success, false)
+}
+
+// windowedLLMClient is a mockLLMClient with a limited context window.
+type windowedLLMClient struct {
+	mockLLMClient
+	contextWindow int
+}
+
+func (m *windowedLLMClient) ContextWindow() int {
+	return m.contextWindow
+}
+
+func oversizedAgent() agent.Agent {
+	ag := agent.NewAgent("agent-1", "prompt")
+	for range 10 {
+		ag.AddMessage(agent.NewMessage(agent.RoleUser, strings.Repeat("x", 400)))
+		ag.AddMessage(agent.NewMessage(agent.RoleAssistant, strings.Repeat("y", 400)))
+	}
+	return ag
+}
+
+func Test_TaskService_RunTask_With_ExceededContextWindow_Should_FailTask(t *testing.T) {
+	// Arrange
+	llm := &windowedLLMClient{
+		mockLLMClient: mockLLMClient{response: agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")},
+		contextWindow: 500,
+	}
+	sut := agent.NewTaskService(llm, &mockToolExecutor{}, &mockEventPublisher{})
+	ag := oversizedAgent()
+	task := agent.NewTask("task-1", "Window Test", "input")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "task must fail", result.Success, false)
+	assert.That(t, "error must mention the context window", strings.HasPrefix(result.Error, agent.ErrContextWindowExceeded.Error()), true)
+	assert.That(t, "error must mention allowed tokens", strings.Contains(result.Error, "allowed 500"), true)
+}
+
+func Test_TaskService_WithContextTrimming_With_ExceededContextWindow_Should_TrimAndSucceed(t *testing.T) {
+	// Arrange
+	var sent []agent.Message
+	llm := &windowedLLMClient{
+		mockLLMClient: mockLLMClient{
+			responseFn: func(messages []agent.Message) agent.LLMResponse {
+				sent = messages
+				return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+			},
+		},
+		contextWindow: 500,
+	}
+	sut := agent.NewTaskService(llm, &mockToolExecutor{}, &mockEventPublisher{}).WithContextTrimming()
+	ag := oversizedAgent()
+	task := agent.NewTask("task-1", "Window Test", "latest input")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "task must succeed", result.Success, true)
+	assert.That(t, "sent messages must fit the window", agent.EstimateTokens(sent) <= 500, true)
+	assert.That(t, "system prompt must be kept", sent[0].Role, agent.RoleSystem)
+	assert.That(t, "latest input must be kept", sent[len(sent)-1].Content, "latest input")
+}
+
+func Test_TaskService_WithContextTrimming_With_ExceededContextWindow_Should_PublishMessagesTrimmed(t *testing.T) {
+	// Arrange
+	llm := &windowedLLMClient{
+		mockLLMClient: mockLLMClient{response: agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")},
+		contextWindow: 500,
+	}
+	publisher := &mockEventPublisher{}
+	sut := agent.NewTaskService(llm, &mockToolExecutor{}, publisher).WithContextTrimming()
+	ag := oversizedAgent()
+	before := len(ag.Messages)
+	task := agent.NewTask("task-1", "Window Test", "latest input")
+
+	// Act
+	_, _ = sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	trimmed := messagesTrimmedEvents(publisher)
+	assert.That(t, "one trimmed event must be published", len(trimmed), 1)
+	assert.That(t, "agent id must be set", trimmed[0].AgentID, "agent-1")
+	assert.That(t, "strategy must be tokens", trimmed[0].Strategy, agent.TrimStrategyTokens)
+	// The input and the response were added, everything else that is missing was trimmed
+	assert.That(t, "removed count must match the dropped messages", trimmed[0].Removed, before+2-len(ag.Messages))
+}
+
+func Test_TaskService_RunTask_With_MaxMessagesExceeded_Should_PublishMessagesTrimmed(t *testing.T) {
+	// Arrange
+	llm := &mockLLMClient{response: agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")}
+	publisher := &mockEventPublisher{}
+	sut := agent.NewTaskService(llm, &mockToolExecutor{}, publisher)
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxMessages(2))
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "first"))
+	ag.AddMessage(agent.NewMessage(agent.RoleAssistant, "second"))
+	task := agent.NewTask("task-1", "Trim Test", "third")
+
+	// Act
+	_, _ = sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	trimmed := messagesTrimmedEvents(publisher)
+	assert.That(t, "trimmed events must be published for the input and the response", len(trimmed), 2)
+	assert.That(t, "strategy must be count", trimmed[0].Strategy, agent.TrimStrategyCount)
+	assert.That(t, "input must remove one message", trimmed[0].Removed, 1)
+	assert.That(t, "response must remove one message", trimmed[1].Removed, 1)
+}
+
+func Test_TaskService_RunTask_With_LimitNotExceeded_Should_NotPublishMessagesTrimmed(t *testing.T) {
+	// Arrange
+	llm := &mockLLMClient{response: agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")}
+	publisher := &mockEventPublisher{}
+	sut := agent.NewTaskService(llm, &mockToolExecutor{}, publisher)
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxMessages(10))
+	task := agent.NewTask("task-1", "Trim Test", "input")
+
+	// Act
+	_, _ = sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "no trimmed event must be published", len(messagesTrimmedEvents(publisher)), 0)
+}
+
+// messagesTrimmedEvents returns the messages trimmed events in publishing order.
+func messagesTrimmedEvents(publisher *mockEventPublisher) []agent.EventMessagesTrimmed {
+	var result []agent.EventMessagesTrimmed
+	for _, e := range publisher.events {
+		if trimmed, ok := e.(agent.EventMessagesTrimmed); ok {
+			result = append(result, trimmed)
+		}
+	}
+	return result
+}
+
+func Test_TaskService_WithContextTrimming_With_PinnedMessages_Should_KeepThem(t *testing.T) {
+	// Arrange
+	var sent []agent.Message
+	llm := &windowedLLMClient{
+		mockLLMClient: mockLLMClient{
+			responseFn: func(messages []agent.Message) agent.LLMResponse {
+				sent = messages
+				return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+			},
+		},
+		contextWindow: 500,
+	}
+	sut := agent.NewTaskService(llm, &mockToolExecutor{}, &mockEventPublisher{}).WithContextTrimming()
+	ag := agent.NewAgent("agent-1", "prompt")
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "my name is Ada").WithPinned())
+	for range 10 {
+		ag.AddMessage(agent.NewMessage(agent.RoleUser, strings.Repeat("x", 400)))
+	}
+	task := agent.NewTask("task-1", "Window Test", "latest input")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "task must succeed", result.Success, true)
+	assert.That(t, "sent messages must fit the window", agent.EstimateTokens(sent) <= 500, true)
+	assert.That(t, "pinned message must follow the system prompt", sent[1].Content, "my name is Ada")
+	assert.That(t, "latest input must be kept", sent[len(sent)-1].Content, "latest input")
+}
+
+func Test_TaskService_WithContextTrimming_With_OnlyPinnedMessages_Should_FailTask(t *testing.T) {
+	// Arrange
+	llm := &windowedLLMClient{
+		mockLLMClient: mockLLMClient{response: agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")},
+		contextWindow: 500,
+	}
+	sut := agent.NewTaskService(llm, &mockToolExecutor{}, &mockEventPublisher{}).WithContextTrimming()
+	ag := agent.NewAgent("agent-1", "prompt")
+	for range 10 {
+		ag.AddMessage(agent.NewMessage(agent.RoleUser, strings.Repeat("x", 400)).WithPinned())
+	}
+	task := agent.NewTask("task-1", "Window Test", "latest input")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "task must fail", result.Success, false)
+	assert.That(t, "pinned messages must be kept", ag.MessageCount() >= 10, true)
+}
+
+func Test_TaskService_RunTask_With_Examples_Should_SendThemAfterSystemPrompt(t *testing.T) {
+	// Arrange
+	var sent []agent.Message
+	llm := &mockLLMClient{
+		responseFn: func(messages []agent.Message) agent.LLMResponse {
+			sent = messages
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+		},
+	}
+	sut := agent.NewTaskService(llm, &mockToolExecutor{}, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithExamples(
+		agent.NewMessage(agent.RoleUser, "example question"),
+		agent.NewMessage(agent.RoleAssistant, "example answer"),
+	))
+	task := agent.NewTask("task-1", "Examples Test", "real question")
+
+	// Act
+	_, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err == nil, true)
+	assert.That(t, "LLM must receive 4 messages", len(sent), 4)
+	assert.That(t, "system prompt must come first", sent[0].Role, agent.RoleSystem)
+	assert.That(t, "example question must come second", sent[1].Content, "example question")
+	assert.That(t, "example answer must come third", sent[2].Content, "example answer")
+	assert.That(t, "real input must come last", sent[3].Content, "real question")
+	assert.That(t, "examples must not be added to the conversation", ag.GetMessages()[0].Content, "real question")
+}
+
+func Test_EstimateTokens_Should_CountContentAndOverhead(t *testing.T) {
+	// Arrange
+	messages := []agent.Message{
+		agent.NewMessage(agent.RoleUser, "12345678"),
+		agent.NewMessage(agent.RoleAssistant, "123"),
+	}
+
+	// Act
+	tokens := agent.EstimateTokens(messages)
+
+	// Assert
+	assert.That(t, "tokens must be 2+4 and 1+4", tokens, 11)
+}
+
+func Test_TaskService_WithCandidateSelector_Should_ContinueWithSelectedCandidate(t *testing.T) {
+	// Arrange
+	callCount := 0
+	mockLLM := &mockLLMClient{
+		responseFn: func(_ []agent.Message) agent.LLMResponse {
+			callCount++
+			if callCount > 1 {
+				return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+			}
+			candidates := []agent.Message{
+				agent.NewMessage(agent.RoleAssistant, "guess"),
+				agent.NewMessage(agent.RoleAssistant, "").WithToolCalls([]agent.ToolCall{
+					agent.NewToolCall("tc-1", "search", `{"query":"test"}`),
+				}),
+			}
+			return agent.NewLLMResponse(candidates[0], "stop").WithCandidates(candidates)
+		},
+	}
+	executor := &mockToolExecutor{result: "found"}
+	publisher := &mockEventPublisher{}
+	var received int
+	sut := agent.NewTaskService(mockLLM, executor, publisher).
+		WithCandidateSelector(func(candidates []agent.Message) int {
+			received = len(candidates)
+			for i, candidate := range candidates {
+				if len(candidate.ToolCalls) > 0 {
+					return i
+				}
+			}
+			return 0
+		})
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Candidates", "input")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "selector must receive all candidates", received, 2)
+	assert.That(t, "selected tool call must be executed", executor.called, true)
+	assert.That(t, "tool call count must be 1", result.ToolCallCount, 1)
+	assert.That(t, "output must come from the follow-up call", result.Output, "done")
+}
+
+func Test_TaskService_WithCandidateSelector_With_OutOfRangeIndex_Should_KeepFirstCandidate(t *testing.T) {
+	// Arrange
+	candidates := []agent.Message{
+		agent.NewMessage(agent.RoleAssistant, "first"),
+		agent.NewMessage(agent.RoleAssistant, "second"),
+	}
+	mockLLM := &mockLLMClient{
+		response: agent.NewLLMResponse(candidates[0], "stop").WithCandidates(candidates),
+	}
+	publisher := &mockEventPublisher{}
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{}, publisher).
+		WithCandidateSelector(func(_ []agent.Message) int { return 5 })
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Candidates", "input")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "output must be the first candidate", result.Output, "first")
+}
+
+// recordingToolExecutor records the order in which tools are executed.
+type recordingToolExecutor struct {
+	mockToolExecutor
+	failing string
+	order   []string
+}
+
+func (m *recordingToolExecutor) Execute(_ context.Context, toolName string, _ string) (string, error) {
+	m.order = append(m.order, toolName)
+	if toolName == m.failing {
+		return "", errors.New("tool failed")
+	}
+	return toolName + " result", nil
+}
+
+func Test_TaskService_WithTerminalTool_Should_CompleteWithToolResult(t *testing.T) {
+	// Arrange
+	executor := &recordingToolExecutor{}
+	publisher := &mockEventPublisher{}
+	sut := agent.NewTaskService(parallelToolCallsLLM("search", "finish", "write"), executor, publisher).
+		WithTerminalTool("finish")
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Terminal Test", "input")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "task must complete", result.Success, true)
+	assert.That(t, "output must be the terminal tool result", result.Output, "finish result")
+	assert.That(t, "final LLM round must be skipped", result.IterationCount, 1)
+	assert.That(t, "all tool calls must be counted", result.ToolCallCount, 3)
+	assert.That(t, "non-terminal tools must execute first", executor.order, []string{"search", "write", "finish"})
+}
+
+func Test_TaskService_WithTerminalTool_With_ParallelExecution_Should_CompleteWithToolResult(t *testing.T) {
+	// Arrange
+	executor := &blockingToolExecutor{}
+	publisher := &mockEventPublisher{}
+	sut := agent.NewTaskService(parallelToolCallsLLM("search", "finish", "write"), executor, publisher).
+		WithParallelToolExecution().
+		WithTerminalTool("finish")
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Terminal Test", "input")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "output must be the terminal tool result", result.Output, "finish result")
+	assert.That(t, "final LLM round must be skipped", result.IterationCount, 1)
+	assert.That(t, "all tool calls must be counted", result.ToolCallCount, 3)
+}
+
+func Test_TaskService_WithTerminalTool_With_FailingTool_Should_ContinueLoop(t *testing.T) {
+	// Arrange
+	executor := &recordingToolExecutor{failing: "finish"}
+	publisher := &mockEventPublisher{}
+	sut := agent.NewTaskService(parallelToolCallsLLM("finish"), executor, publisher).
+		WithTerminalTool("finish")
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Terminal Test", "input")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "output must come from the next LLM round", result.Output, "done")
+	assert.That(t, "loop must run another iteration", result.IterationCount, 2)
+}
+
+func Test_TaskService_RunTask_With_DeveloperMessage_Should_KeepSystemPromptFirst(t *testing.T) {
+	// Arrange
+	var sent []agent.Message
+	mockLLM := &mockLLMClient{
+		responseFn: func(messages []agent.Message) agent.LLMResponse {
+			sent = messages
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+		},
+	}
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{}, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "You are helpful.")
+	ag.AddMessage(agent.NewMessage(agent.RoleDeveloper, "Answer in JSON."))
+	task := agent.NewTask("task-1", "Developer Test", "input")
+
+	// Act
+	_, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "system prompt must come first", sent[0], agent.NewMessage(agent.RoleSystem, "You are helpful.").WithCacheControl())
+	assert.That(t, "developer message must be kept", sent[1].Role, agent.RoleDeveloper)
+}
+
+// definitionsToolExecutor reports the tool definitions it currently holds.
+type definitionsToolExecutor struct {
+	mockToolExecutor
+	definitions []agent.ToolDefinition
+}
+
+func (m *definitionsToolExecutor) GetToolDefinitions() []agent.ToolDefinition {
+	return m.definitions
+}
+
+func Test_TaskService_RunTask_With_ToolDescriptionsInPrompt_Should_ListToolsInSystemPrompt(t *testing.T) {
+	// Arrange
+	var sent []agent.Message
+	mockLLM := &mockLLMClient{
+		responseFn: func(messages []agent.Message) agent.LLMResponse {
+			sent = messages
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+		},
+	}
+	executor := &mockToolExecutor{}
+	sut := agent.NewTaskService(mockLLM, executor, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "You are helpful.", agent.WithToolDescriptionsInPrompt(executor))
+
+	// Act
+	_, err := sut.RunTask(context.Background(), &ag, agent.NewTask("task-1", "Tools", "input"))
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "system prompt must list the tools", sent[0].Content,
+		"You are helpful.\n\nAvailable tools:\n- search: Search for items\n- loop_tool: A tool that loops")
+}
+
+func Test_TaskService_RunTask_With_ToolDescriptionsInPrompt_Should_FollowToolChanges(t *testing.T) {
+	// Arrange
+	var sent []agent.Message
+	mockLLM := &mockLLMClient{
+		responseFn: func(messages []agent.Message) agent.LLMResponse {
+			sent = messages
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+		},
+	}
+	executor := &definitionsToolExecutor{definitions: []agent.ToolDefinition{agent.NewToolDefinition("search", "Search for items")}}
+	sut := agent.NewTaskService(mockLLM, executor, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "You are helpful.", agent.WithToolDescriptionsInPrompt(executor))
+	_, _ = sut.RunTask(context.Background(), &ag, agent.NewTask("task-1", "Before", "input"))
+	executor.definitions = append(executor.definitions, agent.NewToolDefinition("fetch", "Fetch a URL"))
+
+	// Act
+	_, err := sut.RunTask(context.Background(), &ag, agent.NewTask("task-2", "After", "input"))
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "system prompt must list the new tool", strings.Contains(sent[0].Content, "- fetch: Fetch a URL"), true)
+	assert.That(t, "system prompt must keep the old tool", strings.Contains(sent[0].Content, "- search: Search for items"), true)
+}
+
+func Test_TaskService_RunTask_Without_ToolDescriptionsInPrompt_Should_KeepSystemPrompt(t *testing.T) {
+	// Arrange
+	var sent []agent.Message
+	mockLLM := &mockLLMClient{
+		responseFn: func(messages []agent.Message) agent.LLMResponse {
+			sent = messages
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+		},
+	}
+	sut := agent.NewTaskService(mockLLM, &mockToolExecutor{}, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "You are helpful.")
+
+	// Act
+	_, err := sut.RunTask(context.Background(), &ag, agent.NewTask("task-1", "Tools", "input"))
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "system prompt must be unchanged", sent[0].Content, "You are helpful.")
+}
+
+// scriptedToolExecutor returns "<name> result" for every tool except the failing one.
+type scriptedToolExecutor struct {
+	mockToolExecutor
+	failing string
+}
+
+func (m *scriptedToolExecutor) Execute(_ context.Context, name string, _ string) (string, error) {
+	if name == m.failing {
+		return "", errors.New(name + " failed")
+	}
+	return name + " result", nil
+}
+
+func Test_TaskService_RunTask_With_MultipleToolCalls_Should_RecordTrace(t *testing.T) {
+	// Arrange
+	callCount := 0
+	mockLLM := &mockLLMClient{
+		responseFn: func(_ []agent.Message) agent.LLMResponse {
+			callCount++
+			switch callCount {
+			case 1:
+				return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, ""), "tool_calls").
+					WithToolCalls([]agent.ToolCall{
+						agent.NewToolCall("tc-1", "search", `{"query":"go"}`),
+						agent.NewToolCall("tc-2", "loop_tool", `{}`),
+					})
+			case 2:
+				return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, ""), "tool_calls").
+					WithToolCalls([]agent.ToolCall{
+						agent.NewToolCall("tc-3", "search", `{"query":"agents"}`),
+					})
+			default:
+				return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+			}
+		},
+	}
+	sut := agent.NewTaskService(mockLLM, &scriptedToolExecutor{failing: "loop_tool"}, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithTaskHistory(1))
+	task := agent.NewTask("task-1", "Trace Test", "input")
+
+	// Act
+	_, _ = sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	trace := task.ToolCalls
+	assert.That(t, "three tool calls must be recorded", len(trace), 3)
+	assert.That(t, "first call must be in iteration 1", trace[0].Iteration, 1)
+	assert.That(t, "first call name must match", trace[0].Name, "search")
+	assert.That(t, "first call arguments must match", trace[0].Arguments, `{"query":"go"}`)
+	assert.That(t, "first call result must match", trace[0].Result, "search result")
+	assert.That(t, "first call must be completed", trace[0].Status, agent.ToolCallStatusCompleted)
+	assert.That(t, "second call must be in iteration 1", trace[1].Iteration, 1)
+	assert.That(t, "second call must be failed", trace[1].Status, agent.ToolCallStatusFailed)
+	assert.That(t, "second call error must match", trace[1].Error, "loop_tool failed")
+	assert.That(t, "third call must be in iteration 2", trace[2].Iteration, 2)
+	assert.That(t, "third call arguments must match", trace[2].Arguments, `{"query":"agents"}`)
+	assert.That(t, "history must retain the trace", ag.TaskHistory()[0].ToolCalls, trace)
+}
+
+// validatingToolExecutor validates the arguments against its tool definitions before executing.
+type validatingToolExecutor struct {
+	mockToolExecutor
+}
+
+func (m *validatingToolExecutor) Execute(_ context.Context, name string, arguments string) (string, error) {
+	for _, def := range m.GetToolDefinitions() {
+		if def.Name == name {
+			if err := agent.ValidateArgs(def, arguments); err != nil {
+				return "", err
+			}
+		}
+	}
+	return name + " result", nil
+}
+
+// fixArgumentsLLM requests search with a wrong argument type, then fixes it after the validation error.
+func fixArgumentsLLM(sent *[][]agent.Message) *mockLLMClient {
+	return &mockLLMClient{
+		responseFn: func(messages []agent.Message) agent.LLMResponse {
+			*sent = append(*sent, messages)
+			switch len(*sent) {
+			case 1:
+				return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, ""), "tool_calls").
+					WithToolCalls([]agent.ToolCall{agent.NewToolCall("tc-1", "search", `{"query":42}`)})
+			case 2:
+				return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, ""), "tool_calls").
+					WithToolCalls([]agent.ToolCall{agent.NewToolCall("tc-2", "search", `{"query":"go"}`)})
+			default:
+				return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop")
+			}
+		},
+	}
+}
+
+func Test_TaskService_RunTask_With_ValidationRetry_Should_LetModelFixArguments(t *testing.T) {
+	// Arrange
+	var sent [][]agent.Message
+	sut := agent.NewTaskService(fixArgumentsLLM(&sent), &validatingToolExecutor{}, &mockEventPublisher{}).
+		WithValidationRetry(1)
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxIterations(2))
+	task := agent.NewTask("task-1", "Validation Test", "input")
+
+	// Act
+	result, err := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	feedback := sent[1][len(sent[1])-1]
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "task must succeed", result.Success, true)
+	assert.That(t, "output must match", result.Output, "done")
+	assert.That(t, "validation error must be fed back", strings.HasPrefix(feedback.Content, "Invalid arguments: validation failed for tool search"), true)
+	assert.That(t, "failed call must not count as tool call", result.ToolCallCount, 1)
+	assert.That(t, "refunded iteration must not be counted", result.IterationCount, 2)
+	assert.That(t, "task iterations must match the result", task.Iterations, 2)
+}
+
+func Test_TaskService_RunTask_With_ValidationRetry_And_MaxIterationsReached_Should_NotCountRefundedIteration(t *testing.T) {
+	// Arrange
+	llm := &mockLLMClient{
+		responseFn: func([]agent.Message) agent.LLMResponse {
+			return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, ""), "tool_calls").
+				WithToolCalls([]agent.ToolCall{agent.NewToolCall("tc-1", "search", `{"query":42}`)})
+		},
+	}
+	sut := agent.NewTaskService(llm, &validatingToolExecutor{}, &mockEventPublisher{}).
+		WithValidationRetry(1)
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxIterations(2))
+	task := agent.NewTask("task-1", "Validation Test", "input")
+
+	// Act
+	result, _ := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "error must be max iterations", result.Error, agent.ErrMaxIterationsReached.Error())
+	assert.That(t, "refunded iteration must not be counted", result.IterationCount, 2)
+	assert.That(t, "task iterations must match the result", task.Iterations, 2)
+}
+
+func Test_TaskService_RunTask_Without_ValidationRetry_Should_UseIterationBudget(t *testing.T) {
+	// Arrange
+	var sent [][]agent.Message
+	sut := agent.NewTaskService(fixArgumentsLLM(&sent), &validatingToolExecutor{}, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxIterations(2))
+	task := agent.NewTask("task-1", "Validation Test", "input")
+
+	// Act
+	result, _ := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "task must fail", result.Success, false)
+	assert.That(t, "error must be max iterations", result.Error, agent.ErrMaxIterationsReached.Error())
+}
+
+func Test_TaskService_RunTask_With_ValidationRetry_And_UserFacingError_Should_UseIterationBudget(t *testing.T) {
+	// Arrange
+	var sent [][]agent.Message
+	executor := &flakyToolExecutor{
+		err:      agent.NewToolError("search", "confirm must be true", nil).WithUserFacing(),
+		failures: 1,
+	}
+	sut := agent.NewTaskService(fixArgumentsLLM(&sent), executor, &mockEventPublisher{}).
+		WithValidationRetry(1)
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithMaxIterations(2))
+	task := agent.NewTask("task-1", "Validation Test", "input")
+
+	// Act
+	result, _ := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "task must fail", result.Success, false)
+	assert.That(t, "error must be max iterations", result.Error, agent.ErrMaxIterationsReached.Error())
+}
+
+func Test_TaskService_CollectToolCallResults_With_DroppedResult_Should_ReportMissingCall(t *testing.T) {
+	// Arrange
+	var logs strings.Builder
+	sut := agent.NewTaskService(&mockLLMClient{}, &mockToolExecutor{}, &mockEventPublisher{}).
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	ag := agent.NewAgent("agent-1", "prompt")
+	toolCalls := []agent.ToolCall{
+		agent.NewToolCall("tc-1", "search", `{}`),
+		agent.NewToolCall("tc-2", "search", `{}`),
+		agent.NewToolCall("tc-3", "search", `{}`),
+	}
+	toolCalls[0].Complete("first")
+	toolCalls[2].Complete("third")
+
+	// Act
+	count := sut.CollectToolCallResults(context.Background(), &ag, toolCalls, []int{2, 0, 0})
+
+	// Assert
+	assert.That(t, "every tool call must produce a message", count, 3)
+	assert.That(t, "messages must keep the original order", []agent.ToolCallID{
+		ag.Messages[0].ToolCallID, ag.Messages[1].ToolCallID, ag.Messages[2].ToolCallID,
+	}, []agent.ToolCallID{"tc-1", "tc-2", "tc-3"})
+	assert.That(t, "missing call must be failed", ag.Messages[1].Content, "Error: "+agent.ErrToolResultMissing.Error())
+	assert.That(t, "missing result must be logged", strings.Contains(logs.String(), "tool call result missing"), true)
+	assert.That(t, "duplicate result must be logged", strings.Contains(logs.String(), "duplicate tool call result"), true)
+}
+
+// choiceRecordingLLMClient records the tool choice override of each call.
+// It requests a tool call first and answers with text afterwards.
+type choiceRecordingLLMClient struct {
+	choices []agent.ToolChoice
+}
+
+func (m *choiceRecordingLLMClient) Run(ctx context.Context, _ []agent.Message, _ []agent.ToolDefinition) (agent.LLMResponse, error) {
+	choice, _ := agent.ToolChoiceFromContext(ctx)
+	m.choices = append(m.choices, choice)
+	if len(m.choices) == 1 {
+		return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, ""), "tool_calls").
+			WithToolCalls([]agent.ToolCall{agent.NewToolCall("tc-1", "search", `{}`)}), nil
+	}
+	return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, "done"), "stop"), nil
+}
+
+func Test_TaskService_RunTask_With_ToolChoice_Should_OverrideFirstIterationOnly(t *testing.T) {
+	// Arrange
+	llm := &choiceRecordingLLMClient{}
+	sut := agent.NewTaskService(llm, &mockToolExecutor{result: "found"}, &mockEventPublisher{})
+	ag := agent.NewAgent("agent-1", "prompt")
+	task := agent.NewTask("task-1", "Forced", "input").WithToolChoice(agent.NewNamedToolChoice("search"))
+
+	// Act
+	result, _ := sut.RunTask(context.Background(), &ag, task)
+
+	// Assert
+	assert.That(t, "task must succeed", result.Success, true)
+	assert.That(t, "LLM must be called twice", len(llm.choices), 2)
+	assert.That(t, "first call must carry the override", llm.choices[0], agent.NewNamedToolChoice("search"))
+	assert.That(t, "second call must use the default", llm.choices[1].IsZero(), true)
+}
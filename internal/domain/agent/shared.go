@@ -41,6 +41,15 @@ type TokenUsage struct {
 	TotalTokens      int // Total tokens used
 }
 
+// Add returns the sum of both usages.
+func (u TokenUsage) Add(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+	}
+}
+
 // NewResult creates a new Result for the given task.
 func NewResult(taskID TaskID, success bool, output string) Result {
 	return Result{
@@ -80,6 +89,15 @@ func (r Result) WithToolCallCount(count int) Result {
 	return r
 }
 
+// ContentPartType identifies the kind of a multi-modal message part.
+type ContentPartType string
+
+// Content part types (alphabetically sorted).
+const (
+	ContentPartTypeImageURL ContentPartType = "image_url" // Image referenced by URL or data URL
+	ContentPartTypeText     ContentPartType = "text"      // Plain text
+)
+
 // Role represents the role of a message in a conversation.
 // It follows the OpenAI chat completion API role convention.
 type Role string
@@ -87,11 +105,23 @@ type Role string
 // Standard conversation roles for LLM chat completions (alphabetically sorted).
 const (
 	RoleAssistant Role = "assistant" // LLM response
+	RoleDeveloper Role = "developer" // Developer instructions (reasoning models); falls back to system
 	RoleSystem    Role = "system"    // System instructions/prompt
 	RoleTool      Role = "tool"      // Tool execution result
 	RoleUser      Role = "user"      // Human input
 )
 
+// TagMatch selects how MemorySearchOptions.Tags are compared against note tags.
+type TagMatch string
+
+// Tag matching modes (alphabetically sorted).
+// The zero value behaves like TagMatchExact.
+const (
+	TagMatchExact  TagMatch = "exact"  // Tag equals the filter
+	TagMatchGlob   TagMatch = "glob"   // Tag matches the filter as a shell pattern (*, ?, [...])
+	TagMatchPrefix TagMatch = "prefix" // Tag starts with the filter; a trailing * is ignored
+)
+
 // TaskStatus represents the lifecycle state of a task.
 // Tasks transition: Pending → InProgress → Completed/Failed.
 type TaskStatus string
@@ -116,6 +146,16 @@ const (
 	ToolCallStatusPending   ToolCallStatus = "pending"   // Queued for execution
 )
 
+// ToolErrorKind classifies why a tool call failed, so the model can decide how to react.
+type ToolErrorKind string
+
+// Tool error kinds (alphabetically sorted).
+const (
+	ToolErrorKindSystem    ToolErrorKind = "system"    // Backend failure, retrying will not help
+	ToolErrorKindTemporary ToolErrorKind = "temporary" // Transient failure, a retry may succeed
+	ToolErrorKindUser      ToolErrorKind = "user"      // Invalid arguments the model can fix
+)
+
 // Tool represents a complete tool aggregate with its function and definition.
 // Use this to bundle a tool's implementation with its LLM-facing definition.
 type Tool struct {
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// stateVersion is the version of the serialized agent state.
+// It is incremented when the layout changes incompatibly.
+const stateVersion = 1
+
+// agentState is the serialized form of an agent's session: the conversation and its statistics.
+// Configuration (ID, system prompt, examples, limits, metadata) is not part of the state,
+// so a restored session always uses the configuration of the agent it is loaded into.
+type agentState struct {
+	Messages []Message     `json:"messages"`
+	Tasks    []*Task       `json:"tasks,omitempty"`
+	History  []TaskSummary `json:"history,omitempty"`
+	Totals   TaskTotals    `json:"totals"`
+	Version  int           `json:"version"`
+}
+
+// MarshalState serializes the conversation, tasks, task history, and totals of the agent,
+// e.g. to persist a session across restarts. Use UnmarshalState to restore it.
+func (a *Agent) MarshalState() ([]byte, error) {
+	return json.Marshal(agentState{
+		History:  a.TaskHistory(),
+		Messages: a.Messages,
+		Tasks:    a.Tasks,
+		Totals:   a.Totals,
+		Version:  stateVersion,
+	})
+}
+
+// UnmarshalState restores a state created by MarshalState.
+// The agent is left unchanged if the data is invalid.
+// The task history is cut to the agent's WithTaskHistory capacity.
+func (a *Agent) UnmarshalState(data []byte) error {
+	var state agentState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidState, err)
+	}
+	if state.Version != stateVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidState, state.Version)
+	}
+
+	a.Messages = state.Messages
+	if a.Messages == nil {
+		a.Messages = make([]Message, 0)
+	}
+	a.Tasks = state.Tasks
+	if a.Tasks == nil {
+		a.Tasks = make([]*Task, 0)
+	}
+	a.Totals = state.Totals
+	if a.historyMax > 0 {
+		history := state.History[max(len(state.History)-a.historyMax, 0):]
+		a.history = append(make([]TaskSummary, 0, a.historyMax), history...)
+		a.historyNext = 0
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package agent_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+)
+
+// newAgentWithSession creates an agent with two finished tasks and a conversation.
+func newAgentWithSession() agent.Agent {
+	ag := agent.NewAgent("agent-1", "prompt", agent.WithTaskHistory(5))
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "My name is Alice.").WithPinned())
+	ag.AddMessage(agent.NewMessage(agent.RoleAssistant, "Hi Alice!"))
+	for _, status := range []agent.TaskStatus{agent.TaskStatusCompleted, agent.TaskStatusFailed} {
+		task := agent.NewTask(agent.TaskID("task-"+string(status)), "chat", "input")
+		task.Status = status
+		ag.AddTask(task)
+		ag.RecordTask(task)
+		ag.RecordResult(agent.NewResult(task.ID, status == agent.TaskStatusCompleted, "").
+			WithIterationCount(2).
+			WithTokens(agent.TokenUsage{TotalTokens: 50}).
+			WithToolCallCount(1))
+	}
+	return ag
+}
+
+func Test_Agent_UnmarshalState_With_MarshaledState_Should_RestoreSession(t *testing.T) {
+	// Arrange
+	original := newAgentWithSession()
+	data, _ := original.MarshalState()
+	restored := agent.NewAgent("agent-1", "prompt", agent.WithTaskHistory(5))
+
+	// Act
+	err := restored.UnmarshalState(data)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "messages must be restored", restored.MessageCount(), 2)
+	assert.That(t, "pinned flag must be restored", restored.Messages[0].Pinned, true)
+	assert.That(t, "tasks must be restored", restored.TaskCount(), 2)
+	assert.That(t, "completed tasks must be restored", restored.CompletedTaskCount(), 1)
+	assert.That(t, "failed tasks must be restored", restored.FailedTaskCount(), 1)
+	assert.That(t, "totals must be restored", restored.Totals, original.Totals)
+	assert.That(t, "task history must be restored", restored.TaskHistory(), original.TaskHistory())
+}
+
+func Test_Agent_UnmarshalState_With_SmallerHistory_Should_KeepNewestSummaries(t *testing.T) {
+	// Arrange
+	original := newAgentWithSession()
+	data, _ := original.MarshalState()
+	restored := agent.NewAgent("agent-1", "prompt", agent.WithTaskHistory(1))
+
+	// Act
+	_ = restored.UnmarshalState(data)
+
+	// Assert
+	history := restored.TaskHistory()
+	assert.That(t, "history must be cut to capacity", len(history), 1)
+	assert.That(t, "newest summary must be kept", history[0].ID, agent.TaskID("task-failed"))
+}
+
+func Test_Agent_UnmarshalState_With_CorruptData_Should_ReturnErrInvalidState(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt")
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "keep me"))
+
+	// Act
+	err := ag.UnmarshalState([]byte(`{"messages": [`))
+
+	// Assert
+	assert.That(t, "error must be ErrInvalidState", errors.Is(err, agent.ErrInvalidState), true)
+	assert.That(t, "agent must be unchanged", ag.MessageCount(), 1)
+}
+
+func Test_Agent_UnmarshalState_With_UnknownVersion_Should_ReturnErrInvalidState(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("agent-1", "prompt")
+
+	// Act
+	err := ag.UnmarshalState([]byte(`{"version": 99, "messages": []}`))
+
+	// Assert
+	assert.That(t, "error must be ErrInvalidState", errors.Is(err, agent.ErrInvalidState), true)
+}
@@ -5,16 +5,35 @@ import "time"
 // Task represents a unit of work for the agent to execute.
 // It has a defined lifecycle: Pending → Running → Completed/Failed.
 type Task struct {
-	CompletedAt time.Time
-	CreatedAt   time.Time
-	StartedAt   time.Time
-	Error       string
-	Input       string
-	Name        string
-	Output      string
-	ID          TaskID
-	Status      TaskStatus
-	Iterations  int
+	CompletedAt   time.Time
+	CreatedAt     time.Time
+	StartedAt     time.Time
+	Error         string
+	Input         string
+	Name          string
+	Output        string
+	ID            TaskID
+	Status        TaskStatus
+	ToolChoice    ToolChoice       // Overrides the LLM client's tool choice for the first iteration
+	ToolCalls     []ToolCallRecord // Tool calls executed so far, in execution order
+	Iterations    int
+	MaxIterations int
+}
+
+// ToolCallRecord is a tool call executed during a task, including its arguments and outcome.
+type ToolCallRecord struct {
+	ToolCall
+	Iteration int // Task iteration in which the call was made (1-based)
+}
+
+// TaskSummary is a compact record of a finished task kept in the agent's task history.
+type TaskSummary struct {
+	Name       string
+	ID         TaskID
+	Status     TaskStatus
+	ToolCalls  []ToolCallRecord
+	Duration   time.Duration
+	Iterations int
 }
 
 // NewTask creates a new Task with the given ID, name, and input.
@@ -28,6 +47,21 @@ func NewTask(id TaskID, name string, input string) *Task {
 	}
 }
 
+// WithMaxIterations overrides the agent's maximum iterations for this task only.
+// Zero keeps the agent's default.
+func (t *Task) WithMaxIterations(maxIter int) *Task {
+	t.MaxIterations = maxIter
+	return t
+}
+
+// WithToolChoice overrides the LLM client's tool choice for this task.
+// It applies to the first iteration only, so a forced tool call is not repeated
+// in every iteration; later iterations use the client's default.
+func (t *Task) WithToolChoice(choice ToolChoice) *Task {
+	t.ToolChoice = choice
+	return t
+}
+
 // Complete marks the task as successfully completed with the given output.
 func (t *Task) Complete(output string) {
 	t.CompletedAt = time.Now()
@@ -64,6 +98,26 @@ func (t *Task) IsTerminal() bool {
 	return t.Status == TaskStatusCompleted || t.Status == TaskStatusFailed
 }
 
+// RecordToolCalls appends the executed tool calls to the task's trace,
+// attributing them to the current iteration.
+func (t *Task) RecordToolCalls(toolCalls []ToolCall) {
+	for _, tc := range toolCalls {
+		t.ToolCalls = append(t.ToolCalls, ToolCallRecord{Iteration: t.Iterations, ToolCall: tc})
+	}
+}
+
+// Summary returns a compact summary of the task.
+func (t *Task) Summary() TaskSummary {
+	return TaskSummary{
+		Duration:   t.Duration(),
+		ID:         t.ID,
+		Iterations: t.Iterations,
+		Name:       t.Name,
+		Status:     t.Status,
+		ToolCalls:  t.ToolCalls,
+	}
+}
+
 // Start marks the task as running.
 func (t *Task) Start() {
 	t.StartedAt = time.Now()
@@ -0,0 +1,50 @@
+package agent
+
+import "context"
+
+// ToolChoiceMode controls whether the LLM may, must, or must not call tools.
+type ToolChoiceMode string
+
+// Supported tool choice modes (alphabetically sorted).
+const (
+	ToolChoiceAuto     ToolChoiceMode = "auto"     // The LLM decides whether to call tools
+	ToolChoiceFunction ToolChoiceMode = "function" // The LLM must call the named tool
+	ToolChoiceNone     ToolChoiceMode = "none"     // The LLM must not call tools
+	ToolChoiceRequired ToolChoiceMode = "required" // The LLM must call at least one tool
+)
+
+// ToolChoice tells the LLM how to use the tools it is offered.
+// The zero value leaves the choice to the LLM client's default.
+type ToolChoice struct {
+	Mode ToolChoiceMode
+	Name string // Tool to call if Mode is ToolChoiceFunction
+}
+
+// NewToolChoice creates a ToolChoice with the given mode.
+func NewToolChoice(mode ToolChoiceMode) ToolChoice {
+	return ToolChoice{Mode: mode}
+}
+
+// NewNamedToolChoice creates a ToolChoice that forces a call of the named tool.
+func NewNamedToolChoice(name string) ToolChoice {
+	return ToolChoice{Mode: ToolChoiceFunction, Name: name}
+}
+
+// IsZero returns true if no tool choice is set.
+func (c ToolChoice) IsZero() bool {
+	return c.Mode == ""
+}
+
+// toolChoiceKey is the context key of a tool choice override.
+type toolChoiceKey struct{}
+
+// ContextWithToolChoice returns a context that overrides the LLM client's tool choice for calls made with it.
+func ContextWithToolChoice(ctx context.Context, choice ToolChoice) context.Context {
+	return context.WithValue(ctx, toolChoiceKey{}, choice)
+}
+
+// ToolChoiceFromContext returns the tool choice override of the context, if any.
+func ToolChoiceFromContext(ctx context.Context) (ToolChoice, bool) {
+	choice, ok := ctx.Value(toolChoiceKey{}).(ToolChoice)
+	return choice, ok && !choice.IsZero()
+}
@@ -0,0 +1,41 @@
+package agent_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+)
+
+func Test_ToolChoiceFromContext_With_Override_Should_ReturnChoice(t *testing.T) {
+	// Arrange
+	ctx := agent.ContextWithToolChoice(context.Background(), agent.NewToolChoice(agent.ToolChoiceNone))
+
+	// Act
+	choice, ok := agent.ToolChoiceFromContext(ctx)
+
+	// Assert
+	assert.That(t, "override must be found", ok, true)
+	assert.That(t, "mode must match", choice.Mode, agent.ToolChoiceNone)
+}
+
+func Test_ToolChoiceFromContext_With_ZeroChoice_Should_ReturnFalse(t *testing.T) {
+	// Arrange
+	ctx := agent.ContextWithToolChoice(context.Background(), agent.ToolChoice{})
+
+	// Act
+	_, ok := agent.ToolChoiceFromContext(ctx)
+
+	// Assert
+	assert.That(t, "zero choice must not count as override", ok, false)
+}
+
+func Test_NewNamedToolChoice_Should_SetFunctionMode(t *testing.T) {
+	// Arrange & Act
+	choice := agent.NewNamedToolChoice("search")
+
+	// Assert
+	assert.That(t, "mode must be function", choice.Mode, agent.ToolChoiceFunction)
+	assert.That(t, "name must match", choice.Name, "search")
+}
@@ -3,6 +3,7 @@ package agent
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/andygeiss/cloud-native-utils/slices"
@@ -26,6 +27,7 @@ type ParameterDefinition struct {
 	Default     string
 	Description string
 	Name        string
+	Items       ParameterType // Element type of array parameters
 	Type        ParameterType
 	Enum        []string
 	Required    bool
@@ -57,6 +59,12 @@ func (p ParameterDefinition) WithEnum(values ...string) ParameterDefinition {
 	return p
 }
 
+// WithItems sets the element type of an array parameter.
+func (p ParameterDefinition) WithItems(itemType ParameterType) ParameterDefinition {
+	p.Items = itemType
+	return p
+}
+
 // WithRequired marks the parameter as required.
 func (p ParameterDefinition) WithRequired() ParameterDefinition {
 	p.Required = true
@@ -80,6 +88,108 @@ func NewToolDefinition(name string, description string) ToolDefinition {
 	}
 }
 
+// jsonSchema is the subset of a JSON Schema object understood by NewToolDefinitionFromSchema.
+type jsonSchema struct {
+	Properties map[string]jsonSchemaProperty `json:"properties"`
+	Type       string                        `json:"type"`
+	Required   []string                      `json:"required"`
+}
+
+// jsonSchemaProperty is the subset of a JSON Schema property understood by NewToolDefinitionFromSchema.
+type jsonSchemaProperty struct {
+	Default     any                 `json:"default"`
+	Items       *jsonSchemaProperty `json:"items"`
+	Description string              `json:"description"`
+	Type        string              `json:"type"`
+	Enum        []any               `json:"enum"`
+}
+
+// NewToolDefinitionFromSchema creates a ToolDefinition from the JSON Schema of the function parameters,
+// e.g. {"type":"object","properties":{...},"required":[...]}.
+// The type, description, enum, default, and item type of each property are kept; other keywords are ignored.
+// Parameters are sorted by name, since JSON objects are unordered.
+// Returns ErrInvalidSchema if the schema is malformed, not an object, or uses unsupported types.
+func NewToolDefinitionFromSchema(name, description, schemaJSON string) (ToolDefinition, error) {
+	var schema jsonSchema
+	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
+		return ToolDefinition{}, fmt.Errorf("%w: %s", ErrInvalidSchema, err.Error())
+	}
+	if schema.Type != "" && schema.Type != string(ParamTypeObject) {
+		return ToolDefinition{}, fmt.Errorf("%w: type must be object, got %q", ErrInvalidSchema, schema.Type)
+	}
+	for _, required := range schema.Required {
+		if _, ok := schema.Properties[required]; !ok {
+			return ToolDefinition{}, fmt.Errorf("%w: required property %q is not defined", ErrInvalidSchema, required)
+		}
+	}
+
+	names := make([]string, 0, len(schema.Properties))
+	for paramName := range schema.Properties {
+		names = append(names, paramName)
+	}
+	sort.Strings(names)
+
+	td := NewToolDefinition(name, description)
+	for _, paramName := range names {
+		param, err := parameterFromSchema(paramName, schema.Properties[paramName])
+		if err != nil {
+			return ToolDefinition{}, err
+		}
+		if slices.Contains(schema.Required, paramName) {
+			param = param.WithRequired()
+		}
+		td = td.WithParameterDef(param)
+	}
+	return td, nil
+}
+
+// parameterFromSchema converts a JSON Schema property to a parameter definition.
+func parameterFromSchema(name string, prop jsonSchemaProperty) (ParameterDefinition, error) {
+	paramType, err := schemaType(name, prop.Type)
+	if err != nil {
+		return ParameterDefinition{}, err
+	}
+	param := NewParameterDefinition(name, paramType).WithDescription(prop.Description)
+
+	if prop.Items != nil {
+		itemType, err := schemaType(name+".items", prop.Items.Type)
+		if err != nil {
+			return ParameterDefinition{}, err
+		}
+		param = param.WithItems(itemType)
+	}
+	if len(prop.Enum) > 0 {
+		values := make([]string, len(prop.Enum))
+		for i, value := range prop.Enum {
+			str, ok := value.(string)
+			if !ok {
+				return ParameterDefinition{}, fmt.Errorf("%w: enum of %q must only contain strings", ErrInvalidSchema, name)
+			}
+			values[i] = str
+		}
+		param = param.WithEnum(values...)
+	}
+	switch value := prop.Default.(type) {
+	case nil:
+	case string:
+		param = param.WithDefault(value)
+	default:
+		encoded, _ := json.Marshal(value)
+		param = param.WithDefault(string(encoded))
+	}
+	return param, nil
+}
+
+// schemaType converts a JSON Schema type name to a supported ParameterType.
+func schemaType(name, typeName string) (ParameterType, error) {
+	switch paramType := ParameterType(typeName); paramType {
+	case ParamTypeArray, ParamTypeBoolean, ParamTypeInteger, ParamTypeNumber, ParamTypeObject, ParamTypeString:
+		return paramType, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported type %q of %q", ErrInvalidSchema, typeName, name)
+	}
+}
+
 // WithParameter adds a simple string parameter to the tool definition.
 // For more control, use WithParameterDef instead.
 func (td ToolDefinition) WithParameter(name string, description string) ToolDefinition {
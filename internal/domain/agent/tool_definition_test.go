@@ -39,6 +39,76 @@ func Test_ToolDefinition_WithParameter_With_Params_Should_HaveParameters(t *test
 
 // DecodeArgs tests
 
+// searchSchema is a representative JSON Schema with a required enum and an array of strings.
+const searchSchema = `{
+	"type": "object",
+	"properties": {
+		"mode": {"type": "string", "description": "Search mode", "enum": ["exact", "fuzzy"]},
+		"tags": {"type": "array", "description": "Tags to match", "items": {"type": "string"}},
+		"limit": {"type": "integer", "default": 10}
+	},
+	"required": ["mode"]
+}`
+
+func Test_ToolDefinition_NewToolDefinitionFromSchema_With_ValidSchema_Should_ParseParameters(t *testing.T) {
+	// Arrange
+	expected := agent.NewToolDefinition("search", "Search notes").
+		WithParameterDef(agent.NewParameterDefinition("limit", agent.ParamTypeInteger).WithDefault("10")).
+		WithParameterDef(agent.NewParameterDefinition("mode", agent.ParamTypeString).
+			WithDescription("Search mode").
+			WithEnum("exact", "fuzzy").
+			WithRequired()).
+		WithParameterDef(agent.NewParameterDefinition("tags", agent.ParamTypeArray).
+			WithDescription("Tags to match").
+			WithItems(agent.ParamTypeString))
+
+	// Act
+	td, err := agent.NewToolDefinitionFromSchema("search", "Search notes", searchSchema)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "definition must match", td, expected)
+	assert.That(t, "required parameters must match", td.GetRequiredParameters(), []string{"mode"})
+}
+
+func Test_ToolDefinition_NewToolDefinitionFromSchema_With_ParsedSchema_Should_ValidateArgs(t *testing.T) {
+	// Arrange
+	td, _ := agent.NewToolDefinitionFromSchema("search", "Search notes", searchSchema)
+
+	// Act
+	missing := agent.ValidateArgs(td, `{"tags":["a"]}`)
+	invalid := agent.ValidateArgs(td, `{"mode":"regex"}`)
+	valid := agent.ValidateArgs(td, `{"mode":"exact","tags":["a","b"]}`)
+
+	// Assert
+	assert.That(t, "missing required enum must fail", missing != nil, true)
+	assert.That(t, "unknown enum value must fail", invalid != nil, true)
+	assert.That(t, "valid arguments must pass", valid, nil)
+}
+
+func Test_ToolDefinition_NewToolDefinitionFromSchema_With_InvalidSchema_Should_ReturnErrInvalidSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{name: "malformed JSON", schema: `{"type":`},
+		{name: "not an object", schema: `{"type":"array"}`},
+		{name: "unsupported type", schema: `{"type":"object","properties":{"a":{"type":"date"}}}`},
+		{name: "unsupported item type", schema: `{"type":"object","properties":{"a":{"type":"array","items":{"type":"date"}}}}`},
+		{name: "non-string enum", schema: `{"type":"object","properties":{"a":{"type":"integer","enum":[1,2]}}}`},
+		{name: "undefined required", schema: `{"type":"object","properties":{},"required":["a"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, err := agent.NewToolDefinitionFromSchema("tool", "desc", tt.schema)
+
+			// Assert
+			assert.That(t, "err must be ErrInvalidSchema", errors.Is(err, agent.ErrInvalidSchema), true)
+		})
+	}
+}
+
 func Test_DecodeArgs_With_ValidJSON_Should_DecodeSuccessfully(t *testing.T) {
 	// Arrange
 	type args struct {
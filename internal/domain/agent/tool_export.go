@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"encoding/json"
+
+	"github.com/andygeiss/cloud-native-utils/slices"
+	"github.com/andygeiss/go-agent/internal/domain/openai"
+)
+
+// ExportToolsJSON returns the tools array the agent sends to an OpenAI-compatible API,
+// e.g. to register the same tools with an external orchestrator.
+// The document is indented; no tools yield an empty array.
+func ExportToolsJSON(defs []ToolDefinition) ([]byte, error) {
+	tools := OpenAITools(defs)
+	if tools == nil {
+		tools = []openai.Tool{}
+	}
+	return json.MarshalIndent(tools, "", "  ")
+}
+
+// OpenAITools converts tool definitions to OpenAI function tools.
+// Returns nil if there are no definitions, so the tools field can be omitted.
+func OpenAITools(defs []ToolDefinition) []openai.Tool {
+	if len(defs) == 0 {
+		return nil
+	}
+	return slices.Map(defs, func(tool ToolDefinition) openai.Tool {
+		properties := make(map[string]openai.PropertyDefinition)
+		for _, param := range tool.Parameters {
+			prop := openai.PropertyDefinition{
+				Type:        string(param.Type),
+				Description: param.Description,
+			}
+			if len(param.Enum) > 0 {
+				prop.Enum = param.Enum
+			}
+			if param.Items != "" {
+				prop.Items = &openai.PropertyDefinition{Type: string(param.Items)}
+			}
+			properties[param.Name] = prop
+		}
+
+		return openai.Tool{
+			Type: "function",
+			Function: openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters: openai.ParametersDefinition{
+					Type:       "object",
+					Properties: properties,
+					Required:   tool.GetRequiredParameters(),
+				},
+			},
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package agent_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+	"github.com/andygeiss/go-agent/internal/domain/openai"
+)
+
+func Test_ExportToolsJSON_With_NoDefinitions_Should_ReturnEmptyArray(t *testing.T) {
+	// Arrange
+	var defs []agent.ToolDefinition
+
+	// Act
+	document, err := agent.ExportToolsJSON(defs)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "document must be an empty array", string(document), "[]")
+}
+
+func Test_ExportToolsJSON_With_Definition_Should_ProduceFunctionTool(t *testing.T) {
+	// Arrange
+	def := agent.NewToolDefinition("search", "Search files").
+		WithParameterDef(agent.NewParameterDefinition("query", agent.ParamTypeString).WithDescription("Search text").WithRequired()).
+		WithParameterDef(agent.NewParameterDefinition("mode", agent.ParamTypeString).WithEnum("fast", "full")).
+		WithParameterDef(agent.NewParameterDefinition("paths", agent.ParamTypeArray).WithItems(agent.ParamTypeString))
+
+	// Act
+	document, err := agent.ExportToolsJSON([]agent.ToolDefinition{def})
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	var tools []openai.Tool
+	assert.That(t, "document must be valid JSON", json.Unmarshal(document, &tools), nil)
+	assert.That(t, "one tool must be exported", len(tools), 1)
+	tool := tools[0]
+	assert.That(t, "type must be function", tool.Type, "function")
+	assert.That(t, "name must match", tool.Function.Name, "search")
+	assert.That(t, "parameters must be an object", tool.Function.Parameters.Type, "object")
+	assert.That(t, "required must list query", tool.Function.Parameters.Required, []string{"query"})
+	props := tool.Function.Parameters.Properties
+	assert.That(t, "query must be a string", props["query"].Type, "string")
+	assert.That(t, "mode must keep its enum", props["mode"].Enum, []string{"fast", "full"})
+	assert.That(t, "paths must describe its items", props["paths"].Items, &openai.PropertyDefinition{Type: "string"})
+}
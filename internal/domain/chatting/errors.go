@@ -0,0 +1,8 @@
+package chatting
+
+import "errors"
+
+// Sentinel errors for chatting use cases (alphabetically sorted).
+var (
+	ErrInputTooLong = errors.New("input too long")
+)
@@ -3,21 +3,26 @@ package chatting
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync/atomic"
+	"unicode/utf8"
 
 	"github.com/andygeiss/go-agent/internal/domain/agent"
 )
 
 // AgentStats contains statistics about the agent.
 type AgentStats struct {
-	AgentID        string
-	Model          string
-	CompletedTasks int
-	FailedTasks    int
-	MaxIterations  int
-	MaxMessages    int
-	MessageCount   int
-	TaskCount      int
+	AgentID                  string
+	Model                    string
+	AverageIterationsPerTask float64
+	CompletedTasks           int
+	FailedTasks              int
+	MaxIterations            int
+	MaxMessages              int
+	MessageCount             int
+	TaskCount                int
+	TotalTokens              int
+	TotalToolCalls           int
 }
 
 // ClearConversationUseCase handles clearing the conversation history.
@@ -32,9 +37,16 @@ func NewClearConversationUseCase(ag *agent.Agent) *ClearConversationUseCase {
 	}
 }
 
-// Execute clears the conversation history.
-func (uc *ClearConversationUseCase) Execute() {
+// ClearAll resets the conversation completely, including pinned and system messages.
+// The system prompt and examples are part of the agent's configuration and are kept.
+func (uc *ClearConversationUseCase) ClearAll() {
 	uc.agent.ClearMessages()
+	uc.agent.ResetIteration()
+}
+
+// Execute clears the dialogue, keeping system and pinned messages.
+func (uc *ClearConversationUseCase) Execute() {
+	uc.agent.ClearConversation()
 }
 
 // GetAgentStatsUseCase handles retrieving agent statistics.
@@ -52,17 +64,43 @@ func NewGetAgentStatsUseCase(ag *agent.Agent) *GetAgentStatsUseCase {
 // Execute retrieves the agent statistics.
 func (uc *GetAgentStatsUseCase) Execute() AgentStats {
 	return AgentStats{
-		AgentID:        string(uc.agent.ID),
-		Model:          uc.agent.GetMetadata("model"),
-		CompletedTasks: uc.agent.CompletedTaskCount(),
-		FailedTasks:    uc.agent.FailedTaskCount(),
-		MaxIterations:  uc.agent.MaxIterations,
-		MaxMessages:    uc.agent.MaxMessages,
-		MessageCount:   uc.agent.MessageCount(),
-		TaskCount:      uc.agent.TaskCount(),
+		AgentID:                  string(uc.agent.ID),
+		Model:                    uc.agent.GetMetadata("model"),
+		AverageIterationsPerTask: uc.agent.AverageIterationsPerTask(),
+		CompletedTasks:           uc.agent.CompletedTaskCount(),
+		FailedTasks:              uc.agent.FailedTaskCount(),
+		MaxIterations:            uc.agent.MaxIterations,
+		MaxMessages:              uc.agent.MaxMessages,
+		MessageCount:             uc.agent.MessageCount(),
+		TaskCount:                uc.agent.TaskCount(),
+		TotalTokens:              uc.agent.Totals.Tokens,
+		TotalToolCalls:           uc.agent.Totals.ToolCalls,
+	}
+}
+
+// GetLastTaskTraceUseCase handles retrieving the tool calls of the most recent task.
+type GetLastTaskTraceUseCase struct {
+	agent *agent.Agent
+}
+
+// NewGetLastTaskTraceUseCase creates a new GetLastTaskTraceUseCase.
+// The agent must retain a task history (see agent.WithTaskHistory).
+func NewGetLastTaskTraceUseCase(ag *agent.Agent) *GetLastTaskTraceUseCase {
+	return &GetLastTaskTraceUseCase{
+		agent: ag,
 	}
 }
 
+// Execute returns the summary of the most recently finished task, including its tool calls.
+// Returns false if no task has finished yet.
+func (uc *GetLastTaskTraceUseCase) Execute() (agent.TaskSummary, bool) {
+	history := uc.agent.TaskHistory()
+	if len(history) == 0 {
+		return agent.TaskSummary{}, false
+	}
+	return history[len(history)-1], true
+}
+
 // SendMessageInput contains the input for sending a message.
 type SendMessageInput struct {
 	Message string
@@ -73,16 +111,24 @@ type SendMessageOutput struct {
 	Duration       string
 	Error          string
 	Response       string
+	Tokens         agent.TokenUsage
 	IterationCount int
 	ToolCallCount  int
 	Success        bool
 }
 
+// autoRecallPriority places recalled memories after the base system prompt.
+const autoRecallPriority = 10
+
 // SendMessageUseCase handles sending a message to the agent and getting a response.
 type SendMessageUseCase struct {
-	agent       *agent.Agent
-	taskRunner  agent.TaskRunner
-	taskCounter atomic.Int64
+	agent         *agent.Agent
+	memory        agent.MemorySearcher
+	taskRunner    agent.TaskRunner
+	maxInputRunes int
+	recallTopK    int
+	taskCounter   atomic.Int64
+	truncateInput bool
 }
 
 // NewSendMessageUseCase creates a new SendMessageUseCase.
@@ -93,12 +139,48 @@ func NewSendMessageUseCase(runner agent.TaskRunner, ag *agent.Agent) *SendMessag
 	}
 }
 
+// WithAutoRecall enables automatic memory retrieval before each task.
+// The searcher finds the topK notes most relevant to the user message,
+// whose summaries are injected into the system prompt for that task.
+func (uc *SendMessageUseCase) WithAutoRecall(searcher agent.MemorySearcher, topK int) *SendMessageUseCase {
+	uc.memory = searcher
+	uc.recallTopK = topK
+	return uc
+}
+
+// WithInputTruncation cuts inputs longer than the WithMaxInputLength limit
+// to the limit instead of rejecting them.
+func (uc *SendMessageUseCase) WithInputTruncation() *SendMessageUseCase {
+	uc.truncateInput = true
+	return uc
+}
+
+// WithMaxInputLength limits the length of a message to maxRunes characters, so that a huge
+// pasted input fails fast instead of overflowing the model's context window.
+// Longer inputs are rejected with ErrInputTooLong unless WithInputTruncation is set.
+// Zero disables the limit (default).
+func (uc *SendMessageUseCase) WithMaxInputLength(maxRunes int) *SendMessageUseCase {
+	uc.maxInputRunes = maxRunes
+	return uc
+}
+
 // Execute sends a message to the agent and returns the response.
 func (uc *SendMessageUseCase) Execute(ctx context.Context, input SendMessageInput) (SendMessageOutput, error) {
+	message, err := uc.limitInput(input.Message)
+	if err != nil {
+		return SendMessageOutput{
+			Success: false,
+			Error:   err.Error(),
+		}, err
+	}
+	input.Message = message
+
 	taskNum := uc.taskCounter.Add(1)
 	taskID := agent.TaskID(fmt.Sprintf("task-%d", taskNum))
 	task := agent.NewTask(taskID, "chat", input.Message)
 
+	uc.recall(ctx, input.Message)
+
 	result, err := uc.taskRunner.RunTask(ctx, uc.agent, task)
 	if err != nil {
 		return SendMessageOutput{
@@ -113,6 +195,47 @@ func (uc *SendMessageUseCase) Execute(ctx context.Context, input SendMessageInpu
 		Error:          result.Error,
 		Duration:       result.Duration.Round(1000000).String(),
 		IterationCount: result.IterationCount,
+		Tokens:         result.Tokens,
 		ToolCallCount:  result.ToolCallCount,
 	}, nil
 }
+
+// limitInput applies the maximum input length to the message.
+func (uc *SendMessageUseCase) limitInput(message string) (string, error) {
+	if uc.maxInputRunes <= 0 {
+		return message, nil
+	}
+	length := utf8.RuneCountInString(message)
+	if length <= uc.maxInputRunes {
+		return message, nil
+	}
+	if uc.truncateInput {
+		return string([]rune(message)[:uc.maxInputRunes]), nil
+	}
+	return "", fmt.Errorf("%w: %d characters exceed the limit of %d", ErrInputTooLong, length, uc.maxInputRunes)
+}
+
+// recall injects the summaries of relevant memory notes as a system segment.
+// Failures are ignored so that recall never blocks the conversation.
+func (uc *SendMessageUseCase) recall(ctx context.Context, message string) {
+	if uc.memory == nil || uc.recallTopK <= 0 {
+		return
+	}
+
+	notes, err := uc.memory.SmartSearch(ctx, message, uc.recallTopK, nil)
+	if err != nil || len(notes) == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Relevant memories:")
+	for _, note := range notes {
+		summary := note.Summary
+		if summary == "" {
+			summary = note.RawContent
+		}
+		sb.WriteString("\n- ")
+		sb.WriteString(summary)
+	}
+	uc.agent.AddSystemSegment(autoRecallPriority, sb.String())
+}
@@ -2,6 +2,7 @@ package chatting_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -13,10 +14,14 @@ import (
 // mockTaskRunner implements agent.TaskRunner for testing.
 type mockTaskRunner struct {
 	err    error
+	input  string
 	result agent.Result
+	calls  int
 }
 
-func (m *mockTaskRunner) RunTask(_ context.Context, _ *agent.Agent, _ *agent.Task) (agent.Result, error) {
+func (m *mockTaskRunner) RunTask(_ context.Context, _ *agent.Agent, task *agent.Task) (agent.Result, error) {
+	m.calls++
+	m.input = task.Input
 	return m.result, m.err
 }
 
@@ -46,6 +51,52 @@ func Test_ClearConversationUseCase_Execute_With_EmptyHistory_Should_NotPanic(t *
 	assert.That(t, "message count must be 0", ag.MessageCount(), 0)
 }
 
+func Test_ClearConversationUseCase_Execute_Should_PreserveSystemExamplesAndPinned(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("test-agent", "test prompt",
+		agent.WithExamples(
+			agent.NewMessage(agent.RoleUser, "2+2?"),
+			agent.NewMessage(agent.RoleAssistant, "4"),
+		),
+	)
+	ag.AddMessage(agent.NewMessage(agent.RoleSystem, "context"))
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "My name is Alice.").WithPinned())
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "Hello"))
+	ag.AddMessage(agent.NewMessage(agent.RoleAssistant, "Hi!"))
+	ag.AddMessage(agent.NewMessage(agent.RoleTool, "result"))
+	ag.IncrementIteration()
+	uc := chatting.NewClearConversationUseCase(&ag)
+
+	// Act
+	uc.Execute()
+
+	// Assert
+	messages := ag.GetMessages()
+	assert.That(t, "system prompt must be kept", ag.SystemPrompt, "test prompt")
+	assert.That(t, "examples must be kept", len(ag.Examples), 2)
+	assert.That(t, "system and pinned messages must remain", len(messages), 2)
+	assert.That(t, "system message must be kept", messages[0].Role, agent.RoleSystem)
+	assert.That(t, "pinned message must be kept", messages[1].Pinned, true)
+	assert.That(t, "iteration must be reset", ag.Iteration, 0)
+}
+
+func Test_ClearConversationUseCase_ClearAll_Should_RemoveAllMessages(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("test-agent", "test prompt")
+	ag.AddMessage(agent.NewMessage(agent.RoleSystem, "context"))
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "My name is Alice.").WithPinned())
+	ag.AddMessage(agent.NewMessage(agent.RoleUser, "Hello"))
+	ag.IncrementIteration()
+	uc := chatting.NewClearConversationUseCase(&ag)
+
+	// Act
+	uc.ClearAll()
+
+	// Assert
+	assert.That(t, "message count must be 0", ag.MessageCount(), 0)
+	assert.That(t, "iteration must be reset", ag.Iteration, 0)
+}
+
 // GetAgentStatsUseCase tests
 
 func Test_GetAgentStatsUseCase_Execute_Should_ReturnCorrectStats(t *testing.T) {
@@ -107,8 +158,60 @@ func Test_GetAgentStatsUseCase_Execute_With_Tasks_Should_ReturnTaskCounts(t *tes
 	assert.That(t, "failed tasks must be 1", stats.FailedTasks, 1)
 }
 
+func Test_GetAgentStatsUseCase_Execute_With_RecordedResults_Should_ReturnAggregates(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("test-agent", "test prompt")
+	ag.RecordResult(agent.NewResult("task-1", true, "a").WithIterationCount(1).WithToolCallCount(0))
+	ag.RecordResult(agent.NewResult("task-2", true, "b").WithIterationCount(3).WithToolCallCount(4).
+		WithTokens(agent.TokenUsage{TotalTokens: 120}))
+	ag.RecordResult(agent.NewResult("task-3", false, "").WithIterationCount(5).WithToolCallCount(2).
+		WithTokens(agent.TokenUsage{TotalTokens: 30}))
+	uc := chatting.NewGetAgentStatsUseCase(&ag)
+
+	// Act
+	stats := uc.Execute()
+
+	// Assert
+	assert.That(t, "total tool calls must be 6", stats.TotalToolCalls, 6)
+	assert.That(t, "total tokens must be 150", stats.TotalTokens, 150)
+	assert.That(t, "average iterations must be 3", stats.AverageIterationsPerTask, 3.0)
+}
+
 // SendMessageUseCase tests
 
+func Test_GetLastTaskTraceUseCase_Execute_With_FinishedTasks_Should_ReturnNewest(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("test-agent", "test prompt", agent.WithTaskHistory(5))
+	for _, id := range []agent.TaskID{"task-1", "task-2"} {
+		task := agent.NewTask(id, "chat", "input")
+		task.IncrementIterations()
+		task.RecordToolCalls([]agent.ToolCall{agent.NewToolCall("tc-1", "search", "{}")})
+		task.Complete("done")
+		ag.RecordTask(task)
+	}
+	uc := chatting.NewGetLastTaskTraceUseCase(&ag)
+
+	// Act
+	summary, ok := uc.Execute()
+
+	// Assert
+	assert.That(t, "a task must be found", ok, true)
+	assert.That(t, "newest task must be returned", summary.ID, agent.TaskID("task-2"))
+	assert.That(t, "tool calls must be included", len(summary.ToolCalls), 1)
+}
+
+func Test_GetLastTaskTraceUseCase_Execute_Without_Tasks_Should_ReturnFalse(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("test-agent", "test prompt", agent.WithTaskHistory(5))
+	uc := chatting.NewGetLastTaskTraceUseCase(&ag)
+
+	// Act
+	_, ok := uc.Execute()
+
+	// Assert
+	assert.That(t, "no task must be found", ok, false)
+}
+
 func Test_SendMessageUseCase_Execute_With_FailedResponse_Should_ReturnError(t *testing.T) {
 	// Arrange
 	ag := agent.NewAgent("test-agent", "test prompt")
@@ -128,6 +231,52 @@ func Test_SendMessageUseCase_Execute_With_FailedResponse_Should_ReturnError(t *t
 	assert.That(t, "error must match", output.Error, "task failed")
 }
 
+func Test_SendMessageUseCase_Execute_With_InputOverLimit_Should_RejectWithoutRunningTask(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("test-agent", "test prompt")
+	runner := &mockTaskRunner{result: agent.Result{Success: true, Output: "OK"}}
+	uc := chatting.NewSendMessageUseCase(runner, &ag).WithMaxInputLength(5)
+
+	// Act
+	output, err := uc.Execute(context.Background(), chatting.SendMessageInput{Message: "Grüße!"})
+
+	// Assert
+	assert.That(t, "err must be ErrInputTooLong", errors.Is(err, chatting.ErrInputTooLong), true)
+	assert.That(t, "success must be false", output.Success, false)
+	assert.That(t, "error must explain the limit", output.Error, "input too long: 6 characters exceed the limit of 5")
+	assert.That(t, "task must not run", runner.calls, 0)
+}
+
+func Test_SendMessageUseCase_Execute_With_InputAtLimit_Should_RunTask(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("test-agent", "test prompt")
+	runner := &mockTaskRunner{result: agent.Result{Success: true, Output: "OK"}}
+	uc := chatting.NewSendMessageUseCase(runner, &ag).WithMaxInputLength(5)
+
+	// Act
+	output, err := uc.Execute(context.Background(), chatting.SendMessageInput{Message: "Grüße"})
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "success must be true", output.Success, true)
+	assert.That(t, "input must be passed unchanged", runner.input, "Grüße")
+}
+
+func Test_SendMessageUseCase_Execute_With_InputTruncation_Should_CutInputToLimit(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("test-agent", "test prompt")
+	runner := &mockTaskRunner{result: agent.Result{Success: true, Output: "OK"}}
+	uc := chatting.NewSendMessageUseCase(runner, &ag).WithMaxInputLength(5).WithInputTruncation()
+
+	// Act
+	output, err := uc.Execute(context.Background(), chatting.SendMessageInput{Message: "Grüße, Welt!"})
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "success must be true", output.Success, true)
+	assert.That(t, "input must be truncated", runner.input, "Grüße")
+}
+
 func Test_SendMessageUseCase_Execute_With_MultipleCalls_Should_IncrementTaskCounter(t *testing.T) {
 	// Arrange
 	ag := agent.NewAgent("test-agent", "test prompt")
@@ -155,6 +304,7 @@ func Test_SendMessageUseCase_Execute_With_SuccessfulResponse_Should_ReturnOutput
 			Duration:       100 * time.Millisecond,
 			IterationCount: 1,
 			ToolCallCount:  0,
+			Tokens:         agent.TokenUsage{PromptTokens: 40, CompletionTokens: 2, TotalTokens: 42},
 		},
 	}
 	uc := chatting.NewSendMessageUseCase(runner, &ag)
@@ -167,4 +317,83 @@ func Test_SendMessageUseCase_Execute_With_SuccessfulResponse_Should_ReturnOutput
 	assert.That(t, "success must be true", output.Success, true)
 	assert.That(t, "response must match", output.Response, "Hello!")
 	assert.That(t, "iteration count must be 1", output.IterationCount, 1)
+	assert.That(t, "total tokens must be 42", output.Tokens.TotalTokens, 42)
+}
+
+// promptCapturingRunner records the system prompt seen by the task.
+type promptCapturingRunner struct {
+	systemPrompt string
+}
+
+func (m *promptCapturingRunner) RunTask(_ context.Context, ag *agent.Agent, task *agent.Task) (agent.Result, error) {
+	m.systemPrompt = ag.BuildSystemPrompt()
+	return agent.NewResult(task.ID, true, "ok"), nil
+}
+
+// mockMemorySearcher implements agent.MemorySearcher and records the search it receives.
+type mockMemorySearcher struct {
+	err   error
+	notes []*agent.MemoryNote
+	query string
+	topK  int
+}
+
+func (m *mockMemorySearcher) SmartSearch(_ context.Context, query string, topK int, _ *agent.MemorySearchOptions) ([]*agent.MemoryNote, error) {
+	m.query = query
+	m.topK = topK
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.notes[:min(topK, len(m.notes))], nil
+}
+
+func Test_SendMessageUseCase_WithAutoRecall_Should_InjectTopKSummaries(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("test-agent", "base prompt")
+	searcher := &mockMemorySearcher{notes: []*agent.MemoryNote{
+		agent.NewFactNote("note-2", "User prefers Go"),
+		agent.NewFactNote("note-3", "User uses vim"),
+		agent.NewFactNote("note-1", "User likes tea"),
+	}}
+	runner := &promptCapturingRunner{}
+	uc := chatting.NewSendMessageUseCase(runner, &ag).WithAutoRecall(searcher, 2)
+
+	// Act
+	_, err := uc.Execute(context.Background(), chatting.SendMessageInput{Message: "Which language?"})
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "query must be the user message", searcher.query, "Which language?")
+	assert.That(t, "topK must be passed", searcher.topK, 2)
+	assert.That(t, "prompt must contain recalled summaries", runner.systemPrompt,
+		"base prompt\n\nRelevant memories:\n- User prefers Go\n- User uses vim")
+}
+
+func Test_SendMessageUseCase_WithAutoRecall_With_NoMatches_Should_NotInjectSegment(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("test-agent", "base prompt")
+	runner := &promptCapturingRunner{}
+	uc := chatting.NewSendMessageUseCase(runner, &ag).WithAutoRecall(&mockMemorySearcher{}, 3)
+
+	// Act
+	_, _ = uc.Execute(context.Background(), chatting.SendMessageInput{Message: "Hello"})
+
+	// Assert
+	assert.That(t, "prompt must be the base prompt", runner.systemPrompt, "base prompt")
+}
+
+func Test_SendMessageUseCase_WithAutoRecall_With_SearchError_Should_StillRunTask(t *testing.T) {
+	// Arrange
+	ag := agent.NewAgent("test-agent", "base prompt")
+	runner := &promptCapturingRunner{}
+	uc := chatting.NewSendMessageUseCase(runner, &ag).
+		WithAutoRecall(&mockMemorySearcher{err: errors.New("search failed")}, 3)
+
+	// Act
+	output, err := uc.Execute(context.Background(), chatting.SendMessageInput{Message: "Hello"})
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "task must succeed", output.Success, true)
+	assert.That(t, "prompt must be the base prompt", runner.systemPrompt, "base prompt")
 }
@@ -16,6 +16,10 @@ type IndexStore interface {
 	GetLatestSnapshot(ctx context.Context) (Snapshot, error)
 	// GetSnapshot retrieves a snapshot by ID.
 	GetSnapshot(ctx context.Context, id SnapshotID) (Snapshot, error)
+	// GetSnapshotByLabel retrieves the snapshot carrying the label.
+	GetSnapshotByLabel(ctx context.Context, label string) (Snapshot, error)
+	// LabelSnapshot sets the label of a snapshot, removing it from any other snapshot.
+	LabelSnapshot(ctx context.Context, id SnapshotID, label string) error
 	// SaveSnapshot persists a snapshot.
 	SaveSnapshot(ctx context.Context, snapshot Snapshot) error
 }
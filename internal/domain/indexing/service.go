@@ -3,25 +3,30 @@ package indexing
 import (
 	"context"
 	"errors"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/andygeiss/cloud-native-utils/slices"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
 )
 
 // Sentinel errors for the indexing service.
 var (
-	ErrSnapshotNotFound = errors.New("snapshot not found")
+	ErrSnapshotNotFound     = errors.New("snapshot not found")
+	ErrSnapshotRootsUnknown = errors.New("snapshot has no recorded roots")
 )
 
 // Service provides file system indexing use cases.
 type Service struct {
-	idGen  func() string
-	store  IndexStore
-	walker FileWalker
+	idGen   agent.IDGenerator
+	store   IndexStore
+	walker  FileWalker
+	baseDir string
 }
 
 // NewService creates a new indexing service.
-func NewService(walker FileWalker, store IndexStore, idGenerator func() string) *Service {
+func NewService(walker FileWalker, store IndexStore, idGenerator agent.IDGenerator) *Service {
 	return &Service{
 		idGen:  idGenerator,
 		store:  store,
@@ -29,51 +34,195 @@ func NewService(walker FileWalker, store IndexStore, idGenerator func() string)
 	}
 }
 
+// WithBaseDir stores file paths relative to baseDir (with forward slashes),
+// so snapshots taken in different locations with the same layout diff as unchanged.
+// Files outside baseDir keep the path produced by the walker.
+// Empty stores paths as walked (default).
+func (s *Service) WithBaseDir(baseDir string) *Service {
+	s.baseDir = baseDir
+	return s
+}
+
 // ChangedSince returns files from the latest snapshot that were modified after the given time.
-func (s *Service) ChangedSince(ctx context.Context, since time.Time) ([]FileInfo, error) {
+// If pathPrefix is not empty, only files whose path begins with it are returned.
+func (s *Service) ChangedSince(ctx context.Context, since time.Time, pathPrefix string) (ChangedSinceResult, error) {
 	snapshot, err := s.store.GetLatestSnapshot(ctx)
 	if err != nil {
-		return nil, err
+		return ChangedSinceResult{}, err
 	}
 
 	changed := slices.Filter(snapshot.Files, func(f FileInfo) bool {
-		return f.ModTime.After(since)
+		return f.ModTime.After(since) && strings.HasPrefix(f.Path, pathPrefix)
 	})
 
-	return changed, nil
+	return ChangedSinceResult{Files: changed, Since: since, SnapshotID: snapshot.ID}, nil
+}
+
+// ChangedSinceSnapshot scans the baseline snapshot's roots again and compares
+// the current on-disk state against it. Unlike ChangedSince, it also reports
+// files that were removed. The fresh scan is not persisted.
+// fromID may also be a snapshot label.
+func (s *Service) ChangedSinceSnapshot(ctx context.Context, fromID SnapshotID) (DiffResult, error) {
+	baseline, err := s.resolveSnapshot(ctx, fromID)
+	if err != nil {
+		return DiffResult{}, err
+	}
+	if len(baseline.Roots) == 0 {
+		return DiffResult{}, ErrSnapshotRootsUnknown
+	}
+
+	return s.diffAgainstWalk(ctx, baseline, baseline.Roots, baseline.Ignore)
+}
+
+// DiffAgainstCurrent compares the baseline snapshot against a live scan of the given
+// paths, combining Scan and DiffSnapshots without persisting a second snapshot.
+// Baseline files outside the scanned paths are reported as removed, so the paths
+// should cover the baseline's roots. fromID may also be a snapshot label.
+func (s *Service) DiffAgainstCurrent(ctx context.Context, fromID SnapshotID, paths, ignore []string) (DiffResult, error) {
+	baseline, err := s.resolveSnapshot(ctx, fromID)
+	if err != nil {
+		return DiffResult{}, err
+	}
+
+	return s.diffAgainstWalk(ctx, baseline, paths, ignore)
 }
 
 // DiffSnapshots compares two snapshots and returns the differences.
 // fromID is the older snapshot, toID is the newer snapshot.
-func (s *Service) DiffSnapshots(ctx context.Context, fromID, toID SnapshotID) (DiffResult, error) {
-	fromSnapshot, err := s.store.GetSnapshot(ctx, fromID)
+// Either ID may also be a snapshot label.
+// If pathPrefix is not empty, only files whose path begins with it are compared.
+func (s *Service) DiffSnapshots(ctx context.Context, fromID, toID SnapshotID, pathPrefix string) (DiffResult, error) {
+	fromSnapshot, err := s.resolveSnapshot(ctx, fromID)
 	if err != nil {
 		return DiffResult{}, err
 	}
 
-	toSnapshot, err := s.store.GetSnapshot(ctx, toID)
+	toSnapshot, err := s.resolveSnapshot(ctx, toID)
 	if err != nil {
 		return DiffResult{}, err
 	}
 
-	return diffSnapshots(fromSnapshot, toSnapshot), nil
+	return diffSnapshots(fromSnapshot, toSnapshot).FilterByPrefix(pathPrefix), nil
+}
+
+// LabelSnapshot names the snapshot, so that the label can be used in place of its ID.
+// Labels are unique: a label already carried by another snapshot is moved.
+func (s *Service) LabelSnapshot(ctx context.Context, id SnapshotID, label string) error {
+	return s.store.LabelSnapshot(ctx, id, label)
 }
 
 // Scan walks the given directories, builds a snapshot, and persists it.
 // Returns the created snapshot.
 func (s *Service) Scan(ctx context.Context, roots []string, ignore []string) (Snapshot, error) {
-	files, err := s.walker.Walk(ctx, roots, ignore)
+	return s.ScanWithLabel(ctx, roots, ignore, "")
+}
+
+// ScanWithLabel scans like Scan and labels the created snapshot.
+// An empty label leaves the snapshot unlabeled.
+func (s *Service) ScanWithLabel(ctx context.Context, roots []string, ignore []string, label string) (Snapshot, error) {
+	snapshot, err := s.ScanDryRun(ctx, roots, ignore)
 	if err != nil {
 		return Snapshot{}, err
 	}
 
-	snapshot := NewSnapshot(SnapshotID(s.idGen()), files)
-
 	if err := s.store.SaveSnapshot(ctx, snapshot); err != nil {
 		return Snapshot{}, err
 	}
+	if label == "" {
+		return snapshot, nil
+	}
+
+	if err := s.store.LabelSnapshot(ctx, snapshot.ID, label); err != nil {
+		return Snapshot{}, err
+	}
+	return snapshot.WithLabel(label), nil
+}
+
+// ScanDryRun walks the given directories and builds a snapshot like Scan,
+// but does not persist it. Use it to preview what a scan would index.
+// Files reached through overlapping roots (e.g. "." and "./src") appear once.
+func (s *Service) ScanDryRun(ctx context.Context, roots []string, ignore []string) (Snapshot, error) {
+	files, err := s.walk(ctx, roots, ignore)
+	if err != nil {
+		return Snapshot{}, err
+	}
 
-	return snapshot, nil
+	return NewSnapshot(SnapshotID(s.idGen.NewID()), files).WithRoots(roots, ignore), nil
+}
+
+// resolveSnapshot retrieves the snapshot with the given ID or, failing that, the given label.
+// If neither exists, the error of the ID lookup is returned.
+func (s *Service) resolveSnapshot(ctx context.Context, ref SnapshotID) (Snapshot, error) {
+	snapshot, err := s.store.GetSnapshot(ctx, ref)
+	if err == nil {
+		return snapshot, nil
+	}
+	if labeled, labelErr := s.store.GetSnapshotByLabel(ctx, string(ref)); labelErr == nil {
+		return labeled, nil
+	}
+	return Snapshot{}, err
+}
+
+// diffAgainstWalk walks the given roots and diffs the result against the baseline.
+func (s *Service) diffAgainstWalk(ctx context.Context, baseline Snapshot, roots, ignore []string) (DiffResult, error) {
+	files, err := s.walk(ctx, roots, ignore)
+	if err != nil {
+		return DiffResult{}, err
+	}
+
+	return diffSnapshots(baseline, NewSnapshot("", files)), nil
+}
+
+// walk walks the given roots, removes duplicate files, and applies the base directory.
+func (s *Service) walk(ctx context.Context, roots, ignore []string) ([]FileInfo, error) {
+	files, err := s.walker.Walk(ctx, roots, ignore)
+	if err != nil {
+		return nil, err
+	}
+	files = dedupeFiles(files)
+	if s.baseDir != "" {
+		files = relativeFiles(files, s.baseDir)
+	}
+	return files, nil
+}
+
+// relativeFiles rewrites the paths of files inside baseDir relative to it.
+func relativeFiles(files []FileInfo, baseDir string) []FileInfo {
+	base, err := filepath.Abs(baseDir)
+	if err != nil {
+		return files
+	}
+	for i, f := range files {
+		path, err := filepath.Abs(f.Path)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		files[i].Path = filepath.ToSlash(rel)
+	}
+	return files
+}
+
+// dedupeFiles keeps the first occurrence of each file, comparing absolute paths,
+// so that "./src/a.go" and "src/a.go" count as the same file.
+func dedupeFiles(files []FileInfo) []FileInfo {
+	seen := make(map[string]bool, len(files))
+	result := make([]FileInfo, 0, len(files))
+	for _, f := range files {
+		key, err := filepath.Abs(f.Path)
+		if err != nil {
+			key = filepath.Clean(f.Path)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, f)
+	}
+	return result
 }
 
 // diffSnapshots computes the diff between two snapshots.
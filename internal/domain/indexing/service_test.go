@@ -2,20 +2,26 @@ package indexing_test
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
 	"github.com/andygeiss/go-agent/internal/domain/indexing"
 )
 
 // mockFileWalker is a test double for FileWalker.
 type mockFileWalker struct {
-	err   error
-	files []indexing.FileInfo
+	err    error
+	files  []indexing.FileInfo
+	ignore []string
+	roots  []string
 }
 
-func (m *mockFileWalker) Walk(_ context.Context, _ []string, _ []string) ([]indexing.FileInfo, error) {
+func (m *mockFileWalker) Walk(_ context.Context, roots []string, ignore []string) ([]indexing.FileInfo, error) {
+	m.roots = roots
+	m.ignore = ignore
 	return m.files, m.err
 }
 
@@ -58,6 +64,36 @@ func (m *mockIndexStore) GetSnapshot(_ context.Context, id indexing.SnapshotID)
 	return indexing.Snapshot{}, indexing.ErrSnapshotNotFound
 }
 
+func (m *mockIndexStore) GetSnapshotByLabel(_ context.Context, label string) (indexing.Snapshot, error) {
+	if m.err != nil {
+		return indexing.Snapshot{}, m.err
+	}
+	for _, snapshot := range m.snapshots {
+		if label != "" && snapshot.Label == label {
+			return snapshot, nil
+		}
+	}
+	return indexing.Snapshot{}, indexing.ErrSnapshotNotFound
+}
+
+func (m *mockIndexStore) LabelSnapshot(_ context.Context, id indexing.SnapshotID, label string) error {
+	if m.err != nil {
+		return m.err
+	}
+	if _, ok := m.snapshots[id]; !ok {
+		return indexing.ErrSnapshotNotFound
+	}
+	for key, snapshot := range m.snapshots {
+		if key == id {
+			snapshot.Label = label
+		} else if snapshot.Label == label {
+			snapshot.Label = ""
+		}
+		m.snapshots[key] = snapshot
+	}
+	return nil
+}
+
 func Test_Service_Scan_Should_ReturnSnapshotWithFiles(t *testing.T) {
 	// Arrange
 	now := time.Now()
@@ -69,10 +105,10 @@ func Test_Service_Scan_Should_ReturnSnapshotWithFiles(t *testing.T) {
 	walker := &mockFileWalker{files: files}
 	store := newMockIndexStore()
 	idCounter := 0
-	idGen := func() string {
+	idGen := agent.IDGeneratorFunc(func() string {
 		idCounter++
 		return "snap-" + string(rune('0'+idCounter))
-	}
+	})
 
 	svc := indexing.NewService(walker, store, idGen)
 
@@ -99,15 +135,17 @@ func Test_Service_ChangedSince_Should_ReturnFilesModifiedAfterTime(t *testing.T)
 	store.latest.Files = files // Ensure files are set
 
 	walker := &mockFileWalker{}
-	svc := indexing.NewService(walker, store, func() string { return "id" })
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "id" }))
 
 	// Act
 	sinceTime := baseTime
-	changed, err := svc.ChangedSince(context.Background(), sinceTime)
+	changed, err := svc.ChangedSince(context.Background(), sinceTime, "")
 
 	// Assert
 	assert.That(t, "error must be nil", err == nil, true)
-	assert.That(t, "changed count must be 2", len(changed), 2)
+	assert.That(t, "changed count must be 2", len(changed.Files), 2)
+	assert.That(t, "snapshot ID must be the compared snapshot", changed.SnapshotID, indexing.SnapshotID("snap-1"))
+	assert.That(t, "since must be reported", changed.Since, sinceTime)
 }
 
 func Test_Service_DiffSnapshots_Should_ReturnAddedChangedRemoved(t *testing.T) {
@@ -133,10 +171,10 @@ func Test_Service_DiffSnapshots_Should_ReturnAddedChangedRemoved(t *testing.T) {
 	store.snapshots["snap-to"] = toSnapshot
 
 	walker := &mockFileWalker{}
-	svc := indexing.NewService(walker, store, func() string { return "id" })
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "id" }))
 
 	// Act
-	diff, err := svc.DiffSnapshots(context.Background(), "snap-from", "snap-to")
+	diff, err := svc.DiffSnapshots(context.Background(), "snap-from", "snap-to", "")
 
 	// Assert
 	assert.That(t, "error must be nil", err == nil, true)
@@ -148,6 +186,50 @@ func Test_Service_DiffSnapshots_Should_ReturnAddedChangedRemoved(t *testing.T) {
 	assert.That(t, "removed path must match", diff.Removed[0].Path, "/path/to/removed.go")
 }
 
+func Test_Service_ChangedSince_With_PathPrefix_Should_ReturnOnlyFilesUnderPrefix(t *testing.T) {
+	// Arrange
+	since := time.Now().Add(-time.Hour)
+	store := newMockIndexStore()
+	store.latest = indexing.NewSnapshot("snap-1", []indexing.FileInfo{
+		indexing.NewFileInfo("/repo/services/api/main.go", time.Now(), 100),
+		indexing.NewFileInfo("/repo/services/web/main.go", time.Now(), 200),
+		indexing.NewFileInfo("/repo/services/api/old.go", since.Add(-time.Hour), 300),
+	})
+	svc := indexing.NewService(&mockFileWalker{}, store, agent.IDGeneratorFunc(func() string { return "id" }))
+
+	// Act
+	changed, err := svc.ChangedSince(context.Background(), since, "/repo/services/api/")
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "changed count must be 1", len(changed.Files), 1)
+	assert.That(t, "changed path must be under prefix", changed.Files[0].Path, "/repo/services/api/main.go")
+}
+
+func Test_Service_DiffSnapshots_With_PathPrefix_Should_ReturnOnlyFilesUnderPrefix(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	store := newMockIndexStore()
+	store.snapshots["snap-from"] = indexing.NewSnapshot("snap-from", []indexing.FileInfo{
+		indexing.NewFileInfo("/repo/api/removed.go", now, 100),
+		indexing.NewFileInfo("/repo/web/removed.go", now, 100),
+		indexing.NewFileInfo("/repo/api/changed.go", now, 100),
+	})
+	store.snapshots["snap-to"] = indexing.NewSnapshot("snap-to", []indexing.FileInfo{
+		indexing.NewFileInfo("/repo/api/changed.go", now, 150),
+		indexing.NewFileInfo("/repo/api/added.go", now, 100),
+		indexing.NewFileInfo("/repo/web/added.go", now, 100),
+	})
+	svc := indexing.NewService(&mockFileWalker{}, store, agent.IDGeneratorFunc(func() string { return "id" }))
+
+	// Act
+	diff, err := svc.DiffSnapshots(context.Background(), "snap-from", "snap-to", "/repo/api/")
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "diff must only contain files under prefix", diff.String(), "+ /repo/api/added.go\n~ /repo/api/changed.go\n- /repo/api/removed.go\n")
+}
+
 func Test_Service_DiffSnapshots_With_SizeChange_Should_DetectChange(t *testing.T) {
 	// Arrange
 	now := time.Now()
@@ -165,10 +247,10 @@ func Test_Service_DiffSnapshots_With_SizeChange_Should_DetectChange(t *testing.T
 	store.snapshots["snap-to"] = indexing.NewSnapshot("snap-to", toFiles)
 
 	walker := &mockFileWalker{}
-	svc := indexing.NewService(walker, store, func() string { return "id" })
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "id" }))
 
 	// Act
-	diff, err := svc.DiffSnapshots(context.Background(), "snap-from", "snap-to")
+	diff, err := svc.DiffSnapshots(context.Background(), "snap-from", "snap-to", "")
 
 	// Assert
 	assert.That(t, "error must be nil", err == nil, true)
@@ -179,11 +261,284 @@ func Test_Service_DiffSnapshots_With_NonexistentSnapshot_Should_ReturnError(t *t
 	// Arrange
 	store := newMockIndexStore()
 	walker := &mockFileWalker{}
-	svc := indexing.NewService(walker, store, func() string { return "id" })
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "id" }))
 
 	// Act
-	_, err := svc.DiffSnapshots(context.Background(), "nonexistent", "also-nonexistent")
+	_, err := svc.DiffSnapshots(context.Background(), "nonexistent", "also-nonexistent", "")
 
 	// Assert
 	assert.That(t, "error must not be nil", err != nil, true)
 }
+
+func Test_Service_ScanDryRun_Should_ReturnSnapshotWithoutSaving(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	files := []indexing.FileInfo{
+		indexing.NewFileInfo("/project/a.go", now, 100),
+		indexing.NewFileInfo("/project/b.go", now, 200),
+	}
+	store := newMockIndexStore()
+	svc := indexing.NewService(&mockFileWalker{files: files}, store, agent.IDGeneratorFunc(func() string { return "snap-1" }))
+
+	// Act
+	snapshot, err := svc.ScanDryRun(context.Background(), []string{"/project"}, []string{".git"})
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "snapshot ID must be set", snapshot.ID, indexing.SnapshotID("snap-1"))
+	assert.That(t, "snapshot must contain all files", snapshot.Files, files)
+	assert.That(t, "snapshot must record roots", snapshot.Roots, []string{"/project"})
+	assert.That(t, "snapshot must record ignore patterns", snapshot.Ignore, []string{".git"})
+	assert.That(t, "store must not contain snapshots", len(store.snapshots), 0)
+	assert.That(t, "latest snapshot must not be set", store.latest.ID, indexing.SnapshotID(""))
+}
+
+func Test_Service_Scan_Should_RecordRootsAndIgnore(t *testing.T) {
+	// Arrange
+	store := newMockIndexStore()
+	walker := &mockFileWalker{}
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "snap-1" }))
+
+	// Act
+	snapshot, err := svc.Scan(context.Background(), []string{"/project"}, []string{".git"})
+
+	// Assert
+	assert.That(t, "error must be nil", err == nil, true)
+	assert.That(t, "roots must be recorded", snapshot.Roots, []string{"/project"})
+	assert.That(t, "ignore must be recorded", snapshot.Ignore, []string{".git"})
+}
+
+func Test_Service_ChangedSinceSnapshot_With_DeletedFile_Should_ReportRemoved(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	baselineFiles := []indexing.FileInfo{
+		indexing.NewFileInfo("/project/kept.go", now, 100).WithHash("hash1"),
+		indexing.NewFileInfo("/project/edited.go", now, 200).WithHash("hash2"),
+		indexing.NewFileInfo("/project/deleted.go", now, 300).WithHash("hash3"),
+	}
+	store := newMockIndexStore()
+	store.snapshots["baseline"] = indexing.NewSnapshot("baseline", baselineFiles).
+		WithRoots([]string{"/project"}, nil)
+
+	walker := &mockFileWalker{files: []indexing.FileInfo{
+		indexing.NewFileInfo("/project/kept.go", now, 100).WithHash("hash1"),
+		indexing.NewFileInfo("/project/edited.go", now, 250).WithHash("hash2-new"),
+		indexing.NewFileInfo("/project/new.go", now, 50).WithHash("hash4"),
+	}}
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "id" }))
+
+	// Act
+	diff, err := svc.ChangedSinceSnapshot(context.Background(), "baseline")
+
+	// Assert
+	assert.That(t, "error must be nil", err == nil, true)
+	assert.That(t, "removed count must be 1", len(diff.Removed), 1)
+	assert.That(t, "removed path must match", diff.Removed[0].Path, "/project/deleted.go")
+	assert.That(t, "added path must match", diff.Added[0].Path, "/project/new.go")
+	assert.That(t, "changed path must match", diff.Changed[0].Path, "/project/edited.go")
+	assert.That(t, "fresh scan must not be persisted", len(store.snapshots), 1)
+}
+
+func Test_Service_ChangedSinceSnapshot_Without_Roots_Should_ReturnError(t *testing.T) {
+	// Arrange
+	store := newMockIndexStore()
+	store.snapshots["legacy"] = indexing.NewSnapshot("legacy", nil)
+	svc := indexing.NewService(&mockFileWalker{}, store, agent.IDGeneratorFunc(func() string { return "id" }))
+
+	// Act
+	_, err := svc.ChangedSinceSnapshot(context.Background(), "legacy")
+
+	// Assert
+	assert.That(t, "error must be ErrSnapshotRootsUnknown", err, indexing.ErrSnapshotRootsUnknown)
+}
+
+func Test_Service_ChangedSinceSnapshot_With_NonexistentSnapshot_Should_ReturnError(t *testing.T) {
+	// Arrange
+	svc := indexing.NewService(&mockFileWalker{}, newMockIndexStore(), agent.IDGeneratorFunc(func() string { return "id" }))
+
+	// Act
+	_, err := svc.ChangedSinceSnapshot(context.Background(), "missing")
+
+	// Assert
+	assert.That(t, "error must be ErrSnapshotNotFound", err, indexing.ErrSnapshotNotFound)
+}
+
+func Test_Service_DiffAgainstCurrent_With_ModifiedFiles_Should_ClassifyChanges(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	store := newMockIndexStore()
+	store.snapshots["baseline"] = indexing.NewSnapshot("baseline", []indexing.FileInfo{
+		indexing.NewFileInfo("/project/kept.go", now, 100).WithHash("hash1"),
+		indexing.NewFileInfo("/project/edited.go", now, 200).WithHash("hash2"),
+		indexing.NewFileInfo("/project/deleted.go", now, 300).WithHash("hash3"),
+	})
+	walker := &mockFileWalker{files: []indexing.FileInfo{
+		indexing.NewFileInfo("/project/kept.go", now, 100).WithHash("hash1"),
+		indexing.NewFileInfo("/project/edited.go", now, 250).WithHash("hash2-new"),
+		indexing.NewFileInfo("/project/new.go", now, 50).WithHash("hash4"),
+	}}
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "id" }))
+
+	// Act
+	diff, err := svc.DiffAgainstCurrent(context.Background(), "baseline", []string{"/project"}, []string{".git"})
+
+	// Assert
+	assert.That(t, "error must be nil", err == nil, true)
+	assert.That(t, "added count must be 1", len(diff.Added), 1)
+	assert.That(t, "added path must match", diff.Added[0].Path, "/project/new.go")
+	assert.That(t, "changed count must be 1", len(diff.Changed), 1)
+	assert.That(t, "changed path must match", diff.Changed[0].Path, "/project/edited.go")
+	assert.That(t, "removed count must be 1", len(diff.Removed), 1)
+	assert.That(t, "removed path must match", diff.Removed[0].Path, "/project/deleted.go")
+	assert.That(t, "walker must scan the given paths", walker.roots, []string{"/project"})
+	assert.That(t, "walker must use the given ignore patterns", walker.ignore, []string{".git"})
+	assert.That(t, "live scan must not be persisted", len(store.snapshots), 1)
+}
+
+func Test_Service_DiffAgainstCurrent_With_NonexistentSnapshot_Should_ReturnError(t *testing.T) {
+	// Arrange
+	svc := indexing.NewService(&mockFileWalker{}, newMockIndexStore(), agent.IDGeneratorFunc(func() string { return "id" }))
+
+	// Act
+	_, err := svc.DiffAgainstCurrent(context.Background(), "missing", []string{"/project"}, nil)
+
+	// Assert
+	assert.That(t, "error must be ErrSnapshotNotFound", err, indexing.ErrSnapshotNotFound)
+}
+
+func Test_Service_Scan_With_OverlappingRoots_Should_ContainEachFileOnce(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	walker := &mockFileWalker{files: []indexing.FileInfo{
+		indexing.NewFileInfo("main.go", now, 100),
+		indexing.NewFileInfo("src/util.go", now, 200),
+		indexing.NewFileInfo("./src/util.go", now, 200),
+		indexing.NewFileInfo("src/../main.go", now, 100),
+	}}
+	svc := indexing.NewService(walker, newMockIndexStore(), agent.IDGeneratorFunc(func() string { return "snap-1" }))
+
+	// Act
+	snapshot, err := svc.Scan(context.Background(), []string{".", "./src"}, nil)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "each file must appear once", snapshot.FileCount(), 2)
+	assert.That(t, "first occurrence must be kept", snapshot.Files[1].Path, "src/util.go")
+}
+
+func Test_Service_DiffAgainstCurrent_With_OverlappingRoots_Should_NotReportDuplicates(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	walker := &mockFileWalker{files: []indexing.FileInfo{
+		indexing.NewFileInfo("/repo/a.go", now, 100),
+		indexing.NewFileInfo("/repo/a.go", now, 100),
+	}}
+	store := newMockIndexStore()
+	store.snapshots["snap-1"] = indexing.NewSnapshot("snap-1", nil)
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "snap-2" }))
+
+	// Act
+	diff, err := svc.DiffAgainstCurrent(context.Background(), "snap-1", []string{"/repo", "/repo"}, nil)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "added file must be reported once", len(diff.Added), 1)
+}
+
+func Test_Service_WithBaseDir_Should_MakeSnapshotsFromDifferentLocationsDiffable(t *testing.T) {
+	// Arrange
+	modTime := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	layout := func(root string) []indexing.FileInfo {
+		return []indexing.FileInfo{
+			indexing.NewFileInfo(filepath.Join(root, "main.go"), modTime, 100),
+			indexing.NewFileInfo(filepath.Join(root, "src", "util.go"), modTime, 200),
+		}
+	}
+	store := newMockIndexStore()
+	ids := []string{"snap-1", "snap-2"}
+	idGen := agent.IDGeneratorFunc(func() string {
+		id := ids[0]
+		ids = ids[1:]
+		return id
+	})
+	laptop := indexing.NewService(&mockFileWalker{files: layout("/home/alice/repo")}, store, idGen).WithBaseDir("/home/alice/repo")
+	server := indexing.NewService(&mockFileWalker{files: layout("/srv/build/repo")}, store, idGen).WithBaseDir("/srv/build/repo")
+	first, _ := laptop.Scan(context.Background(), []string{"/home/alice/repo"}, nil)
+	_, _ = server.Scan(context.Background(), []string{"/srv/build/repo"}, nil)
+
+	// Act
+	diff, err := server.DiffSnapshots(context.Background(), "snap-1", "snap-2", "")
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "paths must be relative", first.Files[1].Path, "src/util.go")
+	assert.That(t, "diff must be empty", diff.IsEmpty(), true)
+}
+
+func Test_Service_WithBaseDir_With_FileOutsideBase_Should_KeepPath(t *testing.T) {
+	// Arrange
+	walker := &mockFileWalker{files: []indexing.FileInfo{
+		indexing.NewFileInfo("/repo/main.go", time.Now(), 100),
+		indexing.NewFileInfo("/other/notes.md", time.Now(), 50),
+	}}
+	svc := indexing.NewService(walker, newMockIndexStore(), agent.IDGeneratorFunc(func() string { return "snap-1" })).
+		WithBaseDir("/repo")
+
+	// Act
+	snapshot, err := svc.ScanDryRun(context.Background(), []string{"/repo", "/other"}, nil)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "file inside base must be relative", snapshot.Files[0].Path, "main.go")
+	assert.That(t, "file outside base must keep its path", snapshot.Files[1].Path, "/other/notes.md")
+}
+
+func Test_Service_DiffSnapshots_With_Labels_Should_ResolveLabels(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	store := newMockIndexStore()
+	store.snapshots["snap-from"] = indexing.NewSnapshot("snap-from", []indexing.FileInfo{
+		indexing.NewFileInfo("/repo/removed.go", now, 100),
+	})
+	store.snapshots["snap-to"] = indexing.NewSnapshot("snap-to", []indexing.FileInfo{
+		indexing.NewFileInfo("/repo/added.go", now, 100),
+	})
+	svc := indexing.NewService(&mockFileWalker{}, store, agent.IDGeneratorFunc(func() string { return "id" }))
+	_ = svc.LabelSnapshot(context.Background(), "snap-from", "before-refactor")
+
+	// Act
+	diff, err := svc.DiffSnapshots(context.Background(), "before-refactor", "snap-to", "")
+
+	// Assert
+	assert.That(t, "error must be nil", err == nil, true)
+	assert.That(t, "added path must match", diff.Added[0].Path, "/repo/added.go")
+	assert.That(t, "removed path must match", diff.Removed[0].Path, "/repo/removed.go")
+}
+
+func Test_Service_DiffSnapshots_With_UnknownLabel_Should_ReturnNotFound(t *testing.T) {
+	// Arrange
+	store := newMockIndexStore()
+	store.snapshots["snap-to"] = indexing.NewSnapshot("snap-to", nil)
+	svc := indexing.NewService(&mockFileWalker{}, store, agent.IDGeneratorFunc(func() string { return "id" }))
+
+	// Act
+	_, err := svc.DiffSnapshots(context.Background(), "missing", "snap-to", "")
+
+	// Assert
+	assert.That(t, "error must be ErrSnapshotNotFound", err, indexing.ErrSnapshotNotFound)
+}
+
+func Test_Service_ScanWithLabel_Should_LabelSavedSnapshot(t *testing.T) {
+	// Arrange
+	store := newMockIndexStore()
+	walker := &mockFileWalker{files: []indexing.FileInfo{indexing.NewFileInfo("/repo/a.go", time.Now(), 1)}}
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "snap-1" }))
+
+	// Act
+	snapshot, err := svc.ScanWithLabel(context.Background(), []string{"/repo"}, nil, "before-refactor")
+
+	// Assert
+	assert.That(t, "error must be nil", err == nil, true)
+	assert.That(t, "returned snapshot must carry the label", snapshot.Label, "before-refactor")
+	assert.That(t, "stored snapshot must carry the label", store.snapshots["snap-1"].Label, "before-refactor")
+}
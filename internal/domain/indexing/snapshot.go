@@ -7,7 +7,12 @@ import (
 	"encoding/hex"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/andygeiss/cloud-native-utils/slices"
 )
 
 // SnapshotID is the unique identifier for a snapshot.
@@ -37,10 +42,15 @@ func (f FileInfo) WithHash(hash string) FileInfo {
 }
 
 // Snapshot represents a point-in-time capture of file system state.
+// A label names a checkpoint (e.g. "before-refactor") and can be used in place of the ID.
 type Snapshot struct {
-	CreatedAt time.Time  // When the snapshot was created
-	ID        SnapshotID // Unique identifier
-	Files     []FileInfo // List of files in the snapshot
+	CreatedAt time.Time         // When the snapshot was created
+	Meta      map[string]string // Arbitrary metadata
+	ID        SnapshotID        // Unique identifier
+	Label     string            // Human-readable name, unique among snapshots
+	Files     []FileInfo        // List of files in the snapshot
+	Ignore    []string          // Ignore patterns used when scanning
+	Roots     []string          // Directories that were scanned
 }
 
 // NewSnapshot creates a new Snapshot with the given ID and files.
@@ -52,11 +62,56 @@ func NewSnapshot(id SnapshotID, files []FileInfo) Snapshot {
 	}
 }
 
+// WithLabel sets the human-readable label of the snapshot.
+func (s Snapshot) WithLabel(label string) Snapshot {
+	s.Label = label
+	return s
+}
+
+// WithMeta sets arbitrary metadata on the snapshot.
+func (s Snapshot) WithMeta(meta map[string]string) Snapshot {
+	s.Meta = meta
+	return s
+}
+
+// WithRoots records the scanned directories and ignore patterns on the snapshot.
+func (s Snapshot) WithRoots(roots []string, ignore []string) Snapshot {
+	s.Roots = roots
+	s.Ignore = ignore
+	return s
+}
+
+// DirectoryBreakdown returns the number of files per directory (the parent directory of each file path).
+func (s Snapshot) DirectoryBreakdown() map[string]int {
+	breakdown := make(map[string]int)
+	for _, f := range s.Files {
+		breakdown[filepath.Dir(f.Path)]++
+	}
+	return breakdown
+}
+
+// ExtensionBreakdown returns the number of files per lowercase file extension
+// (including the dot, e.g. ".go"). Files without an extension are counted under "".
+func (s Snapshot) ExtensionBreakdown() map[string]int {
+	breakdown := make(map[string]int)
+	for _, f := range s.Files {
+		breakdown[strings.ToLower(filepath.Ext(f.Path))]++
+	}
+	return breakdown
+}
+
 // FileCount returns the number of files in the snapshot.
 func (s Snapshot) FileCount() int {
 	return len(s.Files)
 }
 
+// FilesOverSize returns the files larger than threshold bytes, largest first.
+func (s Snapshot) FilesOverSize(threshold int64) []FileInfo {
+	return sortBySizeDesc(slices.Filter(s.Files, func(f FileInfo) bool {
+		return f.Size > threshold
+	}))
+}
+
 // GetFileByPath returns the FileInfo for the given path, or nil if not found.
 func (s Snapshot) GetFileByPath(path string) *FileInfo {
 	for i := range s.Files {
@@ -67,6 +122,41 @@ func (s Snapshot) GetFileByPath(path string) *FileInfo {
 	return nil
 }
 
+// LargestFiles returns the n largest files, largest first.
+// Files of equal size are ordered by path.
+func (s Snapshot) LargestFiles(n int) []FileInfo {
+	files := sortBySizeDesc(append([]FileInfo(nil), s.Files...))
+	return files[:min(max(n, 0), len(files))]
+}
+
+// TotalSize returns the sum of all file sizes in bytes.
+func (s Snapshot) TotalSize() int64 {
+	var total int64
+	for _, f := range s.Files {
+		total += f.Size
+	}
+	return total
+}
+
+// sortBySizeDesc sorts the files in place by size (largest first), then by path.
+func sortBySizeDesc(files []FileInfo) []FileInfo {
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Size != files[j].Size {
+			return files[i].Size > files[j].Size
+		}
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// ChangedSinceResult holds the files that changed after Since, together with
+// the snapshot they were read from, so callers can report the provenance.
+type ChangedSinceResult struct {
+	Since      time.Time  // Point in time the files were compared against
+	SnapshotID SnapshotID // Snapshot whose files were compared
+	Files      []FileInfo // Files modified after Since
+}
+
 // DiffResult represents the difference between two snapshots.
 type DiffResult struct {
 	Added   []FileInfo // Files in the newer snapshot but not the older
@@ -74,6 +164,81 @@ type DiffResult struct {
 	Removed []FileInfo // Files in the older snapshot but not the newer
 }
 
+// DiffSummary holds the number of added, changed, and removed files of a diff.
+type DiffSummary struct {
+	Added   int
+	Changed int
+	Removed int
+}
+
+// Total returns the total number of differences.
+func (s DiffSummary) Total() int {
+	return s.Added + s.Changed + s.Removed
+}
+
+// FilterByPrefix returns a diff containing only files whose path begins with prefix.
+// An empty prefix returns the diff unchanged.
+func (d DiffResult) FilterByPrefix(prefix string) DiffResult {
+	if prefix == "" {
+		return d
+	}
+	hasPrefix := func(f FileInfo) bool {
+		return strings.HasPrefix(f.Path, prefix)
+	}
+	return DiffResult{
+		Added:   slices.Filter(d.Added, hasPrefix),
+		Changed: slices.Filter(d.Changed, hasPrefix),
+		Removed: slices.Filter(d.Removed, hasPrefix),
+	}
+}
+
+// IsEmpty returns true if the diff contains no differences.
+func (d DiffResult) IsEmpty() bool {
+	return d.Summary().Total() == 0
+}
+
+// String renders the diff as one line per file, sorted by path.
+// Lines are prefixed git-style with "+" (added), "~" (changed), or "-" (removed).
+// An empty diff renders as an empty string.
+func (d DiffResult) String() string {
+	type diffLine struct {
+		path   string
+		marker byte
+	}
+
+	lines := make([]diffLine, 0, d.Summary().Total())
+	for _, f := range d.Added {
+		lines = append(lines, diffLine{path: f.Path, marker: '+'})
+	}
+	for _, f := range d.Changed {
+		lines = append(lines, diffLine{path: f.Path, marker: '~'})
+	}
+	for _, f := range d.Removed {
+		lines = append(lines, diffLine{path: f.Path, marker: '-'})
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].path < lines[j].path
+	})
+
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteByte(line.marker)
+		sb.WriteByte(' ')
+		sb.WriteString(line.path)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Summary returns the number of added, changed, and removed files.
+func (d DiffResult) Summary() DiffSummary {
+	return DiffSummary{
+		Added:   len(d.Added),
+		Changed: len(d.Changed),
+		Removed: len(d.Removed),
+	}
+}
+
 // HashFile computes the SHA-256 hash of a file's contents.
 func HashFile(path string) (string, error) {
 	f, err := os.Open(path) //nolint:gosec // path is validated by caller
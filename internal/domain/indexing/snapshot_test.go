@@ -95,3 +95,190 @@ func Test_Snapshot_GetFileByPath_With_NonexistentPath_Should_ReturnNil(t *testin
 	// Assert
 	assert.That(t, "result must be nil", result == nil, true)
 }
+
+func Test_Snapshot_DirectoryBreakdown_Should_CountFilesPerDirectory(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	snapshot := indexing.NewSnapshot("snap-1", []indexing.FileInfo{
+		indexing.NewFileInfo("/src/main.go", now, 100),
+		indexing.NewFileInfo("/src/util/strings.go", now, 200),
+		indexing.NewFileInfo("/src/util/slices.go", now, 50),
+	})
+
+	// Act
+	breakdown := snapshot.DirectoryBreakdown()
+
+	// Assert
+	assert.That(t, "breakdown must count each directory", breakdown, map[string]int{"/src": 1, "/src/util": 2})
+}
+
+func Test_Snapshot_ExtensionBreakdown_Should_CountFilesPerExtension(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	snapshot := indexing.NewSnapshot("snap-1", []indexing.FileInfo{
+		indexing.NewFileInfo("/src/main.go", now, 100),
+		indexing.NewFileInfo("/src/util.go", now, 200),
+		indexing.NewFileInfo("/src/README.MD", now, 50),
+		indexing.NewFileInfo("/src/docs.md", now, 25),
+		indexing.NewFileInfo("/src/Makefile", now, 10),
+	})
+
+	// Act
+	breakdown := snapshot.ExtensionBreakdown()
+
+	// Assert
+	assert.That(t, "breakdown must count each extension", breakdown, map[string]int{".go": 2, ".md": 2, "": 1})
+}
+
+func Test_Snapshot_TotalSize_Should_SumFileSizes(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	snapshot := indexing.NewSnapshot("snap-1", []indexing.FileInfo{
+		indexing.NewFileInfo("/src/main.go", now, 100),
+		indexing.NewFileInfo("/src/util.go", now, 200),
+		indexing.NewFileInfo("/src/docs.md", now, 25),
+	})
+
+	// Act
+	total := snapshot.TotalSize()
+
+	// Assert
+	assert.That(t, "total size must be the sum of file sizes", total, int64(325))
+}
+
+func Test_Snapshot_TotalSize_With_NoFiles_Should_ReturnZero(t *testing.T) {
+	// Arrange
+	snapshot := indexing.NewSnapshot("snap-1", nil)
+
+	// Act
+	total := snapshot.TotalSize()
+
+	// Assert
+	assert.That(t, "total size must be zero", total, int64(0))
+}
+
+func Test_DiffResult_Summary_Should_CountEachKind(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	diff := indexing.DiffResult{
+		Added:   []indexing.FileInfo{indexing.NewFileInfo("a.go", now, 1), indexing.NewFileInfo("b.go", now, 1)},
+		Changed: []indexing.FileInfo{indexing.NewFileInfo("c.go", now, 1)},
+		Removed: []indexing.FileInfo{indexing.NewFileInfo("d.go", now, 1), indexing.NewFileInfo("e.go", now, 1), indexing.NewFileInfo("f.go", now, 1)},
+	}
+
+	// Act
+	summary := diff.Summary()
+
+	// Assert
+	assert.That(t, "added must be 2", summary.Added, 2)
+	assert.That(t, "changed must be 1", summary.Changed, 1)
+	assert.That(t, "removed must be 3", summary.Removed, 3)
+	assert.That(t, "total must be 6", summary.Total(), 6)
+	assert.That(t, "diff must not be empty", diff.IsEmpty(), false)
+}
+
+func Test_DiffResult_String_Should_RenderLinesSortedByPath(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	diff := indexing.DiffResult{
+		Added:   []indexing.FileInfo{indexing.NewFileInfo("z/new.go", now, 1), indexing.NewFileInfo("a/new.go", now, 1)},
+		Changed: []indexing.FileInfo{indexing.NewFileInfo("m/edit.go", now, 1)},
+		Removed: []indexing.FileInfo{indexing.NewFileInfo("b/old.go", now, 1)},
+	}
+
+	// Act
+	rendered := diff.String()
+
+	// Assert
+	assert.That(t, "output must be sorted by path", rendered, "+ a/new.go\n- b/old.go\n~ m/edit.go\n+ z/new.go\n")
+}
+
+func Test_DiffResult_String_Should_BeStableAcrossRuns(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	diff := indexing.DiffResult{
+		Added:   []indexing.FileInfo{indexing.NewFileInfo("c.go", now, 1), indexing.NewFileInfo("a.go", now, 1)},
+		Changed: []indexing.FileInfo{indexing.NewFileInfo("b.go", now, 1)},
+	}
+	first := diff.String()
+
+	// Act
+	var different bool
+	for range 10 {
+		if diff.String() != first {
+			different = true
+		}
+	}
+
+	// Assert
+	assert.That(t, "output must be stable", different, false)
+}
+
+func Test_DiffResult_String_With_NoDifferences_Should_ReturnEmpty(t *testing.T) {
+	// Arrange
+	diff := indexing.DiffResult{}
+
+	// Act
+	rendered := diff.String()
+
+	// Assert
+	assert.That(t, "output must be empty", rendered, "")
+	assert.That(t, "diff must be empty", diff.IsEmpty(), true)
+}
+
+// newSizedSnapshot creates a snapshot of files with varied sizes.
+func newSizedSnapshot() indexing.Snapshot {
+	now := time.Now()
+	return indexing.NewSnapshot("snap-1", []indexing.FileInfo{
+		indexing.NewFileInfo("/src/small.go", now, 10),
+		indexing.NewFileInfo("/src/huge.bin", now, 5000),
+		indexing.NewFileInfo("/src/medium.go", now, 300),
+		indexing.NewFileInfo("/src/b_large.json", now, 1200),
+		indexing.NewFileInfo("/src/a_large.json", now, 1200),
+	})
+}
+
+// paths returns the paths of the files.
+func paths(files []indexing.FileInfo) []string {
+	result := make([]string, len(files))
+	for i, f := range files {
+		result[i] = f.Path
+	}
+	return result
+}
+
+func Test_Snapshot_LargestFiles_Should_ReturnBiggestFilesFirst(t *testing.T) {
+	// Arrange
+	snapshot := newSizedSnapshot()
+
+	// Act
+	largest := snapshot.LargestFiles(3)
+
+	// Assert
+	assert.That(t, "files must be sorted by size, then path", paths(largest), []string{"/src/huge.bin", "/src/a_large.json", "/src/b_large.json"})
+	assert.That(t, "snapshot order must be unchanged", snapshot.Files[0].Path, "/src/small.go")
+}
+
+func Test_Snapshot_LargestFiles_With_NExceedingFileCount_Should_ReturnAllFiles(t *testing.T) {
+	// Arrange
+	snapshot := newSizedSnapshot()
+
+	// Act
+	largest := snapshot.LargestFiles(10)
+
+	// Assert
+	assert.That(t, "all files must be returned", len(largest), 5)
+	assert.That(t, "smallest file must be last", largest[4].Path, "/src/small.go")
+}
+
+func Test_Snapshot_FilesOverSize_Should_ReturnFilesAboveThreshold(t *testing.T) {
+	// Arrange
+	snapshot := newSizedSnapshot()
+
+	// Act
+	files := snapshot.FilesOverSize(1200)
+
+	// Assert
+	assert.That(t, "only files above the threshold must be returned", paths(files), []string{"/src/huge.bin"})
+	assert.That(t, "threshold 0 must return all files", len(snapshot.FilesOverSize(0)), 5)
+}
@@ -4,6 +4,11 @@ import "errors"
 
 // Sentinel errors for memory service validation (alphabetically sorted).
 var (
-	ErrNoteIDEmpty = errors.New("note ID cannot be empty")
-	ErrNoteNil     = errors.New("note cannot be nil")
+	ErrEmptyEmbedding     = errors.New("embedder returned an empty embedding")
+	ErrFilterEmpty        = errors.New("at least one filter is required")
+	ErrNoSourceNotes      = errors.New("no source notes to consolidate")
+	ErrNoteIDEmpty        = errors.New("note ID cannot be empty")
+	ErrNoteNil            = errors.New("note cannot be nil")
+	ErrSourceNoteNotFound = errors.New("source note not found")
+	ErrSummaryEmpty       = errors.New("summarizer returned an empty summary")
 )
@@ -1,11 +1,124 @@
 package memorizing
 
 import (
+	"cmp"
 	"context"
-
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andygeiss/cloud-native-utils/slices"
 	"github.com/andygeiss/go-agent/internal/domain/agent"
 )
 
+// defaultReEmbedBatchSize is the number of notes ReEmbedAll embeds and writes per batch by default.
+const defaultReEmbedBatchSize = 32
+
+// reEmbedBatchAttempts is the number of times ReEmbedAll tries to embed a batch before failing.
+const reEmbedBatchAttempts = 2
+
+// reEmbedRetryDelay is the time ReEmbedAll waits before retrying a failed batch.
+const reEmbedRetryDelay = 100 * time.Millisecond
+
+// rerankOverFetch is the factor by which SearchReranked over-fetches text matches.
+const rerankOverFetch = 3
+
+// consolidatePrompt instructs the summarizer how to consolidate notes.
+const consolidatePrompt = "You consolidate memory notes. Synthesize the following notes into a single, " +
+	"concise summary that preserves all facts, decisions, and preferences. Reply with the summary only."
+
+// ConsolidateUseCase handles merging several low-level notes into one summary note.
+type ConsolidateUseCase struct {
+	idGen          agent.IDGenerator
+	store          agent.MemoryStore
+	sourceDemotion int
+}
+
+// NewConsolidateUseCase creates a new ConsolidateUseCase with the given store.
+// The idGenerator provides the ID of the summary note.
+func NewConsolidateUseCase(store agent.MemoryStore, idGenerator agent.IDGenerator) *ConsolidateUseCase {
+	return &ConsolidateUseCase{
+		idGen: idGenerator,
+		store: store,
+	}
+}
+
+// WithSourceDemotion lowers the importance of each source note by step
+// once the summary note has been written. Importance never drops below 1.
+func (uc *ConsolidateUseCase) WithSourceDemotion(step int) *ConsolidateUseCase {
+	uc.sourceDemotion = step
+	return uc
+}
+
+// Execute fetches the source notes, asks the summarizer to synthesize them,
+// and writes a summary note that references the sources.
+// All source notes are fetched before anything is written, so a missing ID
+// fails with ErrSourceNoteNotFound without modifying the store.
+func (uc *ConsolidateUseCase) Execute(ctx context.Context, sourceIDs []agent.NoteID, summarizer agent.LLMClient) (*agent.MemoryNote, error) {
+	if len(sourceIDs) == 0 {
+		return nil, ErrNoSourceNotes
+	}
+
+	sources := make([]*agent.MemoryNote, 0, len(sourceIDs))
+	for _, id := range sourceIDs {
+		note, err := uc.store.Get(ctx, id)
+		if err != nil || note == nil {
+			return nil, fmt.Errorf("%w: %s", ErrSourceNoteNotFound, id)
+		}
+		sources = append(sources, note)
+	}
+
+	response, err := summarizer.Run(ctx, buildConsolidateMessages(sources), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize notes: %w", err)
+	}
+	content := strings.TrimSpace(response.Message.Content)
+	if content == "" {
+		return nil, ErrSummaryEmpty
+	}
+
+	ids := make([]string, len(sourceIDs))
+	for i, id := range sourceIDs {
+		ids[i] = string(id)
+	}
+	summary := agent.NewSummaryNote(agent.NoteID(uc.idGen.NewID()), content, ids)
+	if err := uc.store.Write(ctx, summary); err != nil {
+		return nil, err
+	}
+
+	if uc.sourceDemotion > 0 {
+		for _, note := range sources {
+			note.WithImportance(note.Importance - uc.sourceDemotion)
+			if err := uc.store.Write(ctx, note); err != nil {
+				return summary, err
+			}
+		}
+	}
+	return summary, nil
+}
+
+// buildConsolidateMessages builds the summarizer conversation for the source notes.
+func buildConsolidateMessages(sources []*agent.MemoryNote) []agent.Message {
+	var b strings.Builder
+	for i, note := range sources {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		text := note.RawContent
+		if text == "" {
+			text = note.Summary
+		}
+		fmt.Fprintf(&b, "[%s] %s", note.ID, text)
+	}
+	return []agent.Message{
+		agent.NewMessage(agent.RoleSystem, consolidatePrompt),
+		agent.NewMessage(agent.RoleUser, b.String()),
+	}
+}
+
 // DeleteNoteUseCase handles removing memory notes.
 type DeleteNoteUseCase struct {
 	store agent.MemoryStore
@@ -62,10 +175,24 @@ func (uc *SearchNotesUseCase) Execute(ctx context.Context, query string, limit i
 	return uc.store.Search(ctx, query, limit, opts)
 }
 
+// batchEmbedder is implemented by embedding clients that can embed several texts in one call.
+// The embeddings are returned in the order of the texts.
+type batchEmbedder interface {
+	EmbedBatch(ctx context.Context, texts []string) ([]agent.Embedding, error)
+}
+
+// embeddingSearcher is implemented by memory stores that can rank notes by embedding similarity.
+type embeddingSearcher interface {
+	SearchWithEmbedding(ctx context.Context, query string, queryEmbedding agent.Embedding, limit int, opts *agent.MemorySearchOptions) ([]*agent.MemoryNote, error)
+}
+
 // Service provides memory management use cases.
 // It coordinates between memory tools and the storage backend.
 type Service struct {
-	store agent.MemoryStore
+	embedder       agent.EmbeddingClient
+	logger         *slog.Logger
+	store          agent.MemoryStore
+	reEmbedWorkers int
 }
 
 // NewService creates a new memory service with the given store.
@@ -73,6 +200,25 @@ func NewService(store agent.MemoryStore) *Service {
 	return &Service{store: store}
 }
 
+// WithEmbedder sets the embedding client SmartSearch uses to embed queries.
+func (s *Service) WithEmbedder(embedder agent.EmbeddingClient) *Service {
+	s.embedder = embedder
+	return s
+}
+
+// WithLogger sets the logger SmartSearch uses to report falling back to text search.
+func (s *Service) WithLogger(logger *slog.Logger) *Service {
+	s.logger = logger
+	return s
+}
+
+// WithReEmbedWorkers lets ReEmbedAll embed up to workers batches concurrently.
+// Values below 1 embed one batch at a time (default).
+func (s *Service) WithReEmbedWorkers(workers int) *Service {
+	s.reEmbedWorkers = workers
+	return s
+}
+
 // DeleteNote removes a note by ID.
 func (s *Service) DeleteNote(ctx context.Context, id agent.NoteID) error {
 	if id == "" {
@@ -81,6 +227,29 @@ func (s *Service) DeleteNote(ctx context.Context, id agent.NoteID) error {
 	return s.store.Delete(ctx, id)
 }
 
+// DeleteMatching removes every note matching the filters and returns the number of notes deleted.
+// Returns ErrFilterEmpty if opts has no filter, so that a missing filter never deletes all notes.
+// Notes are deleted in ID order; on failure, the notes deleted so far are counted.
+func (s *Service) DeleteMatching(ctx context.Context, opts *agent.MemorySearchOptions) (int, error) {
+	if !opts.HasFilters() {
+		return 0, ErrFilterEmpty
+	}
+
+	// An empty query without a limit matches every note passing the filters
+	notes, err := s.store.Search(ctx, "", 0, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to search notes: %w", err)
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+
+	for i, note := range notes {
+		if err := s.store.Delete(ctx, note.ID); err != nil {
+			return i, fmt.Errorf("failed to delete note %s: %w", note.ID, err)
+		}
+	}
+	return len(notes), nil
+}
+
 // GetNote retrieves a specific note by ID.
 // Returns nil if the note is not found.
 func (s *Service) GetNote(ctx context.Context, id agent.NoteID) (*agent.MemoryNote, error) {
@@ -90,6 +259,173 @@ func (s *Service) GetNote(ctx context.Context, id agent.NoteID) (*agent.MemoryNo
 	return s.store.Get(ctx, id)
 }
 
+// PromoteFrequentlyAccessed raises the importance of every note accessed more than threshold
+// times to newImportance and returns the number of notes promoted.
+// Access counts are only recorded by stores implementing agent.AccessRecorder, e.g. when the
+// memory tools are created with WithAccessTracking. Notes that are already at least as
+// important are left untouched, so importance is never lowered.
+func (s *Service) PromoteFrequentlyAccessed(ctx context.Context, threshold, newImportance int) (int, error) {
+	// An empty query without a limit matches every note
+	notes, err := s.store.Search(ctx, "", 0, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list notes: %w", err)
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+
+	count := 0
+	for _, note := range notes {
+		if note.AccessCount <= threshold || note.Importance >= newImportance {
+			continue
+		}
+		note.WithImportance(newImportance)
+		if err := s.store.Write(ctx, note); err != nil {
+			return count, fmt.Errorf("failed to write note %s: %w", note.ID, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// ReEmbedAll regenerates the embedding of every note from its SearchableText,
+// e.g. after enabling or switching the embedding model, and returns the number of notes updated.
+// Notes are split into batches of batchSize in ID order. A pool of WithReEmbedWorkers workers
+// takes the batches one by one and writes each batch as soon as it is embedded, so a slow
+// batch never holds up the others. Embedders that implement EmbedBatch embed a batch in a
+// single call. A batch that fails is retried once on its own after a short delay; if it fails
+// again, no further batches are started and the run fails once the batches in progress are
+// written, so a failed run keeps its progress and can simply be started again.
+// Notes without searchable text are skipped.
+func (s *Service) ReEmbedAll(ctx context.Context, embedder agent.EmbeddingClient, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = defaultReEmbedBatchSize
+	}
+
+	// An empty query without a limit matches every note
+	notes, err := s.store.Search(ctx, "", 0, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list notes: %w", err)
+	}
+	notes = slices.Filter(notes, func(note *agent.MemoryNote) bool {
+		return strings.TrimSpace(note.SearchableText()) != ""
+	})
+	sort.Slice(notes, func(i, j int) bool { return notes[i].ID < notes[j].ID })
+
+	var (
+		count    int
+		firstErr error
+		mu       sync.Mutex
+		once     sync.Once
+		wg       sync.WaitGroup
+	)
+	failed := make(chan struct{})
+	fail := func(err error) {
+		mu.Lock()
+		firstErr = cmp.Or(firstErr, err)
+		mu.Unlock()
+		once.Do(func() { close(failed) })
+	}
+
+	batches := make(chan []*agent.MemoryNote)
+	for range max(s.reEmbedWorkers, 1) {
+		wg.Go(func() {
+			for batch := range batches {
+				if err := embedBatch(ctx, embedder, batch); err != nil {
+					fail(err)
+					continue
+				}
+				if err := s.writeBatch(ctx, batch, &mu, &count); err != nil {
+					fail(err)
+				}
+			}
+		})
+	}
+
+feed:
+	for start := 0; start < len(notes); start += batchSize {
+		select {
+		case batches <- notes[start:min(start+batchSize, len(notes))]:
+		case <-failed:
+			break feed
+		case <-ctx.Done():
+			fail(ctx.Err())
+			break feed
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	return count, firstErr
+}
+
+// writeBatch writes the embedded notes of a batch and adds them to count.
+// Writes of concurrent batches are serialized by mu, which also guards count.
+func (s *Service) writeBatch(ctx context.Context, batch []*agent.MemoryNote, mu *sync.Mutex, count *int) error {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, note := range batch {
+		if err := s.store.Write(ctx, note); err != nil {
+			return fmt.Errorf("failed to write note %s: %w", note.ID, err)
+		}
+		*count++
+	}
+	return nil
+}
+
+// embedBatch embeds the notes of a batch, retrying the whole batch on failure.
+// The embeddings are only applied once every note of the batch is embedded.
+// Retrying stops early when the context is canceled.
+func embedBatch(ctx context.Context, embedder agent.EmbeddingClient, batch []*agent.MemoryNote) error {
+	for attempt := 1; ; attempt++ {
+		embeddings, err := embedNotes(ctx, embedder, batch)
+		if err == nil {
+			for i, note := range batch {
+				note.WithEmbedding(embeddings[i])
+			}
+			return nil
+		}
+		if attempt >= reEmbedBatchAttempts {
+			return err
+		}
+		timer := time.NewTimer(reEmbedRetryDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// embedNotes embeds the searchable text of each note, in order.
+// Embedders that implement batchEmbedder embed all notes in one call;
+// others embed the notes one by one.
+func embedNotes(ctx context.Context, embedder agent.EmbeddingClient, notes []*agent.MemoryNote) ([]agent.Embedding, error) {
+	if batcher, ok := embedder.(batchEmbedder); ok {
+		texts := make([]string, len(notes))
+		for i, note := range notes {
+			texts[i] = note.SearchableText()
+		}
+		embeddings, err := batcher.EmbedBatch(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed notes %s to %s: %w", notes[0].ID, notes[len(notes)-1].ID, err)
+		}
+		if len(embeddings) != len(notes) {
+			return nil, fmt.Errorf("failed to embed notes %s to %s: got %d embeddings for %d notes", notes[0].ID, notes[len(notes)-1].ID, len(embeddings), len(notes))
+		}
+		return embeddings, nil
+	}
+
+	embeddings := make([]agent.Embedding, len(notes))
+	for i, note := range notes {
+		embedding, err := embedder.Embed(ctx, note.SearchableText())
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed note %s: %w", note.ID, err)
+		}
+		embeddings[i] = embedding
+	}
+	return embeddings, nil
+}
+
 // SearchNotes retrieves notes matching the query with optional filters.
 // Returns up to `limit` notes sorted by relevance.
 func (s *Service) SearchNotes(ctx context.Context, query string, limit int, opts *agent.MemorySearchOptions) ([]*agent.MemoryNote, error) {
@@ -183,6 +519,38 @@ func (s *Service) SearchPreferences(ctx context.Context, query string, limit int
 	return s.SearchBySourceTypes(ctx, query, []agent.SourceType{agent.SourceTypePreference}, limit)
 }
 
+// SearchReranked over-fetches text matches and reranks them by embedding similarity.
+// It retrieves 3*topK notes via text search, embeds the query once, and returns
+// the topK notes with the highest cosine similarity to the query.
+// Notes without an embedding score 0; ties keep their text search order.
+func (s *Service) SearchReranked(ctx context.Context, query string, topK int, embedder agent.EmbeddingClient) ([]*agent.MemoryNote, error) {
+	if topK <= 0 {
+		topK = 10 // Default limit
+	}
+
+	candidates, err := s.store.Search(ctx, query, rerankOverFetch*topK, nil)
+	if err != nil || len(candidates) == 0 {
+		return candidates, err
+	}
+
+	queryEmbedding, err := embedder.Embed(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rank a copy so the slice returned by the store is not reordered
+	ranked := append([]*agent.MemoryNote(nil), candidates...)
+	scores := make(map[agent.NoteID]float64, len(ranked))
+	for _, note := range ranked {
+		scores[note.ID] = agent.CosineSimilarity(queryEmbedding, note.Embedding)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i].ID] > scores[ranked[j].ID]
+	})
+
+	return ranked[:min(topK, len(ranked))], nil
+}
+
 // SearchRequirements retrieves requirement notes matching the query.
 func (s *Service) SearchRequirements(ctx context.Context, query string, limit int) ([]*agent.MemoryNote, error) {
 	return s.SearchBySourceTypes(ctx, query, []agent.SourceType{agent.SourceTypeRequirement}, limit)
@@ -193,6 +561,38 @@ func (s *Service) SearchSummaries(ctx context.Context, query string, limit int)
 	return s.SearchBySourceTypes(ctx, query, []agent.SourceType{agent.SourceTypeSummary}, limit)
 }
 
+// SmartSearch retrieves the topK notes most relevant to the query, choosing the search itself.
+// If an embedder is configured, the query is not empty, and the store can rank by embedding,
+// the query is embedded and notes are ranked purely by similarity; otherwise, or if the
+// embedding search fails, a text search is used.
+func (s *Service) SmartSearch(ctx context.Context, query string, topK int, opts *agent.MemorySearchOptions) ([]*agent.MemoryNote, error) {
+	if topK <= 0 {
+		topK = 10 // Default limit
+	}
+
+	searcher, ok := s.store.(embeddingSearcher)
+	if !ok || s.embedder == nil || query == "" {
+		return s.store.Search(ctx, query, topK, opts)
+	}
+
+	embedding, err := s.embedder.Embed(ctx, query)
+	if err == nil && len(embedding) == 0 {
+		err = ErrEmptyEmbedding
+	}
+	if err == nil {
+		// An empty query matches all notes, so ranking is purely semantic
+		notes, searchErr := searcher.SearchWithEmbedding(ctx, "", embedding, topK, opts)
+		if searchErr == nil {
+			return notes, nil
+		}
+		err = searchErr
+	}
+	if s.logger != nil {
+		s.logger.Warn("query embedding failed, falling back to text search", "error", err)
+	}
+	return s.store.Search(ctx, query, topK, opts)
+}
+
 // TypedNoteOptions provides optional parameters for WriteTypedNote.
 type TypedNoteOptions struct {
 	ContextDescription string
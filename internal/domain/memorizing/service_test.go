@@ -1,9 +1,15 @@
 package memorizing_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/andygeiss/cloud-native-utils/assert"
 	"github.com/andygeiss/go-agent/internal/domain/agent"
@@ -20,6 +26,7 @@ type mockMemoryStore struct {
 	searchErr   error
 	getErr      error
 	deleteErr   error
+	searchOpts  *agent.MemorySearchOptions
 	searchNotes []*agent.MemoryNote
 }
 
@@ -37,12 +44,13 @@ func (m *mockMemoryStore) Write(_ context.Context, note *agent.MemoryNote) error
 	return nil
 }
 
-func (m *mockMemoryStore) Search(_ context.Context, _ string, limit int, _ *agent.MemorySearchOptions) ([]*agent.MemoryNote, error) {
+func (m *mockMemoryStore) Search(_ context.Context, _ string, limit int, opts *agent.MemorySearchOptions) ([]*agent.MemoryNote, error) {
+	m.searchOpts = opts
 	if m.searchErr != nil {
 		return nil, m.searchErr
 	}
 	if m.searchNotes != nil {
-		if limit < len(m.searchNotes) {
+		if limit > 0 && limit < len(m.searchNotes) {
 			return m.searchNotes[:limit], nil
 		}
 		return m.searchNotes, nil
@@ -61,6 +69,15 @@ func (m *mockMemoryStore) Get(_ context.Context, id agent.NoteID) (*agent.Memory
 	return note, nil
 }
 
+func (m *mockMemoryStore) RecordAccess(_ context.Context, id agent.NoteID) error {
+	note, ok := m.notes[id]
+	if !ok {
+		return ErrMockNotFound
+	}
+	note.AccessCount++
+	return nil
+}
+
 func (m *mockMemoryStore) Delete(_ context.Context, id agent.NoteID) error {
 	if m.deleteErr != nil {
 		return m.deleteErr
@@ -435,3 +452,691 @@ func Test_Service_SearchRequirements_Should_FilterByRequirementType(t *testing.T
 	assert.That(t, "error must be nil", err, nil)
 	assert.That(t, "should return results", len(results), 1)
 }
+
+// mockEmbeddingClient is a test double for the EmbeddingClient interface.
+type mockEmbeddingClient struct {
+	err       error
+	embedding agent.Embedding
+	calls     int
+}
+
+func (m *mockEmbeddingClient) Embed(_ context.Context, _ string) (agent.Embedding, error) {
+	m.calls++
+	return m.embedding, m.err
+}
+
+func Test_Service_SearchReranked_Should_OrderBySimilarity(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{
+		agent.NewFactNote("text-first", "a").WithEmbedding(agent.Embedding{0, 1}),
+		agent.NewFactNote("text-second", "b").WithEmbedding(agent.Embedding{1, 1}),
+		agent.NewFactNote("text-third", "c").WithEmbedding(agent.Embedding{1, 0}),
+	}
+	embedder := &mockEmbeddingClient{embedding: agent.Embedding{1, 0}}
+	svc := memorizing.NewService(store)
+
+	// Act
+	notes, err := svc.SearchReranked(context.Background(), "query", 2, embedder)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "must return topK notes", len(notes), 2)
+	assert.That(t, "most similar note must be first", notes[0].ID, agent.NoteID("text-third"))
+	assert.That(t, "second most similar note must be second", notes[1].ID, agent.NoteID("text-second"))
+	assert.That(t, "query must be embedded once", embedder.calls, 1)
+}
+
+func Test_Service_SearchReranked_With_MissingEmbeddings_Should_KeepTextOrderAsTiebreak(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{
+		agent.NewFactNote("plain-1", "a"),
+		agent.NewFactNote("embedded", "b").WithEmbedding(agent.Embedding{1, 0}),
+		agent.NewFactNote("plain-2", "c"),
+	}
+	svc := memorizing.NewService(store)
+
+	// Act
+	notes, _ := svc.SearchReranked(context.Background(), "query", 3, &mockEmbeddingClient{embedding: agent.Embedding{1, 0}})
+
+	// Assert
+	assert.That(t, "embedded note must be first", notes[0].ID, agent.NoteID("embedded"))
+	assert.That(t, "plain-1 must keep its text rank", notes[1].ID, agent.NoteID("plain-1"))
+	assert.That(t, "plain-2 must keep its text rank", notes[2].ID, agent.NoteID("plain-2"))
+}
+
+func Test_Service_SearchReranked_With_EmbedderError_Should_ReturnError(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{agent.NewFactNote("note-1", "a")}
+	svc := memorizing.NewService(store)
+
+	// Act
+	_, err := svc.SearchReranked(context.Background(), "query", 1, &mockEmbeddingClient{err: errors.New("embed failed")})
+
+	// Assert
+	assert.That(t, "err must not be nil", err != nil, true)
+}
+
+// flakyEmbeddingClient embeds the text length and fails on failures calls,
+// starting with the call with the number failOn.
+type flakyEmbeddingClient struct {
+	failOn   int
+	failures int
+	calls    int
+}
+
+func (m *flakyEmbeddingClient) Embed(_ context.Context, text string) (agent.Embedding, error) {
+	m.calls++
+	if m.failOn > 0 && m.calls >= m.failOn && m.calls < m.failOn+m.failures {
+		return nil, errors.New("embed failed")
+	}
+	return agent.Embedding{float32(len(text))}, nil
+}
+
+func Test_Service_ReEmbedAll_Should_EmbedEveryNote(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{
+		agent.NewFactNote("note-1", "Go is fun"),
+		agent.NewFactNote("note-2", "Rust is fast").WithEmbedding(agent.Embedding{1, 2, 3}),
+		agent.NewFactNote("note-3", "Zig is new"),
+	}
+	embedder := &mockEmbeddingClient{embedding: agent.Embedding{0.5, 0.5}}
+	svc := memorizing.NewService(store)
+
+	// Act
+	count, err := svc.ReEmbedAll(context.Background(), embedder, 2)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "all notes must be re-embedded", count, 3)
+	for _, id := range []agent.NoteID{"note-1", "note-2", "note-3"} {
+		assert.That(t, "note "+string(id)+" must have the new embedding", store.notes[id].Embedding, agent.Embedding{0.5, 0.5})
+	}
+}
+
+func Test_Service_ReEmbedAll_With_EmptyText_Should_SkipNote(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{
+		agent.NewFactNote("note-1", "Go is fun"),
+		agent.NewMemoryNote("empty", agent.SourceTypeFact),
+	}
+	embedder := &mockEmbeddingClient{embedding: agent.Embedding{1}}
+	svc := memorizing.NewService(store)
+
+	// Act
+	count, _ := svc.ReEmbedAll(context.Background(), embedder, 10)
+
+	// Assert
+	assert.That(t, "only the note with text must be re-embedded", count, 1)
+	assert.That(t, "embedder must be called once", embedder.calls, 1)
+	_, written := store.notes["empty"]
+	assert.That(t, "empty note must not be written", written, false)
+}
+
+func Test_Service_ReEmbedAll_With_FailureMidway_Should_KeepProgressAndResume(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{
+		agent.NewFactNote("note-3", "third"),
+		agent.NewFactNote("note-1", "first"),
+		agent.NewFactNote("note-2", "second"),
+	}
+	svc := memorizing.NewService(store)
+
+	// Act
+	first, err := svc.ReEmbedAll(context.Background(), &flakyEmbeddingClient{failOn: 3, failures: 2}, 2)
+	second, retryErr := svc.ReEmbedAll(context.Background(), &flakyEmbeddingClient{}, 2)
+
+	// Assert
+	assert.That(t, "first run must fail", err != nil, true)
+	assert.That(t, "first batch must be kept", first, 2)
+	assert.That(t, "retry must succeed", retryErr, nil)
+	assert.That(t, "retry must re-embed all notes", second, 3)
+	note := store.notes["note-3"]
+	assert.That(t, "note-3 must be embedded", note.Embedding, agent.Embedding{float32(len(note.SearchableText()))})
+}
+
+func Test_Service_ReEmbedAll_With_TransientFailure_Should_RetryFailedBatchOnly(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{
+		agent.NewFactNote("note-1", "first"),
+		agent.NewFactNote("note-2", "second"),
+		agent.NewFactNote("note-3", "third"),
+	}
+	embedder := &flakyEmbeddingClient{failOn: 3, failures: 1}
+	svc := memorizing.NewService(store)
+
+	// Act
+	count, err := svc.ReEmbedAll(context.Background(), embedder, 2)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "all notes must be re-embedded", count, 3)
+	assert.That(t, "only the failed batch must be embedded again", embedder.calls, 4)
+}
+
+// failingTextEmbeddingClient fails to embed one text and embeds the length of every other text.
+type failingTextEmbeddingClient struct {
+	cancel   context.CancelFunc // Called on every failure, if set
+	failText string
+}
+
+func (m *failingTextEmbeddingClient) Embed(_ context.Context, text string) (agent.Embedding, error) {
+	if strings.Contains(text, m.failText) {
+		if m.cancel != nil {
+			m.cancel()
+		}
+		return nil, errors.New("embed failed")
+	}
+	return agent.Embedding{float32(len(text))}, nil
+}
+
+func Test_Service_ReEmbedAll_With_FailedBatch_Should_WriteBatchesOfOtherWorkers(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{
+		agent.NewFactNote("note-1", "first"),
+		agent.NewFactNote("note-2", "second"),
+		agent.NewFactNote("note-3", "third"),
+	}
+	svc := memorizing.NewService(store).WithReEmbedWorkers(2)
+
+	// Act
+	count, err := svc.ReEmbedAll(context.Background(), &failingTextEmbeddingClient{failText: "first"}, 1)
+
+	// Assert
+	_, failedWritten := store.notes["note-1"]
+	assert.That(t, "run must fail", err != nil, true)
+	assert.That(t, "batches of the other worker must be counted", count, 2)
+	assert.That(t, "failed batch must not be written", failedWritten, false)
+	for _, id := range []agent.NoteID{"note-2", "note-3"} {
+		assert.That(t, string(id)+" must be written", store.notes[id].Embedding, agent.Embedding{float32(len(store.notes[id].SearchableText()))})
+	}
+}
+
+func Test_Service_ReEmbedAll_With_FailedBatch_Should_NotStartFurtherBatches(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{
+		agent.NewFactNote("note-1", "first"),
+		agent.NewFactNote("note-2", "second"),
+	}
+	embedder := &flakyEmbeddingClient{failOn: 1, failures: 2}
+	svc := memorizing.NewService(store)
+
+	// Act
+	count, err := svc.ReEmbedAll(context.Background(), embedder, 1)
+
+	// Assert
+	assert.That(t, "run must fail", err != nil, true)
+	assert.That(t, "no note must be re-embedded", count, 0)
+	assert.That(t, "later batch must not be started", embedder.calls, 2)
+}
+
+// slowEmbeddingClient blocks on the slow text until every other text is embedded.
+// It fails if the other texts are not embedded in time, e.g. because they wait for the slow one.
+type slowEmbeddingClient struct {
+	others   chan struct{}
+	slowText string
+}
+
+func (m *slowEmbeddingClient) Embed(_ context.Context, text string) (agent.Embedding, error) {
+	if !strings.Contains(text, m.slowText) {
+		m.others <- struct{}{}
+		return agent.Embedding{float32(len(text))}, nil
+	}
+	for range cap(m.others) {
+		select {
+		case <-m.others:
+		case <-time.After(time.Second):
+			return nil, errors.New("other batches stalled")
+		}
+	}
+	return agent.Embedding{float32(len(text))}, nil
+}
+
+func Test_Service_ReEmbedAll_With_SlowBatch_Should_NotStallOtherBatches(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{
+		agent.NewFactNote("note-1", "slow"),
+		agent.NewFactNote("note-2", "second"),
+		agent.NewFactNote("note-3", "third"),
+		agent.NewFactNote("note-4", "fourth"),
+	}
+	embedder := &slowEmbeddingClient{others: make(chan struct{}, 3), slowText: "slow"}
+	svc := memorizing.NewService(store).WithReEmbedWorkers(2)
+
+	// Act
+	count, err := svc.ReEmbedAll(context.Background(), embedder, 1)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "all notes must be re-embedded", count, 4)
+}
+
+// batchEmbeddingClient embeds the text length and records the size of each EmbedBatch call.
+type batchEmbeddingClient struct {
+	batches    []int
+	embedCalls int
+	mu         sync.Mutex
+}
+
+func (m *batchEmbeddingClient) Embed(_ context.Context, text string) (agent.Embedding, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.embedCalls++
+	return agent.Embedding{float32(len(text))}, nil
+}
+
+func (m *batchEmbeddingClient) EmbedBatch(_ context.Context, texts []string) ([]agent.Embedding, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches = append(m.batches, len(texts))
+	embeddings := make([]agent.Embedding, len(texts))
+	for i, text := range texts {
+		embeddings[i] = agent.Embedding{float32(len(text))}
+	}
+	return embeddings, nil
+}
+
+func Test_Service_ReEmbedAll_With_BatchEmbedder_Should_EmbedEachBatchInOneCall(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{
+		agent.NewFactNote("note-1", "first"),
+		agent.NewFactNote("note-2", "second"),
+		agent.NewFactNote("note-3", "third"),
+	}
+	embedder := &batchEmbeddingClient{}
+	svc := memorizing.NewService(store)
+
+	// Act
+	count, err := svc.ReEmbedAll(context.Background(), embedder, 2)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "all notes must be re-embedded", count, 3)
+	assert.That(t, "each batch must be embedded in one call", embedder.batches, []int{2, 1})
+	assert.That(t, "single embeds must not be used", embedder.embedCalls, 0)
+	for _, note := range store.searchNotes {
+		assert.That(t, "note "+string(note.ID)+" must have its own embedding", store.notes[note.ID].Embedding, agent.Embedding{float32(len(note.SearchableText()))})
+	}
+}
+
+func Test_Service_ReEmbedAll_With_CanceledContext_Should_NotRetryBatch(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{agent.NewFactNote("note-1", "first")}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	embedder := &failingTextEmbeddingClient{cancel: cancel, failText: "first"}
+	svc := memorizing.NewService(store)
+
+	// Act
+	count, err := svc.ReEmbedAll(ctx, embedder, 1)
+
+	// Assert
+	assert.That(t, "err must be context canceled", errors.Is(err, context.Canceled), true)
+	assert.That(t, "no note must be re-embedded", count, 0)
+}
+
+// concurrentEmbeddingClient embeds the text length and records the highest number of concurrent calls.
+type concurrentEmbeddingClient struct {
+	inFlight    int
+	maxInFlight int
+	mu          sync.Mutex
+}
+
+func (m *concurrentEmbeddingClient) Embed(_ context.Context, text string) (agent.Embedding, error) {
+	m.mu.Lock()
+	m.inFlight++
+	m.maxInFlight = max(m.maxInFlight, m.inFlight)
+	m.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	m.mu.Lock()
+	m.inFlight--
+	m.mu.Unlock()
+	return agent.Embedding{float32(len(text))}, nil
+}
+
+func Test_Service_ReEmbedAll_With_Workers_Should_EmbedBatchesConcurrently(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	for i := range 12 {
+		id := agent.NoteID(fmt.Sprintf("note-%02d", i))
+		store.searchNotes = append(store.searchNotes, agent.NewFactNote(id, strings.Repeat("x", i+1)))
+	}
+	embedder := &concurrentEmbeddingClient{}
+	svc := memorizing.NewService(store).WithReEmbedWorkers(3)
+
+	// Act
+	count, err := svc.ReEmbedAll(context.Background(), embedder, 2)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "all notes must be re-embedded", count, 12)
+	assert.That(t, "batches must be embedded concurrently", embedder.maxInFlight > 1, true)
+	assert.That(t, "concurrency must be bounded by the workers", embedder.maxInFlight <= 3, true)
+	for _, note := range store.searchNotes {
+		assert.That(t, "note "+string(note.ID)+" must have its own embedding", store.notes[note.ID].Embedding, agent.Embedding{float32(len(note.SearchableText()))})
+	}
+}
+
+// mockSummarizer is a test double for the LLMClient interface used to consolidate notes.
+type mockSummarizer struct {
+	err      error
+	content  string
+	messages []agent.Message
+}
+
+func (m *mockSummarizer) Run(_ context.Context, messages []agent.Message, _ []agent.ToolDefinition) (agent.LLMResponse, error) {
+	m.messages = messages
+	if m.err != nil {
+		return agent.LLMResponse{}, m.err
+	}
+	return agent.NewLLMResponse(agent.NewMessage(agent.RoleAssistant, m.content), "stop"), nil
+}
+
+func newConsolidateStore() *mockMemoryStore {
+	store := newMockMemoryStore()
+	store.notes["note-a"] = agent.NewMemoryNote("note-a", agent.SourceTypeFact).
+		WithRawContent("User likes Go").
+		WithImportance(4)
+	store.notes["note-b"] = agent.NewMemoryNote("note-b", agent.SourceTypePreference).
+		WithRawContent("User prefers tabs").
+		WithImportance(1)
+	return store
+}
+
+func Test_ConsolidateUseCase_Execute_Should_WriteSummaryReferencingSources(t *testing.T) {
+	// Arrange
+	store := newConsolidateStore()
+	summarizer := &mockSummarizer{content: "User likes Go and prefers tabs"}
+	uc := memorizing.NewConsolidateUseCase(store, agent.IDGeneratorFunc(func() string { return "summary-1" }))
+
+	// Act
+	summary, err := uc.Execute(context.Background(), []agent.NoteID{"note-a", "note-b"}, summarizer)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "summary must be stored", store.notes["summary-1"], summary)
+	assert.That(t, "summary must be a summary note", summary.SourceType, agent.SourceTypeSummary)
+	assert.That(t, "summary content must come from summarizer", summary.Summary, "User likes Go and prefers tabs")
+	assert.That(t, "summary must reference sources", summary.ContextDescription, "Summarizes notes: note-a, note-b")
+	assert.That(t, "summarizer must receive source content", strings.Contains(summarizer.messages[1].Content, "User prefers tabs"), true)
+	assert.That(t, "source importance must be unchanged", store.notes["note-a"].Importance, 4)
+}
+
+func Test_ConsolidateUseCase_Execute_WithSourceDemotion_Should_LowerSourceImportance(t *testing.T) {
+	// Arrange
+	store := newConsolidateStore()
+	summarizer := &mockSummarizer{content: "consolidated"}
+	uc := memorizing.NewConsolidateUseCase(store, agent.IDGeneratorFunc(func() string { return "summary-1" })).
+		WithSourceDemotion(2)
+
+	// Act
+	_, err := uc.Execute(context.Background(), []agent.NoteID{"note-a", "note-b"}, summarizer)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "note-a importance must be lowered", store.notes["note-a"].Importance, 2)
+	assert.That(t, "note-b importance must not drop below 1", store.notes["note-b"].Importance, 1)
+}
+
+func Test_ConsolidateUseCase_Execute_WithMissingSource_Should_FailWithoutWriting(t *testing.T) {
+	// Arrange
+	store := newConsolidateStore()
+	summarizer := &mockSummarizer{content: "consolidated"}
+	uc := memorizing.NewConsolidateUseCase(store, agent.IDGeneratorFunc(func() string { return "summary-1" })).
+		WithSourceDemotion(1)
+
+	// Act
+	summary, err := uc.Execute(context.Background(), []agent.NoteID{"note-a", "missing"}, summarizer)
+
+	// Assert
+	assert.That(t, "error must be ErrSourceNoteNotFound", errors.Is(err, memorizing.ErrSourceNoteNotFound), true)
+	assert.That(t, "summary must be nil", summary == nil, true)
+	assert.That(t, "summarizer must not be called", summarizer.messages == nil, true)
+	assert.That(t, "no note must be written", len(store.notes), 2)
+	assert.That(t, "source importance must be unchanged", store.notes["note-a"].Importance, 4)
+}
+
+func Test_ConsolidateUseCase_Execute_WithEmptySummary_Should_ReturnError(t *testing.T) {
+	// Arrange
+	store := newConsolidateStore()
+	uc := memorizing.NewConsolidateUseCase(store, agent.IDGeneratorFunc(func() string { return "summary-1" }))
+
+	// Act
+	_, err := uc.Execute(context.Background(), []agent.NoteID{"note-a"}, &mockSummarizer{content: "  "})
+
+	// Assert
+	assert.That(t, "error must be ErrSummaryEmpty", errors.Is(err, memorizing.ErrSummaryEmpty), true)
+	assert.That(t, "no note must be written", len(store.notes), 2)
+}
+
+// embeddingMemoryStore is a mockMemoryStore that can also rank notes by embedding.
+type embeddingMemoryStore struct {
+	*mockMemoryStore
+	embeddingErr   error
+	embeddingNotes []*agent.MemoryNote
+	queryEmbedding agent.Embedding
+	query          string
+}
+
+func (m *embeddingMemoryStore) SearchWithEmbedding(_ context.Context, query string, queryEmbedding agent.Embedding, _ int, _ *agent.MemorySearchOptions) ([]*agent.MemoryNote, error) {
+	m.query = query
+	m.queryEmbedding = queryEmbedding
+	return m.embeddingNotes, m.embeddingErr
+}
+
+func Test_Service_SmartSearch_With_Embedder_Should_SearchByEmbedding(t *testing.T) {
+	// Arrange
+	store := &embeddingMemoryStore{
+		mockMemoryStore: newMockMemoryStore(),
+		embeddingNotes:  []*agent.MemoryNote{agent.NewFactNote("semantic", "a")},
+	}
+	store.searchNotes = []*agent.MemoryNote{agent.NewFactNote("text", "b")}
+	embedder := &mockEmbeddingClient{embedding: agent.Embedding{1, 0}}
+	svc := memorizing.NewService(store).WithEmbedder(embedder)
+
+	// Act
+	notes, err := svc.SmartSearch(context.Background(), "query", 5, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "must return the embedding match", notes[0].ID, agent.NoteID("semantic"))
+	assert.That(t, "query must be embedded once", embedder.calls, 1)
+	assert.That(t, "store must receive the query embedding", store.queryEmbedding, agent.Embedding{1, 0})
+	assert.That(t, "ranking must be purely semantic", store.query, "")
+}
+
+func Test_Service_SmartSearch_Without_Embedder_Should_SearchByText(t *testing.T) {
+	// Arrange
+	store := &embeddingMemoryStore{
+		mockMemoryStore: newMockMemoryStore(),
+		embeddingNotes:  []*agent.MemoryNote{agent.NewFactNote("semantic", "a")},
+	}
+	store.searchNotes = []*agent.MemoryNote{agent.NewFactNote("text", "b")}
+	svc := memorizing.NewService(store)
+
+	// Act
+	notes, err := svc.SmartSearch(context.Background(), "query", 5, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "must return the text match", notes[0].ID, agent.NoteID("text"))
+	assert.That(t, "embedding search must not be used", store.queryEmbedding == nil, true)
+}
+
+func Test_Service_SmartSearch_With_EmbedderError_Should_FallBackToText(t *testing.T) {
+	// Arrange
+	store := &embeddingMemoryStore{mockMemoryStore: newMockMemoryStore()}
+	store.searchNotes = []*agent.MemoryNote{agent.NewFactNote("text", "b")}
+	svc := memorizing.NewService(store).WithEmbedder(&mockEmbeddingClient{err: errors.New("embed failed")})
+
+	// Act
+	notes, err := svc.SmartSearch(context.Background(), "query", 5, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "must return the text match", notes[0].ID, agent.NoteID("text"))
+}
+
+func Test_Service_SmartSearch_With_EmbeddingSearchError_Should_FallBackToTextAndLog(t *testing.T) {
+	// Arrange
+	store := &embeddingMemoryStore{mockMemoryStore: newMockMemoryStore(), embeddingErr: errors.New("index unavailable")}
+	store.searchNotes = []*agent.MemoryNote{agent.NewFactNote("text", "b")}
+	var logs bytes.Buffer
+	svc := memorizing.NewService(store).
+		WithEmbedder(&mockEmbeddingClient{embedding: agent.Embedding{1, 0}}).
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	// Act
+	notes, err := svc.SmartSearch(context.Background(), "query", 5, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "must return the text match", notes[0].ID, agent.NoteID("text"))
+	assert.That(t, "fallback must be logged", strings.Contains(logs.String(), "index unavailable"), true)
+}
+
+func Test_Service_SmartSearch_With_EmptyEmbedding_Should_FallBackToTextAndLogReason(t *testing.T) {
+	// Arrange
+	store := &embeddingMemoryStore{mockMemoryStore: newMockMemoryStore()}
+	store.searchNotes = []*agent.MemoryNote{agent.NewFactNote("text", "b")}
+	var logs bytes.Buffer
+	svc := memorizing.NewService(store).
+		WithEmbedder(&mockEmbeddingClient{}).
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	// Act
+	notes, err := svc.SmartSearch(context.Background(), "query", 5, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "must return the text match", notes[0].ID, agent.NoteID("text"))
+	assert.That(t, "embedding search must not be used", store.queryEmbedding == nil, true)
+	assert.That(t, "reason must be logged", strings.Contains(logs.String(), memorizing.ErrEmptyEmbedding.Error()), true)
+}
+
+func Test_Service_SmartSearch_With_EmptyQuery_Should_NotEmbed(t *testing.T) {
+	// Arrange
+	store := &embeddingMemoryStore{mockMemoryStore: newMockMemoryStore()}
+	store.searchNotes = []*agent.MemoryNote{agent.NewFactNote("text", "b")}
+	embedder := &mockEmbeddingClient{embedding: agent.Embedding{1, 0}}
+	svc := memorizing.NewService(store).WithEmbedder(embedder)
+
+	// Act
+	notes, err := svc.SmartSearch(context.Background(), "", 5, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "must return the text match", notes[0].ID, agent.NoteID("text"))
+	assert.That(t, "query must not be embedded", embedder.calls, 0)
+}
+
+func Test_Service_SmartSearch_With_TextOnlyStore_Should_SearchByText(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{agent.NewFactNote("text", "b")}
+	embedder := &mockEmbeddingClient{embedding: agent.Embedding{1, 0}}
+	svc := memorizing.NewService(store).WithEmbedder(embedder)
+
+	// Act
+	notes, err := svc.SmartSearch(context.Background(), "query", 5, nil)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "must return the text match", notes[0].ID, agent.NoteID("text"))
+	assert.That(t, "query must not be embedded", embedder.calls, 0)
+}
+
+func Test_Service_PromoteFrequentlyAccessed_Should_RaiseImportanceOfFrequentNotes(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	frequent := agent.NewFactNote("frequent", "Go is fun").WithImportance(2)
+	rare := agent.NewFactNote("rare", "Rust is fast").WithImportance(2)
+	store.notes["frequent"] = frequent
+	store.notes["rare"] = rare
+	store.searchNotes = []*agent.MemoryNote{frequent, rare}
+	for range 4 {
+		_ = store.RecordAccess(context.Background(), "frequent")
+	}
+	_ = store.RecordAccess(context.Background(), "rare")
+	svc := memorizing.NewService(store)
+
+	// Act
+	count, err := svc.PromoteFrequentlyAccessed(context.Background(), 3, 5)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "one note must be promoted", count, 1)
+	assert.That(t, "frequent note must be promoted", store.notes["frequent"].Importance, 5)
+	assert.That(t, "rare note must keep its importance", store.notes["rare"].Importance, 2)
+}
+
+func Test_Service_PromoteFrequentlyAccessed_With_MoreImportantNote_Should_NotLowerImportance(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	note := agent.NewFactNote("note-1", "Go is fun").WithImportance(5)
+	store.notes["note-1"] = note
+	store.searchNotes = []*agent.MemoryNote{note}
+	for range 3 {
+		_ = store.RecordAccess(context.Background(), "note-1")
+	}
+	svc := memorizing.NewService(store)
+
+	// Act
+	count, _ := svc.PromoteFrequentlyAccessed(context.Background(), 1, 3)
+
+	// Assert
+	assert.That(t, "no note must be promoted", count, 0)
+	assert.That(t, "importance must be kept", store.notes["note-1"].Importance, 5)
+}
+
+func Test_Service_DeleteMatching_Should_DeleteOnlyMatchingNotes(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	for _, id := range []agent.NoteID{"keep", "stale-1", "stale-2"} {
+		store.notes[id] = agent.NewFactNote(id, string(id))
+	}
+	// The store applies the filters and returns the matching notes
+	store.searchNotes = []*agent.MemoryNote{store.notes["stale-2"], store.notes["stale-1"]}
+	opts := &agent.MemorySearchOptions{Tags: []string{"stale"}, MaxImportance: 2}
+	svc := memorizing.NewService(store)
+
+	// Act
+	count, err := svc.DeleteMatching(context.Background(), opts)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "matching notes must be counted", count, 2)
+	assert.That(t, "filters must be passed to the store", store.searchOpts, opts)
+	_, kept := store.notes["keep"]
+	assert.That(t, "non-matching note must be kept", kept, true)
+	assert.That(t, "only the non-matching note must remain", len(store.notes), 1)
+}
+
+func Test_Service_DeleteMatching_Without_Filters_Should_ReturnError(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.notes["note-1"] = agent.NewFactNote("note-1", "Go is fun")
+	store.searchNotes = []*agent.MemoryNote{store.notes["note-1"]}
+	svc := memorizing.NewService(store)
+
+	// Act
+	count, err := svc.DeleteMatching(context.Background(), &agent.MemorySearchOptions{WholeWord: true})
+
+	// Assert
+	assert.That(t, "err must be ErrFilterEmpty", errors.Is(err, memorizing.ErrFilterEmpty), true)
+	assert.That(t, "no note must be deleted", count, 0)
+	assert.That(t, "note must be kept", len(store.notes), 1)
+}
@@ -1,5 +1,7 @@
 package openai
 
+import "encoding/json"
+
 // ---------------------------------------------------------------------------
 // ChatCompletionChoice
 // ---------------------------------------------------------------------------
@@ -11,17 +13,72 @@ type ChatCompletionChoice struct {
 	Index        int     `json:"index"`
 }
 
+// ---------------------------------------------------------------------------
+// ChatCompletionChunk
+// ---------------------------------------------------------------------------
+
+// ChatCompletionChunk represents one server-sent event of a streamed chat completion.
+// Usage is only reported by the last chunk, and only by servers that support it.
+type ChatCompletionChunk struct {
+	Usage             *ChatCompletionUsage        `json:"usage,omitempty"`
+	ID                string                      `json:"id"`
+	Model             string                      `json:"model"`
+	Object            string                      `json:"object"`
+	SystemFingerprint string                      `json:"system_fingerprint,omitempty"`
+	Choices           []ChatCompletionChunkChoice `json:"choices"`
+	Created           int64                       `json:"created"`
+}
+
+// ChatCompletionChunkChoice represents the delta of a single choice in a chunk.
+type ChatCompletionChunkChoice struct {
+	Delta        ChatCompletionDelta `json:"delta"`
+	FinishReason string              `json:"finish_reason,omitempty"`
+	Index        int                 `json:"index"`
+}
+
+// ChatCompletionDelta holds the message fragments carried by a chunk.
+type ChatCompletionDelta struct {
+	Content   string          `json:"content,omitempty"`
+	Role      string          `json:"role,omitempty"`
+	ToolCalls []ToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+// ToolCallDelta is a fragment of a tool call. Index identifies the call the
+// fragment belongs to; the ID, type and name are usually only sent with the first fragment.
+type ToolCallDelta struct {
+	Function FunctionCall `json:"function"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Index    int          `json:"index"`
+}
+
 // ---------------------------------------------------------------------------
 // ChatCompletionRequest
 // ---------------------------------------------------------------------------
 
 // ChatCompletionRequest represents a request to the chat completions endpoint.
+// ToolChoice is either a mode string ("auto", "none", "required") or a NamedToolChoice.
 type ChatCompletionRequest struct {
-	Messages []Message `json:"messages"`
-	Model    string    `json:"model"`
-	Tools    []Tool    `json:"tools,omitempty"`
+	ToolChoice    any            `json:"tool_choice,omitempty"`
+	Messages      []Message      `json:"messages"`
+	Model         string         `json:"model"`
+	N             int            `json:"n,omitempty"`
+	Seed          *int           `json:"seed,omitempty"`
+	Stop          []string       `json:"stop,omitempty"`
+	Tools         []Tool         `json:"tools,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
 }
 
+// StreamOptions configures a streamed completion.
+// IncludeUsage asks the API to send the token usage in a final chunk without choices.
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// MaxStopSequences is the maximum number of stop sequences accepted by the API.
+const MaxStopSequences = 4
+
 // NewChatCompletionRequest creates a new chat completion request.
 func NewChatCompletionRequest(model string, messages []Message) ChatCompletionRequest {
 	return ChatCompletionRequest{
@@ -30,6 +87,44 @@ func NewChatCompletionRequest(model string, messages []Message) ChatCompletionRe
 	}
 }
 
+// WithN sets the number of completions to generate.
+// Zero omits the field, which lets the API default to a single completion.
+func (r ChatCompletionRequest) WithN(n int) ChatCompletionRequest {
+	r.N = n
+	return r
+}
+
+// WithSeed sets the sampling seed for reproducible completions.
+// A nil seed omits the field from the request.
+func (r ChatCompletionRequest) WithSeed(seed *int) ChatCompletionRequest {
+	r.Seed = seed
+	return r
+}
+
+// WithStop sets the sequences at which the model stops generating.
+func (r ChatCompletionRequest) WithStop(stop []string) ChatCompletionRequest {
+	r.Stop = stop
+	return r
+}
+
+// WithStream requests the completion as a stream of server-sent ChatCompletionChunk events.
+// A streamed request also asks for the token usage, which is otherwise omitted from streams.
+func (r ChatCompletionRequest) WithStream(stream bool) ChatCompletionRequest {
+	r.Stream = stream
+	r.StreamOptions = nil
+	if stream {
+		r.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+	return r
+}
+
+// WithToolChoice sets how the model uses the tools.
+// A nil choice omits the field, which lets the API default to "auto".
+func (r ChatCompletionRequest) WithToolChoice(choice any) ChatCompletionRequest {
+	r.ToolChoice = choice
+	return r
+}
+
 // WithTools adds tools to the request.
 func (r ChatCompletionRequest) WithTools(tools []Tool) ChatCompletionRequest {
 	r.Tools = tools
@@ -42,12 +137,13 @@ func (r ChatCompletionRequest) WithTools(tools []Tool) ChatCompletionRequest {
 
 // ChatCompletionResponse represents a response from the chat completions endpoint.
 type ChatCompletionResponse struct {
-	ID      string                 `json:"id"`
-	Model   string                 `json:"model"`
-	Object  string                 `json:"object"`
-	Choices []ChatCompletionChoice `json:"choices"`
-	Usage   ChatCompletionUsage    `json:"usage"`
-	Created int64                  `json:"created"`
+	ID                string                 `json:"id"`
+	Model             string                 `json:"model"`
+	Object            string                 `json:"object"`
+	SystemFingerprint string                 `json:"system_fingerprint,omitempty"`
+	Choices           []ChatCompletionChoice `json:"choices"`
+	Usage             ChatCompletionUsage    `json:"usage"`
+	Created           int64                  `json:"created"`
 }
 
 // GetFirstChoice returns the first choice from the response, or nil if empty.
@@ -69,16 +165,73 @@ type ChatCompletionUsage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
+// ---------------------------------------------------------------------------
+// ContentPart
+// ---------------------------------------------------------------------------
+
+// Content part types (alphabetically sorted).
+const (
+	ContentPartTypeImageURL = "image_url"
+	ContentPartTypeText     = "text"
+)
+
+// CacheControlTypeEphemeral marks a prompt prefix for short-lived provider-side caching.
+const CacheControlTypeEphemeral = "ephemeral"
+
+// CacheControl marks the end of a prompt prefix the provider may cache.
+type CacheControl struct {
+	Type string `json:"type"`
+}
+
+// ContentPart represents one part of a multi-modal message content array.
+type ContentPart struct {
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
+	ImageURL     *ImageURL     `json:"image_url,omitempty"`
+	Text         string        `json:"text,omitempty"`
+	Type         string        `json:"type"`
+}
+
+// ImageURL references an image by URL or base64 data URL.
+// Detail is "low", "high" or "auto"; empty lets the API decide.
+type ImageURL struct {
+	Detail string `json:"detail,omitempty"`
+	URL    string `json:"url"`
+}
+
+// NewImageURLPart creates an image content part.
+func NewImageURLPart(url, detail string) ContentPart {
+	return ContentPart{
+		ImageURL: &ImageURL{Detail: detail, URL: url},
+		Type:     ContentPartTypeImageURL,
+	}
+}
+
+// NewTextPart creates a text content part.
+func NewTextPart(text string) ContentPart {
+	return ContentPart{
+		Text: text,
+		Type: ContentPartTypeText,
+	}
+}
+
+// WithCacheControl marks the part as the end of a cacheable prompt prefix.
+func (p ContentPart) WithCacheControl() ContentPart {
+	p.CacheControl = &CacheControl{Type: CacheControlTypeEphemeral}
+	return p
+}
+
 // ---------------------------------------------------------------------------
 // Message
 // ---------------------------------------------------------------------------
 
 // Message represents a message in the chat completion request/response.
+// If Parts is set, it is sent as the content array instead of Content.
 type Message struct {
-	Content    string     `json:"content"`
-	Role       string     `json:"role"`
-	ToolCallID string     `json:"tool_call_id,omitempty"`
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	Content    string        `json:"content"`
+	Role       string        `json:"role"`
+	ToolCallID string        `json:"tool_call_id,omitempty"`
+	Parts      []ContentPart `json:"-"`
+	ToolCalls  []ToolCall    `json:"tool_calls,omitempty"`
 }
 
 // NewMessage creates a new message with the given role and content.
@@ -89,6 +242,33 @@ func NewMessage(role, content string) Message {
 	}
 }
 
+// MarshalJSON encodes the message per the OpenAI schema.
+// A multi-modal message is sent with its parts as the content array.
+// An assistant message that only carries tool calls is sent with "content": null,
+// since some providers reject an empty string alongside tool_calls.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message
+	if len(m.Parts) > 0 {
+		return json.Marshal(struct {
+			message
+			Content []ContentPart `json:"content"`
+		}{Content: m.Parts, message: message(m)})
+	}
+	if m.Content != "" || len(m.ToolCalls) == 0 {
+		return json.Marshal(message(m))
+	}
+	return json.Marshal(struct {
+		Content *string `json:"content"`
+		message
+	}{message: message(m)})
+}
+
+// WithParts sets the content parts for multi-modal messages.
+func (m Message) WithParts(parts []ContentPart) Message {
+	m.Parts = parts
+	return m
+}
+
 // WithToolCallID sets the tool call ID for tool response messages.
 func (m Message) WithToolCallID(id string) Message {
 	m.ToolCallID = id
@@ -1,6 +1,8 @@
 package openai_test
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/andygeiss/cloud-native-utils/assert"
@@ -88,6 +90,55 @@ func Test_ChatCompletionRequest_WithTools_Should_ReplaceExistingTools(t *testing
 	assert.That(t, "tool name must be new", req.Tools[0].Function.Name, "new_tool")
 }
 
+func Test_ChatCompletionRequest_WithSeed_Should_SetSeed(t *testing.T) {
+	// Arrange
+	req := openai.NewChatCompletionRequest("gpt-4", []openai.Message{openai.NewMessage("user", "Hello")})
+	seed := 42
+
+	// Act
+	req = req.WithSeed(&seed)
+
+	// Assert
+	assert.That(t, "seed must be set", *req.Seed, 42)
+}
+
+func Test_ChatCompletionRequest_WithStop_Should_SetStop(t *testing.T) {
+	// Arrange
+	req := openai.NewChatCompletionRequest("gpt-4", []openai.Message{openai.NewMessage("user", "Hello")})
+
+	// Act
+	req = req.WithStop([]string{"END"})
+
+	// Assert
+	assert.That(t, "must have 1 stop sequence", len(req.Stop), 1)
+	assert.That(t, "stop sequence must match", req.Stop[0], "END")
+}
+
+func Test_ChatCompletionRequest_WithStream_Should_RequestStream(t *testing.T) {
+	// Arrange
+	req := openai.NewChatCompletionRequest("gpt-4", []openai.Message{openai.NewMessage("user", "Hello")})
+
+	// Act
+	data, err := json.Marshal(req.WithStream(true))
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "stream must be sent", strings.Contains(string(data), `"stream":true`), true)
+	assert.That(t, "usage must be requested", strings.Contains(string(data), `"stream_options":{"include_usage":true}`), true)
+}
+
+func Test_ChatCompletionRequest_WithStream_With_False_Should_OmitStreamOptions(t *testing.T) {
+	// Arrange
+	req := openai.NewChatCompletionRequest("gpt-4", []openai.Message{openai.NewMessage("user", "Hello")})
+
+	// Act
+	data, err := json.Marshal(req.WithStream(true).WithStream(false))
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "stream options must be omitted", strings.Contains(string(data), `"stream_options"`), false)
+}
+
 // ---------------------------------------------------------------------------
 // ChatCompletionResponse tests
 // ---------------------------------------------------------------------------
@@ -273,3 +324,43 @@ func Test_Message_Chaining_Should_Work(t *testing.T) {
 	assert.That(t, "content must match", msg.Content, "result")
 	assert.That(t, "tool call ID must be set", msg.ToolCallID, "call_123")
 }
+
+func Test_Message_MarshalJSON_With_OnlyToolCalls_Should_SendNullContent(t *testing.T) {
+	// Arrange
+	msg := openai.NewMessage("assistant", "").
+		WithToolCalls([]openai.ToolCall{openai.NewToolCall("call_1", "get_time", "{}")})
+
+	// Act
+	data, err := json.Marshal(msg)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "JSON must match", string(data), `{"content":null,"role":"assistant","tool_calls":[{"function":{"arguments":"{}","name":"get_time"},"id":"call_1","type":"function"}]}`)
+}
+
+func Test_Message_MarshalJSON_With_EmptyContent_Should_SendEmptyString(t *testing.T) {
+	// Arrange
+	msg := openai.NewMessage("assistant", "")
+
+	// Act
+	data, err := json.Marshal(msg)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "JSON must match", string(data), `{"content":"","role":"assistant"}`)
+}
+
+func Test_Message_MarshalJSON_With_Parts_Should_SendContentArray(t *testing.T) {
+	// Arrange
+	msg := openai.NewMessage("user", "ignored").WithParts([]openai.ContentPart{
+		openai.NewTextPart("What is this?"),
+		openai.NewImageURLPart("data:image/png;base64,AAAA", ""),
+	})
+
+	// Act
+	data, err := json.Marshal(msg)
+
+	// Assert
+	assert.That(t, "must not return error", err, nil)
+	assert.That(t, "JSON must match", string(data), `{"role":"user","content":[{"text":"What is this?","type":"text"},{"image_url":{"url":"data:image/png;base64,AAAA"},"type":"image_url"}]}`)
+}
@@ -14,6 +14,20 @@ func NewEmbeddingRequest(model, input string) EmbeddingRequest {
 	}
 }
 
+// EmbeddingBatchRequest represents a request to embed several inputs in one call.
+type EmbeddingBatchRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// NewEmbeddingBatchRequest creates a new embedding request for several inputs.
+func NewEmbeddingBatchRequest(model string, inputs []string) EmbeddingBatchRequest {
+	return EmbeddingBatchRequest{
+		Input: inputs,
+		Model: model,
+	}
+}
+
 // EmbeddingResponse represents a response from the embeddings endpoint.
 type EmbeddingResponse struct {
 	Model  string          `json:"model"`
@@ -42,3 +56,19 @@ func (r EmbeddingResponse) GetFirstEmbedding() []float32 {
 	}
 	return r.Data[0].Embedding
 }
+
+// GetEmbeddings returns the embeddings ordered by their input index.
+// It returns nil if the response does not hold exactly one embedding per index from 0 to n-1.
+func (r EmbeddingResponse) GetEmbeddings(n int) [][]float32 {
+	if len(r.Data) != n {
+		return nil
+	}
+	embeddings := make([][]float32, n)
+	for _, data := range r.Data {
+		if data.Index < 0 || data.Index >= n || embeddings[data.Index] != nil || len(data.Embedding) == 0 {
+			return nil
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+	return embeddings
+}
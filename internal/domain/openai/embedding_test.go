@@ -20,6 +20,15 @@ func Test_NewEmbeddingRequest_Should_SetModelAndInput(t *testing.T) {
 	assert.That(t, "input must be set", request.Input, "Hello, world!")
 }
 
+func Test_NewEmbeddingBatchRequest_Should_SetModelAndInputs(t *testing.T) {
+	// Arrange & Act
+	request := openai.NewEmbeddingBatchRequest("text-embedding-3-small", []string{"first", "second"})
+
+	// Assert
+	assert.That(t, "model must be set", request.Model, "text-embedding-3-small")
+	assert.That(t, "inputs must be set", request.Input, []string{"first", "second"})
+}
+
 // -----------------------------------------------------------------------------
 // EmbeddingResponse tests
 // -----------------------------------------------------------------------------
@@ -54,3 +63,35 @@ func Test_EmbeddingResponse_GetFirstEmbedding_With_EmptyData_Should_ReturnNil(t
 	// Assert
 	assert.That(t, "result must be nil", result == nil, true)
 }
+
+func Test_EmbeddingResponse_GetEmbeddings_With_UnorderedData_Should_OrderByIndex(t *testing.T) {
+	// Arrange
+	response := openai.EmbeddingResponse{
+		Data: []openai.EmbeddingData{
+			{Embedding: []float32{0.4}, Index: 1},
+			{Embedding: []float32{0.1}, Index: 0},
+		},
+	}
+
+	// Act
+	result := response.GetEmbeddings(2)
+
+	// Assert
+	assert.That(t, "embeddings must be ordered by index", result, [][]float32{{0.1}, {0.4}})
+}
+
+func Test_EmbeddingResponse_GetEmbeddings_With_MissingIndex_Should_ReturnNil(t *testing.T) {
+	// Arrange
+	response := openai.EmbeddingResponse{
+		Data: []openai.EmbeddingData{
+			{Embedding: []float32{0.1}, Index: 0},
+			{Embedding: []float32{0.4}, Index: 0},
+		},
+	}
+
+	// Act
+	result := response.GetEmbeddings(2)
+
+	// Assert
+	assert.That(t, "result must be nil", result == nil, true)
+}
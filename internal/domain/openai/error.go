@@ -0,0 +1,35 @@
+package openai
+
+import "fmt"
+
+// ---------------------------------------------------------------------------
+// ErrorResponse
+// ---------------------------------------------------------------------------
+
+// ErrorResponse represents the error envelope returned with non-2xx responses.
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes an API error.
+// Code is a string for OpenAI (e.g. "context_length_exceeded"), but some
+// compatible servers send a number or null, so it is decoded loosely.
+type ErrorDetail struct {
+	Code    any    `json:"code"`
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+// CodeString returns the error code as a string, or "" if it is not set.
+func (d ErrorDetail) CodeString() string {
+	switch code := d.Code.(type) {
+	case nil:
+		return ""
+	case string:
+		return code
+	case float64:
+		return fmt.Sprintf("%g", code)
+	default:
+		return fmt.Sprint(code)
+	}
+}
@@ -0,0 +1,52 @@
+package openai_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/domain/openai"
+)
+
+// ---------------------------------------------------------------------------
+// ErrorResponse tests
+// ---------------------------------------------------------------------------
+
+func Test_ErrorResponse_Unmarshal_With_StringCode_Should_ReturnCode(t *testing.T) {
+	// Arrange
+	body := `{"error":{"message":"too long","type":"invalid_request_error","code":"context_length_exceeded"}}`
+	var response openai.ErrorResponse
+
+	// Act
+	err := json.Unmarshal([]byte(body), &response)
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "code must match", response.Error.CodeString(), "context_length_exceeded")
+	assert.That(t, "message must match", response.Error.Message, "too long")
+	assert.That(t, "type must match", response.Error.Type, "invalid_request_error")
+}
+
+func Test_ErrorDetail_CodeString_With_NumericCode_Should_FormatNumber(t *testing.T) {
+	// Arrange
+	var response openai.ErrorResponse
+	_ = json.Unmarshal([]byte(`{"error":{"message":"rate limited","code":429}}`), &response)
+
+	// Act
+	code := response.Error.CodeString()
+
+	// Assert
+	assert.That(t, "code must be formatted", code, "429")
+}
+
+func Test_ErrorDetail_CodeString_With_NullCode_Should_ReturnEmpty(t *testing.T) {
+	// Arrange
+	var response openai.ErrorResponse
+	_ = json.Unmarshal([]byte(`{"error":{"message":"bad request","code":null}}`), &response)
+
+	// Act
+	code := response.Error.CodeString()
+
+	// Assert
+	assert.That(t, "code must be empty", code, "")
+}
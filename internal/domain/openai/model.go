@@ -0,0 +1,28 @@
+package openai
+
+// ---------------------------------------------------------------------------
+// ModelList
+// ---------------------------------------------------------------------------
+
+// Model represents a single model served by the API.
+type Model struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	OwnedBy string `json:"owned_by,omitempty"`
+}
+
+// ModelList represents a response from the models endpoint.
+type ModelList struct {
+	Object string  `json:"object"`
+	Data   []Model `json:"data"`
+}
+
+// HasModel returns true if the list contains a model with the given ID.
+func (l ModelList) HasModel(id string) bool {
+	for _, model := range l.Data {
+		if model.ID == id {
+			return true
+		}
+	}
+	return false
+}
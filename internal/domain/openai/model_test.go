@@ -0,0 +1,32 @@
+package openai_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/domain/openai"
+)
+
+func Test_ModelList_HasModel_With_ListedModel_Should_ReturnTrue(t *testing.T) {
+	// Arrange
+	var list openai.ModelList
+	_ = json.Unmarshal([]byte(`{"object":"list","data":[{"id":"qwen3","object":"model"},{"id":"nomic-embed","object":"model"}]}`), &list)
+
+	// Act
+	found := list.HasModel("nomic-embed")
+
+	// Assert
+	assert.That(t, "model must be found", found, true)
+}
+
+func Test_ModelList_HasModel_With_UnknownModel_Should_ReturnFalse(t *testing.T) {
+	// Arrange
+	list := openai.ModelList{Data: []openai.Model{{ID: "qwen3"}}}
+
+	// Act
+	found := list.HasModel("llama")
+
+	// Assert
+	assert.That(t, "model must not be found", found, false)
+}
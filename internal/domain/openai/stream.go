@@ -0,0 +1,179 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidStreamedArguments is returned if the assembled arguments of a streamed tool call are not valid JSON.
+var ErrInvalidStreamedArguments = errors.New("streamed tool call arguments are not valid JSON")
+
+// StreamAccumulator assembles the chunks of a streamed chat completion into
+// the response the server would have returned without streaming.
+//
+// Assembly policy:
+//   - Choices are merged by their index and returned in index order.
+//   - Content fragments are concatenated in arrival order. Tool call fragments
+//     interleaved with the content do not split it, so the assembled content is
+//     the same as the content of the non-streamed message.
+//   - Tool call fragments are merged by their index and returned in index order.
+//     The ID, type and name are taken from the first fragment that sets them;
+//     the argument fragments are concatenated in arrival order.
+//   - Empty arguments become "{}"; other arguments must form valid JSON once assembled.
+//   - The role defaults to "assistant"; the finish reason is the last one sent.
+//   - ID, model, created time and system fingerprint are taken from the first chunk
+//     that sets them, the usage from the last chunk that reports it.
+type StreamAccumulator struct {
+	choices  map[int]*streamedChoice
+	response ChatCompletionResponse
+}
+
+// streamedChoice holds the assembled state of one choice.
+type streamedChoice struct {
+	toolCalls    map[int]*streamedToolCall
+	content      strings.Builder
+	finishReason string
+	role         string
+}
+
+// streamedToolCall holds the assembled state of one tool call.
+type streamedToolCall struct {
+	arguments strings.Builder
+	id        string
+	name      string
+	callType  string
+}
+
+// NewStreamAccumulator creates an empty StreamAccumulator.
+func NewStreamAccumulator() *StreamAccumulator {
+	return &StreamAccumulator{choices: make(map[int]*streamedChoice)}
+}
+
+// Add merges a chunk into the response.
+func (a *StreamAccumulator) Add(chunk ChatCompletionChunk) {
+	a.addMetadata(chunk)
+	for _, c := range chunk.Choices {
+		choice := a.choice(c.Index)
+		if choice.role == "" {
+			choice.role = c.Delta.Role
+		}
+		choice.content.WriteString(c.Delta.Content)
+		if c.FinishReason != "" {
+			choice.finishReason = c.FinishReason
+		}
+		for _, delta := range c.Delta.ToolCalls {
+			choice.addToolCall(delta)
+		}
+	}
+}
+
+// Response returns the assembled response.
+// Returns ErrInvalidStreamedArguments if the arguments of a tool call are not valid JSON.
+func (a *StreamAccumulator) Response() (ChatCompletionResponse, error) {
+	response := a.response
+	response.Choices = make([]ChatCompletionChoice, 0, len(a.choices))
+	for _, index := range sortedKeys(a.choices) {
+		message, err := a.choices[index].message()
+		if err != nil {
+			return ChatCompletionResponse{}, err
+		}
+		response.Choices = append(response.Choices, ChatCompletionChoice{
+			FinishReason: a.choices[index].finishReason,
+			Index:        index,
+			Message:      message,
+		})
+	}
+	return response, nil
+}
+
+// addMetadata keeps the first response metadata and the last reported usage.
+func (a *StreamAccumulator) addMetadata(chunk ChatCompletionChunk) {
+	if a.response.ID == "" {
+		a.response.ID = chunk.ID
+	}
+	if a.response.Model == "" {
+		a.response.Model = chunk.Model
+	}
+	if a.response.Created == 0 {
+		a.response.Created = chunk.Created
+	}
+	if a.response.SystemFingerprint == "" {
+		a.response.SystemFingerprint = chunk.SystemFingerprint
+	}
+	if chunk.Usage != nil {
+		a.response.Usage = *chunk.Usage
+	}
+	a.response.Object = "chat.completion"
+}
+
+// choice returns the state of the choice, creating it on first use.
+func (a *StreamAccumulator) choice(index int) *streamedChoice {
+	choice, ok := a.choices[index]
+	if !ok {
+		choice = &streamedChoice{toolCalls: make(map[int]*streamedToolCall)}
+		a.choices[index] = choice
+	}
+	return choice
+}
+
+// addToolCall merges a tool call fragment into the choice.
+func (c *streamedChoice) addToolCall(delta ToolCallDelta) {
+	call, ok := c.toolCalls[delta.Index]
+	if !ok {
+		call = &streamedToolCall{}
+		c.toolCalls[delta.Index] = call
+	}
+	if call.id == "" {
+		call.id = delta.ID
+	}
+	if call.callType == "" {
+		call.callType = delta.Type
+	}
+	if call.name == "" {
+		call.name = delta.Function.Name
+	}
+	call.arguments.WriteString(delta.Function.Arguments)
+}
+
+// message returns the assembled message of the choice.
+func (c *streamedChoice) message() (Message, error) {
+	role := c.role
+	if role == "" {
+		role = "assistant"
+	}
+	message := NewMessage(role, c.content.String())
+	if len(c.toolCalls) == 0 {
+		return message, nil
+	}
+
+	toolCalls := make([]ToolCall, 0, len(c.toolCalls))
+	for _, index := range sortedKeys(c.toolCalls) {
+		call := c.toolCalls[index]
+		arguments := call.arguments.String()
+		if strings.TrimSpace(arguments) == "" {
+			arguments = "{}"
+		}
+		if !json.Valid([]byte(arguments)) {
+			return Message{}, fmt.Errorf("%w: tool call %q", ErrInvalidStreamedArguments, call.name)
+		}
+		toolCall := NewToolCall(call.id, call.name, arguments)
+		if call.callType != "" {
+			toolCall.Type = call.callType
+		}
+		toolCalls = append(toolCalls, toolCall)
+	}
+	return message.WithToolCalls(toolCalls), nil
+}
+
+// sortedKeys returns the keys of the map in ascending order.
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
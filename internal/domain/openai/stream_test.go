@@ -0,0 +1,128 @@
+package openai_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/domain/openai"
+)
+
+// fragmentedChunks streams an assistant turn that interleaves content with two
+// tool calls whose arguments are split across many deltas.
+func fragmentedChunks() []openai.ChatCompletionChunk {
+	chunk := func(delta openai.ChatCompletionDelta, finishReason string) openai.ChatCompletionChunk {
+		return openai.ChatCompletionChunk{
+			Choices: []openai.ChatCompletionChunkChoice{{Delta: delta, FinishReason: finishReason}},
+			Created: 42,
+			ID:      "chatcmpl-1",
+			Model:   "test-model",
+		}
+	}
+	call := func(index int, id, name, arguments string) openai.ChatCompletionDelta {
+		delta := openai.ToolCallDelta{Function: openai.FunctionCall{Arguments: arguments, Name: name}, ID: id, Index: index}
+		if id != "" {
+			delta.Type = "function"
+		}
+		return openai.ChatCompletionDelta{ToolCalls: []openai.ToolCallDelta{delta}}
+	}
+
+	last := chunk(openai.ChatCompletionDelta{}, "tool_calls")
+	last.Usage = &openai.ChatCompletionUsage{CompletionTokens: 5, PromptTokens: 10, TotalTokens: 15}
+	return []openai.ChatCompletionChunk{
+		chunk(openai.ChatCompletionDelta{Role: "assistant"}, ""),
+		chunk(openai.ChatCompletionDelta{Content: "Let me "}, ""),
+		chunk(call(0, "call_1", "read_file", ""), ""),
+		chunk(openai.ChatCompletionDelta{Content: "check "}, ""),
+		chunk(call(0, "", "", `{"pa`), ""),
+		chunk(call(1, "call_2", "list_files", `{"dir`), ""),
+		chunk(call(0, "", "", `th":"a.`), ""),
+		chunk(openai.ChatCompletionDelta{Content: "both."}, ""),
+		chunk(call(1, "", "", `":"."}`), ""),
+		chunk(call(0, "", "", `go"}`), ""),
+		last,
+	}
+}
+
+func Test_StreamAccumulator_Response_With_FragmentedDeltas_Should_EqualNonStreamedResponse(t *testing.T) {
+	// Arrange
+	acc := openai.NewStreamAccumulator()
+	for _, chunk := range fragmentedChunks() {
+		acc.Add(chunk)
+	}
+	expected := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{
+			FinishReason: "tool_calls",
+			Message: openai.NewMessage("assistant", "Let me check both.").WithToolCalls([]openai.ToolCall{
+				openai.NewToolCall("call_1", "read_file", `{"path":"a.go"}`),
+				openai.NewToolCall("call_2", "list_files", `{"dir":"."}`),
+			}),
+		}},
+		Created: 42,
+		ID:      "chatcmpl-1",
+		Model:   "test-model",
+		Object:  "chat.completion",
+		Usage:   openai.ChatCompletionUsage{CompletionTokens: 5, PromptTokens: 10, TotalTokens: 15},
+	}
+
+	// Act
+	response, err := acc.Response()
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "response must equal the non-streamed response", response, expected)
+}
+
+func Test_StreamAccumulator_Response_With_EmptyArguments_Should_UseEmptyObject(t *testing.T) {
+	// Arrange
+	acc := openai.NewStreamAccumulator()
+	acc.Add(openai.ChatCompletionChunk{Choices: []openai.ChatCompletionChunkChoice{{
+		Delta: openai.ChatCompletionDelta{ToolCalls: []openai.ToolCallDelta{{
+			Function: openai.FunctionCall{Name: "get_time"},
+			ID:       "call_1",
+		}}},
+	}}})
+
+	// Act
+	response, err := acc.Response()
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "arguments must be an empty object", response.Choices[0].Message.ToolCalls[0].Function.Arguments, "{}")
+	assert.That(t, "type must default to function", response.Choices[0].Message.ToolCalls[0].Type, "function")
+}
+
+func Test_StreamAccumulator_Response_With_TruncatedArguments_Should_ReturnError(t *testing.T) {
+	// Arrange
+	acc := openai.NewStreamAccumulator()
+	acc.Add(openai.ChatCompletionChunk{Choices: []openai.ChatCompletionChunkChoice{{
+		Delta: openai.ChatCompletionDelta{ToolCalls: []openai.ToolCallDelta{{
+			Function: openai.FunctionCall{Arguments: `{"path":"a.`, Name: "read_file"},
+			ID:       "call_1",
+		}}},
+	}}})
+
+	// Act
+	_, err := acc.Response()
+
+	// Assert
+	assert.That(t, "err must be ErrInvalidStreamedArguments", errors.Is(err, openai.ErrInvalidStreamedArguments), true)
+}
+
+func Test_StreamAccumulator_Response_With_SeveralChoices_Should_OrderByIndex(t *testing.T) {
+	// Arrange
+	acc := openai.NewStreamAccumulator()
+	acc.Add(openai.ChatCompletionChunk{Choices: []openai.ChatCompletionChunkChoice{
+		{Delta: openai.ChatCompletionDelta{Content: "second"}, Index: 1},
+		{Delta: openai.ChatCompletionDelta{Content: "first"}, Index: 0},
+	}})
+
+	// Act
+	response, err := acc.Response()
+
+	// Assert
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "response must have two choices", len(response.Choices), 2)
+	assert.That(t, "first choice must come first", response.Choices[0].Message.Content, "first")
+	assert.That(t, "second choice must come second", response.Choices[1].Message.Content, "second")
+}
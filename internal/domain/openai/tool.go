@@ -13,6 +13,25 @@ type FunctionDefinition struct {
 	Parameters  ParametersDefinition `json:"parameters"`
 }
 
+// NamedToolChoice forces the model to call the named function.
+type NamedToolChoice struct {
+	Function NamedToolChoiceFunction `json:"function"`
+	Type     string                  `json:"type"`
+}
+
+// NamedToolChoiceFunction names the function of a NamedToolChoice.
+type NamedToolChoiceFunction struct {
+	Name string `json:"name"`
+}
+
+// NewNamedToolChoice creates a tool choice that forces a call of the named function.
+func NewNamedToolChoice(name string) NamedToolChoice {
+	return NamedToolChoice{
+		Function: NamedToolChoiceFunction{Name: name},
+		Type:     "function",
+	}
+}
+
 // ParametersDefinition defines the parameters schema for a function.
 type ParametersDefinition struct {
 	Properties           map[string]PropertyDefinition `json:"properties"`
@@ -22,10 +41,12 @@ type ParametersDefinition struct {
 }
 
 // PropertyDefinition defines a single property in a JSON schema.
+// Items describes the elements of an array property.
 type PropertyDefinition struct {
-	Description string   `json:"description"`
-	Type        string   `json:"type"`
-	Enum        []string `json:"enum,omitempty"`
+	Items       *PropertyDefinition `json:"items,omitempty"`
+	Description string              `json:"description"`
+	Type        string              `json:"type"`
+	Enum        []string            `json:"enum,omitempty"`
 }
 
 // Tool defines a tool available to the model.
@@ -0,0 +1,71 @@
+package tooling
+
+import (
+	"sync"
+	"time"
+
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+)
+
+// Bounds of the idempotency keys remembered for memory writes (alphabetically sorted).
+const (
+	idempotencyKeyLimit = 256              // Maximum number of remembered keys
+	idempotencyWindow   = 10 * time.Minute // How long a key is remembered
+)
+
+// idempotentWrite is the note written for an idempotency key.
+type idempotentWrite struct {
+	writtenAt time.Time
+	noteID    agent.NoteID
+}
+
+// recentWrites remembers the notes written for recent idempotency keys.
+// Keys expire after the window; beyond the limit the oldest key is forgotten.
+// The mutex is held across a keyed write, so concurrent retries with the same key
+// cannot both write.
+type recentWrites struct {
+	writes map[string]idempotentWrite
+	order  []string // Keys in insertion order, oldest first
+	limit  int
+	window time.Duration
+	mu     sync.Mutex
+}
+
+// newRecentWrites creates an empty set of remembered writes.
+func newRecentWrites(limit int, window time.Duration) *recentWrites {
+	return &recentWrites{
+		limit:  limit,
+		window: window,
+		writes: make(map[string]idempotentWrite),
+	}
+}
+
+// do returns the note ID remembered for key, or calls write and remembers its note ID
+// if the write succeeds. The boolean reports whether the ID was remembered.
+func (r *recentWrites) do(key string, write func() (agent.NoteID, error)) (agent.NoteID, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if w, ok := r.writes[key]; ok && time.Now().Sub(w.writtenAt) < r.window {
+		return w.noteID, true, nil
+	}
+
+	id, err := write()
+	if err != nil {
+		return "", false, err
+	}
+	r.remember(key, id)
+	return id, false, nil
+}
+
+// remember records the note written for key, dropping the oldest keys beyond the limit.
+func (r *recentWrites) remember(key string, id agent.NoteID) {
+	if _, ok := r.writes[key]; !ok {
+		r.order = append(r.order, key)
+	}
+	r.writes[key] = idempotentWrite{noteID: id, writtenAt: time.Now()}
+	for len(r.order) > r.limit {
+		delete(r.writes, r.order[0])
+		r.order = r.order[1:]
+	}
+}
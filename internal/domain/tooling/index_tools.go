@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/andygeiss/go-agent/internal/domain/agent"
@@ -14,26 +16,35 @@ import (
 // ErrPathsRequired indicates that paths argument is required.
 var ErrPathsRequired = errors.New("paths is required")
 
+// scanNoteTopDirectories is the number of directories named in the memory note of a scan.
+const scanNoteTopDirectories = 5
+
 // indexScanArgs represents the arguments for the index.scan tool.
 type indexScanArgs struct {
+	Label  string   `json:"label,omitempty"`
 	Ignore []string `json:"ignore,omitempty"`
 	Paths  []string `json:"paths"`
 }
 
 // indexChangedSinceArgs represents the arguments for the index.changed_since tool.
 type indexChangedSinceArgs struct {
-	Since string `json:"since"`
+	PathPrefix string `json:"path_prefix,omitempty"`
+	Since      string `json:"since"`
 }
 
 // indexDiffSnapshotArgs represents the arguments for the index.diff_snapshot tool.
 type indexDiffSnapshotArgs struct {
-	FromID string `json:"from_id"`
-	ToID   string `json:"to_id"`
+	FromID     string `json:"from_id"`
+	PathPrefix string `json:"path_prefix,omitempty"`
+	ToID       string `json:"to_id"`
 }
 
 // indexScanResult represents the result of the index.scan tool.
 type indexScanResult struct {
 	IndexedAt    string `json:"indexed_at"`
+	Label        string `json:"label,omitempty"`
+	MemoryError  string `json:"memory_error,omitempty"`
+	MemoryNoteID string `json:"memory_note_id,omitempty"`
 	SnapshotID   string `json:"snapshot_id"`
 	Status       string `json:"status"`
 	FilesIndexed int    `json:"files_indexed"`
@@ -42,9 +53,11 @@ type indexScanResult struct {
 
 // indexChangedSinceResult represents the result of the index.changed_since tool.
 type indexChangedSinceResult struct {
-	Status string            `json:"status"`
-	Files  []indexFileResult `json:"files"`
-	Count  int               `json:"count"`
+	Since      string            `json:"since"`
+	SnapshotID string            `json:"snapshot_id"`
+	Status     string            `json:"status"`
+	Files      []indexFileResult `json:"files"`
+	Count      int               `json:"count"`
 }
 
 // indexFileResult represents a single file in the result.
@@ -56,15 +69,26 @@ type indexFileResult struct {
 
 // indexDiffSnapshotResult represents the result of the index.diff_snapshot tool.
 type indexDiffSnapshotResult struct {
-	Status  string            `json:"status"`
-	Added   []indexFileResult `json:"added"`
-	Changed []indexFileResult `json:"changed"`
-	Removed []indexFileResult `json:"removed"`
+	Diff    string                 `json:"diff"`
+	Status  string                 `json:"status"`
+	Added   []indexFileResult      `json:"added"`
+	Changed []indexFileResult      `json:"changed"`
+	Removed []indexFileResult      `json:"removed"`
+	Summary indexDiffSummaryResult `json:"summary"`
+}
+
+// indexDiffSummaryResult represents the file counts of a diff.
+type indexDiffSummaryResult struct {
+	Added   int `json:"added"`
+	Changed int `json:"changed"`
+	Removed int `json:"removed"`
 }
 
 // IndexToolService provides indexing tool implementations.
 type IndexToolService struct {
-	svc *indexing.Service
+	idGen agent.IDGenerator
+	store agent.MemoryStore
+	svc   *indexing.Service
 }
 
 // NewIndexToolService creates a new index tool service.
@@ -72,6 +96,16 @@ func NewIndexToolService(svc *indexing.Service) *IndexToolService {
 	return &IndexToolService{svc: svc}
 }
 
+// WithMemory makes IndexScan remember each scan as a fact note in the store,
+// with the snapshot ID, the file count, and the directories with the most files,
+// so that the agent can later recall what it indexed and when.
+// The idGenerator provides the note IDs.
+func (s *IndexToolService) WithMemory(store agent.MemoryStore, idGenerator agent.IDGenerator) *IndexToolService {
+	s.idGen = idGenerator
+	s.store = store
+	return s
+}
+
 // IndexScan scans the given paths and creates a new snapshot.
 func (s *IndexToolService) IndexScan(ctx context.Context, arguments string) (string, error) {
 	var args indexScanArgs
@@ -80,10 +114,10 @@ func (s *IndexToolService) IndexScan(ctx context.Context, arguments string) (str
 	}
 
 	if len(args.Paths) == 0 {
-		return "", ErrPathsRequired
+		return "", agent.NewToolError("index.scan", "missing argument", ErrPathsRequired).WithUserFacing()
 	}
 
-	snapshot, err := s.svc.Scan(ctx, args.Paths, args.Ignore)
+	snapshot, err := s.svc.ScanWithLabel(ctx, args.Paths, args.Ignore, args.Label)
 	if err != nil {
 		return "", fmt.Errorf("failed to scan: %w", err)
 	}
@@ -92,10 +126,20 @@ func (s *IndexToolService) IndexScan(ctx context.Context, arguments string) (str
 		FilesIndexed: snapshot.FileCount(),
 		FilesTotal:   snapshot.FileCount(),
 		IndexedAt:    snapshot.CreatedAt.Format(time.RFC3339),
+		Label:        snapshot.Label,
 		SnapshotID:   string(snapshot.ID),
 		Status:       "success",
 	}
 
+	// A failure to remember the scan does not invalidate the snapshot
+	if s.store != nil {
+		if noteID, err := s.rememberScan(ctx, snapshot); err != nil {
+			result.MemoryError = err.Error()
+		} else {
+			result.MemoryNoteID = string(noteID)
+		}
+	}
+
 	output, err := json.Marshal(result)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal result: %w", err)
@@ -113,18 +157,20 @@ func (s *IndexToolService) IndexChangedSince(ctx context.Context, arguments stri
 
 	since, err := time.Parse(time.RFC3339, args.Since)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse since timestamp: %w", err)
+		return "", agent.NewToolError("index.changed_since", "since must be an RFC3339 timestamp", err).WithUserFacing()
 	}
 
-	files, err := s.svc.ChangedSince(ctx, since)
+	changed, err := s.svc.ChangedSince(ctx, since, args.PathPrefix)
 	if err != nil {
 		return "", fmt.Errorf("failed to get changed files: %w", err)
 	}
 
 	result := indexChangedSinceResult{
-		Count:  len(files),
-		Files:  convertFileInfosToResults(files),
-		Status: "success",
+		Count:      len(changed.Files),
+		Files:      convertFileInfosToResults(changed.Files),
+		Since:      changed.Since.Format(time.RFC3339),
+		SnapshotID: string(changed.SnapshotID),
+		Status:     "success",
 	}
 
 	output, err := json.Marshal(result)
@@ -142,16 +188,23 @@ func (s *IndexToolService) IndexDiffSnapshot(ctx context.Context, arguments stri
 		return "", fmt.Errorf("failed to parse arguments: %w", err)
 	}
 
-	diff, err := s.svc.DiffSnapshots(ctx, indexing.SnapshotID(args.FromID), indexing.SnapshotID(args.ToID))
+	diff, err := s.svc.DiffSnapshots(ctx, indexing.SnapshotID(args.FromID), indexing.SnapshotID(args.ToID), args.PathPrefix)
 	if err != nil {
 		return "", fmt.Errorf("failed to diff snapshots: %w", err)
 	}
 
+	summary := diff.Summary()
 	result := indexDiffSnapshotResult{
 		Added:   convertFileInfosToResults(diff.Added),
 		Changed: convertFileInfosToResults(diff.Changed),
+		Diff:    diff.String(),
 		Removed: convertFileInfosToResults(diff.Removed),
 		Status:  "success",
+		Summary: indexDiffSummaryResult{
+			Added:   summary.Added,
+			Changed: summary.Changed,
+			Removed: summary.Removed,
+		},
 	}
 
 	output, err := json.Marshal(result)
@@ -162,6 +215,45 @@ func (s *IndexToolService) IndexDiffSnapshot(ctx context.Context, arguments stri
 	return string(output), nil
 }
 
+// rememberScan writes a fact note describing the snapshot to the memory store.
+func (s *IndexToolService) rememberScan(ctx context.Context, snapshot indexing.Snapshot) (agent.NoteID, error) {
+	id := agent.NoteID(s.idGen.NewID())
+	note := agent.NewFactNote(id, scanNoteContent(snapshot), "index", "snapshot").
+		WithKeywords(string(snapshot.ID))
+	if err := s.store.Write(ctx, note); err != nil {
+		return "", fmt.Errorf("failed to write scan note: %w", err)
+	}
+	return id, nil
+}
+
+// scanNoteContent describes the snapshot in a sentence the agent can recall.
+func scanNoteContent(snapshot indexing.Snapshot) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Indexed %s on %s as snapshot %s with %d files.",
+		strings.Join(snapshot.Roots, ", "), snapshot.CreatedAt.Format(time.RFC3339), snapshot.ID, snapshot.FileCount())
+
+	breakdown := snapshot.DirectoryBreakdown()
+	dirs := make([]string, 0, len(breakdown))
+	for dir := range breakdown {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if breakdown[dirs[i]] != breakdown[dirs[j]] {
+			return breakdown[dirs[i]] > breakdown[dirs[j]]
+		}
+		return dirs[i] < dirs[j]
+	})
+	for i, dir := range dirs[:min(scanNoteTopDirectories, len(dirs))] {
+		if i == 0 {
+			b.WriteString(" Top directories: ")
+		} else {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s (%d)", dir, breakdown[dir])
+	}
+	return b.String()
+}
+
 // convertFileInfosToResults converts FileInfo slices to result format.
 func convertFileInfosToResults(files []indexing.FileInfo) []indexFileResult {
 	results := make([]indexFileResult, len(files))
@@ -184,7 +276,9 @@ func NewIndexScanTool(svc *IndexToolService) agent.Tool {
 				WithDescription("List of directory paths to scan (absolute or relative)").
 				WithRequired()).
 			WithParameterDef(agent.NewParameterDefinition("ignore", agent.ParamTypeArray).
-				WithDescription("Patterns to ignore (e.g., node_modules, .git, *.log)")),
+				WithDescription("Patterns to ignore (e.g., node_modules, .git, *.log)")).
+			WithParameterDef(agent.NewParameterDefinition("label", agent.ParamTypeString).
+				WithDescription("Name for the snapshot (e.g., before-refactor), usable in place of its ID")),
 		Func: svc.IndexScan,
 	}
 }
@@ -196,7 +290,9 @@ func NewIndexChangedSinceTool(svc *IndexToolService) agent.Tool {
 		Definition: agent.NewToolDefinition("index.changed_since", "Get files changed since a given timestamp from the latest snapshot.").
 			WithParameterDef(agent.NewParameterDefinition("since", agent.ParamTypeString).
 				WithDescription("RFC3339 timestamp (e.g., 2024-01-15T10:00:00Z)").
-				WithRequired()),
+				WithRequired()).
+			WithParameterDef(agent.NewParameterDefinition("path_prefix", agent.ParamTypeString).
+				WithDescription("Only return files whose path begins with this prefix (e.g., a subdirectory)")),
 		Func: svc.IndexChangedSince,
 	}
 }
@@ -207,11 +303,13 @@ func NewIndexDiffSnapshotTool(svc *IndexToolService) agent.Tool {
 		ID: "index.diff_snapshot",
 		Definition: agent.NewToolDefinition("index.diff_snapshot", "Compare two snapshots and return added, removed, and changed files.").
 			WithParameterDef(agent.NewParameterDefinition("from_id", agent.ParamTypeString).
-				WithDescription("ID of the older snapshot").
+				WithDescription("ID or label of the older snapshot").
 				WithRequired()).
 			WithParameterDef(agent.NewParameterDefinition("to_id", agent.ParamTypeString).
-				WithDescription("ID of the newer snapshot").
-				WithRequired()),
+				WithDescription("ID or label of the newer snapshot").
+				WithRequired()).
+			WithParameterDef(agent.NewParameterDefinition("path_prefix", agent.ParamTypeString).
+				WithDescription("Only compare files whose path begins with this prefix (e.g., a subdirectory)")),
 		Func: svc.IndexDiffSnapshot,
 	}
 }
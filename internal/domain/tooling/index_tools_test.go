@@ -3,6 +3,8 @@ package tooling_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -61,6 +63,36 @@ func (m *mockIndexingStore) GetSnapshot(_ context.Context, id indexing.SnapshotI
 	return indexing.Snapshot{}, indexing.ErrSnapshotNotFound
 }
 
+func (m *mockIndexingStore) GetSnapshotByLabel(_ context.Context, label string) (indexing.Snapshot, error) {
+	if m.err != nil {
+		return indexing.Snapshot{}, m.err
+	}
+	for _, snapshot := range m.snapshots {
+		if label != "" && snapshot.Label == label {
+			return snapshot, nil
+		}
+	}
+	return indexing.Snapshot{}, indexing.ErrSnapshotNotFound
+}
+
+func (m *mockIndexingStore) LabelSnapshot(_ context.Context, id indexing.SnapshotID, label string) error {
+	if m.err != nil {
+		return m.err
+	}
+	if _, ok := m.snapshots[id]; !ok {
+		return indexing.ErrSnapshotNotFound
+	}
+	for key, snapshot := range m.snapshots {
+		if key == id {
+			snapshot.Label = label
+		} else if snapshot.Label == label {
+			snapshot.Label = ""
+		}
+		m.snapshots[key] = snapshot
+	}
+	return nil
+}
+
 // indexScanResult matches the response structure from IndexScan.
 type indexScanResult struct {
 	SnapshotID   string `json:"snapshot_id"`
@@ -70,6 +102,7 @@ type indexScanResult struct {
 
 // indexChangedSinceResult matches the response structure from IndexChangedSince.
 type indexChangedSinceResult struct {
+	SnapshotID   string   `json:"snapshot_id"`
 	Status       string   `json:"status"`
 	ChangedFiles []string `json:"changed_files"`
 	Count        int      `json:"count"`
@@ -77,6 +110,7 @@ type indexChangedSinceResult struct {
 
 // indexDiffSnapshotResult matches the response structure from IndexDiffSnapshot.
 type indexDiffSnapshotResult struct {
+	Diff    string   `json:"diff"`
 	Status  string   `json:"status"`
 	Added   []string `json:"added"`
 	Changed []string `json:"changed"`
@@ -93,7 +127,7 @@ func Test_IndexToolService_IndexScan_Should_ReturnSnapshotInfo(t *testing.T) {
 
 	walker := &mockIndexFileWalker{files: files}
 	store := newMockIndexingStore()
-	idGen := func() string { return "snap-test" }
+	idGen := agent.IDGeneratorFunc(func() string { return "snap-test" })
 	svc := indexing.NewService(walker, store, idGen)
 	toolSvc := tooling.NewIndexToolService(svc)
 
@@ -113,11 +147,67 @@ func Test_IndexToolService_IndexScan_Should_ReturnSnapshotInfo(t *testing.T) {
 	assert.That(t, "files indexed must be 2", response.FilesIndexed, 2)
 }
 
+func Test_IndexToolService_IndexScan_WithMemory_Should_WriteSnapshotNote(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	files := []indexing.FileInfo{
+		indexing.NewFileInfo("/project/cmd/main.go", now, 100),
+		indexing.NewFileInfo("/project/internal/a.go", now, 100),
+		indexing.NewFileInfo("/project/internal/b.go", now, 100),
+	}
+	svc := indexing.NewService(&mockIndexFileWalker{files: files}, newMockIndexingStore(),
+		agent.IDGeneratorFunc(func() string { return "snap-test" }))
+	memory := newMockMemoryStore()
+	toolSvc := tooling.NewIndexToolService(svc).
+		WithMemory(memory, agent.IDGeneratorFunc(func() string { return "note-scan" }))
+
+	// Act
+	result, err := toolSvc.IndexScan(context.Background(), `{"paths": ["/project"]}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	var response struct {
+		MemoryNoteID string `json:"memory_note_id"`
+	}
+	_ = json.Unmarshal([]byte(result), &response)
+	note := memory.notes["note-scan"]
+	assert.That(t, "note ID must be reported", response.MemoryNoteID, "note-scan")
+	assert.That(t, "note must be written", note != nil, true)
+	assert.That(t, "note must be a fact", note.SourceType, agent.SourceTypeFact)
+	assert.That(t, "note must be tagged", note.HasTag("index"), true)
+	assert.That(t, "note must name the snapshot", strings.Contains(note.RawContent, "snapshot snap-test"), true)
+	assert.That(t, "note must name the file count", strings.Contains(note.RawContent, "with 3 files"), true)
+	assert.That(t, "note must list top directories by file count", strings.Contains(note.RawContent, "Top directories: /project/internal (2), /project/cmd (1)"), true)
+}
+
+func Test_IndexToolService_IndexScan_WithMemory_With_WriteError_Should_StillSucceed(t *testing.T) {
+	// Arrange
+	svc := indexing.NewService(&mockIndexFileWalker{}, newMockIndexingStore(),
+		agent.IDGeneratorFunc(func() string { return "snap-test" }))
+	memory := newMockMemoryStore()
+	memory.writeErr = errors.New("disk full")
+	toolSvc := tooling.NewIndexToolService(svc).
+		WithMemory(memory, agent.IDGeneratorFunc(func() string { return "note-scan" }))
+
+	// Act
+	result, err := toolSvc.IndexScan(context.Background(), `{"paths": ["/project"]}`)
+
+	// Assert
+	var response struct {
+		MemoryError string `json:"memory_error"`
+		SnapshotID  string `json:"snapshot_id"`
+	}
+	_ = json.Unmarshal([]byte(result), &response)
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "snapshot must be reported", response.SnapshotID, "snap-test")
+	assert.That(t, "memory error must be reported", strings.Contains(response.MemoryError, "disk full"), true)
+}
+
 func Test_IndexToolService_IndexScan_With_EmptyPaths_Should_ReturnError(t *testing.T) {
 	// Arrange
 	walker := &mockIndexFileWalker{}
 	store := newMockIndexingStore()
-	svc := indexing.NewService(walker, store, func() string { return "id" })
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "id" }))
 	toolSvc := tooling.NewIndexToolService(svc)
 
 	args := `{"paths": []}`
@@ -126,7 +216,11 @@ func Test_IndexToolService_IndexScan_With_EmptyPaths_Should_ReturnError(t *testi
 	_, err := toolSvc.IndexScan(context.Background(), args)
 
 	// Assert
+	var toolErr *agent.ToolError
 	assert.That(t, "error must not be nil", err != nil, true)
+	assert.That(t, "error must wrap ErrPathsRequired", errors.Is(err, tooling.ErrPathsRequired), true)
+	assert.That(t, "error must be a tool error", errors.As(err, &toolErr), true)
+	assert.That(t, "error must be user facing", toolErr.UserFacing, true)
 }
 
 func Test_IndexToolService_IndexChangedSince_Should_ReturnChangedFiles(t *testing.T) {
@@ -141,7 +235,7 @@ func Test_IndexToolService_IndexChangedSince_Should_ReturnChangedFiles(t *testin
 	store := newMockIndexingStore()
 	store.latest = indexing.NewSnapshot("snap-1", files)
 
-	svc := indexing.NewService(walker, store, func() string { return "id" })
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "id" }))
 	toolSvc := tooling.NewIndexToolService(svc)
 
 	args := `{"since": "` + baseTime.Format(time.RFC3339) + `"}`
@@ -157,13 +251,14 @@ func Test_IndexToolService_IndexChangedSince_Should_ReturnChangedFiles(t *testin
 
 	assert.That(t, "status must be success", response.Status, "success")
 	assert.That(t, "count must be 1", response.Count, 1)
+	assert.That(t, "snapshot ID must be reported", response.SnapshotID, "snap-1")
 }
 
 func Test_IndexToolService_IndexChangedSince_With_InvalidTimestamp_Should_ReturnError(t *testing.T) {
 	// Arrange
 	walker := &mockIndexFileWalker{}
 	store := newMockIndexingStore()
-	svc := indexing.NewService(walker, store, func() string { return "id" })
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "id" }))
 	toolSvc := tooling.NewIndexToolService(svc)
 
 	args := `{"since": "invalid-timestamp"}`
@@ -172,7 +267,10 @@ func Test_IndexToolService_IndexChangedSince_With_InvalidTimestamp_Should_Return
 	_, err := toolSvc.IndexChangedSince(context.Background(), args)
 
 	// Assert
+	var toolErr *agent.ToolError
 	assert.That(t, "error must not be nil", err != nil, true)
+	assert.That(t, "error must be a tool error", errors.As(err, &toolErr), true)
+	assert.That(t, "error must be user facing", toolErr.UserFacing, true)
 }
 
 func Test_IndexToolService_IndexDiffSnapshot_Should_ReturnDiff(t *testing.T) {
@@ -194,7 +292,7 @@ func Test_IndexToolService_IndexDiffSnapshot_Should_ReturnDiff(t *testing.T) {
 	store.snapshots["snap-from"] = indexing.NewSnapshot("snap-from", fromFiles)
 	store.snapshots["snap-to"] = indexing.NewSnapshot("snap-to", toFiles)
 
-	svc := indexing.NewService(walker, store, func() string { return "id" })
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "id" }))
 	toolSvc := tooling.NewIndexToolService(svc)
 
 	args := `{"from_id": "snap-from", "to_id": "snap-to"}`
@@ -211,13 +309,14 @@ func Test_IndexToolService_IndexDiffSnapshot_Should_ReturnDiff(t *testing.T) {
 	assert.That(t, "status must be success", response.Status, "success")
 	assert.That(t, "added count must be 1", len(response.Added), 1)
 	assert.That(t, "removed count must be 1", len(response.Removed), 1)
+	assert.That(t, "diff must be rendered", response.Diff, "+ /path/to/added.go\n- /path/to/removed.go\n")
 }
 
 func Test_NewIndexScanTool_Should_ReturnValidTool(t *testing.T) {
 	// Arrange
 	walker := &mockIndexFileWalker{}
 	store := newMockIndexingStore()
-	svc := indexing.NewService(walker, store, func() string { return "id" })
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "id" }))
 	toolSvc := tooling.NewIndexToolService(svc)
 
 	// Act
@@ -229,11 +328,62 @@ func Test_NewIndexScanTool_Should_ReturnValidTool(t *testing.T) {
 	assert.That(t, "func must not be nil", tool.Func != nil, true)
 }
 
+func Test_IndexToolService_IndexChangedSince_With_PathPrefix_Should_FilterFiles(t *testing.T) {
+	// Arrange
+	since := time.Now().Add(-time.Hour)
+	store := newMockIndexingStore()
+	store.latest = indexing.NewSnapshot("snap-1", []indexing.FileInfo{
+		indexing.NewFileInfo("/repo/api/main.go", time.Now(), 100),
+		indexing.NewFileInfo("/repo/web/main.go", time.Now(), 200),
+	})
+	svc := indexing.NewService(&mockIndexFileWalker{}, store, agent.IDGeneratorFunc(func() string { return "id" }))
+	toolSvc := tooling.NewIndexToolService(svc)
+
+	args := `{"since": "` + since.Format(time.RFC3339) + `", "path_prefix": "/repo/api/"}`
+
+	// Act
+	result, err := toolSvc.IndexChangedSince(context.Background(), args)
+
+	// Assert
+	assert.That(t, "error must be nil", err == nil, true)
+
+	var response indexChangedSinceResult
+	_ = json.Unmarshal([]byte(result), &response)
+
+	assert.That(t, "count must be 1", response.Count, 1)
+}
+
+func Test_IndexToolService_IndexDiffSnapshot_With_PathPrefix_Should_FilterFiles(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	store := newMockIndexingStore()
+	store.snapshots["snap-from"] = indexing.NewSnapshot("snap-from", nil)
+	store.snapshots["snap-to"] = indexing.NewSnapshot("snap-to", []indexing.FileInfo{
+		indexing.NewFileInfo("/repo/api/added.go", now, 100),
+		indexing.NewFileInfo("/repo/web/added.go", now, 100),
+	})
+	svc := indexing.NewService(&mockIndexFileWalker{}, store, agent.IDGeneratorFunc(func() string { return "id" }))
+	toolSvc := tooling.NewIndexToolService(svc)
+
+	args := `{"from_id": "snap-from", "to_id": "snap-to", "path_prefix": "/repo/api/"}`
+
+	// Act
+	result, err := toolSvc.IndexDiffSnapshot(context.Background(), args)
+
+	// Assert
+	assert.That(t, "error must be nil", err == nil, true)
+
+	var response indexDiffSnapshotResult
+	_ = json.Unmarshal([]byte(result), &response)
+
+	assert.That(t, "diff must only contain files under prefix", response.Diff, "+ /repo/api/added.go\n")
+}
+
 func Test_NewIndexChangedSinceTool_Should_ReturnValidTool(t *testing.T) {
 	// Arrange
 	walker := &mockIndexFileWalker{}
 	store := newMockIndexingStore()
-	svc := indexing.NewService(walker, store, func() string { return "id" })
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "id" }))
 	toolSvc := tooling.NewIndexToolService(svc)
 
 	// Act
@@ -249,7 +399,7 @@ func Test_NewIndexDiffSnapshotTool_Should_ReturnValidTool(t *testing.T) {
 	// Arrange
 	walker := &mockIndexFileWalker{}
 	store := newMockIndexingStore()
-	svc := indexing.NewService(walker, store, func() string { return "id" })
+	svc := indexing.NewService(walker, store, agent.IDGeneratorFunc(func() string { return "id" }))
 	toolSvc := tooling.NewIndexToolService(svc)
 
 	// Act
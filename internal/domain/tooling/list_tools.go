@@ -0,0 +1,81 @@
+package tooling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+)
+
+// listToolsParameter represents a single parameter in the list_tools result.
+type listToolsParameter struct {
+	Default     string   `json:"default,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Enum        []string `json:"enum,omitempty"`
+	Required    bool     `json:"required,omitempty"`
+}
+
+// listToolsEntry represents a single tool in the list_tools result.
+type listToolsEntry struct {
+	Description string               `json:"description"`
+	Name        string               `json:"name"`
+	Parameters  []listToolsParameter `json:"parameters"`
+}
+
+// listToolsResult represents the result of the list_tools tool.
+type listToolsResult struct {
+	Status string           `json:"status"`
+	Tools  []listToolsEntry `json:"tools"`
+	Count  int              `json:"count"`
+}
+
+// NewListToolsTool creates the list_tools tool definition.
+// The tool reports the definitions currently registered with the executor,
+// so a model with a minimal prompt can discover its own capabilities.
+func NewListToolsTool(executor agent.ToolExecutor) agent.Tool {
+	return agent.Tool{
+		ID:         "list_tools",
+		Definition: agent.NewToolDefinition("list_tools", "List all available tools with their descriptions and parameters. Use when you are unsure which tools you can call."),
+		Func: func(_ context.Context, _ string) (string, error) {
+			return listTools(executor)
+		},
+	}
+}
+
+// listTools converts the executor's tool definitions to JSON.
+func listTools(executor agent.ToolExecutor) (string, error) {
+	definitions := executor.GetToolDefinitions()
+	result := listToolsResult{
+		Count:  len(definitions),
+		Status: "success",
+		Tools:  make([]listToolsEntry, len(definitions)),
+	}
+	for i, def := range definitions {
+		params := make([]listToolsParameter, len(def.Parameters))
+		for j, param := range def.Parameters {
+			params[j] = listToolsParameter{
+				Default:     param.Default,
+				Description: param.Description,
+				Enum:        param.Enum,
+				Name:        param.Name,
+				Required:    param.Required,
+				Type:        string(param.Type),
+			}
+		}
+		result.Tools[i] = listToolsEntry{
+			Description: def.Description,
+			Name:        def.Name,
+			Parameters:  params,
+		}
+	}
+
+	output, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return string(output), nil
+}
@@ -0,0 +1,123 @@
+package tooling_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/andygeiss/cloud-native-utils/assert"
+	"github.com/andygeiss/go-agent/internal/domain/agent"
+	"github.com/andygeiss/go-agent/internal/domain/tooling"
+)
+
+// mockToolExecutor is a test double for agent.ToolExecutor.
+type mockToolExecutor struct {
+	tools       map[string]agent.ToolFunc
+	definitions []agent.ToolDefinition
+}
+
+func newMockToolExecutor() *mockToolExecutor {
+	return &mockToolExecutor{tools: make(map[string]agent.ToolFunc)}
+}
+
+func (m *mockToolExecutor) Execute(ctx context.Context, toolName string, arguments string) (string, error) {
+	return m.tools[toolName](ctx, arguments)
+}
+
+func (m *mockToolExecutor) GetAvailableTools() []string {
+	names := make([]string, 0, len(m.tools))
+	for name := range m.tools {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (m *mockToolExecutor) GetToolDefinitions() []agent.ToolDefinition {
+	return m.definitions
+}
+
+func (m *mockToolExecutor) HasTool(toolName string) bool {
+	_, ok := m.tools[toolName]
+	return ok
+}
+
+func (m *mockToolExecutor) RegisterTool(name string, fn agent.ToolFunc) {
+	m.tools[name] = fn
+}
+
+func (m *mockToolExecutor) RegisterToolDefinition(def agent.ToolDefinition) {
+	m.definitions = append(m.definitions, def)
+}
+
+func (m *mockToolExecutor) unregisterTool(name string) {
+	delete(m.tools, name)
+	kept := make([]agent.ToolDefinition, 0, len(m.definitions))
+	for _, def := range m.definitions {
+		if def.Name != name {
+			kept = append(kept, def)
+		}
+	}
+	m.definitions = kept
+}
+
+// listToolsOutput mirrors the JSON returned by the list_tools tool.
+type listToolsOutput struct {
+	Tools []struct {
+		Description string `json:"description"`
+		Name        string `json:"name"`
+		Parameters  []struct {
+			Name     string `json:"name"`
+			Type     string `json:"type"`
+			Required bool   `json:"required"`
+		} `json:"parameters"`
+	} `json:"tools"`
+	Count int `json:"count"`
+}
+
+func registerTool(executor agent.ToolExecutor, tool agent.Tool) {
+	executor.RegisterTool(string(tool.ID), tool.Func)
+	executor.RegisterToolDefinition(tool.Definition)
+}
+
+func Test_ListToolsTool_Should_ReturnRegisteredTools(t *testing.T) {
+	// Arrange
+	executor := newMockToolExecutor()
+	registerTool(executor, tooling.NewMemoryGetTool(tooling.NewMemoryToolService(newMockMemoryStore(), testIDGenerator())))
+	tool := tooling.NewListToolsTool(executor)
+	registerTool(executor, tool)
+
+	// Act
+	output, err := executor.Execute(context.Background(), "list_tools", `{}`)
+
+	// Assert
+	var result listToolsOutput
+	unmarshalErr := json.Unmarshal([]byte(output), &result)
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "output must be valid JSON", unmarshalErr, nil)
+	assert.That(t, "count must be 2", result.Count, 2)
+	assert.That(t, "first tool must be memory_get", result.Tools[0].Name, "memory_get")
+	assert.That(t, "description must be included", result.Tools[0].Description != "", true)
+	assert.That(t, "parameter name must be included", result.Tools[0].Parameters[0].Name, "id")
+	assert.That(t, "parameter type must be included", result.Tools[0].Parameters[0].Type, "string")
+	assert.That(t, "parameter must be required", result.Tools[0].Parameters[0].Required, true)
+	assert.That(t, "second tool must be list_tools", result.Tools[1].Name, "list_tools")
+}
+
+func Test_ListToolsTool_With_UnregisteredTool_Should_ReflectCurrentTools(t *testing.T) {
+	// Arrange
+	executor := newMockToolExecutor()
+	registerTool(executor, tooling.NewMemoryGetTool(tooling.NewMemoryToolService(newMockMemoryStore(), testIDGenerator())))
+	tool := tooling.NewListToolsTool(executor)
+	registerTool(executor, tool)
+	executor.unregisterTool("memory_get")
+
+	// Act
+	output, err := tool.Func(context.Background(), `{}`)
+
+	// Assert
+	var result listToolsOutput
+	_ = json.Unmarshal([]byte(output), &result)
+	assert.That(t, "err must be nil", err, nil)
+	assert.That(t, "count must be 1", result.Count, 1)
+	assert.That(t, "only list_tools must remain", result.Tools[0].Name, "list_tools")
+}
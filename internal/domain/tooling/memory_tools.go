@@ -3,14 +3,36 @@ package tooling
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/andygeiss/go-agent/internal/domain/agent"
+	"github.com/andygeiss/go-agent/internal/domain/memorizing"
+)
+
+// Sentinel errors of the memory tools (alphabetically sorted).
+var (
+	ErrConfirmRequired = errors.New("confirm must be true to delete notes")
+	ErrInvalidTagMatch = errors.New("tag_match must be exact, prefix, or glob")
+	ErrSessionRequired = errors.New("no session configured")
+)
+
+// Limits of the memory_session_summary tool (alphabetically sorted).
+const (
+	sessionSummaryBulletChars = 200  // Maximum length of a single bullet
+	sessionSummaryMaxChars    = 2000 // Default maximum total length of the summary
+	sessionSummaryMaxNotes    = 10   // Default maximum number of summarized notes
 )
 
 // memoryWriteArgs represents the arguments for the memory_write tool.
 type memoryWriteArgs struct {
 	ContextDescription string   `json:"context_description"`
+	IdempotencyKey     string   `json:"idempotency_key,omitempty"`
 	RawContent         string   `json:"raw_content"`
 	SessionID          string   `json:"session_id,omitempty"`
 	SourceType         string   `json:"source_type"`
@@ -22,21 +44,56 @@ type memoryWriteArgs struct {
 	Importance         int      `json:"importance"`
 }
 
+// memoryDeleteMatchingArgs represents the arguments for the memory_delete_matching tool.
+type memoryDeleteMatchingArgs struct {
+	CreatedAfter   string   `json:"created_after,omitempty"`
+	CreatedBefore  string   `json:"created_before,omitempty"`
+	TagMatch       string   `json:"tag_match,omitempty"`
+	SourceTypes    []string `json:"source_types,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	MaxImportance  int      `json:"max_importance,omitempty"`
+	MinImportance  int      `json:"min_importance,omitempty"`
+	Confirm        bool     `json:"confirm"`
+	RequireAllTags bool     `json:"require_all_tags,omitempty"`
+}
+
 // memorySearchArgs represents the arguments for the memory_search tool.
 type memorySearchArgs struct {
-	Query         string   `json:"query"`
-	SessionID     string   `json:"session_id,omitempty"`
-	SourceTypes   []string `json:"source_types,omitempty"`
-	TaskID        string   `json:"task_id,omitempty"`
-	UserID        string   `json:"user_id,omitempty"`
-	Tags          []string `json:"tags,omitempty"`
-	Limit         int      `json:"limit,omitempty"`
-	MinImportance int      `json:"min_importance,omitempty"`
+	Query          string   `json:"query"`
+	Keywords       []string `json:"keywords,omitempty"`
+	SessionID      string   `json:"session_id,omitempty"`
+	SourceTypes    []string `json:"source_types,omitempty"`
+	TaskID         string   `json:"task_id,omitempty"`
+	UserID         string   `json:"user_id,omitempty"`
+	TagMatch       string   `json:"tag_match,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	Limit          int      `json:"limit,omitempty"`
+	MinImportance  int      `json:"min_importance,omitempty"`
+	RequireAllTags bool     `json:"require_all_tags,omitempty"`
+	WholeWord      bool     `json:"whole_word,omitempty"`
 }
 
 // memoryGetArgs represents the arguments for the memory_get tool.
 type memoryGetArgs struct {
-	ID string `json:"id"`
+	ID             string `json:"id"`
+	IncludeHistory bool   `json:"include_history,omitempty"`
+}
+
+// memorySessionSummaryArgs represents the arguments for the memory_session_summary tool.
+type memorySessionSummaryArgs struct {
+	Limit    int `json:"limit,omitempty"`
+	MaxChars int `json:"max_chars,omitempty"`
+}
+
+// memorySessionSummaryResult represents the result of the memory_session_summary tool.
+type memorySessionSummaryResult struct {
+	SessionID string   `json:"session_id"`
+	Status    string   `json:"status"`
+	Summary   string   `json:"summary"`
+	NoteIDs   []string `json:"note_ids"`
+	Count     int      `json:"count"`
+	Total     int      `json:"total"`
+	Truncated bool     `json:"truncated"`
 }
 
 // memorySearchResult represents a single search result for JSON output.
@@ -46,28 +103,95 @@ type memorySearchResult struct {
 	SourceType         string   `json:"source_type"`
 	Summary            string   `json:"summary"`
 	Tags               []string `json:"tags"`
+	Score              float64  `json:"score"`
 	Importance         int      `json:"importance"`
 }
 
 // MemoryToolService provides memory tool implementations.
 // It requires a MemoryStore to be injected for actual storage.
 type MemoryToolService struct {
-	embedder agent.EmbeddingClient
-	idGen    func() string
-	session  string
-	store    agent.MemoryStore
-	userID   string
+	embedder    agent.EmbeddingClient
+	weights     *agent.SearchableWeights
+	idGen       agent.IDGenerator
+	logger      *slog.Logger
+	recent      *recentWrites
+	session     string
+	store       agent.MemoryStore
+	userID      string
+	trackAccess bool
 }
 
 // NewMemoryToolService creates a new memory tool service.
-func NewMemoryToolService(store agent.MemoryStore, idGenerator func() string) *MemoryToolService {
+func NewMemoryToolService(store agent.MemoryStore, idGenerator agent.IDGenerator) *MemoryToolService {
 	return &MemoryToolService{
-		idGen: idGenerator,
-		store: store,
+		idGen:  idGenerator,
+		recent: newRecentWrites(idempotencyKeyLimit, idempotencyWindow),
+		store:  store,
+	}
+}
+
+// MemoryDeleteMatching deletes every note matching the filters and reports the number deleted.
+// The call must set confirm to true and at least one filter, so a careless call never
+// deletes notes, let alone all of them. With WithUserID, only that user's notes are deleted.
+func (s *MemoryToolService) MemoryDeleteMatching(ctx context.Context, arguments string) (string, error) {
+	var args memoryDeleteMatchingArgs
+	if err := agent.DecodeArgs(arguments, &args); err != nil {
+		return "", fmt.Errorf("failed to parse arguments: %w", err)
+	}
+	if !args.Confirm {
+		return "", agent.NewToolError("memory_delete_matching", "not confirmed", ErrConfirmRequired).WithUserFacing()
+	}
+
+	createdAfter, err := parseOptionalTimestamp("created_after", args.CreatedAfter)
+	if err != nil {
+		return "", err
+	}
+	createdBefore, err := parseOptionalTimestamp("created_before", args.CreatedBefore)
+	if err != nil {
+		return "", err
+	}
+	tagMatch, err := parseTagMatch("memory_delete_matching", args.TagMatch)
+	if err != nil {
+		return "", err
+	}
+	opts := &agent.MemorySearchOptions{
+		CreatedAfter:   createdAfter,
+		CreatedBefore:  createdBefore,
+		MaxImportance:  args.MaxImportance,
+		MinImportance:  args.MinImportance,
+		RequireAllTags: args.RequireAllTags,
+		SourceTypes:    mapSourceTypes(args.SourceTypes),
+		TagMatch:       tagMatch,
+		Tags:           args.Tags,
+	}
+	// The user scope is added after the check, so it never counts as the required filter
+	if !opts.HasFilters() {
+		return "", agent.NewToolError("memory_delete_matching", "missing filter", memorizing.ErrFilterEmpty).WithUserFacing()
+	}
+	opts.UserID = s.userID
+
+	count, err := memorizing.NewService(s.store).DeleteMatching(ctx, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to delete memory notes: %w", err)
+	}
+	return fmt.Sprintf(`{"status": "success", "deleted": %d}`, count), nil
+}
+
+// parseOptionalTimestamp parses the RFC3339 value of the named memory_delete_matching argument.
+// An empty value yields the zero time, which disables the filter.
+func parseOptionalTimestamp(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, agent.NewToolError("memory_delete_matching", name+" must be an RFC3339 timestamp", err).WithUserFacing()
 	}
+	return t, nil
 }
 
 // MemoryGet retrieves a specific note by ID.
+// With include_history, the earlier versions recorded by the store are returned as well.
 func (s *MemoryToolService) MemoryGet(ctx context.Context, arguments string) (string, error) {
 	var args memoryGetArgs
 	if err := agent.DecodeArgs(arguments, &args); err != nil {
@@ -78,24 +202,34 @@ func (s *MemoryToolService) MemoryGet(ctx context.Context, arguments string) (st
 	if err != nil {
 		return "", fmt.Errorf("failed to get memory note: %w", err)
 	}
+	s.recordAccess(ctx, note)
+
+	fields := map[string]any{
+		"id":                  string(note.ID),
+		"source_type":         string(note.SourceType),
+		"raw_content":         note.RawContent,
+		"summary":             note.Summary,
+		"context_description": note.ContextDescription,
+		"keywords":            note.Keywords,
+		"tags":                note.Tags,
+		"importance":          note.Importance,
+		"user_id":             note.UserID,
+		"session_id":          note.SessionID,
+		"task_id":             note.TaskID,
+		"created_at":          note.CreatedAt,
+		"updated_at":          note.UpdatedAt,
+	}
+	if args.IncludeHistory {
+		history := note.History
+		if history == nil {
+			history = []agent.NoteVersion{}
+		}
+		fields["history"] = history
+	}
 
 	output, err := json.Marshal(map[string]any{
 		"status": "success",
-		"note": map[string]any{
-			"id":                  string(note.ID),
-			"source_type":         string(note.SourceType),
-			"raw_content":         note.RawContent,
-			"summary":             note.Summary,
-			"context_description": note.ContextDescription,
-			"keywords":            note.Keywords,
-			"tags":                note.Tags,
-			"importance":          note.Importance,
-			"user_id":             note.UserID,
-			"session_id":          note.SessionID,
-			"task_id":             note.TaskID,
-			"created_at":          note.CreatedAt,
-			"updated_at":          note.UpdatedAt,
-		},
+		"note":   fields,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal note: %w", err)
@@ -105,35 +239,64 @@ func (s *MemoryToolService) MemoryGet(ctx context.Context, arguments string) (st
 }
 
 // MemorySearch retrieves notes matching the query and filters.
+// If an embedder is configured and the store supports it, notes are ranked by
+// embedding similarity to the query. If embedding the query fails, it falls
+// back to plain text search.
 func (s *MemoryToolService) MemorySearch(ctx context.Context, arguments string) (string, error) {
 	var args memorySearchArgs
 	if err := agent.DecodeArgs(arguments, &args); err != nil {
 		return "", fmt.Errorf("failed to parse arguments: %w", err)
 	}
 
-	opts := buildMemorySearchOpts(args)
+	opts, err := buildMemorySearchOpts(args)
+	if err != nil {
+		return "", err
+	}
 	limit := defaultLimit(args.Limit, 10)
 
-	notes, err := s.store.Search(ctx, args.Query, limit, opts)
+	notes, err := s.search(ctx, args.Query, limit, opts)
 	if err != nil {
 		return "", fmt.Errorf("failed to search memory: %w", err)
 	}
+	s.recordAccess(ctx, notes...)
 
 	return marshalSearchResults(notes)
 }
 
-// buildMemorySearchOpts creates MemorySearchOptions from args if any filters are set.
-func buildMemorySearchOpts(args memorySearchArgs) *agent.MemorySearchOptions {
-	if args.UserID == "" && args.SessionID == "" && args.TaskID == "" && len(args.Tags) == 0 && len(args.SourceTypes) == 0 && args.MinImportance == 0 {
-		return nil
+// buildMemorySearchOpts creates MemorySearchOptions from args if any option is set.
+// Returns a user-facing ToolError wrapping ErrInvalidTagMatch for an unknown tag_match.
+func buildMemorySearchOpts(args memorySearchArgs) (*agent.MemorySearchOptions, error) {
+	tagMatch, err := parseTagMatch("memory_search", args.TagMatch)
+	if err != nil {
+		return nil, err
+	}
+	if args.UserID == "" && args.SessionID == "" && args.TaskID == "" && len(args.Tags) == 0 &&
+		len(args.Keywords) == 0 && len(args.SourceTypes) == 0 && args.MinImportance == 0 &&
+		tagMatch == "" && !args.RequireAllTags && !args.WholeWord {
+		return nil, nil
 	}
 	return &agent.MemorySearchOptions{
-		MinImportance: args.MinImportance,
-		SessionID:     args.SessionID,
-		SourceTypes:   mapSourceTypes(args.SourceTypes),
-		TaskID:        args.TaskID,
-		UserID:        args.UserID,
-		Tags:          args.Tags,
+		Keywords:       args.Keywords,
+		MinImportance:  args.MinImportance,
+		SessionID:      args.SessionID,
+		SourceTypes:    mapSourceTypes(args.SourceTypes),
+		TaskID:         args.TaskID,
+		UserID:         args.UserID,
+		RequireAllTags: args.RequireAllTags,
+		TagMatch:       tagMatch,
+		Tags:           args.Tags,
+		WholeWord:      args.WholeWord,
+	}, nil
+}
+
+// parseTagMatch validates the tag_match argument of the named tool.
+// An empty value yields the zero TagMatch, which matches tags exactly.
+func parseTagMatch(toolName, value string) (agent.TagMatch, error) {
+	switch tagMatch := agent.TagMatch(value); tagMatch {
+	case "", agent.TagMatchExact, agent.TagMatchGlob, agent.TagMatchPrefix:
+		return tagMatch, nil
+	default:
+		return "", agent.NewToolError(toolName, "unknown tag_match "+strconv.Quote(value), ErrInvalidTagMatch).WithUserFacing()
 	}
 }
 
@@ -155,6 +318,7 @@ func marshalSearchResults(notes []*agent.MemoryNote) (string, error) {
 			ID:                 string(note.ID),
 			SourceType:         string(note.SourceType),
 			Summary:            note.Summary,
+			Score:              note.Score,
 			Importance:         note.Importance,
 		}
 	}
@@ -171,21 +335,125 @@ func marshalSearchResults(notes []*agent.MemoryNote) (string, error) {
 	return string(output), nil
 }
 
+// MemorySessionSummary summarizes the notes of the service's session (see WithSessionID)
+// as a bullet list, most important and most recent first. The number of notes and the
+// total length of the summary are capped; notes beyond the caps are counted but omitted.
+func (s *MemoryToolService) MemorySessionSummary(ctx context.Context, arguments string) (string, error) {
+	var args memorySessionSummaryArgs
+	if err := agent.DecodeArgs(arguments, &args); err != nil {
+		return "", fmt.Errorf("failed to parse arguments: %w", err)
+	}
+	if s.session == "" {
+		return "", agent.NewToolError("memory_session_summary", "cannot summarize", ErrSessionRequired).WithUserFacing()
+	}
+
+	notes, err := s.store.Search(ctx, "", 0, &agent.MemorySearchOptions{SessionID: s.session})
+	if err != nil {
+		return "", fmt.Errorf("failed to search memory: %w", err)
+	}
+	sortByImportanceAndRecency(notes)
+
+	result := summarizeNotes(notes, defaultLimit(args.Limit, sessionSummaryMaxNotes), defaultLimit(args.MaxChars, sessionSummaryMaxChars))
+	result.SessionID = s.session
+
+	output, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal summary: %w", err)
+	}
+
+	return string(output), nil
+}
+
+// sortByImportanceAndRecency sorts notes by importance, then by last update, newest first.
+func sortByImportanceAndRecency(notes []*agent.MemoryNote) {
+	lastChange := func(note *agent.MemoryNote) time.Time {
+		if note.UpdatedAt.IsZero() {
+			return note.CreatedAt
+		}
+		return note.UpdatedAt
+	}
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].Importance != notes[j].Importance {
+			return notes[i].Importance > notes[j].Importance
+		}
+		return lastChange(notes[i]).After(lastChange(notes[j]))
+	})
+}
+
+// summarizeNotes renders one bullet per note until maxNotes notes or maxChars characters are reached.
+func summarizeNotes(notes []*agent.MemoryNote, maxNotes, maxChars int) memorySessionSummaryResult {
+	result := memorySessionSummaryResult{
+		NoteIDs: []string{},
+		Status:  "success",
+		Total:   len(notes),
+	}
+	var b strings.Builder
+	for _, note := range notes {
+		text := note.Summary
+		if text == "" {
+			text = note.RawContent
+		}
+		bullet := fmt.Sprintf("- [%s] %s\n", note.SourceType, truncateRunes(strings.Join(strings.Fields(text), " "), sessionSummaryBulletChars))
+		if result.Count == maxNotes || b.Len()+len(bullet) > maxChars {
+			break
+		}
+		b.WriteString(bullet)
+		result.NoteIDs = append(result.NoteIDs, string(note.ID))
+		result.Count++
+	}
+	result.Summary = strings.TrimSuffix(b.String(), "\n")
+	result.Truncated = result.Count < result.Total
+	return result
+}
+
+// truncateRunes shortens s to at most limit runes, marking the cut with "…".
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
 // MemoryWrite stores a new memory note.
+// A write repeating the idempotency_key of a recent write returns the note ID of
+// that write without writing again, so a retried call does not create a duplicate.
 func (s *MemoryToolService) MemoryWrite(ctx context.Context, arguments string) (string, error) {
 	var args memoryWriteArgs
 	if err := agent.DecodeArgs(arguments, &args); err != nil {
 		return "", fmt.Errorf("failed to parse arguments: %w", err)
 	}
 
-	note := s.buildNote(args)
-	s.applyEmbedding(ctx, note)
+	write := func() (agent.NoteID, error) {
+		note := s.buildNote(args)
+		s.applyEmbedding(ctx, note)
+		if err := s.store.Write(ctx, note); err != nil {
+			return "", fmt.Errorf("failed to write memory note: %w", err)
+		}
+		return note.ID, nil
+	}
+
+	if args.IdempotencyKey == "" {
+		id, err := write()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(`{"status": "success", "note_id": "%s"}`, id), nil
+	}
 
-	if err := s.store.Write(ctx, note); err != nil {
-		return "", fmt.Errorf("failed to write memory note: %w", err)
+	id, duplicate, err := s.recent.do(args.IdempotencyKey, write)
+	if err != nil {
+		return "", err
 	}
+	return fmt.Sprintf(`{"status": "success", "note_id": "%s", "duplicate": %t}`, id, duplicate), nil
+}
 
-	return fmt.Sprintf(`{"status": "success", "note_id": "%s"}`, note.ID), nil
+// WithAccessTracking counts every note returned by memory_get and memory_search
+// as accessed, if the store implements agent.AccessRecorder.
+// The counts let memorizing.Service promote frequently recalled notes.
+func (s *MemoryToolService) WithAccessTracking() *MemoryToolService {
+	s.trackAccess = true
+	return s
 }
 
 // WithEmbedder sets the embedding client for generating note embeddings.
@@ -195,6 +463,21 @@ func (s *MemoryToolService) WithEmbedder(embedder agent.EmbeddingClient) *Memory
 	return s
 }
 
+// WithEmbeddingWeights embeds notes from SearchableTextWeighted with the given weights
+// instead of SearchableText, e.g. to emphasize the summary. Only applies with WithEmbedder.
+func (s *MemoryToolService) WithEmbeddingWeights(weights agent.SearchableWeights) *MemoryToolService {
+	s.weights = &weights
+	return s
+}
+
+// WithLogger sets an optional structured logger.
+// When set, the service logs when a search falls back to text search
+// and when a note is stored without an embedding because embedding failed.
+func (s *MemoryToolService) WithLogger(logger *slog.Logger) *MemoryToolService {
+	s.logger = logger
+	return s
+}
+
 // WithSessionID sets the default session ID for notes.
 func (s *MemoryToolService) WithSessionID(sessionID string) *MemoryToolService {
 	s.session = sessionID
@@ -212,11 +495,19 @@ func (s *MemoryToolService) applyEmbedding(ctx context.Context, note *agent.Memo
 	if s.embedder == nil {
 		return
 	}
-	embedding, err := s.embedder.Embed(ctx, note.SearchableText())
+	text := note.SearchableText()
+	if s.weights != nil {
+		text = note.SearchableTextWeighted(*s.weights)
+	}
+	embedding, err := s.embedder.Embed(ctx, text)
 	if err == nil && len(embedding) > 0 {
 		note.WithEmbedding(embedding)
+		return
+	}
+	// Skip the embedding on error - the note is still useful without it
+	if err != nil && s.logger != nil {
+		s.logger.Warn("note embedding failed, storing note without embedding", "id", note.ID, "error", err)
 	}
-	// Silently skip embedding on error - note is still useful without it
 }
 
 // applyScopeIDs sets user, session, and task IDs on the note.
@@ -240,7 +531,7 @@ func (s *MemoryToolService) applyScopeIDs(note *agent.MemoryNote, args memoryWri
 
 // buildNote creates a MemoryNote from write arguments.
 func (s *MemoryToolService) buildNote(args memoryWriteArgs) *agent.MemoryNote {
-	noteID := agent.NoteID(s.idGen())
+	noteID := agent.NoteID(s.idGen.NewID())
 	sourceType := mapSourceType(args.SourceType)
 
 	note := agent.NewMemoryNote(noteID, sourceType).
@@ -255,6 +546,28 @@ func (s *MemoryToolService) buildNote(args memoryWriteArgs) *agent.MemoryNote {
 	return note
 }
 
+// recordAccess counts the notes as accessed if access tracking is enabled.
+// Failures are logged and ignored, so tracking never fails a retrieval.
+func (s *MemoryToolService) recordAccess(ctx context.Context, notes ...*agent.MemoryNote) {
+	recorder, ok := s.store.(agent.AccessRecorder)
+	if !s.trackAccess || !ok {
+		return
+	}
+	for _, note := range notes {
+		if err := recorder.RecordAccess(ctx, note.ID); err != nil && s.logger != nil {
+			s.logger.Warn("recording note access failed", "id", note.ID, "error", err)
+		}
+	}
+}
+
+// search ranks notes by embedding similarity if possible, otherwise by text relevance.
+func (s *MemoryToolService) search(ctx context.Context, query string, limit int, opts *agent.MemorySearchOptions) ([]*agent.MemoryNote, error) {
+	return memorizing.NewService(s.store).
+		WithEmbedder(s.embedder).
+		WithLogger(s.logger).
+		SmartSearch(ctx, query, limit, opts)
+}
+
 // mapSourceType maps a string to a SourceType.
 func mapSourceType(s string) agent.SourceType {
 	return agent.ParseSourceType(s)
@@ -272,6 +585,36 @@ func mapSourceTypes(strs []string) []agent.SourceType {
 	return result
 }
 
+// NewMemoryDeleteMatchingTool creates the memory_delete_matching tool definition.
+func NewMemoryDeleteMatchingTool(svc *MemoryToolService) agent.Tool {
+	return agent.Tool{
+		ID: "memory_delete_matching",
+		Definition: agent.NewToolDefinition("memory_delete_matching", "Delete all memory notes matching the filters and return how many were deleted. Use only when the user explicitly asks to clean up memory; at least one filter is required.").
+			WithParameterDef(agent.NewParameterDefinition("confirm", agent.ParamTypeBoolean).
+				WithDescription("Must be true to delete; guards against accidental mass deletion").
+				WithRequired()).
+			WithParameterDef(agent.NewParameterDefinition("source_types", agent.ParamTypeArray).
+				WithDescription("Delete notes of these source types: decision, experiment, external_source, fact, issue, plan_step, preference, requirement, retrospective, summary, tool_result, user_message")).
+			WithParameterDef(agent.NewParameterDefinition("tags", agent.ParamTypeArray).
+				WithDescription("Delete notes with these tags (any match)")).
+			WithParameterDef(agent.NewParameterDefinition("tag_match", agent.ParamTypeString).
+				WithDescription("How tags are matched: exact (default), prefix, or glob").
+				WithEnum(string(agent.TagMatchExact), string(agent.TagMatchPrefix), string(agent.TagMatchGlob)).
+				WithDefault(string(agent.TagMatchExact))).
+			WithParameterDef(agent.NewParameterDefinition("require_all_tags", agent.ParamTypeBoolean).
+				WithDescription("Only delete notes that have every listed tag (default: false = any tag)")).
+			WithParameterDef(agent.NewParameterDefinition("min_importance", agent.ParamTypeInteger).
+				WithDescription("Delete notes with at least this importance (1-5)")).
+			WithParameterDef(agent.NewParameterDefinition("max_importance", agent.ParamTypeInteger).
+				WithDescription("Delete notes with at most this importance (1-5)")).
+			WithParameterDef(agent.NewParameterDefinition("created_after", agent.ParamTypeString).
+				WithDescription("Delete notes created after this RFC3339 timestamp (e.g., 2024-01-15T10:00:00Z)")).
+			WithParameterDef(agent.NewParameterDefinition("created_before", agent.ParamTypeString).
+				WithDescription("Delete notes created before this RFC3339 timestamp (e.g., 2024-01-15T10:00:00Z)")),
+		Func: svc.MemoryDeleteMatching,
+	}
+}
+
 // NewMemoryGetTool creates the memory_get tool definition.
 func NewMemoryGetTool(svc *MemoryToolService) agent.Tool {
 	return agent.Tool{
@@ -279,7 +622,9 @@ func NewMemoryGetTool(svc *MemoryToolService) agent.Tool {
 		Definition: agent.NewToolDefinition("memory_get", "Retrieve full details of a specific memory note by ID. Use after memory_search returns relevant IDs.").
 			WithParameterDef(agent.NewParameterDefinition("id", agent.ParamTypeString).
 				WithDescription("The unique identifier of the note to retrieve").
-				WithRequired()),
+				WithRequired()).
+			WithParameterDef(agent.NewParameterDefinition("include_history", agent.ParamTypeBoolean).
+				WithDescription("Also return earlier versions of the note, oldest first, e.g. to answer what was believed before")),
 		Func: svc.MemoryGet,
 	}
 }
@@ -303,12 +648,37 @@ func NewMemorySearchTool(svc *MemoryToolService) agent.Tool {
 				WithDescription("Filter by user ID")).
 			WithParameterDef(agent.NewParameterDefinition("session_id", agent.ParamTypeString).
 				WithDescription("Filter by session ID")).
+			WithParameterDef(agent.NewParameterDefinition("keywords", agent.ParamTypeArray).
+				WithDescription("Filter by keywords (any match)")).
 			WithParameterDef(agent.NewParameterDefinition("tags", agent.ParamTypeArray).
-				WithDescription("Filter by tags (any match)")),
+				WithDescription("Filter by tags (any match)")).
+			WithParameterDef(agent.NewParameterDefinition("tag_match", agent.ParamTypeString).
+				WithDescription("How tags are matched: exact (default), prefix (config* matches config-db), or glob (shell patterns like *-db)").
+				WithEnum(string(agent.TagMatchExact), string(agent.TagMatchPrefix), string(agent.TagMatchGlob)).
+				WithDefault(string(agent.TagMatchExact))).
+			WithParameterDef(agent.NewParameterDefinition("require_all_tags", agent.ParamTypeBoolean).
+				WithDescription("Only return notes that have every listed tag (default: false = any tag)")).
+			WithParameterDef(agent.NewParameterDefinition("whole_word", agent.ParamTypeBoolean).
+				WithDescription("Match query terms as whole words, so 'art' does not match 'start' (default: false)")),
 		Func: svc.MemorySearch,
 	}
 }
 
+// NewMemorySessionSummaryTool creates the memory_session_summary tool definition.
+func NewMemorySessionSummaryTool(svc *MemoryToolService) agent.Tool {
+	return agent.Tool{
+		ID: "memory_session_summary",
+		Definition: agent.NewToolDefinition("memory_session_summary", "Summarize the memory notes of the current session as bullets, most important first. Use when the user asks to be caught up on this session.").
+			WithParameterDef(agent.NewParameterDefinition("limit", agent.ParamTypeInteger).
+				WithDescription("Maximum number of notes to summarize (default: 10)").
+				WithDefault("10")).
+			WithParameterDef(agent.NewParameterDefinition("max_chars", agent.ParamTypeInteger).
+				WithDescription("Maximum length of the summary in characters (default: 2000)").
+				WithDefault("2000")),
+		Func: svc.MemorySessionSummary,
+	}
+}
+
 // NewMemoryWriteTool creates the memory_write tool definition.
 func NewMemoryWriteTool(svc *MemoryToolService) agent.Tool {
 	return agent.Tool{
@@ -332,7 +702,9 @@ func NewMemoryWriteTool(svc *MemoryToolService) agent.Tool {
 				WithDescription("Tags like: preference, config, api_result, task_summary, bug, codebase_fact")).
 			WithParameterDef(agent.NewParameterDefinition("importance", agent.ParamTypeInteger).
 				WithDescription("1-5 importance score: 1=minor session info, 5=critical preference").
-				WithDefault("2")),
+				WithDefault("2")).
+			WithParameterDef(agent.NewParameterDefinition("idempotency_key", agent.ParamTypeString).
+				WithDescription("Unique key for this write; retrying with the same key returns the original note instead of storing a duplicate")),
 		Func: svc.MemoryWrite,
 	}
 }
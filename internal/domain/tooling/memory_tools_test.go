@@ -1,13 +1,19 @@
 package tooling_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/andygeiss/cloud-native-utils/assert"
 	"github.com/andygeiss/go-agent/internal/domain/agent"
+	"github.com/andygeiss/go-agent/internal/domain/memorizing"
+	"github.com/andygeiss/go-agent/internal/domain/openai"
 	"github.com/andygeiss/go-agent/internal/domain/tooling"
 )
 
@@ -16,11 +22,13 @@ var ErrMockNotFound = errors.New("note not found")
 
 // mockMemoryStore is a test double for the MemoryStore interface.
 type mockMemoryStore struct {
-	notes       map[agent.NoteID]*agent.MemoryNote
-	writeErr    error
-	searchErr   error
-	getErr      error
-	searchNotes []*agent.MemoryNote
+	notes        map[agent.NoteID]*agent.MemoryNote
+	writeErr     error
+	searchErr    error
+	getErr       error
+	searchOpts   *agent.MemorySearchOptions
+	searchNotes  []*agent.MemoryNote
+	searchByUser bool // Search returns only the searchNotes of opts.UserID, if set
 }
 
 func newMockMemoryStore() *mockMemoryStore {
@@ -37,10 +45,20 @@ func (m *mockMemoryStore) Write(_ context.Context, note *agent.MemoryNote) error
 	return nil
 }
 
-func (m *mockMemoryStore) Search(_ context.Context, _ string, limit int, _ *agent.MemorySearchOptions) ([]*agent.MemoryNote, error) {
+func (m *mockMemoryStore) Search(_ context.Context, _ string, limit int, opts *agent.MemorySearchOptions) ([]*agent.MemoryNote, error) {
+	m.searchOpts = opts
 	if m.searchErr != nil {
 		return nil, m.searchErr
 	}
+	if m.searchNotes != nil && m.searchByUser && opts != nil && opts.UserID != "" {
+		var notes []*agent.MemoryNote
+		for _, note := range m.searchNotes {
+			if note.UserID == opts.UserID {
+				notes = append(notes, note)
+			}
+		}
+		return notes, nil
+	}
 	if m.searchNotes != nil {
 		if limit > 0 && limit < len(m.searchNotes) {
 			return m.searchNotes[:limit], nil
@@ -66,12 +84,12 @@ func (m *mockMemoryStore) Delete(_ context.Context, id agent.NoteID) error {
 	return nil
 }
 
-func testIDGenerator() func() string {
+func testIDGenerator() agent.IDGenerator {
 	counter := 0
-	return func() string {
+	return agent.IDGeneratorFunc(func() string {
 		counter++
 		return "test-note-" + string(rune('0'+counter))
-	}
+	})
 }
 
 func Test_MemoryToolService_MemoryWrite_Should_StoreNote(t *testing.T) {
@@ -227,6 +245,48 @@ func Test_MemoryToolService_MemoryGet_Should_ReturnNote(t *testing.T) {
 	assert.That(t, "raw content must match", note["raw_content"], "User prefers German")
 }
 
+func Test_MemoryToolService_MemoryGet_With_IncludeHistory_Should_ReturnVersions(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	note := agent.NewMemoryNote("note-123", agent.SourceTypePreference).WithRawContent("User prefers Go")
+	note.History = []agent.NoteVersion{{RawContent: "User prefers Java"}, {RawContent: "User prefers Rust"}}
+	store.notes["note-123"] = note
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+
+	// Act
+	result, err := svc.MemoryGet(context.Background(), `{"id": "note-123", "include_history": true}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	var response struct {
+		Note struct {
+			History []agent.NoteVersion `json:"history"`
+		} `json:"note"`
+	}
+	_ = json.Unmarshal([]byte(result), &response)
+	assert.That(t, "history must be returned", len(response.Note.History), 2)
+	assert.That(t, "oldest version must come first", response.Note.History[0].RawContent, "User prefers Java")
+}
+
+func Test_MemoryToolService_MemoryGet_Without_IncludeHistory_Should_OmitHistory(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	note := agent.NewMemoryNote("note-123", agent.SourceTypePreference).WithRawContent("User prefers Go")
+	note.History = []agent.NoteVersion{{RawContent: "User prefers Java"}}
+	store.notes["note-123"] = note
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+
+	// Act
+	result, err := svc.MemoryGet(context.Background(), `{"id": "note-123"}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	var response map[string]any
+	_ = json.Unmarshal([]byte(result), &response)
+	_, ok := response["note"].(map[string]any)["history"]
+	assert.That(t, "history must be omitted", ok, false)
+}
+
 func Test_MemoryToolService_MemoryGet_WithNonexistent_Should_ReturnNotFound(t *testing.T) {
 	// Arrange
 	store := newMockMemoryStore()
@@ -243,6 +303,193 @@ func Test_MemoryToolService_MemoryGet_WithNonexistent_Should_ReturnNotFound(t *t
 	assert.That(t, "result must be empty on error", result, "")
 }
 
+// accessRecordingMemoryStore is a mockMemoryStore that counts note accesses.
+type accessRecordingMemoryStore struct {
+	*mockMemoryStore
+	accesses map[agent.NoteID]int
+}
+
+func newAccessRecordingMemoryStore() *accessRecordingMemoryStore {
+	return &accessRecordingMemoryStore{
+		mockMemoryStore: newMockMemoryStore(),
+		accesses:        make(map[agent.NoteID]int),
+	}
+}
+
+func (m *accessRecordingMemoryStore) RecordAccess(_ context.Context, id agent.NoteID) error {
+	m.accesses[id]++
+	return nil
+}
+
+func Test_MemoryToolService_MemoryGet_With_AccessTracking_Should_RecordAccess(t *testing.T) {
+	// Arrange
+	store := newAccessRecordingMemoryStore()
+	store.notes["note-1"] = agent.NewFactNote("note-1", "Go is fun")
+	svc := tooling.NewMemoryToolService(store, testIDGenerator()).WithAccessTracking()
+
+	// Act
+	_, _ = svc.MemoryGet(context.Background(), `{"id": "note-1"}`)
+	_, err := svc.MemoryGet(context.Background(), `{"id": "note-1"}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "each get must be recorded", store.accesses["note-1"], 2)
+}
+
+func Test_MemoryToolService_MemorySearch_With_AccessTracking_Should_RecordEveryResult(t *testing.T) {
+	// Arrange
+	store := newAccessRecordingMemoryStore()
+	store.searchNotes = []*agent.MemoryNote{agent.NewFactNote("note-1", "a"), agent.NewFactNote("note-2", "b")}
+	svc := tooling.NewMemoryToolService(store, testIDGenerator()).WithAccessTracking()
+
+	// Act
+	_, err := svc.MemorySearch(context.Background(), `{"query": "x"}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "accesses must be recorded per result", store.accesses, map[agent.NoteID]int{"note-1": 1, "note-2": 1})
+}
+
+func Test_MemoryToolService_MemoryGet_Without_AccessTracking_Should_NotRecordAccess(t *testing.T) {
+	// Arrange
+	store := newAccessRecordingMemoryStore()
+	store.notes["note-1"] = agent.NewFactNote("note-1", "Go is fun")
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+
+	// Act
+	_, _ = svc.MemoryGet(context.Background(), `{"id": "note-1"}`)
+
+	// Assert
+	assert.That(t, "no access must be recorded", len(store.accesses), 0)
+}
+
+func Test_ExportToolsJSON_With_MemoryTools_Should_MatchSchema(t *testing.T) {
+	// Arrange
+	svc := tooling.NewMemoryToolService(newMockMemoryStore(), testIDGenerator())
+	defs := []agent.ToolDefinition{
+		tooling.NewMemoryGetTool(svc).Definition,
+		tooling.NewMemorySearchTool(svc).Definition,
+		tooling.NewMemoryWriteTool(svc).Definition,
+	}
+
+	// Act
+	document, err := agent.ExportToolsJSON(defs)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	var tools []openai.Tool
+	assert.That(t, "document must be valid JSON", json.Unmarshal(document, &tools), nil)
+	assert.That(t, "every tool must be exported", len(tools), 3)
+
+	get := tools[0].Function.Parameters
+	assert.That(t, "memory_get must require id", get.Required, []string{"id"})
+	assert.That(t, "include_history must be a boolean", get.Properties["include_history"].Type, "boolean")
+
+	search := tools[1].Function.Parameters
+	assert.That(t, "memory_search must require query", search.Required, []string{"query"})
+	assert.That(t, "limit must be an integer", search.Properties["limit"].Type, "integer")
+	assert.That(t, "tags must be an array", search.Properties["tags"].Type, "array")
+	assert.That(t, "tag_match must list its modes", search.Properties["tag_match"].Enum, []string{"exact", "prefix", "glob"})
+
+	write := tools[2].Function.Parameters
+	assert.That(t, "memory_write must require its content", write.Required, []string{"source_type", "raw_content", "summary"})
+	assert.That(t, "source_type must list every type", len(write.Properties["source_type"].Enum), 12)
+	assert.That(t, "importance must be an integer", write.Properties["importance"].Type, "integer")
+}
+
+// newDeleteMatchingStore returns a store with three notes, of which the search returns the two stale ones.
+func newDeleteMatchingStore() *mockMemoryStore {
+	store := newMockMemoryStore()
+	for _, id := range []agent.NoteID{"keep", "stale-1", "stale-2"} {
+		store.notes[id] = agent.NewFactNote(id, string(id))
+	}
+	store.searchNotes = []*agent.MemoryNote{store.notes["stale-1"], store.notes["stale-2"]}
+	return store
+}
+
+func Test_MemoryToolService_MemoryDeleteMatching_Should_DeleteOnlyMatchingNotes(t *testing.T) {
+	// Arrange
+	store := newDeleteMatchingStore()
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+	args := `{"confirm": true, "tags": ["stale"], "max_importance": 2, "created_before": "2025-01-01T00:00:00Z"}`
+
+	// Act
+	result, err := svc.MemoryDeleteMatching(context.Background(), args)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "result must report the count", result, `{"status": "success", "deleted": 2}`)
+	_, kept := store.notes["keep"]
+	assert.That(t, "non-matching note must be kept", kept, true)
+	assert.That(t, "only the non-matching note must remain", len(store.notes), 1)
+	assert.That(t, "tags must be passed", store.searchOpts.Tags, []string{"stale"})
+	assert.That(t, "max importance must be passed", store.searchOpts.MaxImportance, 2)
+	assert.That(t, "created before must be parsed", store.searchOpts.CreatedBefore, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func Test_MemoryToolService_MemoryDeleteMatching_Without_Confirm_Should_ReturnErrorAndKeepNotes(t *testing.T) {
+	// Arrange
+	store := newDeleteMatchingStore()
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+
+	// Act
+	_, err := svc.MemoryDeleteMatching(context.Background(), `{"tags": ["stale"]}`)
+
+	// Assert
+	assert.That(t, "error must be ErrConfirmRequired", errors.Is(err, tooling.ErrConfirmRequired), true)
+	assert.That(t, "no note must be deleted", len(store.notes), 3)
+}
+
+func Test_MemoryToolService_MemoryDeleteMatching_Without_Filters_Should_ReturnErrorAndKeepNotes(t *testing.T) {
+	// Arrange
+	store := newDeleteMatchingStore()
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+
+	// Act
+	_, err := svc.MemoryDeleteMatching(context.Background(), `{"confirm": true}`)
+
+	// Assert
+	assert.That(t, "error must be ErrFilterEmpty", errors.Is(err, memorizing.ErrFilterEmpty), true)
+	assert.That(t, "no note must be deleted", len(store.notes), 3)
+}
+
+func Test_MemoryToolService_MemoryDeleteMatching_With_InvalidTimestamp_Should_ReturnError(t *testing.T) {
+	// Arrange
+	store := newDeleteMatchingStore()
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+
+	// Act
+	_, err := svc.MemoryDeleteMatching(context.Background(), `{"confirm": true, "created_after": "yesterday"}`)
+
+	// Assert
+	assert.That(t, "error must not be nil", err != nil, true)
+	assert.That(t, "no note must be deleted", len(store.notes), 3)
+}
+
+func Test_MemoryToolService_MemoryDeleteMatching_With_UserID_Should_KeepOtherUsersNotes(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	alice := agent.NewFactNote("alice-stale", "stale").WithUserID("alice")
+	bob := agent.NewFactNote("bob-stale", "stale").WithUserID("bob")
+	store.notes[alice.ID] = alice
+	store.notes[bob.ID] = bob
+	store.searchNotes = []*agent.MemoryNote{alice, bob}
+	store.searchByUser = true
+	svc := tooling.NewMemoryToolService(store, testIDGenerator()).WithUserID("alice")
+
+	// Act
+	result, err := svc.MemoryDeleteMatching(context.Background(), `{"confirm": true, "min_importance": 1}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "result must report the count", result, `{"status": "success", "deleted": 1}`)
+	assert.That(t, "search must be scoped to the user", store.searchOpts.UserID, "alice")
+	_, aliceKept := store.notes["alice-stale"]
+	_, bobKept := store.notes["bob-stale"]
+	assert.That(t, "own note must be deleted", aliceKept, false)
+	assert.That(t, "other user's note must be kept", bobKept, true)
+}
+
 func Test_NewMemoryWriteTool_Should_CreateValidTool(t *testing.T) {
 	// Arrange
 	store := newMockMemoryStore()
@@ -341,6 +588,113 @@ func Test_MemoryToolService_MemorySearch_WithSourceTypesFilter_Should_PassToStor
 	assert.That(t, "result must not be empty", result != "", true)
 }
 
+func Test_MemoryToolService_MemorySearch_WithTagMatch_Should_PassToStore(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+	args := `{"query": "test", "tags": ["config*"], "tag_match": "prefix"}`
+
+	// Act
+	_, err := svc.MemorySearch(context.Background(), args)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "tag match must be passed", store.searchOpts.TagMatch, agent.TagMatchPrefix)
+	assert.That(t, "tags must be passed", store.searchOpts.Tags, []string{"config*"})
+}
+
+func Test_MemoryToolService_MemorySearch_WithRequireAllTags_Should_PassToStore(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+	args := `{"query": "test", "tags": ["go", "api"], "require_all_tags": true}`
+
+	// Act
+	_, err := svc.MemorySearch(context.Background(), args)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "require all tags must be passed", store.searchOpts.RequireAllTags, true)
+}
+
+func Test_MemoryToolService_MemorySearch_WithOnlyTagMatchOptions_Should_PassToStore(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+	args := `{"query": "test", "tag_match": "glob", "require_all_tags": true}`
+
+	// Act
+	_, err := svc.MemorySearch(context.Background(), args)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "options must be passed", store.searchOpts != nil, true)
+	assert.That(t, "tag match must be passed", store.searchOpts.TagMatch, agent.TagMatchGlob)
+	assert.That(t, "require all tags must be passed", store.searchOpts.RequireAllTags, true)
+}
+
+func Test_MemoryToolService_MemorySearch_WithUnknownTagMatch_Should_ReturnUserFacingError(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+	args := `{"query": "test", "tags": ["go"], "tag_match": "fuzzy"}`
+
+	// Act
+	_, err := svc.MemorySearch(context.Background(), args)
+
+	// Assert
+	var toolErr *agent.ToolError
+	assert.That(t, "error must be ErrInvalidTagMatch", errors.Is(err, tooling.ErrInvalidTagMatch), true)
+	assert.That(t, "error must be a tool error", errors.As(err, &toolErr), true)
+	assert.That(t, "error must be user facing", toolErr.UserFacing, true)
+	assert.That(t, "store must not be searched", store.searchOpts == nil, true)
+}
+
+func Test_MemoryToolService_MemorySearch_WithKeywords_Should_PassToStore(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+	args := `{"query": "test", "keywords": ["postgres"], "source_types": ["fact"]}`
+
+	// Act
+	_, err := svc.MemorySearch(context.Background(), args)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "keywords must be passed", store.searchOpts.Keywords, []string{"postgres"})
+	assert.That(t, "source types must be passed", store.searchOpts.SourceTypes, []agent.SourceType{agent.SourceTypeFact})
+}
+
+func Test_MemoryToolService_MemorySearch_WithWholeWord_Should_PassToStore(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+	args := `{"query": "art", "whole_word": true}`
+
+	// Act
+	_, err := svc.MemorySearch(context.Background(), args)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "whole word must be passed", store.searchOpts.WholeWord, true)
+}
+
+func Test_MemoryToolService_MemorySearch_Should_IncludeScore(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	note := agent.NewMemoryNote("note-1", agent.SourceTypeFact).WithRawContent("fact")
+	note.Score = 4
+	store.searchNotes = []*agent.MemoryNote{note}
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+
+	// Act
+	result, err := svc.MemorySearch(context.Background(), `{"query": "fact"}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "score must be in output", strings.Contains(result, `"score":4`), true)
+}
+
 func Test_MemoryToolService_MemorySearch_WithMinImportance_Should_PassToStore(t *testing.T) {
 	// Arrange
 	store := newMockMemoryStore()
@@ -460,6 +814,21 @@ func Test_MemoryToolService_WithEmbedder_Should_GenerateEmbedding(t *testing.T)
 	}
 }
 
+func Test_MemoryToolService_WithEmbeddingWeights_Should_EmbedWeightedText(t *testing.T) {
+	// Arrange
+	embedder := &mockEmbeddingClient{embedding: agent.Embedding{0.1, 0.2, 0.3}}
+	svc := tooling.NewMemoryToolService(newMockMemoryStore(), testIDGenerator()).
+		WithEmbedder(embedder).
+		WithEmbeddingWeights(agent.SearchableWeights{RawContent: 1, Summary: 3})
+
+	// Act
+	_, err := svc.MemoryWrite(context.Background(), `{"source_type": "fact", "raw_content": "The sky is blue", "summary": "Sky color", "tags": ["science"]}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "embedder must receive the weighted text", embedder.lastInput, "Sky color Sky color Sky color The sky is blue")
+}
+
 func Test_MemoryToolService_WithEmbedder_Should_UseSearchableText(t *testing.T) {
 	// Arrange
 	store := newMockMemoryStore()
@@ -520,6 +889,24 @@ func Test_MemoryToolService_WithEmbedder_OnError_Should_StillStoreNote(t *testin
 	}
 }
 
+func Test_MemoryToolService_WithEmbedder_OnError_Should_LogFailure(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	var logs bytes.Buffer
+	svc := tooling.NewMemoryToolService(store, testIDGenerator()).
+		WithEmbedder(&mockEmbeddingClient{err: errors.New("embedding service unavailable")}).
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	// Act
+	_, err := svc.MemoryWrite(context.Background(), `{"source_type": "fact", "raw_content": "Test content", "summary": "Test summary"}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "store must have 1 note", len(store.notes), 1)
+	assert.That(t, "failure must be logged", strings.Contains(logs.String(), "note embedding failed"), true)
+	assert.That(t, "cause must be logged", strings.Contains(logs.String(), "embedding service unavailable"), true)
+}
+
 func Test_MemoryToolService_WithoutEmbedder_Should_NotGenerateEmbedding(t *testing.T) {
 	// Arrange
 	store := newMockMemoryStore()
@@ -549,6 +936,65 @@ func Test_MemoryToolService_WithoutEmbedder_Should_NotGenerateEmbedding(t *testi
 }
 
 // containsSubstring checks if s contains substr (helper for tests).
+// mockEmbeddingMemoryStore is a mock store that can also rank notes by embedding.
+type mockEmbeddingMemoryStore struct {
+	*mockMemoryStore
+	embeddingNotes []*agent.MemoryNote
+	embeddingCalls int
+}
+
+func (m *mockEmbeddingMemoryStore) SearchWithEmbedding(_ context.Context, _ string, _ agent.Embedding, _ int, _ *agent.MemorySearchOptions) ([]*agent.MemoryNote, error) {
+	m.embeddingCalls++
+	return m.embeddingNotes, nil
+}
+
+func newMockEmbeddingMemoryStore() *mockEmbeddingMemoryStore {
+	store := &mockEmbeddingMemoryStore{mockMemoryStore: newMockMemoryStore()}
+	store.searchNotes = []*agent.MemoryNote{
+		agent.NewMemoryNote("text-1", agent.SourceTypeFact).WithSummary("text result"),
+	}
+	store.embeddingNotes = []*agent.MemoryNote{
+		agent.NewMemoryNote("semantic-1", agent.SourceTypeFact).WithSummary("semantic result"),
+	}
+	return store
+}
+
+func Test_MemoryToolService_MemorySearch_WithEmbedder_Should_RankByEmbedding(t *testing.T) {
+	// Arrange
+	store := newMockEmbeddingMemoryStore()
+	embedder := &mockEmbeddingClient{embedding: agent.Embedding{0.1, 0.2, 0.3}}
+	svc := tooling.NewMemoryToolService(store, testIDGenerator()).
+		WithEmbedder(embedder)
+
+	// Act
+	result, err := svc.MemorySearch(context.Background(), `{"query": "golang"}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "embedder must receive the query", embedder.lastInput, "golang")
+	assert.That(t, "store must be searched by embedding", store.embeddingCalls, 1)
+	assert.That(t, "result must contain semantic note", strings.Contains(result, "semantic-1"), true)
+}
+
+func Test_MemoryToolService_MemorySearch_WithFailingEmbedder_Should_FallBackToTextSearch(t *testing.T) {
+	// Arrange
+	store := newMockEmbeddingMemoryStore()
+	embedder := &mockEmbeddingClient{err: errors.New("embedding service unavailable")}
+	var logs bytes.Buffer
+	svc := tooling.NewMemoryToolService(store, testIDGenerator()).
+		WithEmbedder(embedder).
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	// Act
+	result, err := svc.MemorySearch(context.Background(), `{"query": "golang"}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	assert.That(t, "store must not be searched by embedding", store.embeddingCalls, 0)
+	assert.That(t, "result must contain text note", strings.Contains(result, "text-1"), true)
+	assert.That(t, "fallback must be logged", strings.Contains(logs.String(), "falling back to text search"), true)
+}
+
 func containsSubstring(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
 		(len(s) > 0 && len(substr) > 0 && findSubstring(s, substr)))
@@ -562,3 +1008,176 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+// sessionMemoryStore is a mock store whose Search filters the stored notes by session.
+type sessionMemoryStore struct {
+	*mockMemoryStore
+}
+
+func (m sessionMemoryStore) Search(_ context.Context, _ string, _ int, opts *agent.MemorySearchOptions) ([]*agent.MemoryNote, error) {
+	var notes []*agent.MemoryNote
+	for _, note := range m.notes {
+		if opts == nil || opts.SessionID == "" || note.SessionID == opts.SessionID {
+			notes = append(notes, note)
+		}
+	}
+	return notes, nil
+}
+
+func newSessionMemoryStore() sessionMemoryStore {
+	store := sessionMemoryStore{newMockMemoryStore()}
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, n := range []struct {
+		id, session, summary string
+		importance           int
+	}{
+		{"n1", "s1", "User prefers Go", 3},
+		{"n2", "s1", "Deploy runs on Fridays", 5},
+		{"n3", "s2", "Other session note", 5},
+		{"n4", "s1", "Tests use assert.That", 3},
+	} {
+		note := agent.NewMemoryNote(agent.NoteID(n.id), agent.SourceTypeFact).
+			WithSummary(n.summary).
+			WithSessionID(n.session).
+			WithImportance(n.importance)
+		note.UpdatedAt = base.Add(time.Duration(i) * time.Hour)
+		store.notes[note.ID] = note
+	}
+	return store
+}
+
+func Test_MemoryToolService_MemorySessionSummary_Should_SummarizeOnlyCurrentSession(t *testing.T) {
+	// Arrange
+	svc := tooling.NewMemoryToolService(newSessionMemoryStore(), testIDGenerator()).WithSessionID("s1")
+
+	// Act
+	result, err := svc.MemorySessionSummary(context.Background(), `{}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	var response struct {
+		SessionID string   `json:"session_id"`
+		Summary   string   `json:"summary"`
+		NoteIDs   []string `json:"note_ids"`
+		Count     int      `json:"count"`
+		Truncated bool     `json:"truncated"`
+	}
+	_ = json.Unmarshal([]byte(result), &response)
+	assert.That(t, "session must match", response.SessionID, "s1")
+	assert.That(t, "notes must be sorted by importance, then recency", response.NoteIDs, []string{"n2", "n4", "n1"})
+	assert.That(t, "count must match", response.Count, 3)
+	assert.That(t, "summary must not be truncated", response.Truncated, false)
+	assert.That(t, "summary must not contain other sessions", strings.Contains(response.Summary, "Other session"), false)
+	assert.That(t, "summary must list bullets", strings.HasPrefix(response.Summary, "- [fact] Deploy runs on Fridays"), true)
+}
+
+func Test_MemoryToolService_MemorySessionSummary_With_Limit_Should_CapNotes(t *testing.T) {
+	// Arrange
+	svc := tooling.NewMemoryToolService(newSessionMemoryStore(), testIDGenerator()).WithSessionID("s1")
+
+	// Act
+	result, err := svc.MemorySessionSummary(context.Background(), `{"limit": 1}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	var response map[string]any
+	_ = json.Unmarshal([]byte(result), &response)
+	assert.That(t, "count must be capped", response["count"], float64(1))
+	assert.That(t, "total must count all session notes", response["total"], float64(3))
+	assert.That(t, "summary must be marked truncated", response["truncated"], true)
+}
+
+func Test_MemoryToolService_MemorySessionSummary_With_MaxChars_Should_CapLength(t *testing.T) {
+	// Arrange
+	svc := tooling.NewMemoryToolService(newSessionMemoryStore(), testIDGenerator()).WithSessionID("s1")
+
+	// Act
+	result, err := svc.MemorySessionSummary(context.Background(), `{"max_chars": 40}`)
+
+	// Assert
+	assert.That(t, "error must be nil", err, nil)
+	var response map[string]any
+	_ = json.Unmarshal([]byte(result), &response)
+	assert.That(t, "summary must fit the limit", len(response["summary"].(string)) <= 40, true)
+	assert.That(t, "count must be capped", response["count"], float64(1))
+}
+
+func Test_MemoryToolService_MemorySessionSummary_Without_Session_Should_ReturnError(t *testing.T) {
+	// Arrange
+	svc := tooling.NewMemoryToolService(newSessionMemoryStore(), testIDGenerator())
+
+	// Act
+	_, err := svc.MemorySessionSummary(context.Background(), `{}`)
+
+	// Assert
+	assert.That(t, "err must wrap ErrSessionRequired", errors.Is(err, tooling.ErrSessionRequired), true)
+}
+
+func Test_NewMemorySessionSummaryTool_Should_CreateValidTool(t *testing.T) {
+	// Arrange
+	svc := tooling.NewMemoryToolService(newMockMemoryStore(), testIDGenerator())
+
+	// Act
+	tool := tooling.NewMemorySessionSummaryTool(svc)
+
+	// Assert
+	assert.That(t, "tool id must be memory_session_summary", tool.ID, agent.ToolID("memory_session_summary"))
+	assert.That(t, "tool must have limit param", tool.Definition.HasParameter("limit"), true)
+	assert.That(t, "tool must have max_chars param", tool.Definition.HasParameter("max_chars"), true)
+}
+
+func Test_MemoryToolService_MemoryWrite_With_RepeatedIdempotencyKey_Should_WriteOnce(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+	args := `{"source_type": "fact", "raw_content": "The sky is blue", "summary": "Sky color", "idempotency_key": "write-1"}`
+
+	// Act
+	first, err1 := svc.MemoryWrite(context.Background(), args)
+	second, err2 := svc.MemoryWrite(context.Background(), args)
+
+	// Assert
+	assert.That(t, "first error must be nil", err1, nil)
+	assert.That(t, "second error must be nil", err2, nil)
+	var firstResult, secondResult struct {
+		NoteID    string `json:"note_id"`
+		Duplicate bool   `json:"duplicate"`
+	}
+	_ = json.Unmarshal([]byte(first), &firstResult)
+	_ = json.Unmarshal([]byte(second), &secondResult)
+	assert.That(t, "a single note must exist", len(store.notes), 1)
+	assert.That(t, "the same note ID must be returned", secondResult.NoteID, firstResult.NoteID)
+	assert.That(t, "first write must not be a duplicate", firstResult.Duplicate, false)
+	assert.That(t, "second write must be a duplicate", secondResult.Duplicate, true)
+}
+
+func Test_MemoryToolService_MemoryWrite_With_DifferentIdempotencyKeys_Should_WriteBoth(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+
+	// Act
+	_, _ = svc.MemoryWrite(context.Background(), `{"source_type": "fact", "raw_content": "A", "summary": "A", "idempotency_key": "write-1"}`)
+	_, _ = svc.MemoryWrite(context.Background(), `{"source_type": "fact", "raw_content": "B", "summary": "B", "idempotency_key": "write-2"}`)
+
+	// Assert
+	assert.That(t, "two notes must exist", len(store.notes), 2)
+}
+
+func Test_MemoryToolService_MemoryWrite_With_FailedKeyedWrite_Should_AllowRetry(t *testing.T) {
+	// Arrange
+	store := newMockMemoryStore()
+	store.writeErr = errors.New("temporarily unavailable")
+	svc := tooling.NewMemoryToolService(store, testIDGenerator())
+	args := `{"source_type": "fact", "raw_content": "The sky is blue", "summary": "Sky color", "idempotency_key": "write-1"}`
+	_, firstErr := svc.MemoryWrite(context.Background(), args)
+	store.writeErr = nil
+
+	// Act
+	_, err := svc.MemoryWrite(context.Background(), args)
+
+	// Assert
+	assert.That(t, "first write must fail", firstErr != nil, true)
+	assert.That(t, "retry must succeed", err, nil)
+	assert.That(t, "retry must write the note", len(store.notes), 1)
+}